@@ -5,3 +5,26 @@ type Header struct {
 	mustUnderstand bool
 	data interface{}
 }
+
+// NewHeader returns a header carrying data under name. A header the
+// receiver must understand makes it fail the request when it does not.
+func NewHeader(name string, mustUnderstand bool, data interface{}) *Header {
+	return &Header{name: name, mustUnderstand: mustUnderstand, data: data}
+}
+
+func (h *Header) Name() string {
+	return h.name
+}
+
+func (h *Header) MustUnderstand() bool {
+	return h.mustUnderstand
+}
+
+// Data returns the decoded header value.
+func (h *Header) Data() interface{} {
+	return h.data
+}
+
+func (h *Header) SetData(data interface{}) {
+	h.data = data
+}
@@ -5,3 +5,36 @@ type Message struct {
 	responseUri string
 	data interface{}
 }
+
+// NewMessage returns a message for the service method targetURI, such as
+// "UserService.find", whose response is to be sent to responseURI, such as
+// "/1". The body is an AMF0 value or a value carried after the switch to
+// AMF3 marker; Go values are encoded as by amf0.Encoder.
+func NewMessage(targetURI, responseURI string, data interface{}) *Message {
+	return &Message{targetUri: targetURI, responseUri: responseURI, data: data}
+}
+
+func (m *Message) TargetURI() string {
+	return m.targetUri
+}
+
+func (m *Message) ResponseURI() string {
+	return m.responseUri
+}
+
+// Data returns the decoded message body.
+func (m *Message) Data() interface{} {
+	return m.data
+}
+
+func (m *Message) SetTargetURI(uri string) {
+	m.targetUri = uri
+}
+
+func (m *Message) SetResponseURI(uri string) {
+	m.responseUri = uri
+}
+
+func (m *Message) SetData(data interface{}) {
+	m.data = data
+}
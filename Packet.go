@@ -4,6 +4,10 @@ type Packet struct {
 	version uint16
 	headers []*Header
 	messages []*Message
+	// Trailing holds any bytes found after the last message when decoded
+	// with DecoderOptions.CaptureTrailing. They are written back verbatim by
+	// the Encoder.
+	Trailing []byte
 }
 
 func NewPacket(numHeaders, numMessages int) *Packet {
@@ -13,3 +17,70 @@ func NewPacket(numHeaders, numMessages int) *Packet {
 	
 	return &p
 }
+
+// Version returns the packet envelope version, PacketVersion0 or
+// PacketVersion3.
+func (p *Packet) Version() uint16 {
+	return p.version
+}
+
+func (p *Packet) SetVersion(version uint16) {
+	p.version = version
+}
+
+// Headers returns the headers of the packet. A header not yet set by
+// SetHeader is nil.
+func (p *Packet) Headers() []*Header {
+	return p.headers
+}
+
+// Messages returns the messages of the packet. A message not yet set by
+// SetMessage is nil.
+func (p *Packet) Messages() []*Message {
+	return p.messages
+}
+
+// SetHeader sets the header at index i, as allocated by NewPacket.
+func (p *Packet) SetHeader(i int, h *Header) {
+	p.headers[i] = h
+}
+
+// SetMessage sets the message at index i, as allocated by NewPacket.
+func (p *Packet) SetMessage(i int, m *Message) {
+	p.messages[i] = m
+}
+
+func (p *Packet) AddHeader(h *Header) {
+	p.headers = append(p.headers, h)
+}
+
+func (p *Packet) AddMessage(m *Message) {
+	p.messages = append(p.messages, m)
+}
+
+// RewriteMessageTarget replaces the target URI old by new in every message
+// and returns the number of messages changed. Lengths and counts are worked
+// out by the Encoder, so the packet can be written straight away.
+func (p *Packet) RewriteMessageTarget(old, new string) int {
+	n := 0
+	for _, m := range p.messages {
+		if m != nil && m.targetUri == old {
+			m.targetUri = new
+			n++
+		}
+	}
+	return n
+}
+
+// FilterHeaders keeps the headers for which keep returns true, in order, and
+// drops the others along with unset headers.
+func (p *Packet) FilterHeaders(keep func(h *Header) bool) {
+	headers := p.headers[:0]
+	for _, h := range p.headers {
+		if h != nil && keep(h) {
+			headers = append(headers, h)
+		}
+	}
+	clear(p.headers[len(headers):])
+	p.headers = headers
+}
@@ -0,0 +1,63 @@
+package amf
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/marcuswu/amf/amf0"
+)
+
+func TestPacketRewrite(t *testing.T) {
+	p := NewPacket(0, 0)
+	p.headers = []*Header{
+		{name: "Credentials", mustUnderstand: true},
+		{name: "DescribeService"},
+		nil,
+	}
+	p.messages = []*Message{
+		{targetUri: "old.echo", responseUri: "/1"},
+		{targetUri: "other", responseUri: "/2"},
+		{targetUri: "old.echo", responseUri: "/3"},
+	}
+
+	n := p.RewriteMessageTarget("old.echo", "new.echo")
+	if n != 2 {
+		t.Errorf("expected 2 messages rewritten, got %d", n)
+	}
+	if p.messages[0].TargetURI() != "new.echo" || p.messages[1].TargetURI() != "other" || p.messages[2].TargetURI() != "new.echo" {
+		t.Errorf("unexpected targets %v %v %v", p.messages[0], p.messages[1], p.messages[2])
+	}
+
+	p.FilterHeaders(func(h *Header) bool { return !h.MustUnderstand() })
+	if len(p.headers) != 1 || p.headers[0].Name() != "DescribeService" {
+		t.Errorf("expected only DescribeService to be kept, got %v", p.headers)
+	}
+}
+
+func TestPacketBuild(t *testing.T) {
+	p := NewPacket(0, 1)
+	p.SetVersion(PacketVersion3)
+	p.SetMessage(0, NewMessage("UserService.find", "/1", []interface{}{"ann"}))
+	p.AddHeader(NewHeader("Credentials", true, nil))
+	p.AddMessage(NewMessage("UserService.count", "/2", nil))
+
+	var buf bytes.Buffer
+	err := NewEncoder(&buf).Encode(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewDecoder(&buf).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version() != PacketVersion3 || len(got.Headers()) != 1 || got.Headers()[0].Name() != "Credentials" || !got.Headers()[0].MustUnderstand() {
+		t.Fatalf("unexpected packet %+v", got)
+	}
+	msgs := got.Messages()
+	if len(msgs) != 2 || msgs[0].TargetURI() != "UserService.find" || msgs[0].ResponseURI() != "/1" || msgs[1].ResponseURI() != "/2" {
+		t.Fatalf("unexpected messages %v", msgs)
+	}
+	if args, ok := msgs[0].Data().(*amf0.StrictArrayType); !ok || (*args)[0] != amf0.StringType("ann") {
+		t.Errorf("unexpected body %v", msgs[0].Data())
+	}
+}
@@ -0,0 +1,21 @@
+package amf
+
+import (
+	"reflect"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// RegisterAlias maps the ActionScript class className to the Go struct type
+// t in both object encodings. AMF0 typed objects of the class decode into a
+// pointer to a new value of t, and values of t encode as typed objects of
+// the class in AMF0 and as objects of the class in AMF3. Fields are mapped
+// by their amf tags; see amf0.RegisterClass for renaming them without tags.
+func RegisterAlias(className string, t reflect.Type) error {
+	err := amf0.RegisterClass(amf0.ClassMapping{ClassName: className, Type: t})
+	if err != nil {
+		return err
+	}
+	return amf3.RegisterAlias(className, t)
+}
@@ -0,0 +1,56 @@
+package amf
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+type aliasUser struct {
+	Name string `amf:"name"`
+}
+
+func TestRegisterAlias(t *testing.T) {
+	err := RegisterAlias("com.example.User", reflect.TypeOf(aliasUser{}))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	data := []byte{0x10, 0x00, 0x10, 'c', 'o', 'm', '.', 'e', 'x', 'a', 'm', 'p', 'l', 'e', '.', 'U', 's', 'e', 'r',
+		0x00, 0x04, 'n', 'a', 'm', 'e', 0x02, 0x00, 0x03, 'a', 'n', 'n', 0x00, 0x00, 0x09}
+	got, err := amf0.NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	user, ok := got.(*aliasUser)
+	if !ok || user.Name != "ann" {
+		t.Fatalf("expect *aliasUser ann got %#v", got)
+	}
+
+	encoded, err := Marshal(user)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !bytes.Equal(encoded, data) {
+		t.Errorf("expect %v got %v", data, encoded)
+	}
+
+	var buf bytes.Buffer
+	err = amf3.NewEncoder(&buf).Encode(user)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	obj, err := amf3.NewDecoder(&buf).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if obj.(*amf3.ObjectType).Trait.ClassName != "com.example.User" {
+		t.Errorf("expect class com.example.User got %+v", obj)
+	}
+
+	if err := RegisterAlias("bad", reflect.TypeOf(0)); err == nil {
+		t.Errorf("expect error for non-struct type")
+	}
+}
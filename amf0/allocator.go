@@ -0,0 +1,95 @@
+package amf0
+
+import (
+	"sync"
+)
+
+// Allocator provides the maps of decoded objects and ECMA arrays and the
+// slices of decoded strict arrays. NewSlice must return a slice of length n.
+// Arena and PoolAllocator are allocators; by default a Decoder makes new
+// maps and slices.
+type Allocator interface {
+	NewMap() map[StringType]interface{}
+	NewSlice(n int) []interface{}
+}
+
+type stdAllocator struct{}
+
+func (stdAllocator) NewMap() map[StringType]interface{} {
+	return make(map[StringType]interface{})
+}
+
+func (stdAllocator) NewSlice(n int) []interface{} {
+	return make([]interface{}, n)
+}
+
+// PoolAllocator takes maps and slices from sync.Pools, and Recycle gives
+// back those of a decoded value once it is no longer used. Unlike an Arena,
+// it is safe for concurrent use and values can be recycled one at a time.
+type PoolAllocator struct {
+	maps   sync.Pool
+	slices sync.Pool
+}
+
+func NewPoolAllocator() *PoolAllocator {
+	return &PoolAllocator{}
+}
+
+func (p *PoolAllocator) NewMap() map[StringType]interface{} {
+	if m, ok := p.maps.Get().(map[StringType]interface{}); ok {
+		return m
+	}
+	return make(map[StringType]interface{})
+}
+
+func (p *PoolAllocator) NewSlice(n int) []interface{} {
+	if s, ok := p.slices.Get().(*[]interface{}); ok {
+		if cap(*s) >= n {
+			return (*s)[:n]
+		}
+		p.slices.Put(s)
+	}
+	return make([]interface{}, n)
+}
+
+// Recycle returns the maps and slices of v and of the values it holds to
+// the pools. Neither v nor anything decoded into it may be used afterwards.
+func (p *PoolAllocator) Recycle(v interface{}) {
+	p.recycle(v, make(map[interface{}]bool))
+}
+
+func (p *PoolAllocator) recycle(v interface{}, seen map[interface{}]bool) {
+	var m map[StringType]interface{}
+	var s []interface{}
+	switch value := v.(type) {
+	case *ObjectType:
+		m = *value
+	case *EcmaArrayType:
+		m = *value
+	case *TypedObjectType:
+		m = value.Object
+	case *StrictArrayType:
+		s = *value
+	default:
+		return
+	}
+	if seen[v] {
+		return
+	}
+	seen[v] = true
+	for _, elem := range m {
+		p.recycle(elem, seen)
+	}
+	for _, elem := range s {
+		p.recycle(elem, seen)
+	}
+	if m != nil {
+		clear(m)
+		p.maps.Put(m)
+	}
+	if s != nil {
+		clear(s)
+		s = s[:0]
+		p.slices.Put(&s)
+	}
+}
@@ -0,0 +1,46 @@
+package amf0
+
+import (
+	"bytes"
+	"testing"
+)
+
+type countingAllocator struct {
+	maps, slices int
+}
+
+func (a *countingAllocator) NewMap() map[StringType]interface{} {
+	a.maps++
+	return make(map[StringType]interface{})
+}
+
+func (a *countingAllocator) NewSlice(n int) []interface{} {
+	a.slices++
+	return make([]interface{}, n)
+}
+
+func TestDecodeAllocator(t *testing.T) {
+	data := []byte{0x0a, 0x00, 0x00, 0x00, 0x02, 0x03, 0x00, 0x01, 'a', 0x05, 0x00, 0x00, 0x09,
+		0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x09}
+	alloc := &countingAllocator{}
+	_, err := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{Allocator: alloc}).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if alloc.maps != 2 || alloc.slices != 1 {
+		t.Fatalf("expect 2 maps and 1 slice got %d and %d", alloc.maps, alloc.slices)
+	}
+
+	pool := NewPoolAllocator()
+	for i := 0; i < 2; i++ {
+		got, err := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{Allocator: pool}).Decode()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		array := *got.(*StrictArrayType)
+		if len(array) != 2 || (*array[0].(*ObjectType))["a"] != (NullType{}) {
+			t.Fatalf("decode incorrect: %v", got)
+		}
+		pool.Recycle(got)
+	}
+}
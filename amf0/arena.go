@@ -0,0 +1,62 @@
+package amf0
+
+// Arena hands out the maps and slices that make up decoded objects and
+// arrays, and takes all of them back at once with Release. Reusing an arena
+// across request-scoped decodes avoids most of the garbage they produce.
+// An Arena must not be used by more than one Decoder at a time.
+type Arena struct {
+	maps   []map[StringType]interface{}
+	nmaps  int
+	slabs  [][]interface{}
+	nslabs int
+}
+
+const arenaSlabSize = 256
+
+func NewArena() *Arena {
+	return &Arena{}
+}
+
+// NewMap and NewSlice make Arena an Allocator.
+func (a *Arena) NewMap() map[StringType]interface{} {
+	if a.nmaps < len(a.maps) {
+		m := a.maps[a.nmaps]
+		a.nmaps++
+		return m
+	}
+	m := make(map[StringType]interface{})
+	a.maps = append(a.maps, m)
+	a.nmaps++
+	return m
+}
+
+func (a *Arena) NewSlice(n int) []interface{} {
+	for ; a.nslabs < len(a.slabs); a.nslabs++ {
+		slab := a.slabs[a.nslabs]
+		if cap(slab)-len(slab) >= n {
+			a.slabs[a.nslabs] = slab[:len(slab)+n]
+			return slab[len(slab) : len(slab)+n : len(slab)+n]
+		}
+	}
+	size := arenaSlabSize
+	if n > size {
+		size = n
+	}
+	slab := make([]interface{}, n, size)
+	a.slabs = append(a.slabs, slab)
+	return slab[:n:n]
+}
+
+// Release returns every map and slice handed out since the last Release to
+// the arena. Values decoded with the arena must not be used afterwards.
+func (a *Arena) Release() {
+	for i := 0; i < a.nmaps; i++ {
+		clear(a.maps[i])
+	}
+	a.nmaps = 0
+	for i := range a.slabs {
+		clear(a.slabs[i])
+		a.slabs[i] = a.slabs[i][:0]
+	}
+	a.nslabs = 0
+}
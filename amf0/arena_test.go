@@ -0,0 +1,37 @@
+package amf0
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestArenaDecode(t *testing.T) {
+	data := []byte{0x0a, 0x00, 0x00, 0x00, 0x02, 0x03, 0x00, 0x03, 0x66, 0x6f, 0x6f, 0x02, 0x00, 0x03, 0x62, 0x61, 0x72, 0x00, 0x00, 0x09, 0x05}
+	arena := NewArena()
+	var first uintptr
+	for i := 0; i < 2; i++ {
+		dec := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{Arena: arena})
+		got, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		array := *got.(*StrictArrayType)
+		if len(array) != 2 || array[1] != (NullType{}) {
+			t.Fatalf("decode incorrect")
+		}
+		obj := *array[0].(*ObjectType)
+		if obj["foo"] != StringType("bar") {
+			t.Fatalf("decode incorrect")
+		}
+		if i == 0 {
+			first = reflect.ValueOf(obj).Pointer()
+		} else if reflect.ValueOf(obj).Pointer() != first {
+			t.Fatalf("expect the object map to be reused after release")
+		}
+		arena.Release()
+		if len(obj) != 0 {
+			t.Fatalf("expect released map to be cleared")
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package amf0
+
+import (
+	"errors"
+	"io"
+	"unsafe"
+)
+
+// bytesReader reads from an in-memory buffer and can hand out sub-slices of
+// that buffer without copying.
+type bytesReader struct {
+	b   []byte
+	off int
+}
+
+func (r *bytesReader) Read(p []byte) (int, error) {
+	if r.off >= len(r.b) {
+		return 0, io.EOF
+	}
+	n := copy(p, r.b[r.off:])
+	r.off += n
+	return n, nil
+}
+
+func (r *bytesReader) ReadByte() (byte, error) {
+	if r.off >= len(r.b) {
+		return 0, io.EOF
+	}
+	c := r.b[r.off]
+	r.off++
+	return c, nil
+}
+
+// next returns the next n bytes of the buffer without copying them.
+func (r *bytesReader) next(n int) ([]byte, error) {
+	if n > len(r.b)-r.off {
+		r.off = len(r.b)
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := r.b[r.off : r.off+n : r.off+n]
+	r.off += n
+	return b, nil
+}
+
+// viewString returns a string sharing memory with b.
+func viewString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return unsafe.String(&b[0], len(b))
+}
+
+// NewBytesDecoder returns a decoder that reads directly from data, such as a
+// memory-mapped file. Strings decoded by it share memory with data instead of
+// being copied, so data must not be modified or unmapped while any decoded
+// value is still in use.
+func NewBytesDecoder(data []byte) *Decoder {
+	return &Decoder{r: &bytesReader{b: data}}
+}
+
+// NewBytesDecoderWithOptions returns a decoder like NewBytesDecoder that
+// applies opts, such as MaxDepth, MaxStringLength and MaxValueCount, as
+// NewDecoderWithOptions does.
+func NewBytesDecoderWithOptions(data []byte, opts DecoderOptions) *Decoder {
+	dec := NewBytesDecoder(data)
+	dec.setOptions(opts)
+	return dec
+}
+
+// skippedRef stands in the reference table for objects that were skipped
+// rather than decoded.
+type skippedRef struct {
+}
+
+// LazyValue is a value whose decoding has been deferred. Raw holds the encoded
+// bytes of the whole subtree.
+type LazyValue struct {
+	Raw     []byte
+	refBase int
+}
+
+// Decode decodes the deferred value. References from inside the subtree to
+// objects decoded before it cannot be resolved and are reported as errors.
+func (v *LazyValue) Decode() (interface{}, error) {
+	dec := NewBytesDecoder(v.Raw)
+	dec.refObjs = make([]interface{}, v.refBase)
+	for i := range dec.refObjs {
+		dec.refObjs[i] = skippedRef{}
+	}
+	return dec.Decode()
+}
+
+// DecodeLazy skips over the next value and returns a handle that decodes it
+// on demand. It is only available on decoders created by NewBytesDecoder.
+// Objects inside the skipped value cannot be referenced by values decoded
+// later from dec.
+func (dec *Decoder) DecodeLazy() (*LazyValue, error) {
+	br, ok := dec.r.(*bytesReader)
+	if !ok {
+		return nil, errors.New("lazy decoding requires a bytes decoder")
+	}
+	start := br.off
+	v := &LazyValue{refBase: len(dec.refObjs)}
+	err := dec.skipValue()
+	if err != nil {
+		return nil, err
+	}
+	v.Raw = br.b[start:br.off:br.off]
+	return v, nil
+}
@@ -0,0 +1,97 @@
+package amf0
+
+import (
+	"errors"
+	"testing"
+	"unsafe"
+)
+
+func TestBytesDecoderZeroCopy(t *testing.T) {
+	data := []byte{0x02, 0x00, 0x03, 0x66, 0x6f, 0x6f}
+	dec := NewBytesDecoder(data)
+	got, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	s, ok := got.(StringType)
+	if !ok || s != "foo" {
+		t.Fatalf("expect foo got %v", got)
+	}
+	if unsafe.StringData(string(s)) != &data[3] {
+		t.Fatalf("string was copied out of the input")
+	}
+}
+
+func TestDecodeLazy(t *testing.T) {
+	data := []byte{0x03, 0x00, 0x03, 0x66, 0x6f, 0x6f, 0x02, 0x00, 0x03, 0x62, 0x61, 0x72, 0x00, 0x00, 0x09,
+		0x00, 0x40, 0x14, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	dec := NewBytesDecoder(data)
+	lazy, err := dec.DecodeLazy()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(lazy.Raw) != 15 {
+		t.Fatalf("expect 15 raw bytes got %d", len(lazy.Raw))
+	}
+	got, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got != NumberType(5) {
+		t.Fatalf("expect 5 got %v", got)
+	}
+	v, err := lazy.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	obj, ok := v.(*ObjectType)
+	if !ok || (*obj)["foo"] != StringType("bar") {
+		t.Fatalf("decode incorrect")
+	}
+}
+
+func TestDecodeLazyReference(t *testing.T) {
+	// an object referencing itself keeps working when decoded lazily, but a
+	// later reference to the skipped object is an error
+	data := []byte{0x03, 0x00, 0x04, 0x73, 0x65, 0x6c, 0x66, 0x07, 0x00, 0x00, 0x00, 0x00, 0x09, 0x07, 0x00, 0x00}
+	dec := NewBytesDecoder(data)
+	lazy, err := dec.DecodeLazy()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	v, err := lazy.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	obj := v.(*ObjectType)
+	if (*obj)["self"] != obj {
+		t.Fatalf("decode incorrect")
+	}
+	_, err = dec.Decode()
+	if err == nil {
+		t.Fatalf("expect error referencing skipped value")
+	}
+}
+
+func TestBytesDecoderWithOptions(t *testing.T) {
+	// an object holding the string "hello"
+	data := []byte{0x03, 0x00, 0x01, 'a', 0x02, 0x00, 0x05, 'h', 'e', 'l', 'l', 'o', 0x00, 0x00, 0x09}
+	_, err := NewBytesDecoderWithOptions(data, DecoderOptions{MaxStringLength: 4}).Decode()
+	var le *LengthError
+	if !errors.As(err, &le) || le.Limit != 4 {
+		t.Errorf("expect length error over the string limit got %v", err)
+	}
+	nested := []byte{0x0a, 0x00, 0x00, 0x00, 0x01, 0x03, 0x00, 0x00, 0x09}
+	_, err = NewBytesDecoderWithOptions(nested, DecoderOptions{MaxDepth: 1}).Decode()
+	if !errors.Is(err, ErrMaxDepth) {
+		t.Errorf("expect ErrMaxDepth got %v", err)
+	}
+	_, err = NewBytesDecoderWithOptions(data, DecoderOptions{MaxValueCount: 1}).Decode()
+	if !errors.Is(err, ErrMaxValueCount) {
+		t.Errorf("expect ErrMaxValueCount got %v", err)
+	}
+	dec := NewBytesDecoderWithOptions(data, DecoderOptions{MaxStringLength: 4})
+	if dec.Options().AMF3.MaxStringLength != 4 {
+		t.Errorf("expect the AMF3 string limit to default to 4 got %d", dec.Options().AMF3.MaxStringLength)
+	}
+}
@@ -0,0 +1,222 @@
+package amf0
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+
+	"github.com/marcuswu/amf/amf3"
+)
+
+// EncodeError reports a value that cannot be encoded and where it was found,
+// e.g. ".items[2]" or ".user.Name".
+type EncodeError struct {
+	Path string
+	Err  error
+}
+
+func (e *EncodeError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
+
+// CanEncode reports whether an Encoder with default options would accept
+// v, without encoding it.
+func CanEncode(v interface{}) error {
+	return NewEncoder(io.Discard).CanEncode(v)
+}
+
+// CanEncode reports whether enc would accept v, without writing anything.
+// It walks the whole value and reports every unsupported type or field as an
+// *EncodeError, joined with errors.Join.
+func (enc *Encoder) CanEncode(v interface{}) error {
+	c := &checker{enc: enc, seen: make(map[interface{}]bool)}
+	if enc.opts.AVMPlus {
+		c.checkAMF3(v, "")
+	} else {
+		c.check(v, "")
+	}
+	return errors.Join(c.errs...)
+}
+
+type checker struct {
+	enc  *Encoder
+	seen map[interface{}]bool
+	errs []error
+}
+
+func (c *checker) fail(path string, err error) {
+	c.errs = append(c.errs, &EncodeError{Path: path, Err: err})
+}
+
+func (c *checker) check(v interface{}, path string) {
+	if v == nil || c.enc.encoderFor(reflect.TypeOf(v)) != nil {
+		return
+	}
+	switch value := v.(type) {
+	case NumberType, BooleanType, StringType, LongStringType, NullType, UndefinedType,
+		UnsupportedType, DateType, XmlDocumentType, XmlDocumentSpan, UnknownValue:
+		return
+	case RawMessage:
+		if len(value) > 0 {
+			if _, err := rawRefs(value); err != nil {
+				c.fail(path, err)
+			}
+		}
+		return
+	case *TypedObjectType:
+		c.check(map[StringType]interface{}(value.Object), path)
+		return
+	case TypedValue:
+		rv := reflect.ValueOf(value.Value)
+		if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+			rv = rv.Elem()
+		}
+		if rv.Kind() != reflect.Struct && (rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String || rv.IsNil()) {
+			c.fail(path, errors.New("typed value of class "+value.ClassName+" must be a struct or a map with string keys"))
+			return
+		}
+		c.check(value.Value, path)
+		return
+	}
+	if isAMF3(v) {
+		c.checkAMF3(v, path)
+		return
+	}
+	c.checkReflect(reflect.ValueOf(v), path)
+}
+
+// checkAMF3 adds the problems amf3.CanEncode finds in v below path.
+func (c *checker) checkAMF3(v interface{}, path string) {
+	err := amf3.CanEncode(v)
+	if err == nil {
+		return
+	}
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var ee *amf3.EncodeError
+		if errors.As(e, &ee) {
+			c.fail(path+ee.Path, ee.Err)
+		} else {
+			c.fail(path, e)
+		}
+	}
+}
+
+// checkReflect follows the rules of encodeReflect.
+func (c *checker) checkReflect(rv reflect.Value, path string) {
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return
+		}
+		if rv.Kind() == reflect.Ptr {
+			key := refKey{typ: rv.Type(), ptr: rv.UnsafePointer()}
+			if c.seen[key] {
+				return
+			}
+			c.seen[key] = true
+		}
+		c.check(rv.Elem().Interface(), path)
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.String:
+		if rv.Type() == durationType {
+			c.fail(path, &UnsupportedTypeError{Type: rv.Type()})
+		}
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice {
+			if rv.IsNil() {
+				return
+			}
+			key := refKey{typ: rv.Type(), ptr: rv.UnsafePointer(), len: rv.Len()}
+			if c.seen[key] {
+				return
+			}
+			c.seen[key] = true
+		}
+		for i := 0; i < rv.Len(); i++ {
+			c.check(rv.Index(i).Interface(), path+"["+strconv.Itoa(i)+"]")
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			c.fail(path, &UnsupportedTypeError{Type: rv.Type().Key(), MapKey: true})
+			return
+		}
+		if rv.IsNil() {
+			return
+		}
+		key := refKey{typ: rv.Type(), ptr: rv.UnsafePointer()}
+		if c.seen[key] {
+			return
+		}
+		c.seen[key] = true
+		iter := rv.MapRange()
+		for iter.Next() {
+			kpath := path + "." + iter.Key().String()
+			if c.checkName(StringType(iter.Key().String()), kpath) {
+				c.check(iter.Value().Interface(), kpath)
+			}
+		}
+	case reflect.Struct:
+		switch rv.Type() {
+		case timeType:
+		case variantType:
+			c.check(rv.Interface().(Variant).Value, path)
+		default:
+			c.checkStruct(rv, path)
+		}
+	default:
+		c.fail(path, &UnsupportedTypeError{Type: rv.Type()})
+	}
+}
+
+// checkName follows the rules of propertyName for the property name k,
+// reporting whether the encoder would write it.
+func (c *checker) checkName(k StringType, path string) bool {
+	_, err := c.enc.propertyName(k)
+	if err != nil {
+		c.fail(path, err)
+		return false
+	}
+	return true
+}
+
+// checkStruct follows the rules of encodeStruct.
+func (c *checker) checkStruct(rv reflect.Value, path string) {
+	for _, f := range c.enc.registry().structFields(rv.Type()) {
+		if f.unset {
+			continue
+		}
+		fv, ok := fieldByIndex(rv, f.index)
+		if !ok {
+			continue
+		}
+		fpath := path + "." + string(f.name)
+		if !c.checkName(f.name, fpath) {
+			continue
+		}
+		conv, err := f.converter(c.enc.registry(), rv.Type())
+		if err != nil {
+			c.fail(fpath, err)
+			continue
+		}
+		if conv != nil {
+			continue
+		}
+		if f.date {
+			_, err = ToDate(fv.Interface())
+			if err != nil {
+				c.fail(fpath, err)
+			}
+			continue
+		}
+		c.check(fv.Interface(), fpath)
+	}
+}
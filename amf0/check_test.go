@@ -0,0 +1,62 @@
+package amf0
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCanEncode(t *testing.T) {
+	type item struct {
+		Name string
+		Done chan bool
+	}
+	type order struct {
+		Items []item
+		Notes map[int]string
+		Extra interface{}
+	}
+	err := CanEncode(&order{Items: []item{{}, {}}, Extra: func() {}})
+	if err == nil {
+		t.Fatalf("expect errors")
+	}
+	expect := map[string]bool{".Items[0].Done": true, ".Items[1].Done": true, ".Notes": true, ".Extra": true}
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var ee *EncodeError
+		if !errors.As(e, &ee) || !expect[ee.Path] {
+			t.Errorf("unexpected error %v", e)
+			continue
+		}
+		delete(expect, ee.Path)
+	}
+	if len(expect) > 0 {
+		t.Errorf("expect errors at %v", expect)
+	}
+
+	obj := ObjectType{"a": NumberType(1), "b": []string{"x"}}
+	if err := CanEncode(&obj); err != nil {
+		t.Errorf("expect no error got %v", err)
+	}
+}
+
+func TestCanEncodePropertyNames(t *testing.T) {
+	long := strings.Repeat("k", 0x10000)
+	type wide struct {
+		Name string `amf:"\xff"`
+	}
+	for _, v := range []interface{}{map[string]int{"\xff": 1}, ObjectType{StringType(long): NullType{}}, wide{}} {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		if encErr, err := enc.Encode(v), enc.CanEncode(v); (encErr == nil) != (err == nil) {
+			t.Errorf("test for %T: Encode gave %v but CanEncode %v", v, encErr, err)
+		}
+		if NewEncoder(&buf).CanEncode(v) == nil {
+			t.Errorf("test for %T: expect a property name error", v)
+		}
+		opts := EncoderOptions{SanitizeKeys: true, TruncateLongKeys: true}
+		if err := NewEncoderWithOptions(&buf, opts).CanEncode(v); err != nil {
+			t.Errorf("test for %T: expect the names remedied got %v", v, err)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package amf0
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+)
+
+// ClassMapping ties an ActionScript class name to a Go struct type. Structs
+// of a mapped type are encoded as typed objects, and typed objects of the
+// class are decoded into a pointer to a new value of the type.
+type ClassMapping struct {
+	ClassName string
+	Type      reflect.Type
+	// Fields renames properties, from Go field name to property name. It
+	// takes precedence over struct tags.
+	Fields map[string]string
+	// Converters names the converter used for a field, keyed by Go field
+	// name. See RegisterConverter.
+	Converters map[string]string
+}
+
+type class struct {
+	name   string
+	typ    reflect.Type
+	fields []field
+}
+
+// Converter translates a single field between its Go and AMF0 forms.
+type Converter struct {
+	Encode EncoderFunc
+	Decode DecoderFunc
+}
+
+type fieldKey struct {
+	t    reflect.Type
+	name string
+}
+
+// RegisterClass adds or replaces a class mapping.
+func RegisterClass(m ClassMapping) error {
+	return update(func(r *Registry) error {
+		return r.RegisterClass(m)
+	})
+}
+
+// RegisterClass adds or replaces a class mapping in r.
+func (r *Registry) RegisterClass(m ClassMapping) error {
+	t := m.Type
+	if t == nil || t.Kind() != reflect.Struct {
+		return errors.New("class " + m.ClassName + " must map to a struct type")
+	}
+	fields := append([]field(nil), typeFields(t)...)
+	for goName := range m.Fields {
+		if !hasField(fields, goName) {
+			return errors.New("class " + m.ClassName + ": no field " + goName)
+		}
+	}
+	for i := range fields {
+		f := &fields[i]
+		if name, ok := m.Fields[f.goName]; ok {
+			f.name = StringType(name)
+		}
+		if name, ok := m.Converters[f.goName]; ok {
+			conv := r.converters[name]
+			if conv == nil {
+				return errors.New("class " + m.ClassName + ": unknown converter " + name)
+			}
+			f.conv = conv
+		}
+	}
+	for goName := range m.Converters {
+		if !hasField(fields, goName) {
+			return errors.New("class " + m.ClassName + ": no field " + goName)
+		}
+	}
+	c := &class{name: m.ClassName, typ: t, fields: fields}
+	if old := r.byType[t]; old != nil {
+		delete(r.byName, old.name)
+	}
+	r.byName[c.name] = c
+	r.byType[t] = c
+	return nil
+}
+
+func hasField(fields []field, goName string) bool {
+	for _, f := range fields {
+		if f.goName == goName {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterType makes t available to mapping files under key.
+func RegisterType(key string, t reflect.Type) {
+	update(func(r *Registry) error {
+		r.RegisterType(key, t)
+		return nil
+	})
+}
+
+// RegisterType makes t available to mapping files loaded into r.
+func (r *Registry) RegisterType(key string, t reflect.Type) {
+	r.types[key] = t
+}
+
+// RegisterConverter makes a converter available to class mappings under
+// name. The converters "duration", "decimal", "ip" and "date" are built in.
+func RegisterConverter(name string, enc EncoderFunc, dec DecoderFunc) {
+	update(func(r *Registry) error {
+		r.RegisterConverter(name, enc, dec)
+		return nil
+	})
+}
+
+// RegisterConverter makes a converter available to the class mappings of r.
+func (r *Registry) RegisterConverter(name string, enc EncoderFunc, dec DecoderFunc) {
+	r.converters[name] = &Converter{Encode: enc, Decode: dec}
+}
+
+// RegisterFieldConverter makes the field goName of struct type t use the
+// converter registered under name. This reaches fields that cannot be
+// tagged, such as interface-typed fields of types from other packages whose
+// wire type varies.
+func RegisterFieldConverter(t reflect.Type, goName string, name string) error {
+	return update(func(r *Registry) error {
+		return r.RegisterFieldConverter(t, goName, name)
+	})
+}
+
+// RegisterFieldConverter is like the package-level RegisterFieldConverter
+// for codecs bound to r.
+func (r *Registry) RegisterFieldConverter(t reflect.Type, goName string, name string) error {
+	if t == nil || t.Kind() != reflect.Struct {
+		return errors.New("field converters need a struct type")
+	}
+	if !hasField(typeFields(t), goName) {
+		return errors.New(t.String() + ": no field " + goName)
+	}
+	conv := r.converters[name]
+	if conv == nil {
+		return errors.New(t.String() + ": unknown converter " + name)
+	}
+	r.fieldConverters[fieldKey{t, goName}] = conv
+	return nil
+}
+
+// structFields returns the properties of struct type t, taking a class
+// mapping into account.
+func (r *Registry) structFields(t reflect.Type) []field {
+	if c := r.byType[t]; c != nil {
+		return c.fields
+	}
+	return typeFields(t)
+}
+
+// readClass decodes the properties of a typed object of a registered class.
+func (dec *Decoder) readClass(c *class, refIndex int) (interface{}, error) {
+	ptr := reflect.New(c.typ)
+	dec.refObjs[refIndex] = ptr.Interface()
+	err := dec.readFields(ptr.Elem(), c.fields)
+	if err != nil {
+		return nil, err
+	}
+	return ptr.Interface(), nil
+}
+
+// mappingFile is the layout of a class mapping configuration:
+//
+//	{"classes": [{"class": "com.example.User", "type": "user",
+//	  "fields": {"Name": "userName"}, "converters": {"Timeout": "duration"}}]}
+type mappingFile struct {
+	Classes []struct {
+		Class      string            `json:"class"`
+		Type       string            `json:"type"`
+		Fields     map[string]string `json:"fields"`
+		Converters map[string]string `json:"converters"`
+	} `json:"classes"`
+}
+
+// LoadMappings reads class mappings in JSON from r and registers them. Go
+// types are referred to by the key given to RegisterType, so mappings can be
+// adjusted without recompiling.
+func LoadMappings(r io.Reader) error {
+	// decode before taking the lock held by update
+	var file mappingFile
+	err := json.NewDecoder(r).Decode(&file)
+	if err != nil {
+		return err
+	}
+	return update(func(reg *Registry) error {
+		return reg.loadMappings(&file)
+	})
+}
+
+// LoadMappings is like the package-level LoadMappings, registering into reg.
+func (reg *Registry) LoadMappings(r io.Reader) error {
+	var file mappingFile
+	err := json.NewDecoder(r).Decode(&file)
+	if err != nil {
+		return err
+	}
+	return reg.loadMappings(&file)
+}
+
+func (reg *Registry) loadMappings(file *mappingFile) error {
+	for _, c := range file.Classes {
+		t := reg.types[c.Type]
+		if t == nil {
+			return errors.New("class " + c.Class + ": unknown type " + c.Type)
+		}
+		err := reg.RegisterClass(ClassMapping{ClassName: c.Class, Type: t, Fields: c.Fields, Converters: c.Converters})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
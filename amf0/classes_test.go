@@ -0,0 +1,120 @@
+package amf0
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testUser struct {
+	Name    string        `amf:"name"`
+	Timeout time.Duration `amf:"timeout"`
+	Tags    []string
+	Friend  *testUser
+}
+
+func TestLoadMappings(t *testing.T) {
+	RegisterType("user", reflect.TypeOf(testUser{}))
+	err := LoadMappings(strings.NewReader(`{"classes": [{"class": "com.example.User", "type": "user",
+		"fields": {"Name": "userName"}, "converters": {"Timeout": "duration"}}]}`))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	u := testUser{Name: "a", Timeout: 2 * time.Second, Tags: []string{"x"}, Friend: &testUser{Name: "b"}}
+	buf := new(bytes.Buffer)
+	err = NewEncoder(buf).Encode(&u)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("\x10\x00\x10com.example.User\x00\x08userName\x02\x00\x01a")) {
+		t.Fatalf("unexpected encoding %x", buf.Bytes())
+	}
+
+	got, err := NewDecoder(buf).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !reflect.DeepEqual(got, &u) {
+		t.Fatalf("expect %+v got %+v", &u, got)
+	}
+
+	err = LoadMappings(strings.NewReader(`{"classes": [{"class": "x", "type": "missing"}]}`))
+	if err == nil {
+		t.Fatalf("expect error for unknown type key")
+	}
+	err = LoadMappings(strings.NewReader(`{"classes": [{"class": "x", "type": "user", "converters": {"Name": "nope"}}]}`))
+	if err == nil {
+		t.Fatalf("expect error for unknown converter")
+	}
+}
+
+type testFlags string
+
+func (f testFlags) String() string {
+	return string(f)
+}
+
+type testSettings struct {
+	Flags fmt.Stringer `amf:"flags,conv=flags"`
+	Mode  fmt.Stringer `amf:"mode"`
+}
+
+func TestFieldConverters(t *testing.T) {
+	RegisterConverter("flags", func(enc *Encoder, v interface{}) error {
+		return enc.Encode(StringType(v.(fmt.Stringer).String()))
+	}, func(dec *Decoder) (interface{}, error) {
+		v, err := dec.Decode()
+		if err != nil {
+			return nil, err
+		}
+		if n, ok := v.(NumberType); ok {
+			return testFlags(strconv.FormatFloat(float64(n), 'f', -1, 64)), nil
+		}
+		s, ok := v.(StringType)
+		if !ok {
+			return nil, errors.New("unexpected flags value")
+		}
+		return testFlags(s), nil
+	})
+	err := RegisterFieldConverter(reflect.TypeOf(testSettings{}), "Mode", "flags")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	buf := bytes.NewReader([]byte{0x03,
+		0x00, 0x05, 'f', 'l', 'a', 'g', 's', 0x00, 0x40, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x04, 'm', 'o', 'd', 'e', 0x02, 0x00, 0x01, 'x',
+		0x00, 0x00, 0x09})
+	var got testSettings
+	err = NewDecoder(buf).decodeInto(reflect.ValueOf(&got).Elem())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got.Flags != testFlags("3") || got.Mode != testFlags("x") {
+		t.Fatalf("expect 3 and x got %+v", got)
+	}
+
+	out := new(bytes.Buffer)
+	err = NewEncoder(out).Encode(got)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect := []byte{0x03,
+		0x00, 0x05, 'f', 'l', 'a', 'g', 's', 0x02, 0x00, 0x01, '3',
+		0x00, 0x04, 'm', 'o', 'd', 'e', 0x02, 0x00, 0x01, 'x',
+		0x00, 0x00, 0x09}
+	if !bytes.Equal(expect, out.Bytes()) {
+		t.Fatalf("expect %x got %x", expect, out.Bytes())
+	}
+
+	err = RegisterFieldConverter(reflect.TypeOf(testSettings{}), "Missing", "flags")
+	if err == nil {
+		t.Fatalf("expect error for unknown field")
+	}
+}
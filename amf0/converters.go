@@ -0,0 +1,141 @@
+package amf0
+
+import (
+	"errors"
+	"math/big"
+	"net"
+	"reflect"
+	"time"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	ratType      = reflect.TypeOf((*big.Rat)(nil))
+	ipType       = reflect.TypeOf(net.IP(nil))
+)
+
+// The built-in encoders and decoders are filled in by init, as they refer
+// back to Encoder.Encode and Decoder.Decode. The named converters for class
+// mappings are registered there as well.
+var (
+	builtinEncoders map[reflect.Type]EncoderFunc
+	builtinDecoders map[reflect.Type]DecoderFunc
+)
+
+func init() {
+	builtinEncoders = map[reflect.Type]EncoderFunc{
+		durationType: func(enc *Encoder, v interface{}) error {
+			d := v.(time.Duration)
+			return enc.Encode(NumberType(float64(d) / float64(time.Millisecond)))
+		},
+		ratType: func(enc *Encoder, v interface{}) error {
+			r := v.(*big.Rat)
+			if r == nil {
+				return enc.Encode(NullType{})
+			}
+			return enc.Encode(StringType(ratString(r)))
+		},
+		ipType: func(enc *Encoder, v interface{}) error {
+			ip := v.(net.IP)
+			if ip == nil {
+				return enc.Encode(NullType{})
+			}
+			return enc.Encode(StringType(ip.String()))
+		},
+	}
+
+	builtinDecoders = map[reflect.Type]DecoderFunc{
+		durationType: func(dec *Decoder) (interface{}, error) {
+			v, err := dec.Decode()
+			if err != nil {
+				return nil, err
+			}
+			n, ok := v.(NumberType)
+			if !ok {
+				return nil, errors.New("duration must be a number")
+			}
+			return time.Duration(float64(n) * float64(time.Millisecond)), nil
+		},
+		ratType: func(dec *Decoder) (interface{}, error) {
+			v, err := dec.Decode()
+			if err != nil {
+				return nil, err
+			}
+			switch value := v.(type) {
+			case NullType, UndefinedType:
+				return (*big.Rat)(nil), nil
+			case NumberType:
+				r := new(big.Rat).SetFloat64(float64(value))
+				if r == nil {
+					return nil, errors.New("decimal is not finite")
+				}
+				return r, nil
+			case StringType:
+				r, ok := new(big.Rat).SetString(string(value))
+				if !ok {
+					return nil, errors.New("invalid decimal " + string(value))
+				}
+				return r, nil
+			}
+			return nil, errors.New("decimal must be a string or a number")
+		},
+		ipType: func(dec *Decoder) (interface{}, error) {
+			v, err := dec.Decode()
+			if err != nil {
+				return nil, err
+			}
+			switch value := v.(type) {
+			case NullType, UndefinedType:
+				return net.IP(nil), nil
+			case StringType:
+				ip := net.ParseIP(string(value))
+				if ip == nil {
+					return nil, errors.New("invalid IP address " + string(value))
+				}
+				return ip, nil
+			}
+			return nil, errors.New("IP address must be a string")
+		},
+	}
+
+	RegisterConverter("duration", builtinEncoders[durationType], builtinDecoders[durationType])
+	RegisterConverter("decimal", builtinEncoders[ratType], builtinDecoders[ratType])
+	RegisterConverter("ip", builtinEncoders[ipType], builtinDecoders[ipType])
+	RegisterConverter("date", func(enc *Encoder, v interface{}) error {
+		date, err := ToDate(v)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(date)
+	}, func(dec *Decoder) (interface{}, error) {
+		return dec.Decode()
+	})
+}
+
+// ratString formats r as a plain decimal when it has a finite decimal
+// expansion, and as a fraction otherwise.
+func ratString(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	// a finite expansion exists iff the denominator only has factors 2 and 5
+	d := new(big.Int).Set(r.Denom())
+	two, five := big.NewInt(2), big.NewInt(5)
+	m := new(big.Int)
+	twos, fives := 0, 0
+	for m.Mod(d, two).Sign() == 0 {
+		d.Quo(d, two)
+		twos++
+	}
+	for m.Mod(d, five).Sign() == 0 {
+		d.Quo(d, five)
+		fives++
+	}
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return r.RatString()
+	}
+	if fives > twos {
+		twos = fives
+	}
+	return r.FloatString(twos)
+}
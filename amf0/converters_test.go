@@ -0,0 +1,62 @@
+package amf0
+
+import (
+	"bytes"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBuiltinConverters(t *testing.T) {
+	values := []interface{}{
+		1500 * time.Millisecond,
+		big.NewRat(25, 2),
+		big.NewRat(1, 3),
+		net.ParseIP("192.168.0.1"),
+	}
+	expects := []interface{}{
+		NumberType(1500),
+		StringType("12.5"),
+		StringType("1/3"),
+		StringType("192.168.0.1"),
+	}
+	for i, v := range values {
+		buf := new(bytes.Buffer)
+		enc := NewEncoderWithOptions(buf, EncoderOptions{BuiltinConverters: true})
+		err := enc.Encode(v)
+		if err != nil {
+			t.Fatalf("encode %v: %s", v, err)
+		}
+		encoded := buf.Bytes()
+		got, err := NewDecoder(bytes.NewReader(encoded)).Decode()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if got != expects[i] {
+			t.Errorf("expect %v got %v", expects[i], got)
+		}
+
+		dec := NewDecoderWithOptions(bytes.NewReader(encoded), DecoderOptions{BuiltinConverters: true})
+		back, err := dec.DecodeType(reflect.TypeOf(v))
+		if err != nil {
+			t.Fatalf("decode %v: %s", v, err)
+		}
+		if r, ok := v.(*big.Rat); ok {
+			if r.Cmp(back.(*big.Rat)) != 0 {
+				t.Errorf("expect %v got %v", v, back)
+			}
+		} else if !reflect.DeepEqual(v, back) {
+			t.Errorf("expect %v got %v", v, back)
+		}
+	}
+
+	enc := NewEncoder(new(bytes.Buffer))
+	if enc.Encode(time.Second) == nil {
+		t.Errorf("expect durations to be unsupported without the option")
+	}
+	if CanEncode(time.Second) == nil {
+		t.Errorf("expect CanEncode to reject durations without the option")
+	}
+}
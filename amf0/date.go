@@ -0,0 +1,109 @@
+package amf0
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"time"
+)
+
+// NewDate returns the AMF0 date for t, in milliseconds since the Unix epoch.
+func NewDate(t time.Time) DateType {
+	// split at the millisecond so that dates past 2262 do not overflow
+	frac := float64(t.Nanosecond()%int(time.Millisecond)) / float64(time.Millisecond)
+	return DateType{Date: float64(t.UnixMilli()) + frac}
+}
+
+// ToDate normalizes the ways timestamps are commonly stored to an AMF0 date.
+// It accepts time.Time, DateType and pointers to them, and integer or
+// floating point numbers of milliseconds since the Unix epoch.
+func ToDate(v interface{}) (DateType, error) {
+	switch value := v.(type) {
+	case nil:
+		return DateType{}, errors.New("cannot use nil as a date")
+	case DateType:
+		return value, nil
+	case *DateType:
+		if value != nil {
+			return *value, nil
+		}
+	case time.Time:
+		return NewDate(value), nil
+	case *time.Time:
+		if value != nil {
+			return NewDate(*value), nil
+		}
+	case NumberType:
+		return DateType{Date: float64(value)}, nil
+	default:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return DateType{Date: float64(rv.Int())}, nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return DateType{Date: float64(rv.Uint())}, nil
+		case reflect.Float32, reflect.Float64:
+			return DateType{Date: rv.Float()}, nil
+		}
+	}
+	return DateType{}, errors.New("cannot use " + reflect.TypeOf(v).String() + " as a date")
+}
+
+// Time returns the date as a time.Time in UTC.
+func (d DateType) Time() time.Time {
+	ms := int64(d.Date)
+	ns := int64((d.Date - float64(ms)) * float64(time.Millisecond))
+	return time.UnixMilli(ms).Add(time.Duration(ns)).UTC()
+}
+
+// Location returns the fixed zone given by TimeZone, taken as minutes east
+// of UTC, or time.UTC when it is 0. The specification reserves the field
+// and writers normally leave it 0, as NewDate does; the instant never
+// depends on it.
+func (d DateType) Location() *time.Location {
+	if d.TimeZone == 0 {
+		return time.UTC
+	}
+	return time.FixedZone("", int(d.TimeZone)*60)
+}
+
+// The comparisons below go through Time, so that they work on whole
+// nanoseconds rather than on the float milliseconds, whose rounding can
+// order dates that are equal to the millisecond differently. A date that is
+// not a number is neither before nor after any time.
+
+// Before reports whether the date is before t.
+func (d DateType) Before(t time.Time) bool {
+	return !math.IsNaN(d.Date) && d.Time().Before(t)
+}
+
+// After reports whether the date is after t.
+func (d DateType) After(t time.Time) bool {
+	return !math.IsNaN(d.Date) && d.Time().After(t)
+}
+
+// Equal reports whether the date and t are the same instant.
+func (d DateType) Equal(t time.Time) bool {
+	return !math.IsNaN(d.Date) && d.Time().Equal(t)
+}
+
+// Compare compares the date with t, returning -1, 0 or +1 like
+// time.Time.Compare. A date that is not a number sorts first.
+func (d DateType) Compare(t time.Time) int {
+	if math.IsNaN(d.Date) {
+		return -1
+	}
+	return d.Time().Compare(t)
+}
+
+// Add returns the date shifted by dur, keeping its time zone.
+func (d DateType) Add(dur time.Duration) DateType {
+	date := NewDate(d.Time().Add(dur))
+	date.TimeZone = d.TimeZone
+	return date
+}
+
+// Sub returns the duration from t to the date.
+func (d DateType) Sub(t time.Time) time.Duration {
+	return d.Time().Sub(t)
+}
@@ -0,0 +1,49 @@
+package amf0
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDateCompare(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 0, 0, 250_000_000, time.UTC)
+	d := NewDate(at)
+	if !d.Equal(at) || d.Compare(at) != 0 || d.Before(at) || d.After(at) {
+		t.Fatalf("expect %v equal to %v", d, at)
+	}
+	if !d.Before(at.Add(time.Microsecond)) || !d.After(at.Add(-time.Microsecond)) {
+		t.Errorf("expect ordering at microsecond resolution")
+	}
+	later := d.Add(1500 * time.Millisecond)
+	if got := later.Sub(at); got != 1500*time.Millisecond {
+		t.Errorf("expect 1.5s got %v", got)
+	}
+	far := time.Date(2400, 1, 1, 0, 0, 0, 0, time.UTC)
+	if !NewDate(far).Equal(far) {
+		t.Errorf("expect dates past 2262 to survive got %v", NewDate(far).Time())
+	}
+	nan := DateType{Date: math.NaN()}
+	if nan.Before(at) || nan.After(at) || nan.Equal(at) || nan.Compare(at) != -1 {
+		t.Errorf("expect NaN date unordered")
+	}
+}
+
+func TestDateTimeZone(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	d := NewDate(at)
+	d.TimeZone = 120
+	var got struct {
+		At time.Time `amf:"at"`
+	}
+	err := Assign(&got, &ObjectType{"at": d})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !got.At.Equal(at) || got.At.Hour() != 14 {
+		t.Fatalf("expect %v in UTC+2 got %v", at, got.At)
+	}
+	if NewDate(at).Location() != time.UTC {
+		t.Errorf("expect UTC for a date without time zone")
+	}
+}
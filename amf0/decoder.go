@@ -10,53 +10,101 @@ import (
 )
 
 type Decoder struct {
-	r       io.Reader
-	refObjs []interface{}
+	r            io.Reader
+	refObjs      []interface{}
+	seeds        []interface{}
+	opts         DecoderOptions
+	mark         *mark
+	pending      *io.LimitedReader
+	presence     FieldSet
+	presencePath string
+	tokens       []tokenFrame
+	truncated    bool
+	depth        int
+	valueCount   int
+	counter      *countingReader
 }
 
+// ErrMissingObjectEnd is returned by Decode, along with the value, when the
+// input ended where an object could have ended and AllowMissingObjectEnd is
+// set.
+var ErrMissingObjectEnd = errors.New("missing ObjectEndMarker at end of input")
+
 // should use io.LimitedReader
 func NewDecoder(r io.Reader) *Decoder {
-	if _, ok := r.(*bufio.Reader); ok {
-		return &Decoder{r: r}
+	// As in amf3, readers handing out single bytes are not wrapped, so that
+	// a decoder nested in a bounded reader never reads ahead of its bound.
+	c := &countingReader{r: r}
+	if _, ok := r.(io.ByteReader); ok {
+		return &Decoder{r: c, counter: c}
 	}
-	return &Decoder{r: bufio.NewReader(r)}
+	return &Decoder{r: bufio.NewReader(c), counter: c}
 }
 
 func (dec *Decoder) Decode() (interface{}, error) {
-	v, err := dec.decodeValue()
+	err := dec.drainPending()
 	if err != nil {
 		return nil, err
 	}
+	dec.truncated = false
+	v, err := dec.decodeValue()
+	if err != nil {
+		return nil, dec.topError(err)
+	}
+	if dec.truncated {
+		return v, ErrMissingObjectEnd
+	}
 	return v, nil
 }
 
 func (dec *Decoder) decodeValue() (interface{}, error) {
 	u8 := make([]byte, 1)
-	u16 := make([]byte, 2)
-	u32 := make([]byte, 4)
-	u64 := make([]byte, 8)
-	_, err := dec.r.Read(u8)
+	_, err := io.ReadFull(dec.r, u8)
 	if err != nil {
 		return nil, err
 	}
-	marker := u8[0]
+	err = dec.countValue()
+	if err != nil {
+		return nil, dec.valueError(err, u8[0])
+	}
+	v, err := dec.decodeMarker(u8[0])
+	if err != nil {
+		return nil, dec.valueError(err, u8[0])
+	}
+	return v, nil
+}
+
+// decodeMarker decodes the rest of a value whose marker has already been read.
+func (dec *Decoder) decodeMarker(marker byte) (interface{}, error) {
+	if isContainer(marker) {
+		err := dec.enter()
+		if err != nil {
+			return nil, err
+		}
+		defer dec.leave()
+	}
+	u8 := make([]byte, 1)
+	u16 := make([]byte, 2)
+	u32 := make([]byte, 4)
+	u64 := make([]byte, 8)
+	var err error
 	switch marker {
 	case NumberMarker:
-		_, err := dec.r.Read(u64)
+		_, err := io.ReadFull(dec.r, u64)
 		if err != nil {
-			return nil, err
+			return nil, noEOF(err)
 		}
 		u64n := binary.BigEndian.Uint64(u64)
 		number := math.Float64frombits(u64n)
 		return NumberType(number), nil
 	case BooleanMarker:
-		_, err := dec.r.Read(u8)
+		_, err := io.ReadFull(dec.r, u8)
 		if err != nil {
 			return nil, err
 		}
 		return BooleanType(u8[0] != 0), nil
 	case StringMarker:
-		stringBytes, err := readUTF8(dec.r)
+		stringBytes, err := dec.readUTF8()
 		if err != nil {
 			return nil, err
 		}
@@ -71,90 +119,124 @@ func (dec *Decoder) decodeValue() (interface{}, error) {
 		*object = ObjectType(obj)
 		return object, nil
 	case MovieclipMarker:
-		return nil, errors.New("Movieclip Type not supported")
+		return nil, ErrUnsupportedMarker
 	case NullMarker:
 		return NullType{}, nil
 	case UndefinedMarker:
 		return UndefinedType{}, nil
 	case ReferenceMarker:
-		_, err = dec.r.Read(u16)
+		_, err = io.ReadFull(dec.r, u16)
 		if err != nil {
 			return nil, err
 		}
 		refid := binary.BigEndian.Uint16(u16)
 		if int(refid) >= len(dec.refObjs) {
-			return nil, errors.New("reference error")
+			return nil, &ReferenceError{Table: "object", Index: int(refid), Len: len(dec.refObjs)}
+		}
+		if _, ok := dec.refObjs[refid].(skippedRef); ok {
+			return nil, ErrSkippedReference
 		}
 		return dec.refObjs[refid], nil
 	case EcmaArrayMarker:
-		_, err := dec.r.Read(u32)
+		_, err := io.ReadFull(dec.r, u32)
 		if err != nil {
 			return nil, err
 		}
 		object := new(EcmaArrayType)
 		dec.refObjs = append(dec.refObjs, object)
 		associativeCount := binary.BigEndian.Uint32(u32)
+		err = dec.checkCount("ECMA array count", associativeCount)
+		if err != nil {
+			return nil, err
+		}
 		obj, err := dec.readObject()
 		if err != nil {
 			return nil, err
 		}
 		*object = EcmaArrayType(obj)
-		if uint32(len(*object)) != associativeCount {
-			return nil, errors.New("EcmaArray count error")
+		if uint32(len(*object)) != associativeCount && !dec.truncated {
+			return nil, ErrECMACountMismatch
 		}
 		return object, nil
 	case StrictArrayMarker:
-		_, err := dec.r.Read(u32)
+		_, err := io.ReadFull(dec.r, u32)
 		if err != nil {
 			return nil, err
 		}
 		object := new(StrictArrayType)
 		dec.refObjs = append(dec.refObjs, object)
 		arrayCount := binary.BigEndian.Uint32(u32)
-		array := make(StrictArrayType, arrayCount)
+		err = dec.checkCount("strict array count", arrayCount)
+		if err != nil {
+			return nil, err
+		}
+		err = checkLength(dec.r, "strict array count", uint64(arrayCount))
+		if err != nil {
+			return nil, err
+		}
+		var array StrictArrayType
+		if arrayCount <= allocChunk || remaining(dec.r) >= 0 {
+			array = dec.newArray(int(arrayCount))
+		} else {
+			// the count could not be checked, so grow as values arrive
+			array = make(StrictArrayType, 0, allocChunk)
+		}
 		for i := 0; i < int(arrayCount); i++ {
-			array[i], err = dec.decodeValue()
+			value, err := dec.decodeValue()
 			if err != nil {
-				return nil, err
+				return nil, dec.atPath(err, index(i))
+			}
+			if i < len(array) {
+				array[i] = value
+			} else {
+				array = append(array, value)
 			}
 		}
 		*object = array
 		return object, nil
 	case DateMarker:
-		_, err := dec.r.Read(u64)
+		_, err := io.ReadFull(dec.r, u64)
 		if err != nil {
 			return nil, err
 		}
 		u64n := binary.BigEndian.Uint64(u64)
 		date := math.Float64frombits(u64n)
-		_, err = dec.r.Read(u16)
+		_, err = io.ReadFull(dec.r, u16)
 		if err != nil {
 			return nil, err
 		}
-		return DateType{Date: date}, nil
+		timeZone := int16(binary.BigEndian.Uint16(u16))
+		return DateType{TimeZone: timeZone, Date: date}, nil
 	case LongStringMarker:
-		stringBytes, err := readUTF8Long(dec.r)
+		stringBytes, err := readUTF8LongLimit(dec.r, dec.opts.MaxStringLength)
 		if err != nil {
 			return nil, err
 		}
+		stringBytes, err = transcode(dec, stringBytes)
+		if err != nil {
+			return nil, err
+		}
+		if dec.opts.UniformStrings {
+			return StringType(stringBytes), nil
+		}
 		return LongStringType(stringBytes), nil
 	case UnsupportedMarker:
 		return UnsupportedType{}, nil
 	case RecordsetMarker:
-		return nil, errors.New("RecordSet Type not supported")
+		return nil, ErrUnsupportedMarker
 	case XmlDocumentMarker:
-		stringBytes, err := readUTF8Long(dec.r)
-		if err != nil {
-			return nil, err
-		}
-		return XmlDocumentType(stringBytes), nil
+		return dec.readXML()
 	case TypedObjectMarker:
+		refIndex := len(dec.refObjs)
 		object := new(TypedObjectType)
 		dec.refObjs = append(dec.refObjs, object)
-		classNameBytes, err := readUTF8(dec.r)
+		classNameBytes, err := dec.readUTF8()
 		if err != nil {
 			return nil, err
 		}
+		if class := dec.registry().byName[string(classNameBytes)]; class != nil {
+			return dec.readClass(class, refIndex)
+		}
 		obj, err := dec.readObject()
 		if err != nil {
 			return nil, err
@@ -163,52 +245,109 @@ func (dec *Decoder) decodeValue() (interface{}, error) {
 		return object, nil
 	case SwitchToAmf3Marker:
 		var obj interface {}
-		var amf3Decoder *amf3.Decoder = amf3.NewDecoder(dec.r)
+		start := dec.offset()
+		var amf3Decoder *amf3.Decoder = amf3.NewDecoderWithOptions(dec.r, dec.amf3Options())
 		obj, err = amf3Decoder.Decode()
+		dec.countAMF3(amf3Decoder)
+		if de, ok := err.(*DecodeError); ok {
+			de.Offset += start
+		}
 		if err != nil {
 			return nil, err
 		}
 		return obj, nil
 	}
 
-	panic("not reach")
-	return nil, nil
+	return dec.unknownMarker(marker)
 }
 
 func (dec *Decoder) readObject() (_Object, error) {
 	u8 := make([]byte, 1)
-	v := make(map[StringType]interface{})
+	v := dec.newObject()
 	for {
-		name, err := readUTF8(dec.r)
+		name, err := dec.readUTF8()
+		if err == io.EOF && dec.opts.AllowMissingObjectEnd {
+			dec.truncated = true
+			break
+		}
 		if err != nil {
 			return nil, err
 		}
 		if name == "" {
-			_, err := dec.r.Read(u8)
+			_, err := io.ReadFull(dec.r, u8)
+			if err == io.EOF && dec.opts.AllowMissingObjectEnd {
+				dec.truncated = true
+				break
+			}
 			if err != nil {
 				return nil, err
 			}
 			if u8[0] == ObjectEndMarker {
 				break
 			} else {
-				return nil, errors.New("expect ObjectEndMarker here")
+				return nil, ErrExpectedObjectEnd
 			}
 		}
 		value, err := dec.decodeValue()
 		if err != nil {
-			return nil, err
+			return nil, dec.atPath(err, "."+string(name))
 		}
 		if _, ok := v[name]; ok {
-			return nil, errors.New("object-property exists")
+			return nil, dec.atPath(ErrDuplicateProperty, "."+string(name))
 		}
 		v[name] = value
 	}
 	return v, nil
 }
 
+func (dec *Decoder) newObject() map[StringType]interface{} {
+	return dec.allocator().NewMap()
+}
+
+func (dec *Decoder) newArray(n int) StrictArrayType {
+	return dec.allocator().NewSlice(n)
+}
+
+func (dec *Decoder) allocator() Allocator {
+	if dec.opts.Allocator != nil {
+		return dec.opts.Allocator
+	}
+	if dec.opts.Arena != nil {
+		return dec.opts.Arena
+	}
+	return stdAllocator{}
+}
+
+// readUTF8 reads a string, transcoded if the options say so.
+func (dec *Decoder) readUTF8() (StringType, error) {
+	s, err := readUTF8Limit(dec.r, dec.opts.MaxStringLength)
+	if err != nil {
+		return "", err
+	}
+	return transcode(dec, s)
+}
+
+// transcode converts s with the Transcode option, if set.
+func transcode[S StringType | LongStringType](dec *Decoder, s S) (S, error) {
+	if dec.opts.Transcode == nil || s == "" {
+		return s, nil
+	}
+	b, err := dec.opts.Transcode([]byte(s))
+	if err != nil {
+		return "", errors.New("transcoding string: " + err.Error())
+	}
+	return S(b), nil
+}
+
 func readUTF8(r io.Reader) (StringType, error) {
+	return readUTF8Limit(r, 0)
+}
+
+// readUTF8Limit reads a string, failing with a *LengthError before reading
+// its bytes when it is longer than limit; 0 means no limit.
+func readUTF8Limit(r io.Reader, limit uint32) (StringType, error) {
 	u16 := make([]byte, 2)
-	_, err := r.Read(u16)
+	_, err := io.ReadFull(r, u16)
 	if err != nil {
 		return "", err
 	}
@@ -216,8 +355,18 @@ func readUTF8(r io.Reader) (StringType, error) {
 	if stringLength == 0 {
 		return "", nil
 	}
+	if limit > 0 && uint32(stringLength) > limit {
+		return "", &LengthError{What: "string length", Length: uint64(stringLength), Limit: int64(limit)}
+	}
+	if br, ok := r.(*bytesReader); ok {
+		stringBytes, err := br.next(int(stringLength))
+		if err != nil {
+			return "", err
+		}
+		return StringType(viewString(stringBytes)), nil
+	}
 	stringBytes := make([]byte, stringLength)
-	_, err = r.Read(stringBytes)
+	_, err = io.ReadFull(r, stringBytes)
 	if err != nil {
 		return "", err
 	}
@@ -225,8 +374,13 @@ func readUTF8(r io.Reader) (StringType, error) {
 }
 
 func readUTF8Long(r io.Reader) (LongStringType, error) {
+	return readUTF8LongLimit(r, 0)
+}
+
+// readUTF8LongLimit reads a long string as readUTF8Limit reads a string.
+func readUTF8LongLimit(r io.Reader, limit uint32) (LongStringType, error) {
 	u32 := make([]byte, 4)
-	_, err := r.Read(u32)
+	_, err := io.ReadFull(r, u32)
 	if err != nil {
 		return "", err
 	}
@@ -234,10 +388,30 @@ func readUTF8Long(r io.Reader) (LongStringType, error) {
 	if stringLength == 0 {
 		return "", nil
 	}
-	stringBytes := make([]byte, stringLength)
-	_, err = r.Read(stringBytes)
+	if limit > 0 && stringLength > limit {
+		return "", &LengthError{What: "long string length", Length: uint64(stringLength), Limit: int64(limit)}
+	}
+	if br, ok := r.(*bytesReader); ok {
+		stringBytes, err := br.next(int(stringLength))
+		if err != nil {
+			return "", err
+		}
+		return LongStringType(viewString(stringBytes)), nil
+	}
+	stringBytes, err := readLength(r, "long string length", uint64(stringLength))
 	if err != nil {
 		return "", err
 	}
 	return LongStringType(stringBytes), nil
 }
+
+// ResetReferences empties the reference table, so that the next value cannot
+// refer to objects decoded before, but only to those given to
+// SeedReferences. AMF packets start a new table for every header and
+// message body.
+func (dec *Decoder) ResetReferences() {
+	dec.refObjs = append([]interface{}(nil), dec.seeds...)
+	if dec.mark != nil {
+		dec.mark.refObjs = len(dec.refObjs)
+	}
+}
@@ -2,7 +2,15 @@ package amf0
 
 import (
 	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
+
+	"github.com/marcuswu/amf/amf3"
+	"github.com/marcuswu/amf/amftest"
 )
 
 func TestReadUTF8(t *testing.T) {
@@ -244,3 +252,490 @@ func TestDecodeTypedObject(t *testing.T) {
 		t.Fatalf("decode error")
 	}
 }
+
+func TestDecodeUniformStrings(t *testing.T) {
+	buf := bytes.NewReader([]byte{0x0c, 0x00, 0x00, 0x00, 0x03, 0x66, 0x6f, 0x6f})
+	expect := StringType("foo")
+	dec := NewDecoderWithOptions(buf, DecoderOptions{UniformStrings: true})
+	got, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if expect != got {
+		t.Fatalf("expect %v got %v", expect, got)
+	}
+}
+
+func TestDecodeResetReferences(t *testing.T) {
+	buf := bytes.NewReader([]byte{0x03, 0x00, 0x00, 0x09, 0x07, 0x00, 0x00, 0x07, 0x00, 0x00})
+	dec := NewDecoder(buf)
+	obj, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	got, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got != obj {
+		t.Fatalf("expect %v got %v", obj, got)
+	}
+	dec.ResetReferences()
+	_, err = dec.Decode()
+	if err == nil {
+		t.Fatalf("expect reference error after reset")
+	}
+}
+
+func TestDecodeAbsurdCount(t *testing.T) {
+	buf := bytes.NewReader([]byte{0x0a, 0xff, 0xff, 0xff, 0xff, 0x05})
+	_, err := NewDecoder(buf).Decode()
+	var lengthErr *LengthError
+	if !errors.As(err, &lengthErr) {
+		t.Fatalf("expect LengthError got %v", err)
+	}
+	if lengthErr.Length != 0xffffffff || lengthErr.Limit != 1 {
+		t.Fatalf("expect 4294967295 over 1 got %v", lengthErr)
+	}
+}
+
+func TestDecodeAbsurdAMF3Count(t *testing.T) {
+	buf := bytes.NewReader([]byte{0x11, 0x09, 0x0b, 0x01})
+	_, err := NewDecoder(buf).Decode()
+	var lengthErr *LengthError
+	if !errors.As(err, &lengthErr) {
+		t.Fatalf("expect LengthError got %v", err)
+	}
+	if !lengthErr.Remaining || lengthErr.Limit != 1 {
+		t.Fatalf("expect 5 over the 1 byte left got %v", lengthErr)
+	}
+	if msg := lengthErr.Error(); !strings.Contains(msg, "bytes left") {
+		t.Errorf("expect the bytes left in %q", msg)
+	}
+}
+
+func TestDecodeLongStringUnknownSize(t *testing.T) {
+	// the size of the input is unknown, so the length is only found wrong
+	// once the data runs out
+	r := iotest.OneByteReader(bytes.NewReader([]byte{0x0c, 0xff, 0xff, 0xff, 0xff, 'a'}))
+	_, err := NewDecoder(r).Decode()
+	if err == nil {
+		t.Fatalf("expect error for truncated long string")
+	}
+}
+
+func TestDecodeTruncatedNumber(t *testing.T) {
+	_, err := NewDecoder(bytes.NewReader([]byte{0x00, 0x40, 0x09})).Decode()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expect %v got %v", io.ErrUnexpectedEOF, err)
+	}
+	var de *DecodeError
+	if !errors.As(err, &de) || de.Offset != 3 || de.Marker != NumberMarker {
+		t.Errorf("expect the offset and marker of the number got %v", err)
+	}
+}
+
+func TestDecodeMissingObjectEnd(t *testing.T) {
+	// an object holding an ECMA array, both cut before their end markers
+	data := []byte{0x03, 0x00, 0x01, 'a', 0x08, 0x00, 0x00, 0x00, 0x02, 0x00, 0x01, 'b', 0x05}
+	_, err := NewDecoder(bytes.NewReader(data)).Decode()
+	if err == nil {
+		t.Fatalf("expect error for missing ObjectEndMarker")
+	}
+	for _, cut := range [][]byte{data, append(data, 0x00, 0x00)} {
+		got, err := NewDecoderWithOptions(bytes.NewReader(cut), DecoderOptions{AllowMissingObjectEnd: true}).Decode()
+		if err != ErrMissingObjectEnd {
+			t.Fatalf("expect %v got %v", ErrMissingObjectEnd, err)
+		}
+		array := (*got.(*ObjectType))["a"].(*EcmaArrayType)
+		if (*array)["b"] != (NullType{}) {
+			t.Fatalf("expect b to be null got %v", *array)
+		}
+	}
+	got, err := NewDecoderWithOptions(bytes.NewReader([]byte{0x03, 0x00, 0x00, 0x09}), DecoderOptions{AllowMissingObjectEnd: true}).Decode()
+	if err != nil || len(*got.(*ObjectType)) != 0 {
+		t.Fatalf("expect empty object got %v, %v", got, err)
+	}
+}
+
+// latin1 transcodes ISO 8859-1 to UTF-8.
+func latin1(b []byte) ([]byte, error) {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return []byte(string(runes)), nil
+}
+
+func TestDecodeTranscode(t *testing.T) {
+	data := []byte{0x03, 0x00, 0x04, 'c', 'a', 'f', 0xe9, 0x02, 0x00, 0x03, 0xe9, 't', 0xe9,
+		0x00, 0x01, 'x', 0x11, 0x06, 0x05, 0xfc, 'e',
+		0x00, 0x00, 0x09}
+	v, err := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{Transcode: latin1}).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	obj := *v.(*ObjectType)
+	if obj["café"] != StringType("été") || obj["x"] != amf3.StringType("üe") {
+		t.Errorf("expect transcoded strings got %v", obj)
+	}
+
+	fail := func(b []byte) ([]byte, error) { return nil, errors.New("bad byte") }
+	_, err = NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{Transcode: fail}).Decode()
+	if err == nil {
+		t.Errorf("expect transcoding error")
+	}
+}
+
+func TestDecoderOptions(t *testing.T) {
+	opts := NewDecoderWithOptions(bytes.NewReader(nil), DecoderOptions{UniformStrings: true, Transcode: latin1}).Options()
+	if !opts.UniformStrings || opts.Transcode == nil || opts.AMF3.Transcode == nil {
+		t.Errorf("expect options with the AMF3 transcoder inherited got %+v", opts)
+	}
+	encOpts := NewEncoderWithOptions(io.Discard, EncoderOptions{AVMPlus: true}).Options()
+	if !encOpts.AVMPlus {
+		t.Errorf("expect AVMPlus got %+v", encOpts)
+	}
+}
+
+func TestSkipValue(t *testing.T) {
+	data := []byte{0x0a, 0x00, 0x00, 0x00, 0x02,
+		0x03, 0x00, 0x01, 'a', 0x11, 0x09, 0x03, 0x01, 0x06, 0x03, 'x', 0x00, 0x00, 0x09,
+		0x08, 0x00, 0x00, 0x00, 0x01, 0x00, 0x01, 'b', 0x05, 0x00, 0x00, 0x09,
+		0x02, 0x00, 0x01, 'c'}
+	dec := NewDecoder(bytes.NewReader(data))
+	err := dec.SkipValue()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	v, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if v != StringType("c") {
+		t.Errorf("expect c after the skipped array got %v", v)
+	}
+	err = NewDecoder(bytes.NewReader(data[:10])).SkipValue()
+	if err == nil {
+		t.Errorf("expect error skipping a truncated value")
+	}
+}
+
+func TestDecodeMaxDepth(t *testing.T) {
+	// n strict arrays, each holding the next, around a number
+	nested := func(n int, amf3Arrays int) []byte {
+		var data []byte
+		for i := 0; i < n; i++ {
+			data = append(data, 0x0a, 0x00, 0x00, 0x00, 0x01)
+		}
+		data = append(data, 0x11)
+		for i := 0; i < amf3Arrays; i++ {
+			data = append(data, 0x09, 0x03, 0x01)
+		}
+		return append(data, 0x04, 0x01)
+	}
+	opts := DecoderOptions{MaxDepth: 3}
+	_, err := NewDecoderWithOptions(bytes.NewReader(nested(3, 0)), opts).Decode()
+	if err != nil {
+		t.Errorf("expect arrays at the limit to decode got %v", err)
+	}
+	for _, data := range [][]byte{nested(4, 0), nested(2, 2)} {
+		_, err = NewDecoderWithOptions(bytes.NewReader(data), opts).Decode()
+		if !errors.Is(err, ErrMaxDepth) {
+			t.Errorf("expect max depth error got %v", err)
+		}
+		err = NewDecoderWithOptions(bytes.NewReader(data), opts).SkipValue()
+		if !errors.Is(err, ErrMaxDepth) {
+			t.Errorf("expect max depth error skipping got %v", err)
+		}
+		var v interface{}
+		err = NewDecoderWithOptions(bytes.NewReader(data), opts).DecodeValueInto(&v)
+		if !errors.Is(err, ErrMaxDepth) {
+			t.Errorf("expect max depth error decoding into a value got %v", err)
+		}
+	}
+	var slices [][][][]float64
+	err = NewDecoderWithOptions(bytes.NewReader(nested(4, 0)), opts).DecodeValueInto(&slices)
+	if !errors.Is(err, ErrMaxDepth) {
+		t.Errorf("expect max depth error decoding into slices got %v", err)
+	}
+
+	_, err = NewDecoder(bytes.NewReader(nested(DefaultMaxDepth+1, 0))).Decode()
+	if !errors.Is(err, ErrMaxDepth) {
+		t.Errorf("expect the default limit to apply got %v", err)
+	}
+	_, err = NewDecoderWithOptions(bytes.NewReader(nested(DefaultMaxDepth+1, 0)), DecoderOptions{MaxDepth: -1}).Decode()
+	if err != nil {
+		t.Errorf("expect no limit with a negative depth got %v", err)
+	}
+}
+
+func TestDecodeMaxStringLength(t *testing.T) {
+	opts := DecoderOptions{MaxStringLength: 4}
+	for _, data := range [][]byte{
+		{0x02, 0x00, 0x05, 'h', 'e', 'l', 'l', 'o'},
+		{0x0c, 0xff, 0xff, 0xff, 0xf0},
+		{0x03, 0x00, 0x05, 'h', 'e', 'l', 'l', 'o', 0x05, 0x00, 0x00, 0x09},
+		{0x0f, 0x00, 0x00, 0x00, 0x08, '<', 'a', '>', '1', '<', '/', 'a', '>'},
+	} {
+		_, err := NewDecoderWithOptions(bytes.NewReader(data), opts).Decode()
+		var le *LengthError
+		if !errors.As(err, &le) || le.Limit != 4 {
+			t.Errorf("expect length error for %x got %v", data, err)
+		}
+	}
+	// AMF3 values inherit the limit
+	_, err := NewDecoderWithOptions(bytes.NewReader([]byte{0x11, 0x06, 0x0b, 'h', 'e', 'l', 'l', 'o'}), opts).Decode()
+	var le *amf3.LengthError
+	if !errors.As(err, &le) || le.Limit != 4 {
+		t.Errorf("expect AMF3 length error got %v", err)
+	}
+	got, err := NewDecoderWithOptions(bytes.NewReader([]byte{0x02, 0x00, 0x04, 'h', 'e', 'l', 'l'}), opts).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got != StringType("hell") {
+		t.Errorf("expect hell got %v", got)
+	}
+}
+
+func TestDecodeMaxArrayCount(t *testing.T) {
+	opts := DecoderOptions{MaxArrayCount: 2}
+	for _, data := range [][]byte{
+		{0x0a, 0xff, 0xff, 0xff, 0xff},
+		{0x08, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x09},
+		{0x11, 0x09, 0x07, 0x01, 0x04, 0x01, 0x04, 0x02, 0x04, 0x03},
+	} {
+		_, err := NewDecoderWithOptions(bytes.NewReader(data), opts).Decode()
+		var le *LengthError
+		var le3 *amf3.LengthError
+		if !errors.As(err, &le) && !errors.As(err, &le3) {
+			t.Errorf("expect length error for %x got %v", data, err)
+		}
+	}
+	var s []int
+	err := NewDecoderWithOptions(bytes.NewReader([]byte{0x0a, 0x00, 0x00, 0x00, 0x03}), opts).DecodeValueInto(&s)
+	var le *LengthError
+	if !errors.As(err, &le) {
+		t.Errorf("expect length error decoding into a slice got %v", err)
+	}
+	got, err := NewDecoderWithOptions(bytes.NewReader([]byte{0x0a, 0x00, 0x00, 0x00, 0x02, 0x05, 0x05}), opts).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(*got.(*StrictArrayType)) != 2 {
+		t.Errorf("expect 2 elements got %v", got)
+	}
+}
+
+func TestDecodeUnknownMarker(t *testing.T) {
+	_, err := NewDecoder(bytes.NewReader([]byte{0x20})).Decode()
+	var me *MarkerError
+	if !errors.As(err, &me) || me.Marker != 0x20 || !errors.Is(err, ErrUnknownMarker) {
+		t.Errorf("expect unknown marker error got %v", err)
+	}
+	err = NewDecoder(bytes.NewReader([]byte{0x20})).SkipValue()
+	if !errors.Is(err, ErrUnknownMarker) {
+		t.Errorf("expect unknown marker error skipping got %v", err)
+	}
+	// a strict array whose second element has an unknown marker
+	data := []byte{0x0a, 0x00, 0x00, 0x00, 0x02, 0x05, 0x20, 'x', 'y'}
+	got, err := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{CaptureUnknownMarkers: true}).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	array := *got.(*StrictArrayType)
+	if u, ok := array[1].(UnknownValue); !ok || u.Marker != 0x20 || string(u.Data) != "xy" {
+		t.Errorf("expect captured unknown value got %#v", array[1])
+	}
+	var buf bytes.Buffer
+	err = NewEncoder(&buf).Encode(got)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("expect %x got %x", data, buf.Bytes())
+	}
+	dec := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{CaptureUnknownMarkers: true})
+	err = dec.SkipValue()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	_, err = dec.Decode()
+	if err != io.EOF {
+		t.Errorf("expect the input consumed got %v", err)
+	}
+}
+
+func TestDecodeErrorOffset(t *testing.T) {
+	data := []byte{0x03, 0x00, 0x04, 'u', 's', 'e', 'r', 0x03, 0x00, 0x07, 'a', 'd', 'd', 'r', 'e', 's', 's',
+		0x02, 0x00, 0x05, 'a'}
+	_, err := NewDecoder(bytes.NewReader(data)).Decode()
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("expect a DecodeError got %v", err)
+	}
+	if de.Offset != 21 || de.Marker != StringMarker || de.Path != ".user.address" || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expect offset 21, string marker and path .user.address got %v", err)
+	}
+	err = NewDecoder(bytes.NewReader(data)).SkipValue()
+	if !errors.As(err, &de) || de.Offset != 21 || de.Marker != StringMarker {
+		t.Errorf("expect offset 21 and string marker skipping got %v", err)
+	}
+
+	// offsets in AMF3 values count from the start of the AMF0 input
+	data = []byte{0x0a, 0x00, 0x00, 0x00, 0x02, 0x05, 0x11, 0x09, 0x03, 0x01, 0x20}
+	_, err = NewDecoder(bytes.NewReader(data)).Decode()
+	if !errors.As(err, &de) {
+		t.Fatalf("expect a DecodeError got %v", err)
+	}
+	if de.Offset != 11 || de.Marker != 0x20 || de.Path != "[1][0]" || !errors.Is(err, ErrUnknownMarker) {
+		t.Errorf("expect offset 11, marker 0x20 and path [1][0] got %v", err)
+	}
+
+	_, err = NewDecoder(bytes.NewReader(nil)).Decode()
+	if err != io.EOF {
+		t.Errorf("expect EOF before any value got %v", err)
+	}
+}
+
+func TestDecodeMaxValueCount(t *testing.T) {
+	data := []byte{0x0a, 0x00, 0x00, 0x00, 0x02, 0x05, 0x11, 0x09, 0x05, 0x01, 0x04, 0x01, 0x04, 0x02}
+	dec := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{MaxValueCount: 5})
+	_, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if dec.ValueCount() != 5 {
+		t.Errorf("expect 5 values counted got %d", dec.ValueCount())
+	}
+	// the limit holds across the switch to AMF3
+	_, err = NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{MaxValueCount: 4}).Decode()
+	if !errors.Is(err, ErrMaxValueCount) {
+		t.Errorf("expect max value count error got %v", err)
+	}
+	err = NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{MaxValueCount: 4}).SkipValue()
+	if !errors.Is(err, ErrMaxValueCount) {
+		t.Errorf("expect max value count error skipping got %v", err)
+	}
+	var v []interface{}
+	err = NewDecoderWithOptions(bytes.NewReader(data[:6]), DecoderOptions{MaxValueCount: 1}).DecodeValueInto(&v)
+	if !errors.Is(err, ErrMaxValueCount) {
+		t.Errorf("expect max value count error decoding into a slice got %v", err)
+	}
+}
+
+func TestSeedReferences(t *testing.T) {
+	server := &ObjectType{"name": StringType("fms")}
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SeedReferences([]interface{}{server})
+	obj := &ObjectType{"a": StringType("b")}
+	err := enc.Encode(&StrictArrayType{server, obj, obj})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect := []byte{0x0a, 0x00, 0x00, 0x00, 0x03, 0x07, 0x00, 0x00,
+		0x03, 0x00, 0x01, 'a', 0x02, 0x00, 0x01, 'b', 0x00, 0x00, 0x09, 0x07, 0x00, 0x02}
+	if !bytes.Equal(buf.Bytes(), expect) {
+		t.Fatalf("expect %x got %x", expect, buf.Bytes())
+	}
+
+	dec := NewDecoder(bytes.NewReader(append(expect, 0x07, 0x00, 0x00)))
+	dec.SeedReferences([]interface{}{server})
+	v, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	arr := *v.(*StrictArrayType)
+	if arr[0] != server || arr[1] != arr[2] {
+		t.Errorf("expect the seed and the shared object got %v", arr)
+	}
+	// the seeds outlive a reset
+	dec.ResetReferences()
+	v, err = dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if v != server {
+		t.Errorf("expect the seed after reset got %v", v)
+	}
+}
+
+func TestDecodeErrorKinds(t *testing.T) {
+	_, err := NewDecoder(bytes.NewReader([]byte{0x07, 0x00, 0x05})).Decode()
+	var re *ReferenceError
+	if !errors.Is(err, ErrReferenceOutOfRange) || !errors.As(err, &re) || re.Index != 5 || re.Len != 0 {
+		t.Errorf("expect reference 5 out of range got %v", err)
+	}
+	_, err = NewDecoder(bytes.NewReader([]byte{0x08, 0x00, 0x00, 0x00, 0x02, 0x00, 0x01, 'a', 0x05, 0x00, 0x00, 0x09})).Decode()
+	if !errors.Is(err, ErrECMACountMismatch) {
+		t.Errorf("expect ECMA count mismatch got %v", err)
+	}
+	_, err = NewDecoder(bytes.NewReader([]byte{0x03, 0x00, 0x01, 'a', 0x05, 0x00, 0x01, 'a', 0x05, 0x00, 0x00, 0x09})).Decode()
+	var de *DecodeError
+	if !errors.Is(err, ErrDuplicateProperty) || !errors.As(err, &de) || de.Path != ".a" {
+		t.Errorf("expect duplicate property .a got %v", err)
+	}
+	_, err = NewDecoder(bytes.NewReader([]byte{0x03, 0x00, 0x00, 0x05})).Decode()
+	if !errors.Is(err, ErrExpectedObjectEnd) {
+		t.Errorf("expect missing object end marker got %v", err)
+	}
+	_, err = NewDecoder(bytes.NewReader([]byte{0x04})).Decode()
+	if !errors.Is(err, ErrUnsupportedMarker) {
+		t.Errorf("expect unsupported marker got %v", err)
+	}
+	err = NewEncoder(new(bytes.Buffer)).Encode(make(chan int))
+	var ue *UnsupportedTypeError
+	if !errors.Is(err, ErrUnsupportedType) || !errors.As(err, &ue) || ue.Type.Kind() != reflect.Chan {
+		t.Errorf("expect unsupported type chan got %v", err)
+	}
+}
+
+func TestDecodeChunkedReader(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	err := enc.Encode(map[string]interface{}{
+		"n":    1.5,
+		"s":    "str",
+		"long": strings.Repeat("x", 70000),
+		"arr":  []interface{}{1, "a", true},
+	})
+	if err == nil {
+		err = enc.EncodeAMF3(map[string]interface{}{"i": 7, "s": "amf3"})
+	}
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	data := buf.Bytes()
+	var expect []interface{}
+	dec := NewDecoder(bytes.NewReader(data))
+	for i := 0; i < 2; i++ {
+		v, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		expect = append(expect, v)
+	}
+	// reads come back short, as they do from a network connection
+	for _, sizes := range [][]int{nil, {3, 7}, {1000}} {
+		dec := NewDecoder(&amftest.ChunkedReader{R: bytes.NewReader(data), Sizes: sizes})
+		for i := range expect {
+			v, err := dec.Decode()
+			if err != nil {
+				t.Fatalf("chunks of %v: %s", sizes, err)
+			}
+			if !reflect.DeepEqual(v, expect[i]) {
+				t.Errorf("chunks of %v: expect %v got %v", sizes, expect[i], v)
+			}
+		}
+		dec = NewDecoder(&amftest.ChunkedReader{R: bytes.NewReader(data), Sizes: sizes})
+		err := dec.SkipValue()
+		if err == nil {
+			err = dec.SkipValue()
+		}
+		if err != nil {
+			t.Fatalf("chunks of %v: skipping: %s", sizes, err)
+		}
+	}
+}
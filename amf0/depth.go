@@ -0,0 +1,55 @@
+package amf0
+
+import (
+	"github.com/marcuswu/amf/amf3"
+)
+
+// DefaultMaxDepth is the nesting depth allowed when DecoderOptions.MaxDepth
+// is 0.
+const DefaultMaxDepth = amf3.DefaultMaxDepth
+
+func (dec *Decoder) maxDepth() int {
+	if dec.opts.MaxDepth == 0 {
+		return DefaultMaxDepth
+	}
+	return dec.opts.MaxDepth
+}
+
+// enter counts one more level of nesting, failing past the limit. Every
+// successful enter is followed by a leave.
+func (dec *Decoder) enter() error {
+	if limit := dec.maxDepth(); limit > 0 && dec.depth >= limit {
+		return ErrMaxDepth
+	}
+	dec.depth++
+	return nil
+}
+
+func (dec *Decoder) leave() {
+	dec.depth--
+}
+
+// isContainer reports whether the values of marker may hold other values.
+func isContainer(marker byte) bool {
+	switch marker {
+	case ObjectMarker, EcmaArrayMarker, StrictArrayMarker, TypedObjectMarker:
+		return true
+	}
+	return false
+}
+
+// amf3Options returns the options of the decoder of a value switching to
+// AMF3, which counts its nesting towards the limit of dec. As the AMF3
+// options cannot forbid nesting altogether, a value switching to AMF3 at
+// the limit may nest one level more.
+func (dec *Decoder) amf3Options() amf3.DecoderOptions {
+	opts := dec.opts.AMF3
+	if limit := dec.maxDepth(); limit > 0 && opts.MaxDepth == 0 {
+		opts.MaxDepth = max(limit-dec.depth, 1)
+	}
+	// the AMF3 value takes the place of the switch marker, counted already
+	if limit := dec.opts.MaxValueCount; limit > 0 && opts.MaxValueCount == 0 {
+		opts.MaxValueCount = limit - dec.valueCount + 1
+	}
+	return opts
+}
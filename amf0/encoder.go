@@ -6,12 +6,16 @@ import (
 	"errors"
 	"io"
 	"math"
+	"reflect"
+
+	"github.com/marcuswu/amf/amf3"
 )
 
 type Encoder struct {
 	w       io.Writer
 	bw      *bufio.Writer
 	refObjs []interface{}
+	opts    EncoderOptions
 }
 
 func NewEncoder(w io.Writer) *Encoder {
@@ -19,18 +23,38 @@ func NewEncoder(w io.Writer) *Encoder {
 }
 
 func (enc *Encoder) Encode(v interface{}) error {
-	err := enc.encodeValue(v)
+	var err error
+	if enc.opts.AVMPlus {
+		err = enc.encodeAMF3(v)
+	} else {
+		err = enc.encodeValue(v)
+	}
 	if err != nil {
 		return err
 	}
+	if enc.bw.Buffered() < enc.opts.FlushThreshold {
+		return nil
+	}
 	err = enc.bw.Flush()
 	return err
 }
 
+// Flush writes any buffered values to the underlying writer.
+func (enc *Encoder) Flush() error {
+	return enc.bw.Flush()
+}
+
 func (enc *Encoder) encodeValue(v interface{}) error {
 	u32 := make([]byte, 4)
 	u64 := make([]byte, 8)
-	if value, ok := v.(NumberType); ok {
+	if fn := enc.encoderFor(reflect.TypeOf(v)); fn != nil {
+		return fn(enc, v)
+	}
+	if value, ok := v.(RawMessage); ok {
+		return enc.encodeRaw(value)
+	} else if value, ok := v.(UnknownValue); ok {
+		return enc.encodeUnknown(value)
+	} else if value, ok := v.(NumberType); ok {
 		err := enc.bw.WriteByte(NumberMarker)
 		if err != nil {
 			return err
@@ -152,7 +176,8 @@ func (enc *Encoder) encodeValue(v interface{}) error {
 		if err != nil {
 			return err
 		}
-		_, err = enc.bw.Write([]byte{0x00, 0x00})
+		binary.BigEndian.PutUint16(u64, uint16(value.TimeZone))
+		_, err = enc.bw.Write(u64[:2])
 		if err != nil {
 			return err
 		}
@@ -179,6 +204,8 @@ func (enc *Encoder) encodeValue(v interface{}) error {
 		if err != nil {
 			return err
 		}
+	} else if value, ok := v.(TypedValue); ok {
+		return enc.encodeTyped(value)
 	} else if value, ok := v.(*TypedObjectType); ok {
 		ok, err := enc.writeRef(value)
 		if err != nil {
@@ -199,12 +226,52 @@ func (enc *Encoder) encodeValue(v interface{}) error {
 				return err
 			}
 		}
+	} else if value, ok := v.(XmlDocumentSpan); ok {
+		err := enc.bw.WriteByte(XmlDocumentMarker)
+		if err != nil {
+			return err
+		}
+		binary.BigEndian.PutUint32(u32, uint32(len(value)))
+		_, err = enc.bw.Write(u32)
+		if err != nil {
+			return err
+		}
+		_, err = enc.bw.Write(value)
+		if err != nil {
+			return err
+		}
+	} else if isAMF3(v) {
+		return enc.encodeAMF3(v)
 	} else {
-		return errors.New("unsupported type")
+		return enc.encodeReflect(reflect.ValueOf(v))
 	}
 	return nil
 }
 
+// EncodeAMF3 writes the switch to AMF3 marker followed by v in AMF3, as
+// Encode does for every value with AVMPlus. Registered encoders may use it
+// for Go types that peers expect in AMF3.
+func (enc *Encoder) EncodeAMF3(v interface{}) error {
+	err := enc.encodeAMF3(v)
+	if err != nil {
+		return err
+	}
+	if enc.bw.Buffered() < enc.opts.FlushThreshold {
+		return nil
+	}
+	return enc.bw.Flush()
+}
+
+// encodeAMF3 writes the switch to AMF3 marker followed by v in AMF3, with
+// reference tables of its own.
+func (enc *Encoder) encodeAMF3(v interface{}) error {
+	err := enc.bw.WriteByte(SwitchToAmf3Marker)
+	if err != nil {
+		return err
+	}
+	return amf3.NewEncoderWithOptions(enc.bw, enc.opts.AMF3).Encode(v)
+}
+
 func (enc *Encoder) writeRef(v interface{}) (bool, error) {
 	u16 := make([]byte, 2)
 	for i, obj := range enc.refObjs {
@@ -225,8 +292,22 @@ func (enc *Encoder) writeRef(v interface{}) (bool, error) {
 }
 
 func (enc *Encoder) writeObject(obj _Object) error {
+	var written map[StringType]bool
 	for k, v := range obj {
-		err := writeUTF8(enc.bw, k)
+		name, err := enc.propertyName(k)
+		if err != nil {
+			return err
+		}
+		if name != k {
+			if written == nil {
+				written = make(map[StringType]bool, len(obj))
+			}
+			if _, ok := obj[name]; ok || written[name] {
+				return errors.New("property names collide after remediation")
+			}
+			written[name] = true
+		}
+		err = writeUTF8(enc.bw, name)
 		if err != nil {
 			return err
 		}
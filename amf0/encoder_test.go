@@ -2,7 +2,11 @@ package amf0
 
 import (
 	"bytes"
+	"errors"
+	"io"
 	"testing"
+
+	"github.com/marcuswu/amf/amf3"
 )
 
 func TestWriteUTF8(t *testing.T) {
@@ -264,3 +268,174 @@ func TestEncodeTypedObject(t *testing.T) {
 		t.Errorf("expect %x got %x", expect, got)
 	}
 }
+
+func TestEncodeInvalidPropertyName(t *testing.T) {
+	obj := ObjectType{"a\xff": NullType{}}
+	enc := NewEncoder(new(bytes.Buffer))
+	err := enc.Encode(&obj)
+	if err == nil {
+		t.Fatalf("expect error for invalid UTF-8 property name")
+	}
+
+	buf := new(bytes.Buffer)
+	enc = NewEncoderWithOptions(buf, EncoderOptions{SanitizeKeys: true})
+	err = enc.Encode(&obj)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect := []byte{0x03, 0x00, 0x04, 0x61, 0xef, 0xbf, 0xbd, 0x05, 0x00, 0x00, 0x09}
+	got := buf.Bytes()
+	if !bytes.Equal(expect, got) {
+		t.Errorf("expect %x got %x", expect, got)
+	}
+
+	obj = ObjectType{"a\xff": NullType{}, "a�": NullType{}}
+	enc = NewEncoderWithOptions(new(bytes.Buffer), EncoderOptions{SanitizeKeys: true})
+	err = enc.Encode(&obj)
+	if err == nil {
+		t.Fatalf("expect error for colliding property names")
+	}
+}
+
+func TestEncodeLongPropertyName(t *testing.T) {
+	long := StringType(bytes.Repeat([]byte{'a'}, 0x10000))
+	obj := ObjectType{long: NullType{}}
+	enc := NewEncoder(new(bytes.Buffer))
+	err := enc.Encode(&obj)
+	if err == nil {
+		t.Fatalf("expect error for long property name")
+	}
+
+	buf := new(bytes.Buffer)
+	enc = NewEncoderWithOptions(buf, EncoderOptions{TruncateLongKeys: true})
+	err = enc.Encode(&obj)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	got, err := NewBytesDecoder(buf.Bytes()).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for k := range *got.(*ObjectType) {
+		if len(k) != 0xFFFF || k[:100] != long[:100] {
+			t.Errorf("unexpected truncated name %q", k[len(k)-20:])
+		}
+	}
+}
+
+func TestEncodeFlushThreshold(t *testing.T) {
+	buf := new(bytes.Buffer)
+	enc := NewEncoderWithOptions(buf, EncoderOptions{FlushThreshold: 16})
+	err := enc.Encode(NumberType(1))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expect value to stay buffered got %x", buf.Bytes())
+	}
+	err = enc.Flush()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if buf.Len() != 9 {
+		t.Fatalf("expect 9 bytes after flush got %x", buf.Bytes())
+	}
+	err = enc.Encode(StringType("a long enough string"))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if buf.Len() != 9+23 {
+		t.Fatalf("expect value over the threshold to be written got %x", buf.Bytes())
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	data := []byte{0x0a, 0x00, 0x00, 0x00, 0x03,
+		0x0b, 0x40, 0x14, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xc4,
+		0x0f, 0x00, 0x00, 0x00, 0x03, '<', 'a', '>',
+		0x11, 0x06, 0x05, 'h', 'i'}
+	for _, lazy := range []bool{false, true} {
+		v, err := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{LazyXML: lazy}).Decode()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		buf := new(bytes.Buffer)
+		err = NewEncoder(buf).Encode(v)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if !bytes.Equal(data, buf.Bytes()) {
+			t.Errorf("expect %x got %x", data, buf.Bytes())
+		}
+	}
+}
+
+func TestEncodeAVMPlus(t *testing.T) {
+	buf := new(bytes.Buffer)
+	enc := NewEncoderWithOptions(buf, EncoderOptions{AVMPlus: true})
+	for _, v := range []interface{}{"hi", 5} {
+		err := enc.Encode(v)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	expect := []byte{0x11, 0x06, 0x05, 'h', 'i', 0x11, 0x04, 0x05}
+	if !bytes.Equal(expect, buf.Bytes()) {
+		t.Fatalf("expect %x got %x", expect, buf.Bytes())
+	}
+	dec := NewDecoder(bytes.NewReader(buf.Bytes()))
+	for _, want := range []interface{}{amf3.StringType("hi"), amf3.IntegerType(5)} {
+		got, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if got != want {
+			t.Errorf("expect %v got %v", want, got)
+		}
+	}
+}
+
+func TestEncodeAVMPlusAMF0Types(t *testing.T) {
+	type point struct{ X int }
+	testCases := []struct {
+		v      interface{}
+		expect []byte
+	}{
+		{NullType{}, []byte{0x11, 0x01}},
+		{UndefinedType{}, []byte{0x11, 0x00}},
+		{DateType{Date: 1}, []byte{0x11, 0x08, 0x01, 0x3f, 0xf0, 0, 0, 0, 0, 0, 0}},
+		{
+			&TypedObjectType{ClassName: "a.B", Object: _Object{}},
+			[]byte{0x11, 0x0a, 0x0b, 0x07, 'a', '.', 'B', 0x01},
+		},
+		{
+			TypedValue{ClassName: "a.P", Value: point{X: 1}},
+			[]byte{0x11, 0x0a, 0x13, 0x07, 'a', '.', 'P', 0x03, 'X', 0x04, 0x01},
+		},
+	}
+	for _, tc := range testCases {
+		buf := new(bytes.Buffer)
+		enc := NewEncoderWithOptions(buf, EncoderOptions{AVMPlus: true})
+		if err := enc.CanEncode(tc.v); err != nil {
+			t.Errorf("check %#v: %s", tc.v, err)
+		}
+		if err := enc.Encode(tc.v); err != nil {
+			t.Fatalf("%s", err)
+		}
+		if !bytes.Equal(tc.expect, buf.Bytes()) {
+			t.Errorf("encode %#v: expect %x got %x", tc.v, tc.expect, buf.Bytes())
+		}
+	}
+}
+
+func TestEncodeAVMPlusNoEncoding(t *testing.T) {
+	for _, v := range []interface{}{RawMessage{0x05}, UnknownValue{Marker: 0x20}} {
+		enc := NewEncoderWithOptions(io.Discard, EncoderOptions{AVMPlus: true})
+		if err := enc.Encode(v); !errors.Is(err, ErrNoAMF3Encoding) {
+			t.Errorf("encode %#v: expect ErrNoAMF3Encoding got %v", v, err)
+		}
+		if err := enc.CanEncode(v); !errors.Is(err, ErrNoAMF3Encoding) {
+			t.Errorf("check %#v: expect ErrNoAMF3Encoding got %v", v, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package amf0
+
+import (
+	"errors"
+
+	"github.com/marcuswu/amf/amf3"
+)
+
+// Errors that decoding and encoding fail with, for callers to tell the
+// kinds of failure apart with errors.Is. The ones shared with the amf3
+// package are its errors, so that one errors.Is test covers values after
+// the switch to AMF3 too.
+var (
+	// ErrReferenceOutOfRange is wrapped by a *ReferenceError.
+	ErrReferenceOutOfRange = amf3.ErrReferenceOutOfRange
+	// ErrSkippedReference is returned for references to objects that were
+	// skipped rather than decoded.
+	ErrSkippedReference = errors.New("reference to skipped value")
+	// ErrECMACountMismatch is returned when an ECMA array holds another
+	// number of properties than the count written before them.
+	ErrECMACountMismatch = errors.New("EcmaArray count error")
+	// ErrDuplicateProperty is returned, at the path of the property, when
+	// an object holds the same property name twice.
+	ErrDuplicateProperty = errors.New("object-property exists")
+	// ErrExpectedObjectEnd is returned when the empty name ending an
+	// object is followed by another marker than ObjectEndMarker.
+	ErrExpectedObjectEnd = errors.New("expect ObjectEndMarker here")
+	// ErrUnsupportedMarker is returned for the MovieClip and RecordSet
+	// markers, which are reserved and never written.
+	ErrUnsupportedMarker = errors.New("unsupported marker")
+	// ErrUnsupportedType is wrapped by an *UnsupportedTypeError.
+	ErrUnsupportedType = amf3.ErrUnsupportedType
+	// ErrNoAMF3Encoding is returned for RawMessages and UnknownValues
+	// encoded as AMF3, as with EncoderOptions.AVMPlus.
+	ErrNoAMF3Encoding = errors.New("value has no AMF3 encoding")
+	// ErrMaxDepth is returned when objects and arrays are nested deeper
+	// than the decoder allows.
+	ErrMaxDepth = amf3.ErrMaxDepth
+	// ErrUnknownMarker is wrapped by a *MarkerError.
+	ErrUnknownMarker = amf3.ErrUnknownMarker
+	// ErrMaxValueCount is returned when the decoder has decoded more
+	// values than the MaxValueCount option allows.
+	ErrMaxValueCount = amf3.ErrMaxValueCount
+)
+
+// Error types that decoding and encoding fail with, for errors.As. They are
+// the types of the amf3 package, for the same reason as the errors above.
+type (
+	// DecodeError reports where decoding failed, as json.SyntaxError
+	// does: the offset in the input, the marker of the innermost value
+	// being decoded and the path to it, e.g. ".user.address".
+	DecodeError = amf3.DecodeError
+	// MarkerError reports a value starting with a marker that no type
+	// has.
+	MarkerError = amf3.MarkerError
+	// ReferenceError reports a reference past the end of the reference
+	// table.
+	ReferenceError = amf3.ReferenceError
+	// UnsupportedTypeError reports a Go type that has no encoding.
+	UnsupportedTypeError = amf3.UnsupportedTypeError
+	// LengthError reports a count or length read from the input that is
+	// over a configured limit or over what is left of the input.
+	LengthError = amf3.LengthError
+)
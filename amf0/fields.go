@@ -0,0 +1,163 @@
+package amf0
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// field describes a struct field mapped to an object property through its
+// `amf:"name,options"` tag. The options are omitempty, date, variant and
+// conv=name, which picks a converter registered with RegisterConverter.
+type field struct {
+	name      StringType
+	goName    string
+	index     []int
+	typ       reflect.Type
+	omitEmpty bool
+	date      bool
+	variant   bool
+	conv      *Converter
+	convName  string // converter named by the tag
+	unset     bool   // the field is the Unset set of its struct
+}
+
+// Unset records the fields of a struct that are explicitly unset, by Go
+// field name. A struct with a field of this type writes the listed fields as
+// undefined instead of their value, and a decoder fills it with the fields
+// that arrived as undefined, telling them apart from null ones:
+//
+//	type UserPatch struct {
+//		Unset amf0.Unset
+//		Name  string `amf:"name"`
+//	}
+type Unset map[string]bool
+
+var unsetType = reflect.TypeOf(Unset(nil))
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+// typeFields returns the properties of struct type t. Exported fields are
+// mapped under their own name unless the tag renames them; a tag of "-"
+// skips the field. Untagged embedded structs contribute their fields.
+func typeFields(t reflect.Type) []field {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.([]field)
+	}
+	fields := collectFields(t, nil)
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+func collectFields(t reflect.Type, index []int) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("amf")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fi := append(append([]int(nil), index...), i)
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, collectFields(ft, fi)...)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if sf.Type == unsetType {
+			fields = append(fields, field{goName: sf.Name, index: fi, typ: sf.Type, unset: true})
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		f := field{name: StringType(name), goName: sf.Name, index: fi, typ: sf.Type}
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "omitempty":
+				f.omitEmpty = true
+			case "date":
+				f.date = true
+			case "variant":
+				f.variant = true
+			default:
+				if name, ok := strings.CutPrefix(opt, "conv="); ok {
+					f.convName = name
+				}
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// converter returns the converter for the field of struct type t: the one
+// given by a class mapping, by RegisterFieldConverter or by the conv tag
+// option, in that order, or nil.
+func (f *field) converter(r *Registry, t reflect.Type) (*Converter, error) {
+	if f.conv != nil {
+		return f.conv, nil
+	}
+	if conv := r.fieldConverters[fieldKey{t, f.goName}]; conv != nil {
+		return conv, nil
+	}
+	if f.convName == "" {
+		return nil, nil
+	}
+	conv := r.converters[f.convName]
+	if conv == nil {
+		return nil, errors.New("field " + string(f.name) + ": unknown converter " + f.convName)
+	}
+	return conv, nil
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex but reports whether the
+// field is reachable instead of panicking on nil embedded pointers.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// unsetOf returns the Unset set of the struct rv, if it has one.
+func unsetOf(rv reflect.Value, fields []field) (reflect.Value, bool) {
+	for _, f := range fields {
+		if f.unset {
+			return fieldByIndex(rv, f.index)
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// markUnset records in the Unset set of rv whether the field goName arrived
+// as undefined.
+func markUnset(rv reflect.Value, fields []field, goName string, unset bool) {
+	set, ok := unsetOf(rv, fields)
+	if !ok {
+		return
+	}
+	if unset {
+		if set.IsNil() {
+			set.Set(reflect.MakeMap(unsetType))
+		}
+		set.SetMapIndex(reflect.ValueOf(goName), reflect.ValueOf(true))
+	} else if !set.IsNil() {
+		set.SetMapIndex(reflect.ValueOf(goName), reflect.Value{})
+	}
+}
@@ -0,0 +1,42 @@
+package amf0
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// keyHashLength is the number of bytes a truncated property name spends on
+// the separator and the hash of the full name.
+const keyHashLength = 1 + 16
+
+// propertyName checks that k can be written as an object property name and
+// applies the remediation configured in the encoder options.
+func (enc *Encoder) propertyName(k StringType) (StringType, error) {
+	if !utf8.ValidString(string(k)) {
+		if !enc.opts.SanitizeKeys {
+			return "", errors.New("invalid UTF-8 in property name")
+		}
+		k = StringType(strings.ToValidUTF8(string(k), "�"))
+	}
+	if len(k) > 0xFFFF {
+		if !enc.opts.TruncateLongKeys {
+			return "", errors.New("property name too long")
+		}
+		k = truncateKey(k)
+	}
+	return k, nil
+}
+
+// truncateKey cuts k down to the longest valid prefix that leaves room for a
+// separator and a hash of the full name, keeping truncated names distinct.
+func truncateKey(k StringType) StringType {
+	sum := sha256.Sum256([]byte(k))
+	n := 0xFFFF - keyHashLength
+	for n > 0 && !utf8.RuneStart(k[n]) {
+		n--
+	}
+	return k[:n] + "~" + StringType(hex.EncodeToString(sum[:8]))
+}
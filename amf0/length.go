@@ -0,0 +1,95 @@
+package amf0
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/marcuswu/amf/amf3"
+)
+
+// allocChunk is the most that is allocated up front for a length that
+// cannot be checked against the input left; longer values grow as their
+// data arrives.
+const allocChunk = 64 << 10
+
+// remaining returns the number of bytes left in r, or -1 if unknown.
+// Readers that may not know their size report it through Remaining.
+func remaining(r io.Reader) int64 {
+	switch l := r.(type) {
+	case interface{ Remaining() int64 }:
+		return l.Remaining()
+	case interface{ Len() int }:
+		return int64(l.Len())
+	}
+	return -1
+}
+
+// checkLength fails with a *LengthError when n items of at least one byte
+// each cannot fit in what is left of r.
+func checkLength(r io.Reader, what string, n uint64) error {
+	if left := remaining(r); left >= 0 && n > uint64(left) {
+		return &LengthError{What: what, Length: n, Limit: left, Remaining: true}
+	}
+	return nil
+}
+
+// checkCount fails with a *LengthError when an array count is over the
+// MaxArrayCount option.
+func (dec *Decoder) checkCount(what string, n uint32) error {
+	if limit := dec.opts.MaxArrayCount; limit > 0 && n > limit {
+		return &LengthError{What: what, Length: uint64(n), Limit: int64(limit)}
+	}
+	return nil
+}
+
+// countValue counts one more value decoded, skipped or read as a token,
+// failing past the MaxValueCount option.
+func (dec *Decoder) countValue() error {
+	if limit := dec.opts.MaxValueCount; limit > 0 && dec.valueCount >= limit {
+		return ErrMaxValueCount
+	}
+	dec.valueCount++
+	return nil
+}
+
+// countAMF3 adds the values of the AMF3 value decoded or skipped by d to
+// the count of dec, less the switch marker counted already.
+func (dec *Decoder) countAMF3(d *amf3.Decoder) {
+	if n := d.ValueCount(); n > 0 {
+		dec.valueCount += n - 1
+	}
+}
+
+// ValueCount returns the number of values, nested ones included, that dec
+// has decoded, skipped or read as tokens.
+func (dec *Decoder) ValueCount() int {
+	return dec.valueCount
+}
+
+// ResetValueCount starts the count that MaxValueCount limits again, so that
+// a decoder reused for values that are unrelated, such as the arguments of
+// successive RTMP commands, limits each of them on its own.
+func (dec *Decoder) ResetValueCount() {
+	dec.valueCount = 0
+}
+
+// readLength reads the n bytes of a value whose length came from the input.
+func readLength(r io.Reader, what string, n uint64) ([]byte, error) {
+	err := checkLength(r, what, n)
+	if err != nil {
+		return nil, err
+	}
+	if n <= allocChunk || remaining(r) >= 0 {
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		return b, noEOF(err)
+	}
+	var buf bytes.Buffer
+	_, err = io.CopyN(&buf, r, int64(n))
+	return buf.Bytes(), noEOF(err)
+}
+
+// Len reports the bytes left in the buffer.
+func (r *bytesReader) Len() int {
+	return len(r.b) - r.off
+}
@@ -0,0 +1,131 @@
+package amf0
+
+import (
+	"errors"
+	"io"
+)
+
+// replayReader remembers what was read from r since the last mark so that
+// it can be read again.
+type replayReader struct {
+	r      io.Reader
+	buf    []byte // bytes read since the mark, or still waiting to be replayed
+	pos    int    // replay position in buf
+	marked bool
+}
+
+func (rr *replayReader) Read(p []byte) (int, error) {
+	if rr.pos < len(rr.buf) {
+		n := copy(p, rr.buf[rr.pos:])
+		rr.pos += n
+		if !rr.marked && rr.pos == len(rr.buf) {
+			rr.buf, rr.pos = nil, 0
+		}
+		return n, nil
+	}
+	n, err := rr.r.Read(p)
+	if rr.marked {
+		rr.buf = append(rr.buf, p[:n]...)
+		rr.pos = len(rr.buf)
+	}
+	return n, err
+}
+
+func (rr *replayReader) ReadByte() (byte, error) {
+	b := make([]byte, 1)
+	_, err := io.ReadFull(rr, b)
+	return b[0], err
+}
+
+// mark is the state of the decoder when Mark was called.
+type mark struct {
+	off        int // bytes decoder offset
+	refObjs    int
+	valueCount int
+	depth      int
+	tokens     []tokenFrame
+	pending    *io.LimitedReader
+	pendingN   int64
+	truncated  bool
+}
+
+// Mark records the current position and state of the decoder. A later call
+// to Rewind returns to it, so that a parse can be attempted and abandoned
+// without consuming the input. Everything read after the mark is buffered
+// until Rewind or Commit is called.
+func (dec *Decoder) Mark() {
+	m := &mark{
+		refObjs:    len(dec.refObjs),
+		valueCount: dec.valueCount,
+		depth:      dec.depth,
+		tokens:     append([]tokenFrame(nil), dec.tokens...),
+		pending:    dec.pending,
+		truncated:  dec.truncated,
+	}
+	if dec.pending != nil {
+		m.pendingN = dec.pending.N
+	}
+	if br, ok := dec.r.(*bytesReader); ok {
+		m.off = br.off
+	} else {
+		rr, ok := dec.r.(*replayReader)
+		if !ok {
+			rr = &replayReader{r: dec.r}
+			dec.r = rr
+		}
+		rr.buf = rr.buf[rr.pos:]
+		rr.pos = 0
+		rr.marked = true
+	}
+	dec.mark = m
+}
+
+// Rewind moves the decoder back to the position recorded by Mark, restoring
+// its reference table, value count and place in the values read by Token,
+// and forgets the mark. Call Mark again right after Rewind to make another
+// attempt.
+func (dec *Decoder) Rewind() error {
+	if dec.mark == nil {
+		return errors.New("rewind without mark")
+	}
+	if br, ok := dec.r.(*bytesReader); ok {
+		br.off = dec.mark.off
+	} else {
+		rr := dec.r.(*replayReader)
+		rr.pos = 0
+		rr.marked = false
+		if len(rr.buf) == 0 {
+			rr.buf = nil
+		}
+	}
+	m := dec.mark
+	dec.refObjs = dec.refObjs[:m.refObjs]
+	dec.valueCount = m.valueCount
+	dec.depth = m.depth
+	dec.tokens = m.tokens
+	dec.pending = m.pending
+	if m.pending != nil {
+		m.pending.N = m.pendingN
+	}
+	dec.truncated = m.truncated
+	dec.mark = nil
+	return nil
+}
+
+// Commit forgets the mark recorded by Mark, keeping what was decoded since,
+// and stops buffering the input for Rewind.
+func (dec *Decoder) Commit() error {
+	if dec.mark == nil {
+		return errors.New("commit without mark")
+	}
+	if rr, ok := dec.r.(*replayReader); ok {
+		rr.buf = rr.buf[rr.pos:]
+		rr.pos = 0
+		rr.marked = false
+		if len(rr.buf) == 0 {
+			rr.buf = nil
+		}
+	}
+	dec.mark = nil
+	return nil
+}
@@ -0,0 +1,117 @@
+package amf0
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestMarkRewind(t *testing.T) {
+	data := []byte{0x03, 0x00, 0x03, 0x66, 0x6f, 0x6f, 0x02, 0x00, 0x03, 0x62, 0x61, 0x72, 0x00, 0x00, 0x09, 0x07, 0x00, 0x00}
+	decoders := []*Decoder{NewDecoder(bytes.NewReader(data)), NewBytesDecoder(data)}
+	for _, dec := range decoders {
+		dec.Mark()
+		first, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		err = dec.Rewind()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		again, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if first == again {
+			t.Fatalf("expect a fresh object after rewind")
+		}
+		if (*again.(*ObjectType))["foo"] != StringType("bar") {
+			t.Fatalf("decode incorrect after rewind")
+		}
+		// the rolled back object must no longer be in the reference table
+		ref, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if ref != again {
+			t.Fatalf("expect reference to the object decoded after rewind")
+		}
+		if dec.Rewind() == nil {
+			t.Fatalf("expect error rewinding without mark")
+		}
+	}
+}
+
+func TestRewindRestoresState(t *testing.T) {
+	// a strict array of two numbers
+	data := []byte{0x0a, 0x00, 0x00, 0x00, 0x02,
+		0x00, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	decoders := []*Decoder{NewDecoder(bytes.NewReader(data)), NewBytesDecoder(data)}
+	for _, dec := range decoders {
+		_, err := dec.Token()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		count := dec.ValueCount()
+		dec.Mark()
+		for i := 0; i < 2; i++ {
+			_, err = dec.Token()
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+		}
+		err = dec.Rewind()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if dec.ValueCount() != count {
+			t.Fatalf("expect value count %d after rewind got %d", count, dec.ValueCount())
+		}
+		var tokens []Token
+		for {
+			tok, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			tokens = append(tokens, tok)
+		}
+		if len(tokens) != 3 || tokens[0] != NumberType(1) || tokens[1] != NumberType(2) {
+			t.Fatalf("expect 1, 2 and the array end after rewind got %v", tokens)
+		}
+	}
+}
+
+func TestCommit(t *testing.T) {
+	data := []byte{0x02, 0x00, 0x01, 'a', 0x02, 0x00, 0x01, 'b'}
+	dec := NewDecoder(bytes.NewReader(data))
+	if dec.Commit() == nil {
+		t.Fatalf("expect error committing without mark")
+	}
+	dec.Mark()
+	first, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	err = dec.Commit()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if rr := dec.r.(*replayReader); rr.marked || len(rr.buf) != 0 {
+		t.Fatalf("expect no buffering after commit")
+	}
+	second, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if first != StringType("a") || second != StringType("b") {
+		t.Fatalf("expect a then b got %v then %v", first, second)
+	}
+	if dec.Rewind() == nil {
+		t.Fatalf("expect error rewinding after commit")
+	}
+}
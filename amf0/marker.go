@@ -0,0 +1,82 @@
+package amf0
+
+import (
+	"io"
+)
+
+// UnknownValue is what a Decoder with the CaptureUnknownMarkers option
+// returns for a value with an unknown marker: the marker and all the input
+// after it, as where the value ends cannot be told. It encodes as those
+// bytes again.
+type UnknownValue struct {
+	Marker byte
+	Data   []byte
+}
+
+// unknownMarker fails on marker, or captures the rest of the input when the
+// options say so.
+func (dec *Decoder) unknownMarker(marker byte) (interface{}, error) {
+	if !dec.opts.CaptureUnknownMarkers {
+		return nil, &MarkerError{Marker: marker}
+	}
+	r, err := dec.captureReader()
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if err = dec.checkCapture(int64(len(data))); err != nil {
+		return nil, err
+	}
+	return UnknownValue{Marker: marker, Data: data}, nil
+}
+
+// skipUnknown skips what unknownMarker would capture.
+func (dec *Decoder) skipUnknown(marker byte) error {
+	if !dec.opts.CaptureUnknownMarkers {
+		return &MarkerError{Marker: marker}
+	}
+	r, err := dec.captureReader()
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(io.Discard, r)
+	if err != nil {
+		return err
+	}
+	return dec.checkCapture(n)
+}
+
+// captureReader returns the input of a capture, reading one byte past the
+// MaxStringLength option so that checkCapture can tell a capture over it.
+// It fails first when what is left of the input is known to be over.
+func (dec *Decoder) captureReader() (io.Reader, error) {
+	limit := int64(dec.opts.MaxStringLength)
+	if limit == 0 {
+		return dec.r, nil
+	}
+	if left := remaining(dec.r); left > limit {
+		return nil, &LengthError{What: "unknown value length", Length: uint64(left), Limit: limit}
+	}
+	return io.LimitReader(dec.r, limit+1), nil
+}
+
+// checkCapture fails with a *LengthError when a capture of n bytes is over
+// the MaxStringLength option.
+func (dec *Decoder) checkCapture(n int64) error {
+	if limit := int64(dec.opts.MaxStringLength); limit > 0 && n > limit {
+		return &LengthError{What: "unknown value length", Length: uint64(n), Limit: limit}
+	}
+	return nil
+}
+
+func (enc *Encoder) encodeUnknown(v UnknownValue) error {
+	err := enc.bw.WriteByte(v.Marker)
+	if err != nil {
+		return err
+	}
+	_, err = enc.bw.Write(v.Data)
+	return err
+}
@@ -0,0 +1,98 @@
+package amf0
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+)
+
+// countingReader counts the bytes read from r, for the offsets of
+// DecodeErrors.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.(io.ByteReader).ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// Remaining passes on the bytes left in r, so that lengths can still be
+// checked against them.
+func (c *countingReader) Remaining() int64 {
+	return remaining(c.r)
+}
+
+// offset returns the bytes of the input consumed so far, not counting
+// those read ahead or waiting to be replayed after Rewind.
+func (dec *Decoder) offset() int64 {
+	var unread int64
+	r := dec.r
+	for r != nil {
+		switch l := r.(type) {
+		case *recordingReader:
+			r = l.r
+		case *replayReader:
+			unread += int64(len(l.buf) - l.pos)
+			r = l.r
+		case *bytesReader:
+			return int64(l.off) - unread
+		case *bufio.Reader:
+			unread += int64(l.Buffered())
+			r = nil
+		default:
+			r = nil
+		}
+	}
+	if dec.counter == nil {
+		return 0
+	}
+	return dec.counter.n - unread
+}
+
+// valueError returns err, from decoding a value with marker, as a
+// *DecodeError.
+func (dec *Decoder) valueError(err error, marker byte) error {
+	de, ok := err.(*DecodeError)
+	if !ok {
+		return &DecodeError{Offset: dec.offset(), Marker: int(marker), Err: noEOF(err)}
+	}
+	if de.Marker < 0 {
+		de.Marker = int(marker)
+	}
+	return de
+}
+
+// atPath returns err, from decoding the value reached by elem, as a
+// *DecodeError with elem in front of its path.
+func (dec *Decoder) atPath(err error, elem string) error {
+	de, ok := err.(*DecodeError)
+	if !ok {
+		de = &DecodeError{Offset: dec.offset(), Marker: -1, Err: noEOF(err)}
+	}
+	de.Path = elem + de.Path
+	return de
+}
+
+// topError returns err, from one of the exported decoding methods, as a
+// *DecodeError, except for the end of the input between values.
+func (dec *Decoder) topError(err error) error {
+	if _, ok := err.(*DecodeError); ok || err == nil || err == io.EOF {
+		return err
+	}
+	return &DecodeError{Offset: dec.offset(), Marker: -1, Err: err}
+}
+
+func index(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
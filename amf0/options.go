@@ -0,0 +1,144 @@
+package amf0
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/marcuswu/amf/amf3"
+)
+
+// EncoderOptions configures an Encoder.
+type EncoderOptions struct {
+	// BuiltinConverters encodes time.Duration as a number of milliseconds,
+	// and *big.Rat and net.IP as strings.
+	BuiltinConverters bool
+	// SanitizeKeys replaces invalid UTF-8 in property names with U+FFFD
+	// instead of failing.
+	SanitizeKeys bool
+	// TruncateLongKeys shortens property names longer than 65535 bytes to a
+	// prefix followed by a hash of the full name instead of failing.
+	TruncateLongKeys bool
+	// FlushThreshold lets Encode leave values in the buffer until at least
+	// this many bytes are waiting, so that small values are written
+	// together; call Flush to write them out sooner. With 0, every value is
+	// written out as soon as it is encoded.
+	FlushThreshold int
+	// AVMPlus writes every value as the switch to AMF3 marker followed by
+	// its AMF3 encoding, as RTMP connections with objectEncoding 3 expect.
+	AVMPlus bool
+	// AMF3 configures the encoding of AMF3 values.
+	AMF3 amf3.EncoderOptions
+	// Registry, if set, provides the class mappings, converters and
+	// registered encoders in place of the global registry.
+	Registry *Registry
+}
+
+// DecoderOptions configures a Decoder.
+type DecoderOptions struct {
+	// BuiltinConverters lets DecodeType produce time.Duration, *big.Rat and
+	// net.IP values from the representations written by an Encoder with the
+	// same option.
+	BuiltinConverters bool
+	// Arena, if set, provides the maps and slices of decoded objects and
+	// arrays.
+	Arena *Arena
+	// Allocator, if set, provides the maps and slices of decoded objects and
+	// arrays in place of Arena.
+	Allocator Allocator
+	// UniformStrings decodes long strings as StringType, so that callers
+	// need not tell the two string markers apart.
+	UniformStrings bool
+	// MaxXMLLength limits the size in bytes of XML documents; 0 means no
+	// limit.
+	MaxXMLLength uint32
+	// MaxStringLength limits the size in bytes of strings, long strings,
+	// property names and class names, and of XML documents unless
+	// MaxXMLLength is set, failing with a *LengthError before anything is
+	// allocated for them; 0 means no limit. It applies to AMF3 values too
+	// unless AMF3.MaxStringLength is set.
+	MaxStringLength uint32
+	// MaxArrayCount limits the element count of strict arrays and the
+	// count written before ECMA arrays, failing with a *LengthError before
+	// anything is allocated for them; 0 means no limit. Strict arrays are
+	// also checked against what is left of the input where its size is
+	// known. It applies to AMF3 values too unless AMF3.MaxArrayCount is
+	// set.
+	MaxArrayCount uint32
+	// MaxValueCount limits the values, nested ones included, that the
+	// decoder decodes, skips or reads as tokens over its life, failing with
+	// ErrMaxValueCount past it; 0 means no limit. A value switching to AMF3
+	// and the values it holds count towards the same limit unless
+	// AMF3.MaxValueCount is set.
+	MaxValueCount int
+	// LazyXML decodes XML documents as XmlDocumentSpan instead of
+	// XmlDocumentType.
+	LazyXML bool
+	// AllowMissingObjectEnd ends objects left open when the input ends
+	// between two of their properties, as in captures cut short. Decode
+	// then returns what was read along with ErrMissingObjectEnd.
+	AllowMissingObjectEnd bool
+	// Transcode, if set, converts the bytes of every string, property
+	// name and class name to UTF-8, for legacy clients that wrote them in
+	// another character set. It applies to AMF3 values too unless
+	// AMF3.Transcode is set.
+	Transcode amf3.Transcoder
+	// MaxDepth limits how deep objects and arrays nest, failing with
+	// ErrMaxDepth past it; 0 means DefaultMaxDepth and a negative depth
+	// means no limit. Values after a switch to AMF3 count towards the same
+	// limit unless AMF3.MaxDepth is set.
+	MaxDepth int
+	// CaptureUnknownMarkers decodes a value with a marker that no type has
+	// as an UnknownValue holding the rest of the input, instead of failing
+	// with a *MarkerError, so that one odd value does not fail a whole
+	// message. Bound the input, as a packet Decoder does for values with a
+	// known length, to capture no more than the value. Captures longer than
+	// MaxStringLength fail with a *LengthError.
+	CaptureUnknownMarkers bool
+	// AMF3 configures the decoding of values that follow the switch to
+	// AMF3 marker.
+	AMF3 amf3.DecoderOptions
+	// Registry, if set, provides the class mappings, converters and
+	// registered decoders in place of the global registry.
+	Registry *Registry
+}
+
+func NewEncoderWithOptions(w io.Writer, opts EncoderOptions) *Encoder {
+	enc := NewEncoder(w)
+	if opts.FlushThreshold > enc.bw.Size() {
+		enc.bw = bufio.NewWriterSize(w, opts.FlushThreshold)
+	}
+	enc.opts = opts
+	return enc
+}
+
+// Options returns the options enc was created with.
+func (enc *Encoder) Options() EncoderOptions {
+	return enc.opts
+}
+
+// Options returns the options in effect for dec, with the options of AMF3
+// values filled in from its own where they are inherited.
+func (dec *Decoder) Options() DecoderOptions {
+	return dec.opts
+}
+
+func NewDecoderWithOptions(r io.Reader, opts DecoderOptions) *Decoder {
+	dec := NewDecoder(r)
+	dec.setOptions(opts)
+	return dec
+}
+
+// setOptions sets the options of dec, the AMF3 ones defaulting to their
+// AMF0 counterparts.
+func (dec *Decoder) setOptions(opts DecoderOptions) {
+	if opts.AMF3.Transcode == nil {
+		opts.AMF3.Transcode = opts.Transcode
+	}
+	if opts.AMF3.MaxStringLength == 0 {
+		opts.AMF3.MaxStringLength = opts.MaxStringLength
+	}
+	if opts.AMF3.MaxArrayCount == 0 {
+		opts.AMF3.MaxArrayCount = opts.MaxArrayCount
+	}
+	dec.opts = opts
+}
@@ -0,0 +1,118 @@
+package amf0
+
+import (
+	"errors"
+	"io"
+	"reflect"
+)
+
+// RawMessage is the encoding of one value, kept as read. Decoding into a
+// RawMessage, e.g. a struct field, copies the bytes of the value without
+// building it, and encoding one writes the bytes verbatim, so that a
+// payload can be passed on without decoding it. An empty RawMessage encodes
+// as null.
+//
+// References inside the bytes refer to the reference table of the value
+// they were read from, so raw values holding references are only safe to
+// pass on whole, such as message bodies.
+type RawMessage []byte
+
+var rawMessageType = reflect.TypeOf(RawMessage(nil))
+
+// DecodeRaw reads the next value as a RawMessage without decoding it. The
+// objects in it still take up their slots in the reference table.
+func (dec *Decoder) DecodeRaw() (RawMessage, error) {
+	err := dec.drainPending()
+	if err != nil {
+		return nil, err
+	}
+	u8 := make([]byte, 1)
+	_, err = io.ReadFull(dec.r, u8)
+	if err != nil {
+		return nil, err
+	}
+	err = dec.countValue()
+	if err != nil {
+		return nil, err
+	}
+	return dec.readRaw(u8[0])
+}
+
+// readRaw reads the value starting with marker, already consumed, as a
+// RawMessage.
+func (dec *Decoder) readRaw(marker byte) (RawMessage, error) {
+	if br, ok := dec.r.(*bytesReader); ok {
+		start := br.off - 1
+		err := dec.skipMarker(marker)
+		if err != nil {
+			return nil, err
+		}
+		return append(RawMessage(nil), br.b[start:br.off]...), nil
+	}
+	r := dec.r
+	rec := &recordingReader{r: r, buf: []byte{marker}}
+	dec.r = rec
+	err := dec.skipMarker(marker)
+	dec.r = r
+	if err != nil {
+		return nil, err
+	}
+	return rec.buf, nil
+}
+
+// recordingReader keeps a copy of the bytes read from r. Being an
+// io.ByteReader, it is not wrapped by nested decoders, which would read
+// ahead of the value.
+type recordingReader struct {
+	r   io.Reader
+	buf []byte
+}
+
+func (rr *recordingReader) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	rr.buf = append(rr.buf, p[:n]...)
+	return n, err
+}
+
+func (rr *recordingReader) ReadByte() (byte, error) {
+	b := make([]byte, 1)
+	_, err := io.ReadFull(rr, b)
+	return b[0], err
+}
+
+// rawRefs checks that raw holds exactly one value and returns the number of
+// slots its objects take up in the reference table.
+func rawRefs(raw RawMessage) (int, error) {
+	dec := NewBytesDecoder(raw)
+	err := dec.skipValue()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return 0, errors.New("invalid raw message: " + err.Error())
+	}
+	if dec.r.(*bytesReader).off < len(raw) {
+		return 0, errors.New("raw message holds more than one value")
+	}
+	return len(dec.refObjs), nil
+}
+
+func (enc *Encoder) encodeRaw(raw RawMessage) error {
+	if len(raw) == 0 {
+		return enc.bw.WriteByte(NullMarker)
+	}
+	n, err := rawRefs(raw)
+	if err != nil {
+		return err
+	}
+	_, err = enc.bw.Write(raw)
+	if err != nil {
+		return err
+	}
+	// keep the indices of references written later in step with the
+	// decoder, which counts the objects in raw
+	for i := 0; i < n; i++ {
+		enc.refObjs = append(enc.refObjs, skippedRef{})
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package amf0
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestDecodeRaw(t *testing.T) {
+	// an object holding an AMF3 string, an empty object, then a reference
+	// to the second object
+	object := []byte{0x03, 0x00, 0x01, 'a', 0x11, 0x06, 0x03, 'x', 0x00, 0x00, 0x09}
+	data := append(append([]byte{}, object...), 0x03, 0x00, 0x00, 0x09, 0x07, 0x00, 0x01)
+	for _, r := range []*Decoder{
+		NewDecoder(struct{ io.Reader }{bytes.NewReader(data)}),
+		NewBytesDecoder(data),
+	} {
+		raw, err := r.DecodeRaw()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if !bytes.Equal(raw, object) {
+			t.Errorf("expect %x got %x", object, raw)
+		}
+		second, err := r.Decode()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		v, err := r.Decode()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if v != second {
+			t.Errorf("expect the reference to the second object got %#v", v)
+		}
+	}
+}
+
+func TestUnmarshalRawMessage(t *testing.T) {
+	type envelope struct {
+		Op   string     `amf:"op"`
+		Body RawMessage `amf:"body"`
+	}
+	body := []byte{0x0a, 0x00, 0x00, 0x00, 0x01, 0x00, 0x3f, 0xf0, 0, 0, 0, 0, 0, 0}
+	data := []byte{0x03, 0x00, 0x02, 'o', 'p', 0x02, 0x00, 0x01, 'x', 0x00, 0x04, 'b', 'o', 'd', 'y'}
+	data = append(append(data, body...), 0x00, 0x00, 0x09)
+	var got envelope
+	err := Unmarshal(data, &got)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got.Op != "x" || !bytes.Equal(got.Body, body) {
+		t.Errorf("expect op x and body %x got %+v", body, got)
+	}
+
+	// the raw array takes up a reference slot, so the object written after
+	// it is referred to by index 1
+	obj := map[string]interface{}{}
+	out, err := Marshal([]interface{}{got.Body, obj, obj})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect := append([]byte{0x0a, 0x00, 0x00, 0x00, 0x03}, body...)
+	expect = append(expect, 0x03, 0x00, 0x00, 0x09, 0x07, 0x00, 0x02)
+	if !bytes.Equal(out, expect) {
+		t.Errorf("expect %x got %x", expect, out)
+	}
+
+	out, err = Marshal(RawMessage(nil))
+	if err != nil || !bytes.Equal(out, []byte{NullMarker}) {
+		t.Errorf("expect null for an empty raw message got %x %v", out, err)
+	}
+	if _, err := Marshal(RawMessage{0x02, 0x00, 0x05, 'x'}); err == nil {
+		t.Errorf("expect error for a truncated raw message")
+	}
+	if err := CanEncode(RawMessage{0x05, 0x05}); err == nil {
+		t.Errorf("expect error for a raw message with two values")
+	}
+}
@@ -0,0 +1,264 @@
+package amf0
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+	"unsafe"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// refKey identifies a Go map, slice or struct pointer in the reference
+// table, so that a value met again, including one containing itself, is
+// written as a reference instead of being encoded over and over.
+type refKey struct {
+	typ reflect.Type
+	ptr unsafe.Pointer
+	len int
+}
+
+// encodeReflect encodes Go values that are not AMF0 types: booleans,
+// numbers other than time.Duration and strings become their AMF0
+// counterparts, time.Time becomes a date, maps with string keys and structs
+// become objects, and slices and arrays become strict arrays.
+func (enc *Encoder) encodeReflect(rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return enc.encodeValue(NullType{})
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return enc.encodeValue(NullType{})
+		}
+		elem := rv.Elem()
+		if rv.Kind() == reflect.Ptr && isObjectStruct(elem.Type()) && enc.encoderFor(elem.Type()) == nil {
+			key := refKey{typ: rv.Type(), ptr: rv.UnsafePointer()}
+			ok, err := enc.writeRef(key)
+			if ok || err != nil {
+				return err
+			}
+			return enc.encodeStruct(elem, key, "")
+		}
+		return enc.encodeValue(elem.Interface())
+	case reflect.Bool:
+		return enc.encodeValue(BooleanType(rv.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.Type() == durationType {
+			// durations need the converter, which is not in use
+			return &UnsupportedTypeError{Type: rv.Type()}
+		}
+		return enc.encodeValue(NumberType(rv.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return enc.encodeValue(NumberType(rv.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return enc.encodeValue(NumberType(rv.Float()))
+	case reflect.String:
+		if rv.Len() > 0xFFFF {
+			return enc.encodeValue(LongStringType(rv.String()))
+		}
+		return enc.encodeValue(StringType(rv.String()))
+	case reflect.Slice:
+		if rv.IsNil() {
+			return enc.encodeValue(NullType{})
+		}
+		key := refKey{typ: rv.Type(), ptr: rv.UnsafePointer(), len: rv.Len()}
+		ok, err := enc.writeRef(key)
+		if ok || err != nil {
+			return err
+		}
+		enc.refObjs = append(enc.refObjs, key)
+		err = enc.bw.WriteByte(StrictArrayMarker)
+		if err != nil {
+			return err
+		}
+		u32 := make([]byte, 4)
+		binary.BigEndian.PutUint32(u32, uint32(rv.Len()))
+		_, err = enc.bw.Write(u32)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < rv.Len(); i++ {
+			err = enc.encodeValue(rv.Index(i).Interface())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Array:
+		array := make(StrictArrayType, rv.Len())
+		for i := range array {
+			array[i] = rv.Index(i).Interface()
+		}
+		return enc.encodeValue(&array)
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return &UnsupportedTypeError{Type: rv.Type().Key(), MapKey: true}
+		}
+		if rv.IsNil() {
+			return enc.encodeValue(NullType{})
+		}
+		key := refKey{typ: rv.Type(), ptr: rv.UnsafePointer()}
+		ok, err := enc.writeRef(key)
+		if ok || err != nil {
+			return err
+		}
+		names := make([]StringType, 0, rv.Len())
+		values := make([]interface{}, 0, rv.Len())
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			names = append(names, StringType(k.String()))
+			values = append(values, rv.MapIndex(k).Interface())
+		}
+		enc.refObjs = append(enc.refObjs, key)
+		err = enc.bw.WriteByte(ObjectMarker)
+		if err != nil {
+			return err
+		}
+		return enc.writeProperties(names, values, nil)
+	case reflect.Struct:
+		if rv.Type() == timeType {
+			return enc.encodeValue(NewDate(rv.Interface().(time.Time)))
+		}
+		if rv.Type() == variantType {
+			return enc.encodeValue(rv.Interface().(Variant).Value)
+		}
+		return enc.encodeStruct(rv, skippedRef{}, "")
+	}
+	return &UnsupportedTypeError{Type: rv.Type()}
+}
+
+// encodeStruct writes the struct rv, entering ref in the reference table.
+// A className other than "" makes it a typed object of that class, whether
+// or not its type is registered.
+func (enc *Encoder) encodeStruct(rv reflect.Value, ref interface{}, className string) error {
+	c := enc.registry().byType[rv.Type()]
+	var fields []field
+	if c != nil {
+		fields = c.fields
+	} else {
+		fields = typeFields(rv.Type())
+	}
+	names := make([]StringType, 0, len(fields))
+	values := make([]interface{}, 0, len(fields))
+	convs := make([]*Converter, 0, len(fields))
+	var unset Unset
+	if set, ok := unsetOf(rv, fields); ok {
+		unset = set.Interface().(Unset)
+	}
+	for _, f := range fields {
+		if f.unset {
+			continue
+		}
+		if unset[f.goName] {
+			names = append(names, f.name)
+			values = append(values, UndefinedType{})
+			convs = append(convs, nil)
+			continue
+		}
+		fv, ok := fieldByIndex(rv, f.index)
+		if !ok {
+			continue
+		}
+		if f.omitEmpty && fv.IsZero() {
+			continue
+		}
+		conv, err := f.converter(enc.registry(), rv.Type())
+		if err != nil {
+			return err
+		}
+		value := fv.Interface()
+		if f.date && conv == nil {
+			date, err := ToDate(value)
+			if err != nil {
+				return errors.New("field " + string(f.name) + ": " + err.Error())
+			}
+			value = date
+		}
+		names = append(names, f.name)
+		values = append(values, value)
+		convs = append(convs, conv)
+	}
+	enc.refObjs = append(enc.refObjs, ref)
+	if className == "" && c != nil {
+		className = c.name
+	}
+	if className != "" {
+		err := enc.bw.WriteByte(TypedObjectMarker)
+		if err != nil {
+			return err
+		}
+		err = writeUTF8(enc.bw, StringType(className))
+		if err != nil {
+			return err
+		}
+	} else {
+		err := enc.bw.WriteByte(ObjectMarker)
+		if err != nil {
+			return err
+		}
+	}
+	return enc.writeProperties(names, values, convs)
+}
+
+// writeProperties writes the properties of an object in the given order,
+// followed by the object end marker. convs optionally holds a converter for
+// each value.
+func (enc *Encoder) writeProperties(names []StringType, values []interface{}, convs []*Converter) error {
+	for i, k := range names {
+		name, err := enc.propertyName(k)
+		if err != nil {
+			return err
+		}
+		err = writeUTF8(enc.bw, name)
+		if err != nil {
+			return err
+		}
+		if convs != nil && convs[i] != nil && convs[i].Encode != nil {
+			err = convs[i].Encode(enc, values[i])
+		} else {
+			err = enc.encodeValue(values[i])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := enc.bw.Write([]byte{0x00, 0x00, ObjectEndMarker})
+	return err
+}
+
+// TypedValue encodes Value, a struct, a pointer to one or a map with string
+// keys, as a typed object of class ClassName, without registering a class
+// for its type. It suits one-off messages built from anonymous structs.
+type TypedValue struct {
+	ClassName string
+	Value     interface{}
+}
+
+func (enc *Encoder) encodeTyped(tv TypedValue) error {
+	rv := reflect.ValueOf(tv.Value)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().Kind() == reflect.Struct {
+		key := refKey{typ: rv.Type(), ptr: rv.UnsafePointer()}
+		ok, err := enc.writeRef(key)
+		if ok || err != nil {
+			return err
+		}
+		return enc.encodeStruct(rv.Elem(), key, tv.ClassName)
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		return enc.encodeStruct(rv, skippedRef{}, tv.ClassName)
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String && !rv.IsNil() {
+			obj := make(_Object, rv.Len())
+			iter := rv.MapRange()
+			for iter.Next() {
+				obj[StringType(iter.Key().String())] = iter.Value().Interface()
+			}
+			return enc.encodeValue(&TypedObjectType{ClassName: StringType(tv.ClassName), Object: obj})
+		}
+	}
+	return errors.New("typed value of class " + tv.ClassName + " must be a struct or a map with string keys")
+}
@@ -0,0 +1,176 @@
+package amf0
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testEvent struct {
+	Name    string    `amf:"name"`
+	Created time.Time `amf:"created"`
+	Updated int64     `amf:"updated,date"`
+	Expires float64   `amf:"expires,date"`
+	Seen    DateType  `amf:"seen,date"`
+	Skipped string    `amf:"-"`
+	Note    string    `amf:"note,omitempty"`
+	private int
+}
+
+func TestEncodeStruct(t *testing.T) {
+	ts := time.UnixMilli(5)
+	ev := testEvent{Name: "a", Created: ts, Updated: 5, Expires: 5, Seen: DateType{Date: 5}, Skipped: "x"}
+	buf := new(bytes.Buffer)
+	err := NewEncoder(buf).Encode(ev)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	date := []byte{0x0b, 0x40, 0x14, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	expect := []byte{0x03, 0x00, 0x04, 'n', 'a', 'm', 'e', 0x02, 0x00, 0x01, 'a'}
+	for _, name := range []string{"created", "updated", "expires", "seen"} {
+		expect = append(expect, 0x00, byte(len(name)))
+		expect = append(expect, name...)
+		expect = append(expect, date...)
+	}
+	expect = append(expect, 0x00, 0x00, 0x09)
+	got := buf.Bytes()
+	if !bytes.Equal(expect, got) {
+		t.Errorf("expect %x got %x", expect, got)
+	}
+}
+
+func TestEncodeGoValues(t *testing.T) {
+	buf := new(bytes.Buffer)
+	v := map[string]interface{}{"b": []int{1}, "a": true}
+	err := NewEncoder(buf).Encode(v)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect := []byte{0x03, 0x00, 0x01, 'a', 0x01, 0x01, 0x00, 0x01, 'b', 0x0a, 0x00, 0x00, 0x00, 0x01,
+		0x00, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x09}
+	got := buf.Bytes()
+	if !bytes.Equal(expect, got) {
+		t.Errorf("expect %x got %x", expect, got)
+	}
+
+	err = NewEncoder(new(bytes.Buffer)).Encode(map[int]string{1: "a"})
+	if err == nil {
+		t.Errorf("expect error for non-string map keys")
+	}
+}
+
+type testNode struct {
+	Name string    `amf:"name"`
+	Next *testNode `amf:"next"`
+}
+
+func TestEncodeCycles(t *testing.T) {
+	m := map[string]interface{}{}
+	m["self"] = m
+	s := []interface{}{nil}
+	s[0] = s
+	n := &testNode{Name: "a"}
+	n.Next = n
+	tests := []struct {
+		v      interface{}
+		expect []byte
+	}{
+		{m, []byte{0x03, 0x00, 0x04, 's', 'e', 'l', 'f', 0x07, 0x00, 0x00, 0x00, 0x00, 0x09}},
+		{s, []byte{0x0a, 0x00, 0x00, 0x00, 0x01, 0x07, 0x00, 0x00}},
+		{n, []byte{0x03, 0x00, 0x04, 'n', 'a', 'm', 'e', 0x02, 0x00, 0x01, 'a',
+			0x00, 0x04, 'n', 'e', 'x', 't', 0x07, 0x00, 0x00, 0x00, 0x00, 0x09}},
+	}
+	for _, test := range tests {
+		buf := new(bytes.Buffer)
+		err := NewEncoder(buf).Encode(test.v)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if !bytes.Equal(test.expect, buf.Bytes()) {
+			t.Errorf("expect %x got %x", test.expect, buf.Bytes())
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	err := NewEncoder(buf).Encode(n)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var got *testNode
+	err = NewDecoder(buf).decodeInto(reflect.ValueOf(&got).Elem())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got.Name != "a" || got.Next != got {
+		t.Errorf("expect self reference got %+v", got)
+	}
+}
+
+type testPatch struct {
+	Unset Unset
+	Name  string  `amf:"name"`
+	Email *string `amf:"email"`
+}
+
+func TestEncodeUnset(t *testing.T) {
+	patch := testPatch{Unset: Unset{"Name": true}}
+	buf := new(bytes.Buffer)
+	err := NewEncoder(buf).Encode(patch)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect := []byte{0x03, 0x00, 0x04, 'n', 'a', 'm', 'e', 0x06,
+		0x00, 0x05, 'e', 'm', 'a', 'i', 'l', 0x05, 0x00, 0x00, 0x09}
+	if !bytes.Equal(expect, buf.Bytes()) {
+		t.Errorf("expect %x got %x", expect, buf.Bytes())
+	}
+
+	got := testPatch{Name: "stale"}
+	err = NewDecoder(buf).decodeInto(reflect.ValueOf(&got).Elem())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got.Name != "" || !got.Unset["Name"] || got.Unset["Email"] || got.Email != nil {
+		t.Errorf("expect only Name unset got %+v", got)
+	}
+}
+
+func TestEncodeTypedValue(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := NewEncoder(buf).Encode(TypedValue{"com.example.Ping", struct{ TS int64 }{42}})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect := []byte{0x10, 0x00, 0x10, 'c', 'o', 'm', '.', 'e', 'x', 'a', 'm', 'p', 'l', 'e', '.', 'P', 'i', 'n', 'g',
+		0x00, 0x02, 'T', 'S', 0x00, 0x40, 0x45, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x09}
+	if !bytes.Equal(expect, buf.Bytes()) {
+		t.Errorf("expect %x got %x", expect, buf.Bytes())
+	}
+	v, err := NewDecoder(buf).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	obj, ok := v.(*TypedObjectType)
+	if !ok || obj.ClassName != "com.example.Ping" || obj.Object["TS"] != NumberType(42) {
+		t.Errorf("expect typed object com.example.Ping got %#v", v)
+	}
+
+	buf.Reset()
+	err = NewEncoder(buf).Encode(TypedValue{"com.example.Ping", map[string]int{"TS": 42}})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !bytes.Equal(expect, buf.Bytes()) {
+		t.Errorf("expect %x got %x", expect, buf.Bytes())
+	}
+
+	err = NewEncoder(buf).Encode(TypedValue{"com.example.Ping", 42})
+	if err == nil {
+		t.Errorf("expect error encoding a number as a typed value")
+	}
+	err = CanEncode(TypedValue{"com.example.Ping", 42})
+	if err == nil {
+		t.Errorf("expect check to fail for a number as a typed value")
+	}
+}
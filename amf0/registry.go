@@ -0,0 +1,199 @@
+package amf0
+
+import (
+	"errors"
+	"maps"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// EncoderFunc writes v, whose type it was registered for, to enc. It usually
+// converts v to one of the AMF0 types and hands that to enc.Encode.
+type EncoderFunc func(enc *Encoder, v interface{}) error
+
+// DecoderFunc reads a value from dec, usually with dec.Decode, and returns it
+// converted to the type it was registered for.
+type DecoderFunc func(dec *Decoder) (interface{}, error)
+
+// Registry holds class mappings, converters, mapping file types and
+// registered encoders and decoders. The package-level Register functions
+// change the global registry, which every Encoder and Decoder uses unless
+// bound to another Registry through its options. Lookups never take locks:
+// registering replaces the global registry with an updated copy.
+//
+// A Registry from NewRegistry or Snapshot may be changed with its Register
+// methods until it is handed to an Encoder or Decoder; it must not be
+// changed afterwards. It is then safe for concurrent use.
+type Registry struct {
+	encoders   map[reflect.Type]EncoderFunc
+	decoders   map[reflect.Type]DecoderFunc
+	byName     map[string]*class
+	byType     map[reflect.Type]*class
+	types      map[string]reflect.Type
+	converters map[string]*Converter
+	// fieldConverters holds the converters given by RegisterFieldConverter.
+	fieldConverters map[fieldKey]*Converter
+}
+
+// NewRegistry returns an empty registry, without even the built-in
+// converters.
+func NewRegistry() *Registry {
+	return &Registry{
+		encoders:        make(map[reflect.Type]EncoderFunc),
+		decoders:        make(map[reflect.Type]DecoderFunc),
+		byName:          make(map[string]*class),
+		byType:          make(map[reflect.Type]*class),
+		types:           make(map[string]reflect.Type),
+		converters:      make(map[string]*Converter),
+		fieldConverters: make(map[fieldKey]*Converter),
+	}
+}
+
+var global struct {
+	sync.Mutex // held while updating
+	registry   atomic.Pointer[Registry]
+}
+
+var emptyRegistry = NewRegistry()
+
+// current returns the global registry.
+func current() *Registry {
+	if r := global.registry.Load(); r != nil {
+		return r
+	}
+	return emptyRegistry
+}
+
+// update applies fn to a copy of the global registry, which replaces it
+// unless fn fails.
+func update(fn func(r *Registry) error) error {
+	global.Lock()
+	defer global.Unlock()
+	r := current().clone()
+	err := fn(r)
+	if err != nil {
+		return err
+	}
+	global.registry.Store(r)
+	return nil
+}
+
+// Snapshot returns a copy of the global registry, unaffected by later
+// registrations. Bind it to encoders and decoders with the Registry option,
+// for instance to hold different class mappings per tenant.
+func Snapshot() *Registry {
+	return current().clone()
+}
+
+func (r *Registry) clone() *Registry {
+	c := &Registry{
+		encoders:        maps.Clone(r.encoders),
+		decoders:        maps.Clone(r.decoders),
+		byName:          maps.Clone(r.byName),
+		byType:          maps.Clone(r.byType),
+		types:           maps.Clone(r.types),
+		converters:      maps.Clone(r.converters),
+		fieldConverters: maps.Clone(r.fieldConverters),
+	}
+	return c
+}
+
+// RegisterEncoder makes every Encoder use fn for values of type t. This
+// allows types from other packages to be encoded without wrapping them at
+// every call site. fn must not call enc.Encode with a value of type t.
+func RegisterEncoder(t reflect.Type, fn EncoderFunc) {
+	update(func(r *Registry) error {
+		r.RegisterEncoder(t, fn)
+		return nil
+	})
+}
+
+// RegisterEncoder is like the package-level RegisterEncoder for encoders
+// bound to r.
+func (r *Registry) RegisterEncoder(t reflect.Type, fn EncoderFunc) {
+	if fn == nil {
+		delete(r.encoders, t)
+		return
+	}
+	r.encoders[t] = fn
+}
+
+// RegisterDecoder makes Decoder.DecodeType use fn when asked for type t.
+func RegisterDecoder(t reflect.Type, fn DecoderFunc) {
+	update(func(r *Registry) error {
+		r.RegisterDecoder(t, fn)
+		return nil
+	})
+}
+
+// RegisterDecoder is like the package-level RegisterDecoder for decoders
+// bound to r.
+func (r *Registry) RegisterDecoder(t reflect.Type, fn DecoderFunc) {
+	if fn == nil {
+		delete(r.decoders, t)
+		return
+	}
+	r.decoders[t] = fn
+}
+
+// registry returns the registry enc is bound to.
+func (enc *Encoder) registry() *Registry {
+	if enc.opts.Registry != nil {
+		return enc.opts.Registry
+	}
+	return current()
+}
+
+// registry returns the registry dec is bound to.
+func (dec *Decoder) registry() *Registry {
+	if dec.opts.Registry != nil {
+		return dec.opts.Registry
+	}
+	return current()
+}
+
+// encoderFor returns the registered or built-in encoder for t, if any.
+func (enc *Encoder) encoderFor(t reflect.Type) EncoderFunc {
+	if fn := enc.registry().encoders[t]; fn != nil {
+		return fn
+	}
+	if enc.opts.BuiltinConverters {
+		return builtinEncoders[t]
+	}
+	return nil
+}
+
+// DecodeType decodes the next value as type t. A decoder registered for t
+// takes care of the conversion; otherwise the decoded value must be
+// assignable or convertible to t.
+func (dec *Decoder) DecodeType(t reflect.Type) (interface{}, error) {
+	if fn := dec.registry().decoders[t]; fn != nil {
+		return fn(dec)
+	}
+	if dec.opts.BuiltinConverters {
+		if fn := builtinDecoders[t]; fn != nil {
+			return fn(dec)
+		}
+	}
+	v, err := dec.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return convertValue(v, t)
+}
+
+func convertValue(v interface{}, t reflect.Type) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Type().AssignableTo(t) {
+		return v, nil
+	}
+	if rv.Kind() != reflect.String && t.Kind() == reflect.String {
+		// reflect would happily turn numbers into runes
+		return nil, errors.New("cannot decode " + rv.Type().String() + " into " + t.String())
+	}
+	if rv.Type().ConvertibleTo(t) {
+		return rv.Convert(t).Interface(), nil
+	}
+	return nil, errors.New("cannot decode " + rv.Type().String() + " into " + t.String())
+}
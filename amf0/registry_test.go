@@ -0,0 +1,124 @@
+package amf0
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type testPoint struct {
+	X, Y int
+}
+
+func TestRegisteredEncoderDecoder(t *testing.T) {
+	pointType := reflect.TypeOf(testPoint{})
+	RegisterEncoder(pointType, func(enc *Encoder, v interface{}) error {
+		p := v.(testPoint)
+		return enc.Encode(StringType(fmt.Sprintf("%d,%d", p.X, p.Y)))
+	})
+	RegisterDecoder(pointType, func(dec *Decoder) (interface{}, error) {
+		v, err := dec.Decode()
+		if err != nil {
+			return nil, err
+		}
+		s, ok := v.(StringType)
+		if !ok {
+			return nil, errors.New("point must be a string")
+		}
+		var p testPoint
+		_, err = fmt.Sscanf(string(s), "%d,%d", &p.X, &p.Y)
+		return p, err
+	})
+	defer RegisterEncoder(pointType, nil)
+	defer RegisterDecoder(pointType, nil)
+
+	buf := new(bytes.Buffer)
+	enc := NewEncoder(buf)
+	err := enc.Encode(testPoint{3, 4})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect := []byte{0x02, 0x00, 0x03, '3', ',', '4'}
+	if !bytes.Equal(expect, buf.Bytes()) {
+		t.Fatalf("expect %x got %x", expect, buf.Bytes())
+	}
+
+	dec := NewDecoder(buf)
+	got, err := dec.DecodeType(pointType)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got != (testPoint{3, 4}) {
+		t.Fatalf("expect %v got %v", testPoint{3, 4}, got)
+	}
+}
+
+func TestDecodeTypeConvert(t *testing.T) {
+	buf := bytes.NewReader([]byte{0x00, 0x40, 0x14, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+	dec := NewDecoder(buf)
+	got, err := dec.DecodeType(reflect.TypeOf(float64(0)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got != float64(5) {
+		t.Fatalf("expect 5 got %v", got)
+	}
+
+	buf = bytes.NewReader([]byte{0x00, 0x40, 0x14, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+	dec = NewDecoder(buf)
+	_, err = dec.DecodeType(reflect.TypeOf(""))
+	if err == nil {
+		t.Fatalf("expect error decoding a number into a string")
+	}
+}
+
+type tenantUser struct {
+	Name string
+}
+
+func TestRegistrySnapshot(t *testing.T) {
+	userType := reflect.TypeOf(tenantUser{})
+	a := Snapshot()
+	err := a.RegisterClass(ClassMapping{ClassName: "tenant.a.User", Type: userType})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	b := Snapshot()
+	err = b.RegisterClass(ClassMapping{ClassName: "tenant.b.User", Type: userType, Fields: map[string]string{"Name": "login"}})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	aData, err := encodeWith(a, tenantUser{"ann"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	bData, err := encodeWith(b, tenantUser{"ann"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !bytes.Contains(aData, []byte("tenant.a.User")) || !bytes.Contains(bData, []byte("login")) {
+		t.Fatalf("expect per-registry mappings got %q and %q", aData, bData)
+	}
+	global, err := encodeWith(nil, tenantUser{"ann"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if global[0] != ObjectMarker {
+		t.Fatalf("expect the global registry to be unchanged got %q", global)
+	}
+	got, err := NewDecoderWithOptions(bytes.NewReader(bData), DecoderOptions{Registry: b}).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if u, ok := got.(*tenantUser); !ok || u.Name != "ann" {
+		t.Fatalf("expect *tenantUser got %#v", got)
+	}
+}
+
+func encodeWith(r *Registry, v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	err := NewEncoderWithOptions(buf, EncoderOptions{Registry: r}).Encode(v)
+	return buf.Bytes(), err
+}
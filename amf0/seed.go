@@ -0,0 +1,20 @@
+package amf0
+
+// SeedReferences puts objs at the start of the reference table, so that
+// references 0 to len(objs)-1 resolve to them and the objects decoded next
+// count from len(objs), for protocol variants that refer to well-known
+// shared objects by index without ever sending them. The seeds stay in
+// place across ResetReferences; seeding with nil removes them.
+func (dec *Decoder) SeedReferences(objs []interface{}) {
+	dec.seeds = append([]interface{}(nil), objs...)
+	dec.ResetReferences()
+}
+
+// SeedReferences puts objs at the start of the reference table, so that a
+// value identical to one of them is written as a reference to its index
+// instead of in full, as a decoder seeded with the same objects expects.
+// Objects are matched as the encoder matches its own references, so they
+// must be the pointers encoded, such as *ObjectType or *StrictArrayType.
+func (enc *Encoder) SeedReferences(objs []interface{}) {
+	enc.refObjs = append([]interface{}(nil), objs...)
+}
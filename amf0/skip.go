@@ -0,0 +1,170 @@
+package amf0
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/marcuswu/amf/amf3"
+)
+
+// SkipValue consumes the next value, including everything nested in it,
+// without building it, for filtering out values of no interest. Objects in
+// it still take up their slots in the reference table, so that later
+// references keep their indices, though references to them fail to decode.
+func (dec *Decoder) SkipValue() error {
+	err := dec.drainPending()
+	if err != nil {
+		return err
+	}
+	return dec.topError(dec.skipValue())
+}
+
+// skipValue consumes one encoded value without building it. Complex values
+// still take up a slot in the reference table so that later references keep
+// their indices.
+func (dec *Decoder) skipValue() error {
+	u8 := make([]byte, 1)
+	_, err := io.ReadFull(dec.r, u8)
+	if err != nil {
+		return err
+	}
+	err = dec.countValue()
+	if err == nil {
+		err = dec.skipMarker(u8[0])
+	}
+	if err != nil {
+		return dec.valueError(err, u8[0])
+	}
+	return nil
+}
+
+// skipMarker consumes the value starting with marker, already consumed.
+func (dec *Decoder) skipMarker(marker byte) error {
+	var err error
+	if isContainer(marker) {
+		err = dec.enter()
+		if err != nil {
+			return err
+		}
+		defer dec.leave()
+	}
+	switch marker {
+	case NumberMarker:
+		return dec.discard(8)
+	case BooleanMarker:
+		return dec.discard(1)
+	case StringMarker:
+		return dec.skipUTF8()
+	case ObjectMarker:
+		dec.refObjs = append(dec.refObjs, skippedRef{})
+		return dec.skipObject()
+	case MovieclipMarker:
+		return ErrUnsupportedMarker
+	case NullMarker, UndefinedMarker, UnsupportedMarker:
+		return nil
+	case ReferenceMarker:
+		return dec.discard(2)
+	case EcmaArrayMarker:
+		dec.refObjs = append(dec.refObjs, skippedRef{})
+		err = dec.discard(4)
+		if err != nil {
+			return err
+		}
+		return dec.skipObject()
+	case StrictArrayMarker:
+		dec.refObjs = append(dec.refObjs, skippedRef{})
+		u32 := make([]byte, 4)
+		_, err = io.ReadFull(dec.r, u32)
+		if err != nil {
+			return err
+		}
+		arrayCount := binary.BigEndian.Uint32(u32)
+		for i := uint32(0); i < arrayCount; i++ {
+			err = dec.skipValue()
+			if err != nil {
+				return dec.atPath(err, index(int(i)))
+			}
+		}
+		return nil
+	case DateMarker:
+		return dec.discard(10)
+	case LongStringMarker, XmlDocumentMarker:
+		u32 := make([]byte, 4)
+		_, err = io.ReadFull(dec.r, u32)
+		if err != nil {
+			return err
+		}
+		return dec.discard(int(binary.BigEndian.Uint32(u32)))
+	case RecordsetMarker:
+		return ErrUnsupportedMarker
+	case TypedObjectMarker:
+		dec.refObjs = append(dec.refObjs, skippedRef{})
+		err = dec.skipUTF8()
+		if err != nil {
+			return err
+		}
+		return dec.skipObject()
+	case SwitchToAmf3Marker:
+		start := dec.offset()
+		amf3Decoder := amf3.NewDecoderWithOptions(dec.r, dec.amf3Options())
+		err = amf3Decoder.SkipValue()
+		dec.countAMF3(amf3Decoder)
+		if de, ok := err.(*DecodeError); ok {
+			de.Offset += start
+		}
+		return err
+	}
+	return dec.skipUnknown(marker)
+}
+
+func (dec *Decoder) skipObject() error {
+	u8 := make([]byte, 1)
+	u16 := make([]byte, 2)
+	for {
+		_, err := io.ReadFull(dec.r, u16)
+		if err != nil {
+			return err
+		}
+		nameLength := binary.BigEndian.Uint16(u16)
+		if nameLength == 0 {
+			_, err = io.ReadFull(dec.r, u8)
+			if err != nil {
+				return err
+			}
+			if u8[0] != ObjectEndMarker {
+				return ErrExpectedObjectEnd
+			}
+			return nil
+		}
+		err = dec.discard(int(nameLength))
+		if err != nil {
+			return err
+		}
+		err = dec.skipValue()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (dec *Decoder) skipUTF8() error {
+	u16 := make([]byte, 2)
+	_, err := io.ReadFull(dec.r, u16)
+	if err != nil {
+		return err
+	}
+	return dec.discard(int(binary.BigEndian.Uint16(u16)))
+}
+
+// discard consumes n bytes from the underlying reader.
+func (dec *Decoder) discard(n int) error {
+	if br, ok := dec.r.(*bytesReader); ok {
+		_, err := br.next(n)
+		return err
+	}
+	_, err := io.CopyN(io.Discard, dec.r, int64(n))
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
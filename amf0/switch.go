@@ -0,0 +1,95 @@
+package amf0
+
+import (
+	"github.com/marcuswu/amf/amf3"
+)
+
+// isAMF3 reports whether v is an AMF3 value, which an Encoder writes after
+// the switch to AMF3 marker, as found in decoded AVM+ payloads.
+func isAMF3(v interface{}) bool {
+	switch v.(type) {
+	case amf3.UndefinedType, amf3.NullType, amf3.FalseType, amf3.TrueType,
+		amf3.IntegerType, amf3.DoubleType, amf3.StringType, amf3.NullStringType,
+		amf3.XMLDocumentType, *amf3.XMLDocumentType, amf3.DateType, *amf3.DateType,
+		*amf3.ArrayType, *amf3.ObjectType, amf3.XMLType, *amf3.XMLType,
+		amf3.ByteArrayType, *amf3.ByteArrayType,
+		amf3.VectorIntType, *amf3.VectorIntType, amf3.VectorUintType, *amf3.VectorUintType,
+		amf3.VectorDoubleType, *amf3.VectorDoubleType, amf3.VectorObjectType, *amf3.VectorObjectType,
+		amf3.DictionaryType, *amf3.DictionaryType:
+		return true
+	case amf3.Externalizable:
+		return true
+	}
+	return false
+}
+
+// The AMF3 values of the AMF0 types, for encoders with AVMPlus set and for
+// AMF0 values placed in AMF3 trees. Objects, strict arrays, numbers,
+// booleans and strings are already written as their AMF3 counterparts.
+
+func (NullType) AMF3Value() (interface{}, error) {
+	return amf3.NullType{}, nil
+}
+
+func (UndefinedType) AMF3Value() (interface{}, error) {
+	return amf3.UndefinedType{}, nil
+}
+
+func (UnsupportedType) AMF3Value() (interface{}, error) {
+	return amf3.UndefinedType{}, nil
+}
+
+// AMF3Value returns the date without its time zone, which AMF3 dates lack.
+func (d DateType) AMF3Value() (interface{}, error) {
+	return amf3.DateType(d.Date), nil
+}
+
+func (x XmlDocumentType) AMF3Value() (interface{}, error) {
+	return amf3.XMLDocumentType(x), nil
+}
+
+func (x XmlDocumentSpan) AMF3Value() (interface{}, error) {
+	return amf3.XMLDocumentType(x), nil
+}
+
+// AMF3Value returns an object of the class with the properties as dynamic
+// members.
+func (t TypedObjectType) AMF3Value() (interface{}, error) {
+	trait := &amf3.Trait{ClassName: amf3.StringType(t.ClassName), IsDynamic: true}
+	return &amf3.ObjectType{Trait: trait, Dynamic: amf3Members(t.Object)}, nil
+}
+
+// AMF3Value returns an array with the properties as associative members.
+func (a EcmaArrayType) AMF3Value() (interface{}, error) {
+	return &amf3.ArrayType{Associative: amf3Members(_Object(a))}, nil
+}
+
+func (tv TypedValue) AMF3Value() (interface{}, error) {
+	return amf3.TypedValue{ClassName: tv.ClassName, Value: tv.Value}, nil
+}
+
+func (v Variant) AMF3Value() (interface{}, error) {
+	return v.Value, nil
+}
+
+func (v *LazyValue) AMF3Value() (interface{}, error) {
+	return v.Decode()
+}
+
+// AMF3Value fails: the bytes are AMF0.
+func (RawMessage) AMF3Value() (interface{}, error) {
+	return nil, ErrNoAMF3Encoding
+}
+
+// AMF3Value fails: what the bytes stand for is not known.
+func (UnknownValue) AMF3Value() (interface{}, error) {
+	return nil, ErrNoAMF3Encoding
+}
+
+func amf3Members(obj _Object) map[amf3.StringType]interface{} {
+	members := make(map[amf3.StringType]interface{}, len(obj))
+	for k, v := range obj {
+		members[amf3.StringType(k)] = v
+	}
+	return members
+}
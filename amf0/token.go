@@ -0,0 +1,164 @@
+package amf0
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// A Token is one of ObjectStart, EcmaArrayStart, StrictArrayStart, End,
+// PropertyName, Reference, or a scalar value: NumberType, BooleanType,
+// StringType, LongStringType, NullType, UndefinedType, UnsupportedType,
+// DateType, XmlDocumentType, or an AMF3 value found after a switch marker.
+type Token interface{}
+
+// ObjectStart starts an anonymous object, or a typed object when ClassName
+// is set. Pairs of PropertyName and value tokens follow, up to End.
+type ObjectStart struct {
+	ClassName StringType
+}
+
+// EcmaArrayStart starts an ECMA array. Like objects, its entries follow as
+// PropertyName and value tokens up to End. Count is the entry count written
+// by the sender, which is not always accurate.
+type EcmaArrayStart struct {
+	Count uint32
+}
+
+// StrictArrayStart starts a strict array of Count value tokens followed by
+// End.
+type StrictArrayStart struct {
+	Count uint32
+}
+
+// End ends the innermost object or array.
+type End struct {
+}
+
+// PropertyName is the name of the next value of an object or ECMA array.
+type PropertyName StringType
+
+// Reference refers to the object or array with the given index, counting
+// the start tokens of objects and arrays in stream order from zero.
+type Reference uint16
+
+// tokenFrame is an object or array being read by Token.
+type tokenFrame struct {
+	array     bool
+	remaining uint32 // values left in a strict array
+	value     bool   // whether a property name was read and its value is next
+}
+
+// Token returns the next token, holding one marker, property name or
+// scalar value, so that large objects and arrays can be scanned without
+// building them in memory. It returns io.EOF at the end of the input between
+// values. Token keeps its own place in nested values and should not be mixed
+// with Decode on the same decoder in the middle of a value. Errors other
+// than io.EOF are *DecodeErrors giving the offset of the failure.
+func (dec *Decoder) Token() (Token, error) {
+	t, err := dec.token()
+	return t, dec.topError(err)
+}
+
+func (dec *Decoder) token() (Token, error) {
+	if len(dec.tokens) == 0 {
+		return dec.valueToken()
+	}
+	top := &dec.tokens[len(dec.tokens)-1]
+	if top.array {
+		if top.remaining == 0 {
+			dec.tokens = dec.tokens[:len(dec.tokens)-1]
+			return End{}, nil
+		}
+		top.remaining--
+		return dec.nestedToken()
+	}
+	if top.value {
+		top.value = false
+		return dec.nestedToken()
+	}
+	name, err := dec.readUTF8()
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if name == "" {
+		u8 := make([]byte, 1)
+		_, err = io.ReadFull(dec.r, u8)
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		if u8[0] != ObjectEndMarker {
+			return nil, ErrExpectedObjectEnd
+		}
+		dec.tokens = dec.tokens[:len(dec.tokens)-1]
+		return End{}, nil
+	}
+	top.value = true
+	return PropertyName(name), nil
+}
+
+// nestedToken reads a value token inside an object or array, where the end
+// of the input is unexpected.
+func (dec *Decoder) nestedToken() (Token, error) {
+	t, err := dec.valueToken()
+	return t, noEOF(err)
+}
+
+func (dec *Decoder) valueToken() (Token, error) {
+	u8 := make([]byte, 1)
+	_, err := io.ReadFull(dec.r, u8)
+	if err != nil {
+		return nil, err
+	}
+	err = dec.countValue()
+	if err != nil {
+		return nil, err
+	}
+	u16 := make([]byte, 2)
+	u32 := make([]byte, 4)
+	switch u8[0] {
+	case ObjectMarker:
+		dec.tokens = append(dec.tokens, tokenFrame{})
+		return ObjectStart{}, nil
+	case TypedObjectMarker:
+		name, err := dec.readUTF8()
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		dec.tokens = append(dec.tokens, tokenFrame{})
+		return ObjectStart{ClassName: name}, nil
+	case EcmaArrayMarker:
+		_, err = io.ReadFull(dec.r, u32)
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		dec.tokens = append(dec.tokens, tokenFrame{})
+		return EcmaArrayStart{Count: binary.BigEndian.Uint32(u32)}, nil
+	case StrictArrayMarker:
+		_, err = io.ReadFull(dec.r, u32)
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		n := binary.BigEndian.Uint32(u32)
+		dec.tokens = append(dec.tokens, tokenFrame{array: true, remaining: n})
+		return StrictArrayStart{Count: n}, nil
+	case ReferenceMarker:
+		_, err = io.ReadFull(dec.r, u16)
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		return Reference(binary.BigEndian.Uint16(u16)), nil
+	case ObjectEndMarker:
+		return nil, errors.New("unexpected ObjectEndMarker")
+	}
+	v, err := dec.decodeMarker(u8[0])
+	return v, noEOF(err)
+}
+
+// noEOF turns the end of the input in the middle of a value into an error.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
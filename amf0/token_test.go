@@ -0,0 +1,48 @@
+package amf0
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestToken(t *testing.T) {
+	data := []byte{0x02, 0x00, 0x02, 'o', 'n',
+		0x08, 0x00, 0x00, 0x00, 0x02,
+		0x00, 0x01, 'd', 0x00, 0x40, 0x24, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x01, 'k', 0x0a, 0x00, 0x00, 0x00, 0x02, 0x01, 0x01, 0x03, 0x00, 0x00, 0x09,
+		0x00, 0x00, 0x09,
+		0x07, 0x00, 0x01}
+	expect := []Token{
+		StringType("on"),
+		EcmaArrayStart{Count: 2},
+		PropertyName("d"), NumberType(10),
+		PropertyName("k"), StrictArrayStart{Count: 2}, BooleanType(true), ObjectStart{}, End{}, End{},
+		End{},
+		Reference(1),
+	}
+	dec := NewDecoder(bytes.NewReader(data))
+	for i, want := range expect {
+		got, err := dec.Token()
+		if err != nil {
+			t.Fatalf("token %d: %s", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("token %d: expect %#v got %#v", i, want, got)
+		}
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		t.Errorf("expect EOF got %v", err)
+	}
+
+	dec = NewDecoder(bytes.NewReader(data[:12]))
+	var err error
+	for err == nil {
+		_, err = dec.Token()
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expect unexpected EOF got %v", err)
+	}
+}
@@ -0,0 +1,646 @@
+package amf0
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+
+	"github.com/marcuswu/amf/amf3"
+)
+
+// FieldSet holds the paths of the fields found in a payload by
+// UnmarshalWithPresence. A path joins Go field names with dots, map keys
+// count as field names and slice elements add their index, as in
+// "Items[0].Name".
+type FieldSet map[string]bool
+
+// Has reports whether the field at path was present.
+func (fs FieldSet) Has(path string) bool {
+	return fs[path]
+}
+
+// Marshal returns the AMF0 encoding of v.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := NewEncoder(&buf).Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the single AMF0 value in data into the value pointed to
+// by v. Unlike a bytes decoder, the result does not share memory with data.
+func Unmarshal(data []byte, v interface{}) error {
+	return unmarshal(data, v, nil)
+}
+
+// unmarshal is Unmarshal, recording the fields found into presence when it
+// is not nil.
+func unmarshal(data []byte, v interface{}, presence FieldSet) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("unmarshal needs a non-nil pointer")
+	}
+	br := bytes.NewReader(data)
+	dec := NewDecoder(br)
+	dec.presence = presence
+	err := dec.decodeInto(rv.Elem())
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	if br.Len() > 0 {
+		return errors.New("data holds more than one value")
+	}
+	return nil
+}
+
+// DecodeValueInto decodes the next value into the value pointed to by v,
+// with the conversions of Unmarshal: objects and arrays headed for structs,
+// maps and slices are read straight into them, without building the
+// decoded tree first.
+func (dec *Decoder) DecodeValueInto(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("decode needs a non-nil pointer")
+	}
+	err := dec.drainPending()
+	if err != nil {
+		return err
+	}
+	return dec.topError(dec.decodeInto(rv.Elem()))
+}
+
+// UnmarshalWithPresence decodes the AMF0 value in data into the value
+// pointed to by v, and reports which fields were present in the payload, so
+// that a missing field can be told apart from one holding its zero value.
+// Like Unmarshal, it copies out of data and rejects trailing bytes.
+func UnmarshalWithPresence(data []byte, v interface{}) (FieldSet, error) {
+	presence := make(FieldSet)
+	err := unmarshal(data, v, presence)
+	if err != nil {
+		return nil, err
+	}
+	return presence, nil
+}
+
+// enterPresence records the field name below the current path, if presence
+// is tracked, and makes it the current path. It returns the previous path.
+func (dec *Decoder) enterPresence(name string) string {
+	prefix := dec.presencePath
+	if dec.presence == nil {
+		return prefix
+	}
+	if prefix != "" {
+		name = prefix + "." + name
+	}
+	dec.presence[name] = true
+	dec.presencePath = name
+	return prefix
+}
+
+// enterPresenceIndex is enterPresence for slice elements.
+func (dec *Decoder) enterPresenceIndex(i int) string {
+	prefix := dec.presencePath
+	if dec.presence != nil {
+		dec.presencePath = prefix + "[" + strconv.Itoa(i) + "]"
+	}
+	return prefix
+}
+
+// decodeInto decodes the next value into rv, which must be settable. Objects
+// and arrays headed for structs, maps and slices are streamed into place;
+// everything else is decoded as usual and then assigned.
+func (dec *Decoder) decodeInto(rv reflect.Value) error {
+	if fn := dec.decoderFor(rv.Type()); fn != nil {
+		v, err := fn(dec)
+		if err != nil {
+			return err
+		}
+		return dec.registry().assign(rv, v)
+	}
+	u8 := make([]byte, 1)
+	_, err := io.ReadFull(dec.r, u8)
+	if err != nil {
+		return err
+	}
+	err = dec.countValue()
+	if err == nil {
+		err = dec.decodeMarkerInto(rv, u8[0])
+	}
+	if err != nil {
+		return dec.valueError(err, u8[0])
+	}
+	return nil
+}
+
+// decoderFor returns the registered or built-in decoder for t, if any.
+func (dec *Decoder) decoderFor(t reflect.Type) DecoderFunc {
+	if fn := dec.registry().decoders[t]; fn != nil {
+		return fn
+	}
+	if dec.opts.BuiltinConverters {
+		return builtinDecoders[t]
+	}
+	return nil
+}
+
+func (dec *Decoder) decodeMarkerInto(rv reflect.Value, marker byte) error {
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if marker == NullMarker || marker == UndefinedMarker {
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+		if marker != ReferenceMarker {
+			if rv.IsNil() {
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			return dec.decodeMarkerInto(rv.Elem(), marker)
+		}
+	case reflect.Struct:
+		if rv.Type() == variantType {
+			v, err := dec.readVariant(marker)
+			if err != nil {
+				return err
+			}
+			rv.Set(reflect.ValueOf(v))
+			return nil
+		}
+		if rv.Type() != timeType && (marker == ObjectMarker || marker == EcmaArrayMarker || marker == TypedObjectMarker) {
+			return dec.readStructMarker(rv, marker)
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String && (marker == ObjectMarker || marker == EcmaArrayMarker) {
+			return dec.readMapMarker(rv, marker)
+		}
+	case reflect.Slice:
+		if rv.Type() == rawMessageType {
+			raw, err := dec.readRaw(marker)
+			if err != nil {
+				return err
+			}
+			rv.SetBytes(raw)
+			return nil
+		}
+		if marker == StrictArrayMarker {
+			return dec.readSlice(rv)
+		}
+	}
+	v, err := dec.decodeMarker(marker)
+	if err != nil {
+		return err
+	}
+	return dec.registry().assign(rv, v)
+}
+
+func (dec *Decoder) readStructMarker(rv reflect.Value, marker byte) error {
+	err := dec.enter()
+	if err != nil {
+		return err
+	}
+	defer dec.leave()
+	dec.refObjs = append(dec.refObjs, rv.Addr().Interface())
+	switch marker {
+	case EcmaArrayMarker:
+		err := dec.discard(4)
+		if err != nil {
+			return err
+		}
+	case TypedObjectMarker:
+		err := dec.skipUTF8()
+		if err != nil {
+			return err
+		}
+	}
+	return dec.readFields(rv, dec.registry().structFields(rv.Type()))
+}
+
+func (dec *Decoder) readFields(rv reflect.Value, fields []field) error {
+	for {
+		name, err := dec.readPropertyName()
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			return nil
+		}
+		f := findField(fields, name)
+		if f == nil {
+			// keep unknown properties in the reference table
+			_, err = dec.decodeValue()
+			if err != nil {
+				return dec.atPath(err, "."+string(name))
+			}
+			continue
+		}
+		fv := allocFieldByIndex(rv, f.index)
+		prefix := dec.enterPresence(f.goName)
+		err = dec.readField(rv, fields, f, fv)
+		dec.presencePath = prefix
+		if err != nil {
+			return dec.atPath(err, "."+string(name))
+		}
+	}
+}
+
+func (dec *Decoder) readField(rv reflect.Value, fields []field, f *field, fv reflect.Value) error {
+	conv, err := f.converter(dec.registry(), rv.Type())
+	if err != nil {
+		return err
+	}
+	if f.variant && conv == nil && fv.Kind() == reflect.Interface {
+		u8 := make([]byte, 1)
+		_, err = io.ReadFull(dec.r, u8)
+		if err != nil {
+			return err
+		}
+		markUnset(rv, fields, f.goName, u8[0] == UndefinedMarker)
+		v, err := dec.readVariant(u8[0])
+		if err != nil {
+			return err
+		}
+		return dec.registry().assign(fv, v)
+	}
+	if _, ok := unsetOf(rv, fields); ok && conv == nil {
+		u8 := make([]byte, 1)
+		_, err = io.ReadFull(dec.r, u8)
+		if err != nil {
+			return err
+		}
+		markUnset(rv, fields, f.goName, u8[0] == UndefinedMarker)
+		return dec.decodeMarkerInto(fv, u8[0])
+	}
+	if conv != nil && conv.Decode != nil {
+		v, err := conv.Decode(dec)
+		if err != nil {
+			return errors.New("field " + string(f.name) + ": " + err.Error())
+		}
+		err = dec.registry().assign(fv, v)
+		if err != nil {
+			return errors.New("field " + string(f.name) + ": " + err.Error())
+		}
+		return nil
+	}
+	return dec.decodeInto(fv)
+}
+
+func (dec *Decoder) readMapMarker(rv reflect.Value, marker byte) error {
+	err := dec.enter()
+	if err != nil {
+		return err
+	}
+	defer dec.leave()
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMap(rv.Type()))
+	}
+	dec.refObjs = append(dec.refObjs, rv.Interface())
+	if marker == EcmaArrayMarker {
+		err := dec.discard(4)
+		if err != nil {
+			return err
+		}
+	}
+	elemType := rv.Type().Elem()
+	for {
+		name, err := dec.readPropertyName()
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			return nil
+		}
+		elem := reflect.New(elemType).Elem()
+		prefix := dec.enterPresence(string(name))
+		err = dec.decodeInto(elem)
+		dec.presencePath = prefix
+		if err != nil {
+			return dec.atPath(err, "."+string(name))
+		}
+		rv.SetMapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()), elem)
+	}
+}
+
+func (dec *Decoder) readSlice(rv reflect.Value) error {
+	err := dec.enter()
+	if err != nil {
+		return err
+	}
+	defer dec.leave()
+	u32 := make([]byte, 4)
+	_, err = io.ReadFull(dec.r, u32)
+	if err != nil {
+		return err
+	}
+	dec.refObjs = append(dec.refObjs, skippedRef{})
+	err = dec.checkCount("strict array count", binary.BigEndian.Uint32(u32))
+	if err != nil {
+		return err
+	}
+	n := int(binary.BigEndian.Uint32(u32))
+	rv.Set(reflect.MakeSlice(rv.Type(), 0, 0))
+	for i := 0; i < n; i++ {
+		elem := reflect.New(rv.Type().Elem()).Elem()
+		prefix := dec.enterPresenceIndex(i)
+		err = dec.decodeInto(elem)
+		dec.presencePath = prefix
+		if err != nil {
+			return dec.atPath(err, index(i))
+		}
+		rv.Set(reflect.Append(rv, elem))
+	}
+	return nil
+}
+
+// readPropertyName reads the name of the next object property, returning ""
+// once the object end marker has been consumed.
+func (dec *Decoder) readPropertyName() (StringType, error) {
+	name, err := dec.readUTF8()
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		u8 := make([]byte, 1)
+		_, err = io.ReadFull(dec.r, u8)
+		if err != nil {
+			return "", err
+		}
+		if u8[0] != ObjectEndMarker {
+			return "", ErrExpectedObjectEnd
+		}
+	}
+	return name, nil
+}
+
+func findField(fields []field, name StringType) *field {
+	for i := range fields {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// allocFieldByIndex is like reflect.Value.FieldByIndex but allocates nil
+// embedded struct pointers on the way.
+func allocFieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// Assign stores the decoded value v in the value pointed to by dst, with
+// the conversions made by Unmarshal.
+func Assign(dst interface{}, v interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("assign needs a non-nil pointer")
+	}
+	return current().assign(rv.Elem(), v)
+}
+
+// assign stores the decoded value v in rv, converting between the AMF0 types
+// and Go types where that is unambiguous.
+func (r *Registry) assign(rv reflect.Value, v interface{}) error {
+	switch v.(type) {
+	case NullType, UndefinedType, amf3.NullType, amf3.UndefinedType:
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	case nil:
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+	vv := reflect.ValueOf(v)
+	if vv.Type().AssignableTo(rv.Type()) {
+		rv.Set(vv)
+		return nil
+	}
+	// a reference to an object already decoded into a Go value
+	if vv.Kind() == reflect.Ptr && vv.Type().Elem() == rv.Type() {
+		rv.Set(vv.Elem())
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return r.assign(rv.Elem(), v)
+	case reflect.Bool:
+		switch b := v.(type) {
+		case BooleanType:
+			rv.SetBool(bool(b))
+			return nil
+		case amf3.TrueType:
+			rv.SetBool(true)
+			return nil
+		case amf3.FalseType:
+			rv.SetBool(false)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, ok := number(v); ok {
+			rv.SetInt(int64(n))
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, ok := number(v); ok && n >= 0 {
+			rv.SetUint(uint64(n))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, ok := number(v); ok {
+			rv.SetFloat(n)
+			return nil
+		}
+	case reflect.String:
+		switch s := v.(type) {
+		case StringType:
+			rv.SetString(string(s))
+			return nil
+		case LongStringType:
+			rv.SetString(string(s))
+			return nil
+		case XmlDocumentType:
+			rv.SetString(string(s))
+			return nil
+		case amf3.StringType:
+			rv.SetString(string(s))
+			return nil
+		case *amf3.XMLType:
+			rv.SetString(string(*s))
+			return nil
+		case *amf3.XMLDocumentType:
+			rv.SetString(string(*s))
+			return nil
+		}
+	case reflect.Struct:
+		if rv.Type() == timeType {
+			if d, ok := v.(DateType); ok {
+				rv.Set(reflect.ValueOf(d.Time().In(d.Location())))
+				return nil
+			}
+			if d, ok := v.(*amf3.DateType); ok {
+				rv.Set(reflect.ValueOf(d.Time()))
+				return nil
+			}
+			if d, ok := v.(amf3.DateType); ok {
+				rv.Set(reflect.ValueOf(d.Time()))
+				return nil
+			}
+			break
+		}
+		if obj, ok := objectOf(v); ok {
+			fields := r.structFields(rv.Type())
+			for name, value := range obj {
+				f := findField(fields, name)
+				if f == nil {
+					continue
+				}
+				_, undefined := value.(UndefinedType)
+				markUnset(rv, fields, f.goName, undefined)
+				err := r.assign(allocFieldByIndex(rv, f.index), value)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case reflect.Map:
+		obj, ok := objectOf(v)
+		if ok && rv.Type().Key().Kind() == reflect.String {
+			if rv.IsNil() {
+				rv.Set(reflect.MakeMap(rv.Type()))
+			}
+			for name, value := range obj {
+				elem := reflect.New(rv.Type().Elem()).Elem()
+				err := r.assign(elem, value)
+				if err != nil {
+					return err
+				}
+				rv.SetMapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()), elem)
+			}
+			return nil
+		}
+	case reflect.Slice:
+		if b, ok := v.(*amf3.ByteArrayType); ok && rv.Type().Elem().Kind() == reflect.Uint8 {
+			rv.SetBytes(append([]byte(nil), *b...))
+			return nil
+		}
+		if array, ok := v.(*amf3.ArrayType); ok {
+			v = (*StrictArrayType)(&array.Dense)
+		}
+		if items, ok := vectorItems(v); ok {
+			v = &items
+		}
+		if array, ok := v.(*StrictArrayType); ok {
+			s := reflect.MakeSlice(rv.Type(), len(*array), len(*array))
+			for i, value := range *array {
+				err := r.assign(s.Index(i), value)
+				if err != nil {
+					return err
+				}
+			}
+			rv.Set(s)
+			return nil
+		}
+	}
+	c, err := convertValue(v, rv.Type())
+	if err != nil {
+		return err
+	}
+	rv.Set(reflect.ValueOf(c))
+	return nil
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case NumberType:
+		return float64(n), true
+	case DateType:
+		return n.Date, true
+	case amf3.IntegerType:
+		return float64(n), true
+	case amf3.DoubleType:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// vectorItems returns the items of an AMF3 vector or ArrayCollection.
+func vectorItems(v interface{}) (StrictArrayType, bool) {
+	var items StrictArrayType
+	switch vector := v.(type) {
+	case *amf3.VectorIntType:
+		for _, item := range vector.Items {
+			items = append(items, NumberType(item))
+		}
+	case *amf3.VectorUintType:
+		for _, item := range vector.Items {
+			items = append(items, NumberType(item))
+		}
+	case *amf3.VectorDoubleType:
+		for _, item := range vector.Items {
+			items = append(items, NumberType(item))
+		}
+	case *amf3.VectorObjectType:
+		items = vector.Items
+	case *amf3.ArrayCollection:
+		items = StrictArrayType(*vector)
+	default:
+		return nil, false
+	}
+	return items, true
+}
+
+func objectOf(v interface{}) (_Object, bool) {
+	switch obj := v.(type) {
+	case *ObjectType:
+		return _Object(*obj), true
+	case *EcmaArrayType:
+		return _Object(*obj), true
+	case *TypedObjectType:
+		return obj.Object, true
+	case *amf3.ObjectType:
+		o := make(_Object, len(obj.Static)+len(obj.Dynamic))
+		if obj.Trait != nil {
+			for i, name := range obj.Trait.Attrs {
+				if i < len(obj.Static) {
+					o[StringType(name)] = obj.Static[i]
+				}
+			}
+		}
+		for name, value := range obj.Dynamic {
+			o[StringType(name)] = value
+		}
+		return o, true
+	case *amf3.ObjectProxy:
+		o := make(_Object, len(*obj))
+		for name, value := range *obj {
+			o[StringType(name)] = value
+		}
+		return o, true
+	case *amf3.ArrayType:
+		if len(obj.Dense) == 0 {
+			o := make(_Object, len(obj.Associative))
+			for name, value := range obj.Associative {
+				o[StringType(name)] = value
+			}
+			return o, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,156 @@
+package amf0
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/marcuswu/amf/amf3"
+)
+
+type testAddress struct {
+	City string `amf:"city"`
+	Zip  string `amf:"zip"`
+}
+
+type testProfile struct {
+	Name    string            `amf:"name"`
+	Age     float64           `amf:"age"`
+	Address testAddress       `amf:"address"`
+	Tags    map[string]string `amf:"tags"`
+	Items   []testAddress     `amf:"items"`
+}
+
+func TestUnmarshalWithPresence(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := NewEncoder(buf).Encode(map[string]interface{}{
+		"age":     0,
+		"address": map[string]interface{}{"city": "x"},
+		"tags":    map[string]interface{}{"a": "b"},
+		"items":   []interface{}{map[string]interface{}{"zip": "1"}},
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var got testProfile
+	fields, err := UnmarshalWithPresence(buf.Bytes(), &got)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got.Address.City != "x" || got.Items[0].Zip != "1" || got.Tags["a"] != "b" {
+		t.Errorf("decode error %+v", got)
+	}
+	for _, path := range []string{"Age", "Address", "Address.City", "Tags", "Tags.a", "Items", "Items[0].Zip"} {
+		if !fields.Has(path) {
+			t.Errorf("expect %s present in %v", path, fields)
+		}
+	}
+	for _, path := range []string{"Name", "Address.Zip", "Items[0].City"} {
+		if fields.Has(path) {
+			t.Errorf("expect %s absent in %v", path, fields)
+		}
+	}
+
+	_, err = UnmarshalWithPresence(buf.Bytes(), got)
+	if err == nil {
+		t.Errorf("expect error for non-pointer")
+	}
+	var s string
+	_, err = UnmarshalWithPresence([]byte{0x05, 0x05}, &s)
+	if err == nil {
+		t.Errorf("expect error for trailing data")
+	}
+}
+
+func TestUnmarshalWithPresenceCopies(t *testing.T) {
+	data := []byte{StringMarker, 0x00, 0x02, 'h', 'i'}
+	var s string
+	_, err := UnmarshalWithPresence(data, &s)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	data[3] = 'x'
+	if s != "hi" {
+		t.Errorf("expect hi got %s", s)
+	}
+}
+
+func TestUnmarshalAMF3(t *testing.T) {
+	type profile struct {
+		testProfile
+		Admin bool   `amf:"admin"`
+		Photo []byte `amf:"photo,omitempty"`
+	}
+	expect := profile{
+		testProfile: testProfile{
+			Name:    "ann",
+			Age:     31,
+			Address: testAddress{City: "Oslo"},
+			Tags:    map[string]string{"a": "b"},
+			Items:   []testAddress{{City: "Bergen", Zip: "5003"}},
+		},
+		Admin: true,
+		Photo: []byte{1, 2},
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(SwitchToAmf3Marker)
+	err := amf3.NewEncoder(&buf).Encode(&expect)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var got profile
+	err = Unmarshal(buf.Bytes(), &got)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("expect %+v got %+v", expect, got)
+	}
+}
+
+func TestUnmarshalVector(t *testing.T) {
+	data := []byte{SwitchToAmf3Marker, 0x0d, 0x05, 0x00, 0xff, 0xff, 0xff, 0xfe, 0x00, 0x00, 0x00, 0x03}
+	var got []int
+	err := Unmarshal(data, &got)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(got) != 2 || got[0] != -2 || got[1] != 3 {
+		t.Fatalf("expect [-2 3] got %v", got)
+	}
+}
+
+func TestDecodeValueInto(t *testing.T) {
+	buf := new(bytes.Buffer)
+	enc := NewEncoder(buf)
+	enc.Encode(map[string]interface{}{"name": "a", "items": []interface{}{map[string]interface{}{"city": "x"}}})
+	enc.Encode(3)
+	enc.Encode([]interface{}{"b", "c"})
+	dec := NewDecoder(buf)
+	var profile testProfile
+	var n int
+	var names []string
+	for _, v := range []interface{}{&profile, &n, &names} {
+		err := dec.DecodeValueInto(v)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	if profile.Name != "a" || profile.Items[0].City != "x" || n != 3 || !reflect.DeepEqual(names, []string{"b", "c"}) {
+		t.Fatalf("unexpected values %+v %v %v", profile, n, names)
+	}
+	if dec.DecodeValueInto(profile) == nil {
+		t.Fatalf("expect error for a value that is not a pointer")
+	}
+}
+
+func TestUnmarshalAMF3NegativeInteger(t *testing.T) {
+	var got int
+	err := Unmarshal([]byte{SwitchToAmf3Marker, 0x04, 0xff, 0xff, 0xff, 0xff}, &got)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got != -1 {
+		t.Errorf("expect -1 got %d", got)
+	}
+}
@@ -0,0 +1,32 @@
+package amf0
+
+import (
+	"reflect"
+)
+
+// Variant holds a value whose wire type varies from one message to the
+// next, together with the marker it was read with, such as NumberMarker or
+// StringMarker. A struct field of type Variant, or an interface{} field
+// tagged `amf:",variant"`, receives one when decoding, and a Variant is
+// encoded as its Value, so that such fields round-trip unchanged. A
+// reference is kept as ReferenceMarker along with the object referred to.
+type Variant struct {
+	Marker byte
+	Value  interface{}
+}
+
+var variantType = reflect.TypeOf(Variant{})
+
+// isObjectStruct reports whether structs of type t are encoded as objects,
+// as opposed to the structs standing for a single value.
+func isObjectStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && t != variantType
+}
+
+func (dec *Decoder) readVariant(marker byte) (Variant, error) {
+	v, err := dec.decodeMarker(marker)
+	if err != nil {
+		return Variant{}, err
+	}
+	return Variant{Marker: marker, Value: v}, nil
+}
@@ -0,0 +1,40 @@
+package amf0
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type testLegacy struct {
+	Flags Variant     `amf:"flags"`
+	Body  interface{} `amf:"body,variant"`
+}
+
+func TestVariant(t *testing.T) {
+	data := []byte{0x03,
+		0x00, 0x05, 'f', 'l', 'a', 'g', 's', 0x02, 0x00, 0x01, '3',
+		0x00, 0x04, 'b', 'o', 'd', 'y', 0x0a, 0x00, 0x00, 0x00, 0x01, 0x05,
+		0x00, 0x00, 0x09}
+	var got testLegacy
+	err := NewDecoder(bytes.NewReader(data)).decodeInto(reflect.ValueOf(&got).Elem())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got.Flags.Marker != StringMarker || got.Flags.Value != StringType("3") {
+		t.Fatalf("expect string flags got %+v", got.Flags)
+	}
+	body, ok := got.Body.(Variant)
+	if !ok || body.Marker != StrictArrayMarker {
+		t.Fatalf("expect strict array body got %+v", got.Body)
+	}
+
+	buf := new(bytes.Buffer)
+	err = NewEncoder(buf).Encode(got)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !bytes.Equal(data, buf.Bytes()) {
+		t.Fatalf("expect %x got %x", data, buf.Bytes())
+	}
+}
@@ -0,0 +1,97 @@
+package amf0
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// XmlDocumentSpan is an XML document left as raw bytes, returned instead of
+// XmlDocumentType when DecoderOptions.LazyXML is set. Decoded by a bytes
+// decoder it shares memory with the input.
+type XmlDocumentSpan []byte
+
+// XMLDecoder returns an xml.Decoder reading the document.
+func (x XmlDocumentSpan) XMLDecoder() *xml.Decoder {
+	return xml.NewDecoder(bytes.NewReader(x))
+}
+
+// XMLDecoder returns an xml.Decoder reading the document.
+func (x XmlDocumentType) XMLDecoder() *xml.Decoder {
+	return xml.NewDecoder(strings.NewReader(string(x)))
+}
+
+func (dec *Decoder) readXML() (interface{}, error) {
+	n, err := dec.readXMLLength()
+	if err != nil {
+		return nil, err
+	}
+	var b []byte
+	if br, ok := dec.r.(*bytesReader); ok {
+		b, err = br.next(int(n))
+	} else {
+		b, err = readLength(dec.r, "XML document length", uint64(n))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if dec.opts.LazyXML {
+		return XmlDocumentSpan(b), nil
+	}
+	return XmlDocumentType(b), nil
+}
+
+func (dec *Decoder) readXMLLength() (uint32, error) {
+	u32 := make([]byte, 4)
+	_, err := io.ReadFull(dec.r, u32)
+	if err != nil {
+		return 0, err
+	}
+	n := binary.BigEndian.Uint32(u32)
+	limit := dec.opts.MaxXMLLength
+	if limit == 0 {
+		limit = dec.opts.MaxStringLength
+	}
+	if limit > 0 && n > limit {
+		return 0, &LengthError{What: "XML document length", Length: uint64(n), Limit: int64(limit)}
+	}
+	return n, nil
+}
+
+// DecodeXML expects the next value to be an XML document and returns an
+// xml.Decoder streaming it straight from the input, without reading the
+// whole document into memory first. Whatever the xml.Decoder leaves unread
+// is skipped by the next call to Decode.
+func (dec *Decoder) DecodeXML() (*xml.Decoder, error) {
+	err := dec.drainPending()
+	if err != nil {
+		return nil, err
+	}
+	u8 := make([]byte, 1)
+	_, err = io.ReadFull(dec.r, u8)
+	if err != nil {
+		return nil, err
+	}
+	if u8[0] != XmlDocumentMarker {
+		return nil, errors.New("expect XmlDocumentMarker here")
+	}
+	n, err := dec.readXMLLength()
+	if err != nil {
+		return nil, err
+	}
+	dec.pending = &io.LimitedReader{R: dec.r, N: int64(n)}
+	return xml.NewDecoder(dec.pending), nil
+}
+
+// drainPending skips what is left of a document handed out by DecodeXML.
+func (dec *Decoder) drainPending() error {
+	if dec.pending == nil {
+		return nil
+	}
+	n := dec.pending.N
+	dec.pending = nil
+	return dec.discard(int(n))
+}
@@ -0,0 +1,60 @@
+package amf0
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+var testXMLDocument = []byte{0x0f, 0x00, 0x00, 0x00, 0x08, '<', 'a', '>', '1', '<', '/', 'a', '>', 0x05}
+
+func TestDecodeXmlDocumentLimit(t *testing.T) {
+	dec := NewDecoderWithOptions(bytes.NewReader(testXMLDocument), DecoderOptions{MaxXMLLength: 7})
+	_, err := dec.Decode()
+	if err == nil {
+		t.Fatalf("expect error for XML document over the limit")
+	}
+}
+
+func TestDecodeXmlDocumentLazy(t *testing.T) {
+	dec := NewDecoderWithOptions(bytes.NewReader(testXMLDocument), DecoderOptions{LazyXML: true})
+	got, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	span, ok := got.(XmlDocumentSpan)
+	if !ok {
+		t.Fatalf("type incorrect")
+	}
+	var a string
+	err = span.XMLDecoder().Decode(&a)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if a != "1" {
+		t.Fatalf("expect 1 got %v", a)
+	}
+}
+
+func TestDecodeXMLStream(t *testing.T) {
+	dec := NewDecoder(bytes.NewReader(testXMLDocument))
+	xd, err := dec.DecodeXML()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	tok, err := xd.Token()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if start, ok := tok.(xml.StartElement); !ok || start.Name.Local != "a" {
+		t.Fatalf("unexpected token %v", tok)
+	}
+	// the rest of the document is skipped
+	got, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got != (NullType{}) {
+		t.Fatalf("expect null got %v", got)
+	}
+}
@@ -0,0 +1,200 @@
+package amf3
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+)
+
+// EncodeError reports a value that cannot be encoded and where it was found,
+// e.g. ".items[2]".
+type EncodeError struct {
+	Path string
+	Err  error
+}
+
+func (e *EncodeError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
+
+// CanEncode reports whether an Encoder would accept v, without encoding it.
+// Every problem found is reported as an *EncodeError, joined with
+// errors.Join.
+func CanEncode(v interface{}) error {
+	var errs []error
+	check(v, "", make(map[interface{}]bool), &errs)
+	return errors.Join(errs...)
+}
+
+func check(v interface{}, path string, seen map[interface{}]bool, errs *[]error) {
+	switch value := v.(type) {
+	case nil, UndefinedType, NullType, FalseType, TrueType, IntegerType, DoubleType,
+		StringType, NullStringType, XMLDocumentType, *XMLDocumentType, XMLType, *XMLType,
+		DateType, *DateType, ByteArrayType, *ByteArrayType,
+		VectorIntType, *VectorIntType, VectorUintType, *VectorUintType, VectorDoubleType, *VectorDoubleType:
+	case VectorObjectType:
+		check(&value, path, seen, errs)
+	case *VectorObjectType:
+		if seen[value] {
+			return
+		}
+		seen[value] = true
+		for i, elem := range value.Items {
+			check(elem, path+"["+strconv.Itoa(i)+"]", seen, errs)
+		}
+	case *ArrayCollection:
+		check(*value, path, seen, errs)
+	case *ObjectProxy:
+		check(*value, path, seen, errs)
+	case Externalizable:
+		// the body is up to WriteExternal, but the class must be known
+		t := reflect.TypeOf(value)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if _, ok := current().externalNames[t]; !ok {
+			*errs = append(*errs, &EncodeError{Path: path, Err: errors.New("externalizable type " + t.String() + " has no registered class")})
+		}
+	case DictionaryType:
+		check(&value, path, seen, errs)
+	case *DictionaryType:
+		if seen[value] {
+			return
+		}
+		seen[value] = true
+		for i, entry := range value.Entries {
+			check(entry.Key, path+"["+strconv.Itoa(i)+"].key", seen, errs)
+			check(entry.Value, path+"["+strconv.Itoa(i)+"].value", seen, errs)
+		}
+	case *ArrayType:
+		if seen[value] {
+			return
+		}
+		seen[value] = true
+		for k, elem := range value.Associative {
+			check(elem, path+"."+string(k), seen, errs)
+		}
+		for i, elem := range value.Dense {
+			check(elem, path+"["+strconv.Itoa(i)+"]", seen, errs)
+		}
+	case *ObjectType:
+		if seen[value] {
+			return
+		}
+		seen[value] = true
+		var attrs []StringType
+		if value.Trait != nil {
+			attrs = value.Trait.Attrs
+		}
+		if len(value.Static) != len(attrs) {
+			*errs = append(*errs, &EncodeError{Path: path, Err: errors.New("static values do not match traits")})
+		}
+		for i, elem := range value.Static {
+			name := "[" + strconv.Itoa(i) + "]"
+			if i < len(attrs) {
+				name = "." + string(attrs[i])
+			}
+			check(elem, path+name, seen, errs)
+		}
+		for k, elem := range value.Dynamic {
+			check(elem, path+"."+string(k), seen, errs)
+		}
+	case TypedValue:
+		rv := reflect.ValueOf(value.Value)
+		if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+			rv = rv.Elem()
+		}
+		if rv.Kind() != reflect.Struct && (rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String || rv.IsNil()) {
+			*errs = append(*errs, &EncodeError{Path: path, Err: typedValueError(value)})
+			return
+		}
+		check(value.Value, path, seen, errs)
+	case Valuer:
+		if reflect.TypeOf(value).Kind() == reflect.Ptr {
+			if seen[value] {
+				return
+			}
+			seen[value] = true
+		}
+		conv, err := value.AMF3Value()
+		if err != nil {
+			*errs = append(*errs, &EncodeError{Path: path, Err: err})
+			return
+		}
+		check(conv, path, seen, errs)
+	default:
+		checkReflect(reflect.ValueOf(v), path, seen, errs)
+	}
+}
+
+// checkReflect follows the rules of encodeReflect.
+func checkReflect(rv reflect.Value, path string, seen map[interface{}]bool, errs *[]error) {
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return
+		}
+		key := refKey{typ: rv.Type(), ptr: rv.UnsafePointer()}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		check(rv.Elem().Interface(), path, seen, errs)
+	case reflect.Interface:
+		if !rv.IsNil() {
+			check(rv.Elem().Interface(), path, seen, errs)
+		}
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.String:
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice {
+			if rv.IsNil() || rv.Type().Elem().Kind() == reflect.Uint8 {
+				return
+			}
+			key := refKey{typ: rv.Type(), ptr: rv.UnsafePointer(), len: rv.Len()}
+			if seen[key] {
+				return
+			}
+			seen[key] = true
+		}
+		for i := 0; i < rv.Len(); i++ {
+			check(rv.Index(i).Interface(), path+"["+strconv.Itoa(i)+"]", seen, errs)
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			*errs = append(*errs, &EncodeError{Path: path, Err: &UnsupportedTypeError{Type: rv.Type().Key(), MapKey: true}})
+			return
+		}
+		if rv.IsNil() {
+			return
+		}
+		key := refKey{typ: rv.Type(), ptr: rv.UnsafePointer()}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		iter := rv.MapRange()
+		for iter.Next() {
+			check(iter.Value().Interface(), path+"."+iter.Key().String(), seen, errs)
+		}
+	case reflect.Struct:
+		if rv.Type() == timeType {
+			return
+		}
+		for _, f := range typeFields(rv.Type()) {
+			if fv, ok := fieldByIndex(rv, f.index); ok {
+				check(fv.Interface(), path+"."+string(f.name), seen, errs)
+			}
+		}
+	default:
+		*errs = append(*errs, &EncodeError{Path: path, Err: &UnsupportedTypeError{Type: rv.Type()}})
+	}
+}
@@ -0,0 +1,108 @@
+package amf3
+
+import (
+	"errors"
+	"maps"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Registry holds the class aliases of Go struct types and the
+// externalizable classes. The package-level Register functions change the
+// global registry, which every Encoder and Decoder uses unless bound to
+// another Registry through its options. Lookups never take locks:
+// registering replaces the global registry with an updated copy.
+//
+// A Registry from NewRegistry or Snapshot may be changed until it is handed
+// to an Encoder or Decoder, and must not be changed afterwards.
+type Registry struct {
+	aliases map[reflect.Type]StringType
+	// externals and externalNames map externalizable classes to the
+	// struct types whose pointers implement Externalizable, and back.
+	externals     map[StringType]reflect.Type
+	externalNames map[reflect.Type]StringType
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		aliases:       make(map[reflect.Type]StringType),
+		externals:     make(map[StringType]reflect.Type),
+		externalNames: make(map[reflect.Type]StringType),
+	}
+}
+
+var global struct {
+	sync.Mutex // held while updating
+	registry   atomic.Pointer[Registry]
+}
+
+var emptyRegistry = NewRegistry()
+
+func current() *Registry {
+	if r := global.registry.Load(); r != nil {
+		return r
+	}
+	return emptyRegistry
+}
+
+func update(fn func(r *Registry) error) error {
+	global.Lock()
+	defer global.Unlock()
+	r := current().clone()
+	err := fn(r)
+	if err != nil {
+		return err
+	}
+	global.registry.Store(r)
+	return nil
+}
+
+// Snapshot returns a copy of the global registry, unaffected by later
+// registrations.
+func Snapshot() *Registry {
+	return current().clone()
+}
+
+func (r *Registry) clone() *Registry {
+	return &Registry{
+		aliases:       maps.Clone(r.aliases),
+		externals:     maps.Clone(r.externals),
+		externalNames: maps.Clone(r.externalNames),
+	}
+}
+
+// RegisterAlias makes structs of type t encode as objects of the
+// ActionScript class className instead of anonymous objects. Decoded objects
+// keep their class name in their Trait.
+func RegisterAlias(className string, t reflect.Type) error {
+	return update(func(r *Registry) error {
+		return r.RegisterAlias(className, t)
+	})
+}
+
+// RegisterAlias is like the package-level RegisterAlias for encoders bound
+// to r.
+func (r *Registry) RegisterAlias(className string, t reflect.Type) error {
+	if t == nil || t.Kind() != reflect.Struct {
+		return errors.New("class " + className + " must map to a struct type")
+	}
+	r.aliases[t] = StringType(className)
+	return nil
+}
+
+// registry returns the registry enc is bound to.
+func (enc *Encoder) registry() *Registry {
+	if enc.opts.Registry != nil {
+		return enc.opts.Registry
+	}
+	return current()
+}
+
+// registry returns the registry dec is bound to.
+func (dec *Decoder) registry() *Registry {
+	if dec.opts.Registry != nil {
+		return dec.opts.Registry
+	}
+	return current()
+}
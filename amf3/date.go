@@ -0,0 +1,20 @@
+package amf3
+
+import (
+	"time"
+)
+
+// NewDate returns the AMF3 date for t, in milliseconds since the Unix epoch.
+func NewDate(t time.Time) DateType {
+	// split at the millisecond so that dates past 2262 do not overflow
+	frac := float64(t.Nanosecond()%int(time.Millisecond)) / float64(time.Millisecond)
+	return DateType(float64(t.UnixMilli()) + frac)
+}
+
+// Time returns the date as a time.Time in UTC. AMF3 dates carry no time
+// zone.
+func (d DateType) Time() time.Time {
+	ms := int64(d)
+	ns := int64((float64(d) - float64(ms)) * float64(time.Millisecond))
+	return time.UnixMilli(ms).Add(time.Duration(ns)).UTC()
+}
@@ -0,0 +1,13 @@
+package amf3
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateTime(t *testing.T) {
+	at := time.Date(2400, 1, 1, 0, 0, 0, 250_000_000, time.UTC)
+	if got := NewDate(at).Time(); !got.Equal(at) {
+		t.Fatalf("expect %v got %v", at, got)
+	}
+}
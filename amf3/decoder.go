@@ -13,18 +13,36 @@ type Decoder struct {
 	refStrings []StringType  // Strings
 	refObjects []interface{} // Object, Array, XML, XMLDocument, ByteArray, Date and instances of user defined Classes
 	refTraits  []*Trait      // Objects and instances of user defined Classes have trait information
+	opts       DecoderOptions
+	depth      int
+	valueCount int
+	counter    *countingReader
 }
 
 func NewDecoder(r io.Reader) *Decoder {
-	if _, ok := r.(*bufio.Reader); ok {
-		return &Decoder{r: r}
+	// Readers that can hand out single bytes are already cheap to read from
+	// and must not be wrapped: buffering would read past the end of the
+	// value when an AMF0 stream switches to AMF3.
+	c := &countingReader{r: r}
+	if _, ok := r.(io.ByteReader); ok {
+		return &Decoder{r: c, counter: c}
 	}
-	return &Decoder{r: bufio.NewReader(r)}
+	return &Decoder{r: bufio.NewReader(c), counter: c}
 }
 
+// Decode decodes the next value. It returns io.EOF at the end of the input
+// between values; other errors are *DecodeErrors.
+//
+// Values decode as the types of this package only: decoding into Go structs,
+// maps and slices is done by amf0.Unmarshal and amf0.Decoder.DecodeValueInto,
+// which take AMF3 values after the switch to AMF3 marker, as AMF0 streams
+// carry them. Prefix a bare AMF3 value with that marker to decode it so.
 func (dec *Decoder) Decode() (interface{}, error) {
 	v, err := dec.decodeValue()
 	if err != nil {
+		if _, ok := err.(*DecodeError); !ok && err != io.EOF {
+			err = &DecodeError{Offset: dec.offset(), Marker: -1, Err: err}
+		}
 		return nil, err
 	}
 	return v, nil
@@ -32,11 +50,32 @@ func (dec *Decoder) Decode() (interface{}, error) {
 
 func (dec *Decoder) decodeValue() (interface{}, error) {
 	u8 := make([]byte, 1)
-	_, err := dec.r.Read(u8)
+	_, err := io.ReadFull(dec.r, u8)
 	if err != nil {
 		return nil, err
 	}
-	switch u8[0] {
+	err = dec.countValue()
+	if err != nil {
+		return nil, dec.valueError(err, u8[0])
+	}
+	v, err := dec.decodeMarker(u8[0])
+	if err != nil {
+		return nil, dec.valueError(err, u8[0])
+	}
+	return v, nil
+}
+
+// decodeMarker decodes the rest of a value whose marker has already been
+// read.
+func (dec *Decoder) decodeMarker(marker byte) (interface{}, error) {
+	if isContainer(marker) {
+		err := dec.enter()
+		if err != nil {
+			return nil, err
+		}
+		defer dec.leave()
+	}
+	switch marker {
 	case UndefinedMarker:
 		return UndefinedType{}, nil
 	case NullMarker:
@@ -46,7 +85,7 @@ func (dec *Decoder) decodeValue() (interface{}, error) {
 	case TrueMarker:
 		return TrueType{}, nil
 	case IntegerMarker:
-		i, err := DecodeUInt29(dec.r)
+		i, err := DecodeInt29(dec.r)
 		if err != nil {
 			return nil, err
 		}
@@ -76,11 +115,10 @@ func (dec *Decoder) decodeValue() (interface{}, error) {
 			}
 			var ok bool
 			if xmldoc, ok = obj.(*XMLDocumentType); !ok {
-				return nil, errors.New("wrong ref type")
+				return nil, ErrReferenceType
 			}
 		} else {
-			strBytes := make([]byte, i)
-			_, err = dec.r.Read(strBytes)
+			strBytes, err := dec.readStringBytes("XML document length", i)
 			if err != nil {
 				return nil, err
 			}
@@ -101,7 +139,7 @@ func (dec *Decoder) decodeValue() (interface{}, error) {
 			}
 			var ok bool
 			if date, ok = obj.(*DateType); !ok {
-				return nil, errors.New("wrong ref type")
+				return nil, ErrReferenceType
 			}
 		} else {
 			f, err := dec.readFloat()
@@ -114,18 +152,30 @@ func (dec *Decoder) decodeValue() (interface{}, error) {
 		return date, nil
 	case ArrayMarker:
 		ref, i, err := dec.readRefInt()
+		if err != nil {
+			return nil, err
+		}
 		if ref {
 			obj, err := dec.getRefObject(i)
 			if err != nil {
 				return nil, err
 			}
 			if _, ok := obj.(*ArrayType); !ok {
-				return nil, errors.New("wrong ref type")
+				return nil, ErrReferenceType
 			}
 			return obj, nil
 		} else {
 			denseCount := i
+			err = dec.checkCount("dense array count", denseCount)
+			if err != nil {
+				return nil, err
+			}
+			err = checkLength(dec.r, "dense array count", uint64(denseCount))
+			if err != nil {
+				return nil, err
+			}
 			array := new(ArrayType)
+			array.Associative = make(map[StringType]interface{})
 			dec.refObjects = append(dec.refObjects, array)
 			for {
 				s, err := dec.readString()
@@ -137,15 +187,18 @@ func (dec *Decoder) decodeValue() (interface{}, error) {
 				}
 				array.Associative[s], err = dec.decodeValue()
 				if err != nil {
-					return nil, err
+					return nil, dec.atPath(err, "."+string(s))
 				}
 			}
-			array.Dense = make([]interface{}, denseCount)
+			if denseCount <= allocChunk || remaining(dec.r) >= 0 {
+				array.Dense = make([]interface{}, 0, denseCount)
+			}
 			for k := 0; k < int(denseCount); k++ {
-				array.Dense[k], err = dec.decodeValue()
+				value, err := dec.decodeValue()
 				if err != nil {
-					return nil, err
+					return nil, dec.atPath(err, index(k))
 				}
+				array.Dense = append(array.Dense, value)
 			}
 			return array, nil
 		}
@@ -162,11 +215,10 @@ func (dec *Decoder) decodeValue() (interface{}, error) {
 			}
 			var ok bool
 			if xml, ok = obj.(*XMLType); !ok {
-				return nil, errors.New("wrong ref type")
+				return nil, ErrReferenceType
 			}
 		} else {
-			strBytes := make([]byte, i)
-			_, err = dec.r.Read(strBytes)
+			strBytes, err := dec.readStringBytes("XML length", i)
 			if err != nil {
 				return nil, err
 			}
@@ -185,12 +237,11 @@ func (dec *Decoder) decodeValue() (interface{}, error) {
 				return nil, err
 			}
 			if _, ok := obj.(*ByteArrayType); !ok {
-				return nil, errors.New("wrong ref type")
+				return nil, ErrReferenceType
 			}
 			return obj, nil
 		} else {
-			byteArray := make([]byte, i)
-			_, err = dec.r.Read(byteArray)
+			byteArray, err := readLength(dec.r, "byte array length", uint64(i))
 			if err != nil {
 				return nil, err
 			}
@@ -210,69 +261,97 @@ func (dec *Decoder) decodeValue() (interface{}, error) {
 			if err != nil {
 				return nil, err
 			}
-			if _, ok := obj.(*ObjectType); !ok {
-				return nil, errors.New("wrong ref type")
+			// unwrapped Flex wrappers are referred to by what they wrap
+			_, external := obj.(Externalizable)
+			if _, ok := obj.(*ObjectType); !ok && !external && !dec.opts.UnwrapFlexCollections {
+				return nil, ErrReferenceType
 			}
 			return obj, nil
 		} else {
+			refIndex := len(dec.refObjects)
 			obj := new(ObjectType)
 			dec.refObjects = append(dec.refObjects, obj)
-			var trait *Trait
-			if i&0x01 == 0 {
-				trait, err = dec.getRefTrait(i >> 1)
+			trait, err := dec.readTrait(i)
+			if err != nil {
+				return nil, err
+			}
+			if trait.Externalizable {
+				return dec.readExternal(trait, refIndex)
+			}
+			obj.Trait = trait
+			obj.Static = make([]interface{}, len(trait.Attrs))
+			for k := 0; k < len(trait.Attrs); k++ {
+				obj.Static[k], err = dec.decodeValue()
 				if err != nil {
-					return nil, err
+					return nil, dec.atPath(err, "."+string(trait.Attrs[k]))
 				}
-			} else {
-				trait = new(Trait)
-				if i&0x02 == 0 {
-					trait.IsDynamic = i&0x04 == 1
-					attrsCount := int(i >> 3)
-					trait = new(Trait)
-					trait.ClassName, err = dec.readString()
+			}
+			obj.Dynamic = make(map[StringType]interface{})
+			if trait.IsDynamic {
+				for {
+					name, err := dec.readString()
 					if err != nil {
 						return nil, err
 					}
-					trait.Attrs = make([]StringType, attrsCount)
-					for k := 0; k < attrsCount; k++ {
-						trait.Attrs[k], err = dec.readString()
-						if err != nil {
-							return nil, err
-						}
+					if name == "" {
+						break
 					}
-					dec.refTraits = append(dec.refTraits, trait)
-				} else {
-					return nil, errors.New("traits-ext not support")
-				}
-				obj.Trait = trait
-				obj.Static = make([]interface{}, len(trait.Attrs))
-				for k := 0; k < len(trait.Attrs); k++ {
-					obj.Static[k], err = dec.decodeValue()
+					obj.Dynamic[name], err = dec.decodeValue()
 					if err != nil {
-						return nil, err
-					}
-				}
-				obj.Dynamic = make(map[StringType]interface{})
-				if trait.IsDynamic {
-					for {
-						name, err := dec.readString()
-						if err != nil {
-							return nil, err
-						}
-						if name == "" {
-							break
-						}
-						obj.Dynamic[name], err = dec.decodeValue()
-						if err != nil {
-							return nil, err
-						}
+						return nil, dec.atPath(err, "."+string(name))
 					}
 				}
 			}
 			return obj, nil
 		}
+	case VectorIntMarker, VectorUintMarker, VectorDoubleMarker, VectorObjectMarker:
+		return dec.readVector(marker)
+	case DictionaryMarker:
+		return dec.readDictionary()
 	}
-	return nil, nil
+	return nil, &MarkerError{Marker: marker}
+}
+
+// readTrait reads the trait of an object whose header, after the reference
+// bit, is i: a reference to a trait read before, or a trait written inline,
+// which is entered in the trait table.
+func (dec *Decoder) readTrait(i uint32) (*Trait, error) {
+	if i&0x01 == 0 {
+		return dec.getRefTrait(i >> 1)
+	}
+	var err error
+	if i&0x02 != 0 {
+		trait := &Trait{Externalizable: true}
+		trait.ClassName, err = dec.readString()
+		if err != nil {
+			return nil, err
+		}
+		dec.refTraits = append(dec.refTraits, trait)
+		return trait, nil
+	}
+	trait := new(Trait)
+	trait.IsDynamic = i&0x04 != 0
+	attrsCount := int(i >> 3)
+	err = checkLength(dec.r, "trait member count", uint64(attrsCount))
+	if err != nil {
+		return nil, err
+	}
+	trait.ClassName, err = dec.readString()
+	if err != nil {
+		return nil, err
+	}
+	if attrsCount <= allocChunk || remaining(dec.r) >= 0 {
+		trait.Attrs = make([]StringType, 0, attrsCount)
+	}
+	for k := 0; k < attrsCount; k++ {
+		attr, err := dec.readString()
+		if err != nil {
+			return nil, err
+		}
+		trait.Attrs = append(trait.Attrs, attr)
+	}
+	dec.refTraits = append(dec.refTraits, trait)
+	return trait, nil
 }
 
 func (dec *Decoder) readRefInt() (ref bool, i uint32, err error) {
@@ -287,7 +366,7 @@ func (dec *Decoder) readRefInt() (ref bool, i uint32, err error) {
 
 func (dec *Decoder) readFloat() (float64, error) {
 	u64 := make([]byte, 8)
-	_, err := dec.r.Read(u64)
+	_, err := io.ReadFull(dec.r, u64)
 	if err != nil {
 		return 0, err
 	}
@@ -311,11 +390,16 @@ func (dec *Decoder) readString() (StringType, error) {
 			return "", err
 		}
 	} else {
-		strBytes := make([]byte, i)
-		_, err = dec.r.Read(strBytes)
+		strBytes, err := dec.readStringBytes("string length", i)
 		if err != nil {
 			return "", err
 		}
+		if dec.opts.Transcode != nil && len(strBytes) > 0 {
+			strBytes, err = dec.opts.Transcode(strBytes)
+			if err != nil {
+				return "", errors.New("transcoding string: " + err.Error())
+			}
+		}
 		str = StringType(strBytes)
 		if str != "" {
 			dec.refStrings = append(dec.refStrings, str)
@@ -326,21 +410,21 @@ func (dec *Decoder) readString() (StringType, error) {
 
 func (dec *Decoder) getRefString(i uint32) (StringType, error) {
 	if int(i) >= len(dec.refStrings) {
-		return "", errors.New("refStrings index outbound")
+		return "", &ReferenceError{Table: "string", Index: int(i), Len: len(dec.refStrings)}
 	}
 	return dec.refStrings[i], nil
 }
 
 func (dec *Decoder) getRefObject(i uint32) (interface{}, error) {
 	if int(i) >= len(dec.refObjects) {
-		return nil, errors.New("refObjects index outbound")
+		return nil, &ReferenceError{Table: "object", Index: int(i), Len: len(dec.refObjects)}
 	}
 	return dec.refObjects[i], nil
 }
 
 func (dec *Decoder) getRefTrait(i uint32) (*Trait, error) {
 	if int(i) >= len(dec.refTraits) {
-		return nil, errors.New("refTraits index outbound")
+		return nil, &ReferenceError{Table: "trait", Index: int(i), Len: len(dec.refTraits)}
 	}
 	return dec.refTraits[i], nil
 }
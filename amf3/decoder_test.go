@@ -0,0 +1,302 @@
+package amf3
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/marcuswu/amf/amftest"
+)
+
+func TestDecodeObjectTraitReference(t *testing.T) {
+	buf := bytes.NewReader([]byte{0x09, 0x05, 0x01,
+		0x0a, 0x0b, 0x01, 0x03, 'a', 0x04, 0x01, 0x01,
+		0x0a, 0x01, 0x00, 0x04, 0x02, 0x01})
+	got, err := NewDecoder(buf).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	array, ok := got.(*ArrayType)
+	if !ok || len(array.Dense) != 2 {
+		t.Fatalf("expect array of 2 got %v", got)
+	}
+	for i, expect := range []IntegerType{1, 2} {
+		obj, ok := array.Dense[i].(*ObjectType)
+		if !ok {
+			t.Fatalf("expect object got %v", array.Dense[i])
+		}
+		if !obj.Trait.IsDynamic || obj.Dynamic["a"] != expect {
+			t.Errorf("expect dynamic a=%v got %+v", expect, obj)
+		}
+	}
+}
+
+func TestDecodeAssociativeArray(t *testing.T) {
+	buf := bytes.NewReader([]byte{0x09, 0x01, 0x03, 'b', 0x04, 0x05, 0x01})
+	got, err := NewDecoder(buf).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	array, ok := got.(*ArrayType)
+	if !ok || array.Associative["b"] != IntegerType(5) {
+		t.Fatalf("expect b=5 got %v", got)
+	}
+}
+
+func TestDecodeSignedInteger(t *testing.T) {
+	for _, pair := range []struct {
+		b []byte
+		i IntegerType
+	}{
+		{[]byte{0x04, 0xff, 0xff, 0xff, 0xff}, -1},
+		{[]byte{0x04, 0xc0, 0x80, 0x80, 0x00}, -0x10000000},
+		{[]byte{0x04, 0xbf, 0xff, 0xff, 0xff}, 0x0fffffff},
+	} {
+		got, err := NewDecoder(bytes.NewReader(pair.b)).Decode()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if got != pair.i {
+			t.Errorf("test for %x: expect %d got %v", pair.b, pair.i, got)
+		}
+	}
+}
+
+func TestDecodeUnknownMarker(t *testing.T) {
+	_, err := NewDecoder(bytes.NewReader([]byte{0x20})).Decode()
+	if err == nil {
+		t.Fatalf("expect error for unknown marker")
+	}
+	var me *MarkerError
+	if !errors.As(err, &me) || me.Marker != 0x20 || !errors.Is(err, ErrUnknownMarker) {
+		t.Errorf("expect unknown marker error got %v", err)
+	}
+	err = NewDecoder(bytes.NewReader([]byte{0x20})).SkipValue()
+	if !errors.Is(err, ErrUnknownMarker) {
+		t.Errorf("expect unknown marker error skipping got %v", err)
+	}
+}
+
+func TestDecodeAbsurdCount(t *testing.T) {
+	for _, data := range [][]byte{
+		{0x09, 0xff, 0xff, 0xff, 0xff, 0x01},
+		{0x06, 0xff, 0xff, 0xff, 0xff, 'a'},
+		{0x0c, 0xff, 0xff, 0xff, 0xff, 'a'},
+		{0x0a, 0xff, 0xff, 0xff, 0xfb, 0x01},
+	} {
+		_, err := NewDecoder(bytes.NewReader(data)).Decode()
+		var lengthErr *LengthError
+		if !errors.As(err, &lengthErr) {
+			t.Errorf("%x: expect LengthError got %v", data, err)
+		}
+	}
+}
+
+func TestDecodeUnwrapFlexCollections(t *testing.T) {
+	data := []byte{0x09, 0x07, 0x01, 0x0a, 0x07, 0x43}
+	data = append(data, ArrayCollectionClass...)
+	data = append(data, 0x09, 0x03, 0x01, 0x04, 0x01)
+	data = append(data, 0x0a, 0x07, 0x49)
+	data = append(data, ManagedObjectProxyClass...)
+	data = append(data, 0x09, 0x03, 0x01, 0x06, 0x03, 'a', 0x04, 0x02)
+	data = append(data, 0x0a, 0x02)
+
+	_, err := NewDecoder(bytes.NewReader(data)).Decode()
+	if err == nil {
+		t.Fatalf("expect error without UnwrapFlexCollections")
+	}
+	got, err := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{UnwrapFlexCollections: true}).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	array := got.(*ArrayType)
+	source, ok := array.Dense[0].(*ArrayType)
+	if !ok || len(source.Dense) != 1 || source.Dense[0] != IntegerType(1) {
+		t.Fatalf("expect source array [1] got %v", array.Dense[0])
+	}
+	proxy, ok := array.Dense[1].(*ObjectType)
+	if !ok || proxy.Dynamic["a"] != IntegerType(2) {
+		t.Fatalf("expect object a=2 got %v", array.Dense[1])
+	}
+	if array.Dense[2] != array.Dense[0] {
+		t.Fatalf("expect reference to the source array got %v", array.Dense[2])
+	}
+}
+
+func TestDecodeMaxDepth(t *testing.T) {
+	// dense arrays, each holding the next, around an integer
+	nested := func(n int) []byte {
+		var data []byte
+		for i := 0; i < n; i++ {
+			data = append(data, 0x09, 0x03, 0x01)
+		}
+		return append(data, 0x04, 0x01)
+	}
+	opts := DecoderOptions{MaxDepth: 2}
+	_, err := NewDecoderWithOptions(bytes.NewReader(nested(2)), opts).Decode()
+	if err != nil {
+		t.Errorf("expect arrays at the limit to decode got %v", err)
+	}
+	_, err = NewDecoderWithOptions(bytes.NewReader(nested(3)), opts).Decode()
+	if !errors.Is(err, ErrMaxDepth) {
+		t.Errorf("expect max depth error got %v", err)
+	}
+	err = NewDecoderWithOptions(bytes.NewReader(nested(3)), opts).SkipValue()
+	if !errors.Is(err, ErrMaxDepth) {
+		t.Errorf("expect max depth error skipping got %v", err)
+	}
+	_, err = NewDecoder(bytes.NewReader(nested(DefaultMaxDepth + 1))).Decode()
+	if !errors.Is(err, ErrMaxDepth) {
+		t.Errorf("expect the default limit to apply got %v", err)
+	}
+}
+
+func TestDecodeMaxStringLength(t *testing.T) {
+	opts := DecoderOptions{MaxStringLength: 4}
+	for _, data := range [][]byte{
+		{0x06, 0x0b, 'h', 'e', 'l', 'l', 'o'},
+		{0x06, 0xff, 0xff, 0xff, 0xf1},
+		{0x0b, 0x0b, '<', 'a', '/', '>', ' '},
+	} {
+		_, err := NewDecoderWithOptions(bytes.NewReader(data), opts).Decode()
+		var le *LengthError
+		if !errors.As(err, &le) || le.Limit != 4 {
+			t.Errorf("expect length error for %x got %v", data, err)
+		}
+	}
+	got, err := NewDecoderWithOptions(bytes.NewReader([]byte{0x06, 0x09, 'h', 'e', 'l', 'l'}), opts).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got != StringType("hell") {
+		t.Errorf("expect hell got %v", got)
+	}
+}
+
+func TestDecodeMaxArrayCount(t *testing.T) {
+	opts := DecoderOptions{MaxArrayCount: 2}
+	for _, data := range [][]byte{
+		{0x09, 0x07, 0x01, 0x04, 0x01, 0x04, 0x02, 0x04, 0x03},
+		{0x0d, 0x07, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x03},
+		{0x11, 0x07, 0x00},
+	} {
+		_, err := NewDecoderWithOptions(bytes.NewReader(data), opts).Decode()
+		var le *LengthError
+		if !errors.As(err, &le) || le.Limit != 2 {
+			t.Errorf("expect length error for %x got %v", data, err)
+		}
+	}
+	_, err := NewDecoderWithOptions(bytes.NewReader([]byte{0x09, 0x05, 0x01, 0x04, 0x01, 0x04, 0x02}), opts).Decode()
+	if err != nil {
+		t.Errorf("expect array at the limit to decode got %v", err)
+	}
+}
+
+func TestDecodeErrorOffset(t *testing.T) {
+	data := []byte{0x0a, 0x0b, 0x01, 0x03, 'a', 0x09, 0x05, 0x01, 0x04, 0x01, 0x06, 0x07, 'x'}
+	// hide the size of the input so that the string runs into its end
+	_, err := NewDecoder(struct{ io.Reader }{bytes.NewReader(data)}).Decode()
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("expect a DecodeError got %v", err)
+	}
+	if de.Offset != 13 || de.Marker != StringMarker || de.Path != ".a[1]" || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expect offset 13, string marker and path .a[1] got %v", err)
+	}
+	err = NewDecoder(struct{ io.Reader }{bytes.NewReader(data)}).SkipValue()
+	if !errors.As(err, &de) || de.Offset != 13 || de.Path != ".a[1]" {
+		t.Errorf("expect offset 13 and path .a[1] skipping got %v", err)
+	}
+}
+
+func TestDecodeMaxValueCount(t *testing.T) {
+	data := []byte{0x09, 0x05, 0x01, 0x04, 0x01, 0x04, 0x02}
+	dec := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{MaxValueCount: 3})
+	_, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if dec.ValueCount() != 3 {
+		t.Errorf("expect 3 values counted got %d", dec.ValueCount())
+	}
+	_, err = NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{MaxValueCount: 2}).Decode()
+	if !errors.Is(err, ErrMaxValueCount) {
+		t.Errorf("expect max value count error got %v", err)
+	}
+	err = NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{MaxValueCount: 2}).SkipValue()
+	if !errors.Is(err, ErrMaxValueCount) {
+		t.Errorf("expect max value count error skipping got %v", err)
+	}
+}
+
+func TestDecodeErrorKinds(t *testing.T) {
+	_, err := NewDecoder(bytes.NewReader([]byte{0x06, 0x02})).Decode()
+	var re *ReferenceError
+	if !errors.Is(err, ErrReferenceOutOfRange) || !errors.As(err, &re) || re.Table != "string" || re.Index != 1 {
+		t.Errorf("expect string reference 1 out of range got %v", err)
+	}
+	_, err = NewDecoder(bytes.NewReader([]byte{0x09, 0x05, 0x01, 0x09, 0x01, 0x01, 0x08, 0x02})).Decode()
+	if !errors.Is(err, ErrReferenceType) {
+		t.Errorf("expect wrong reference type got %v", err)
+	}
+	err = NewEncoder(new(bytes.Buffer)).Encode(map[float64]int{1: 1})
+	var ue *UnsupportedTypeError
+	if !errors.Is(err, ErrUnsupportedType) || !errors.As(err, &ue) || !ue.MapKey {
+		t.Errorf("expect unsupported map key type got %v", err)
+	}
+}
+
+func TestDecodeChunkedReader(t *testing.T) {
+	var buf bytes.Buffer
+	err := NewEncoder(&buf).Encode(map[string]interface{}{
+		"i":   300000,
+		"d":   2.5,
+		"s":   "str",
+		"arr": []interface{}{1, "str", false},
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	data := buf.Bytes()
+	expect, err := NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	// reads come back short, as they do from a network connection
+	for _, sizes := range [][]int{nil, {3, 7}} {
+		v, err := NewDecoder(&amftest.ChunkedReader{R: bytes.NewReader(data), Sizes: sizes}).Decode()
+		if err != nil {
+			t.Fatalf("chunks of %v: %s", sizes, err)
+		}
+		if !reflect.DeepEqual(v, expect) {
+			t.Errorf("chunks of %v: expect %v got %v", sizes, expect, v)
+		}
+	}
+}
+
+func TestDecodeSeedReferences(t *testing.T) {
+	server := &ObjectType{Dynamic: map[StringType]interface{}{"name": StringType("fms")}}
+	dec := NewDecoder(bytes.NewReader([]byte{0x09, 0x05, 0x01, 0x0a, 0x00, 0x0a, 0x00}))
+	dec.SeedReferences([]interface{}{server})
+	v, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	array := v.(*ArrayType)
+	if array.Dense[0] != server || array.Dense[1] != server {
+		t.Errorf("expect the seeded object got %v", array.Dense)
+	}
+	// the array decoded after the seeds is reference 1
+	dec = NewDecoder(bytes.NewReader([]byte{0x09, 0x03, 0x01, 0x09, 0x02}))
+	dec.SeedReferences([]interface{}{server})
+	v, err = dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	array = v.(*ArrayType)
+	if array.Dense[0] != array {
+		t.Errorf("expect a reference to the array itself got %v", array.Dense[0])
+	}
+}
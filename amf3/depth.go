@@ -0,0 +1,41 @@
+package amf3
+
+import (
+	"errors"
+)
+
+// DefaultMaxDepth is the nesting depth allowed when DecoderOptions.MaxDepth
+// is 0: far deeper than real payloads nest, and shallow enough that crafted
+// input cannot exhaust the stack.
+const DefaultMaxDepth = 1000
+
+// ErrMaxDepth is returned when objects, arrays, vectors and dictionaries
+// are nested deeper than the decoder allows.
+var ErrMaxDepth = errors.New("maximum nesting depth exceeded")
+
+// enter counts one more level of nesting, failing past the limit. Every
+// successful enter is followed by a leave.
+func (dec *Decoder) enter() error {
+	limit := dec.opts.MaxDepth
+	if limit == 0 {
+		limit = DefaultMaxDepth
+	}
+	if limit > 0 && dec.depth >= limit {
+		return ErrMaxDepth
+	}
+	dec.depth++
+	return nil
+}
+
+func (dec *Decoder) leave() {
+	dec.depth--
+}
+
+// isContainer reports whether the values of marker may hold other values.
+func isContainer(marker byte) bool {
+	switch marker {
+	case ArrayMarker, ObjectMarker, VectorObjectMarker, DictionaryMarker:
+		return true
+	}
+	return false
+}
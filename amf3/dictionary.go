@@ -0,0 +1,95 @@
+package amf3
+
+import (
+	"errors"
+	"io"
+)
+
+// readDictionary reads a Dictionary after its marker.
+func (dec *Decoder) readDictionary() (interface{}, error) {
+	ref, i, err := dec.readRefInt()
+	if err != nil {
+		return nil, err
+	}
+	if ref {
+		obj, err := dec.getRefObject(i)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := obj.(*DictionaryType); !ok {
+			return nil, ErrReferenceType
+		}
+		return obj, nil
+	}
+	err = dec.checkCount("dictionary count", i)
+	if err != nil {
+		return nil, err
+	}
+	count := int(i)
+	weak := make([]byte, 1)
+	_, err = io.ReadFull(dec.r, weak)
+	if err != nil {
+		return nil, err
+	}
+	// every entry takes at least two bytes
+	err = checkLength(dec.r, "dictionary count", uint64(count)*2)
+	if err != nil {
+		return nil, err
+	}
+	dict := &DictionaryType{WeakKeys: weak[0] != 0}
+	dec.refObjects = append(dec.refObjects, dict)
+	if count <= allocChunk || remaining(dec.r) >= 0 {
+		dict.Entries = make([]DictionaryEntry, 0, count)
+	}
+	for k := 0; k < count; k++ {
+		var entry DictionaryEntry
+		entry.Key, err = dec.decodeValue()
+		if err != nil {
+			return nil, dec.atPath(err, index(k)+".key")
+		}
+		entry.Value, err = dec.decodeValue()
+		if err != nil {
+			return nil, dec.atPath(err, index(k)+".value")
+		}
+		dict.Entries = append(dict.Entries, entry)
+	}
+	return dict, nil
+}
+
+func (enc *Encoder) encodeDictionary(dict *DictionaryType) error {
+	err := enc.bw.WriteByte(DictionaryMarker)
+	if err != nil {
+		return err
+	}
+	ok, err := enc.writeObjectRef(dict)
+	if ok || err != nil {
+		return err
+	}
+	enc.refObjects = append(enc.refObjects, dict)
+	if len(dict.Entries) > 0x0FFFFFFF {
+		return errors.New("dictionary too long")
+	}
+	err = EncodeUInt29(enc.bw, uint32(len(dict.Entries)<<1|0x01))
+	if err != nil {
+		return err
+	}
+	weak := byte(0)
+	if dict.WeakKeys {
+		weak = 1
+	}
+	err = enc.bw.WriteByte(weak)
+	if err != nil {
+		return err
+	}
+	for _, entry := range dict.Entries {
+		err = enc.encodeValue(entry.Key)
+		if err != nil {
+			return err
+		}
+		err = enc.encodeValue(entry.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
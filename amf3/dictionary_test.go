@@ -0,0 +1,35 @@
+package amf3
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDictionaryRoundTrip(t *testing.T) {
+	key := &ObjectType{Trait: &Trait{IsDynamic: true, Attrs: []StringType{}}, Static: []interface{}{}, Dynamic: map[StringType]interface{}{}}
+	dict := &DictionaryType{WeakKeys: true, Entries: []DictionaryEntry{
+		{Key: IntegerType(1), Value: StringType("one")},
+		{Key: key, Value: TrueType{}},
+		{Key: StringType("one"), Value: key},
+	}}
+	expect := []byte{0x11, 0x07, 0x01,
+		0x04, 0x01, 0x06, 0x07, 'o', 'n', 'e',
+		0x0a, 0x0b, 0x01, 0x01, 0x03,
+		0x06, 0x00, 0x0a, 0x02}
+	got := encode(t, dict)
+	if !bytes.Equal(got, expect) {
+		t.Fatalf("expect %x got %x", expect, got)
+	}
+	decoded, err := NewDecoder(bytes.NewReader(got)).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !reflect.DeepEqual(decoded, dict) {
+		t.Fatalf("expect %+v got %+v", dict, decoded)
+	}
+	entries := decoded.(*DictionaryType).Entries
+	if entries[1].Key != entries[2].Value {
+		t.Fatalf("expect the object key to be referenced")
+	}
+}
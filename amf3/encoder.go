@@ -3,8 +3,11 @@ package amf3
 import (
 	"bufio"
 	"encoding/binary"
+	"errors"
 	"io"
 	"math"
+	"reflect"
+	"sort"
 )
 
 type Encoder struct {
@@ -13,6 +16,9 @@ type Encoder struct {
 	refStrings []StringType  // Strings
 	refObjects []interface{} // Object, Array, XML, XMLDocument, ByteArray, Date and instances of user defined Classes
 	refTraits  []*Trait      // Objects and instances of user defined Classes have trait information	
+	structTraits map[structClass]*Trait // traits of the Go struct types encoded so far
+	converted    map[interface{}]interface{} // the AMF3 values of the Valuers held by pointer encoded so far
+	opts         EncoderOptions
 }
 
 func NewEncoder(w io.Writer) *Encoder {
@@ -28,9 +34,16 @@ func (enc *Encoder) Encode(v interface{}) error {
 	return err
 }
 
+// Flush writes any buffered data to the underlying writer.
+func (enc *Encoder) Flush() error {
+	return enc.bw.Flush()
+}
+
 func (enc *Encoder) encodeValue(v interface{}) error {
 	u64 := make([]byte, 8)
-	if _, ok := v.(UndefinedType); ok {
+	if v == nil {
+		return enc.encodeValue(NullType{})
+	} else if _, ok := v.(UndefinedType); ok {
 		_, err := enc.bw.Write([]byte{UndefinedMarker})
 		if err != nil {
 			return err
@@ -50,12 +63,14 @@ func (enc *Encoder) encodeValue(v interface{}) error {
 		if err != nil {
 			return err
 		}
+	} else if value, ok := v.(IntegerType); ok && (value < -0x10000000 || value > 0x0FFFFFFF) {
+		return enc.encodeValue(DoubleType(value))
 	} else if value, ok := v.(IntegerType); ok {
 		_, err := enc.bw.Write([]byte{IntegerMarker})
 		if err != nil {
 			return err
 		}
-		err = EncodeUInt29(enc.bw, uint32(value))
+		err = EncodeInt29(enc.bw, int32(value))
 		if err != nil {
 			return err
 		}
@@ -120,6 +135,14 @@ func (enc *Encoder) encodeValue(v interface{}) error {
 				return err
 			}
 		}
+	} else if value, ok := v.(XMLDocumentType); ok {
+		return enc.encodeValue(&value)
+	} else if value, ok := v.(XMLType); ok {
+		return enc.encodeValue(&value)
+	} else if value, ok := v.(DateType); ok {
+		return enc.encodeValue(&value)
+	} else if value, ok := v.(ByteArrayType); ok {
+		return enc.encodeValue(&value)
 	} else if value, ok := v.(*DateType); ok {
 		_, err := enc.bw.Write([]byte{DateMarker})
 		if err != nil {
@@ -133,6 +156,10 @@ func (enc *Encoder) encodeValue(v interface{}) error {
 			return nil
 		} else {
 			enc.refObjects = append(enc.refObjects, value)
+			err = enc.bw.WriteByte(0x01)
+			if err != nil {
+				return err
+			}
 			f := math.Float64bits(float64(*value))
 			binary.BigEndian.PutUint64(u64, f)
 			_, err = enc.bw.Write(u64)
@@ -154,12 +181,21 @@ func (enc *Encoder) encodeValue(v interface{}) error {
 		} else {
 			enc.refObjects = append(enc.refObjects, value)
 			length := len(*value)
-			err = EncodeUInt29(enc.bw, uint32(length<<1))
+			err = EncodeUInt29(enc.bw, uint32(length<<1 | 0x01))
+			if err != nil {
+				return err
+			}
+			_, err = enc.bw.Write([]byte(*value))
 			if err != nil {
 				return err
 			}
-			enc.bw.Write([]byte(*value))
 		}
+	} else if value, ok := v.(*ArrayType); ok && enc.opts.WrapFlexCollections && len(value.Associative) == 0 {
+		written, err := enc.writeCollectionHeader(value)
+		if written || err != nil {
+			return err
+		}
+		return enc.encodeArray(reflect.ValueOf(value.Dense), nil)
 	} else if value, ok := v.(*ArrayType); ok {
 		_, err := enc.bw.Write([]byte{ArrayMarker})
 		if err != nil {
@@ -181,19 +217,11 @@ func (enc *Encoder) encodeValue(v interface{}) error {
 				return err
 			}
 
-			//Now the associative part
-			var k StringType
-			for k = range value.Associative {
-				err = enc.writeString(k)
-				if err != nil {
-					return err
-				}
-				err = enc.encodeValue(value.Associative[k])
-				if err != nil {
-					return err
-				}
+			//Now the associative part, ended by the empty string
+			err = enc.writeMembers(value.Associative)
+			if err != nil {
+				return err
 			}
-			enc.encodeValue(NullType{})
 
 			//Finally, the dense items
 			for i := range value.Dense {
@@ -203,11 +231,135 @@ func (enc *Encoder) encodeValue(v interface{}) error {
 				}
 			}
 		}
+	} else if value, ok := v.(*ObjectType); ok {
+		_, err := enc.bw.Write([]byte{ObjectMarker})
+		if err != nil {
+			return err
+		}
+		ok, err := enc.writeObjectRef(value)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		enc.refObjects = append(enc.refObjects, value)
+		trait := value.Trait
+		if trait == nil {
+			trait = &Trait{IsDynamic: true}
+		}
+		if trait.Externalizable {
+			return errors.New("externalizable object without a body")
+		}
+		if len(value.Static) != len(trait.Attrs) {
+			return errors.New("static values do not match traits")
+		}
+		err = enc.writeTraits(trait)
+		if err != nil {
+			return err
+		}
+		for i := range value.Static {
+			err = enc.encodeValue(value.Static[i])
+			if err != nil {
+				return err
+			}
+		}
+		if trait.IsDynamic {
+			err = enc.writeMembers(value.Dynamic)
+			if err != nil {
+				return err
+			}
+		}
+	} else if value, ok := v.(VectorIntType); ok {
+		return enc.encodeValue(&value)
+	} else if value, ok := v.(VectorUintType); ok {
+		return enc.encodeValue(&value)
+	} else if value, ok := v.(VectorDoubleType); ok {
+		return enc.encodeValue(&value)
+	} else if value, ok := v.(VectorObjectType); ok {
+		return enc.encodeValue(&value)
+	} else if isVector(v, VectorIntMarker) || isVector(v, VectorUintMarker) ||
+		isVector(v, VectorDoubleMarker) || isVector(v, VectorObjectMarker) {
+		return enc.encodeVector(v)
+	} else if value, ok := v.(ArrayCollection); ok {
+		return enc.encodeValue(&value)
+	} else if value, ok := v.(ObjectProxy); ok {
+		return enc.encodeValue(&value)
+	} else if value, ok := v.(Externalizable); ok {
+		return enc.encodeExternal(value)
+	} else if value, ok := v.(DictionaryType); ok {
+		return enc.encodeValue(&value)
+	} else if value, ok := v.(*DictionaryType); ok {
+		return enc.encodeDictionary(value)
+	} else if value, ok := v.(TypedValue); ok {
+		return enc.encodeTyped(value)
+	} else if value, ok := v.(Valuer); ok {
+		return enc.encodeValuer(value)
+	} else {
+		return enc.encodeReflect(reflect.ValueOf(v))
+	}
+	return nil
+}
+
+// writeMembers writes name and value pairs in name order, followed by the
+// empty string.
+func (enc *Encoder) writeMembers(members map[StringType]interface{}) error {
+	names := make([]StringType, 0, len(members))
+	for k := range members {
+		names = append(names, k)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	for _, k := range names {
+		err := enc.writeString(k)
+		if err != nil {
+			return err
+		}
+		err = enc.encodeValue(members[k])
+		if err != nil {
+			return err
+		}
+	}
+	return enc.writeString("")
+}
+
+// writeTraits writes trait inline the first time and by reference after.
+func (enc *Encoder) writeTraits(trait *Trait) error {
+	for i, t := range enc.refTraits {
+		if t == trait && !enc.opts.InlineTraits {
+			return EncodeUInt29(enc.bw, uint32(i<<2|0x01))
+		}
+	}
+	enc.refTraits = append(enc.refTraits, trait)
+	u := uint32(len(trait.Attrs)<<4 | 0x03)
+	if trait.IsDynamic {
+		u |= 0x08
+	}
+	if trait.Externalizable {
+		// the body stands for the members
+		u = 0x07
+	}
+	err := EncodeUInt29(enc.bw, u)
+	if err != nil {
+		return err
+	}
+	err = enc.writeString(trait.ClassName)
+	if err != nil {
+		return err
+	}
+	for _, attr := range trait.Attrs {
+		err = enc.writeString(attr)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
 func (enc *Encoder) writeString(str StringType) error {
+	if str == "" {
+		// the empty string is never sent by reference
+		return enc.bw.WriteByte(0x01)
+	}
 	for i, s := range enc.refStrings {
 		if s == str {
 			u := uint32(i<<1)
@@ -0,0 +1,113 @@
+package amf3
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func encode(t *testing.T, v interface{}) []byte {
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	err := NewEncoder(bw).Encode(v)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	bw.Flush()
+	return buf.Bytes()
+}
+
+func TestEncodeObjectTraitReference(t *testing.T) {
+	trait := &Trait{IsDynamic: true}
+	array := &ArrayType{Dense: []interface{}{
+		&ObjectType{Trait: trait, Dynamic: map[StringType]interface{}{"a": IntegerType(1)}},
+		&ObjectType{Trait: trait, Dynamic: map[StringType]interface{}{"a": IntegerType(2)}},
+	}}
+	expect := []byte{0x09, 0x05, 0x01,
+		0x0a, 0x0b, 0x01, 0x03, 'a', 0x04, 0x01, 0x01,
+		0x0a, 0x01, 0x00, 0x04, 0x02, 0x01}
+	got := encode(t, array)
+	if !bytes.Equal(got, expect) {
+		t.Fatalf("expect %v got %v", expect, got)
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	date := DateType(1.5e12)
+	bytesValue := ByteArrayType("xyz")
+	obj := &ObjectType{
+		Trait:  &Trait{ClassName: "com.example.User", Attrs: []StringType{"when", "data"}},
+		Static: []interface{}{&date, &bytesValue},
+	}
+	data := encode(t, &ArrayType{Dense: []interface{}{obj, obj}})
+	got, err := NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	array := got.(*ArrayType)
+	if len(array.Dense) != 2 || array.Dense[0] != array.Dense[1] {
+		t.Fatalf("expect the object twice by reference got %v", array.Dense)
+	}
+	decoded := array.Dense[0].(*ObjectType)
+	if decoded.Trait.ClassName != "com.example.User" || len(decoded.Static) != 2 {
+		t.Fatalf("expect %+v got %+v", obj, decoded)
+	}
+	if *decoded.Static[0].(*DateType) != date {
+		t.Errorf("expect %v got %v", date, decoded.Static[0])
+	}
+	if string(*decoded.Static[1].(*ByteArrayType)) != "xyz" {
+		t.Errorf("expect xyz got %v", decoded.Static[1])
+	}
+}
+
+func TestEncodeLargeInteger(t *testing.T) {
+	for _, v := range []interface{}{IntegerType(0x20000000), IntegerType(0x10000000), IntegerType(-0x10000001), 0x10000000, -0x10000001} {
+		got := encode(t, v)
+		if got[0] != DoubleMarker {
+			t.Fatalf("test for %v: expect double marker got %v", v, got)
+		}
+	}
+}
+
+func TestEncodeNegativeInteger(t *testing.T) {
+	for _, pair := range []struct {
+		v interface{}
+		i IntegerType
+		b []byte
+	}{
+		{IntegerType(-1), -1, []byte{0x04, 0xff, 0xff, 0xff, 0xff}},
+		{-1, -1, []byte{0x04, 0xff, 0xff, 0xff, 0xff}},
+		{IntegerType(-0x10000000), -0x10000000, []byte{0x04, 0xc0, 0x80, 0x80, 0x00}},
+		{0x0fffffff, 0x0fffffff, []byte{0x04, 0xbf, 0xff, 0xff, 0xff}},
+	} {
+		got := encode(t, pair.v)
+		if !bytes.Equal(got, pair.b) {
+			t.Errorf("test for %v: expect %x got %x", pair.v, pair.b, got)
+		}
+		back, err := NewDecoder(bytes.NewReader(got)).Decode()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if back != pair.i {
+			t.Errorf("test for %v: expect %d got %v", pair.v, pair.i, back)
+		}
+	}
+}
+
+func TestByteArrayReference(t *testing.T) {
+	blob := &ByteArrayType{0x89, 'P', 'N', 'G'}
+	expect := []byte{0x09, 0x05, 0x01, 0x0c, 0x09, 0x89, 'P', 'N', 'G', 0x0c, 0x02}
+	got := encode(t, &ArrayType{Dense: []interface{}{blob, blob}})
+	if !bytes.Equal(got, expect) {
+		t.Fatalf("expect %x got %x", expect, got)
+	}
+	v, err := NewDecoder(bytes.NewReader(got)).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	array := v.(*ArrayType)
+	first, ok := array.Dense[0].(*ByteArrayType)
+	if !ok || !bytes.Equal(*first, *blob) || array.Dense[1] != array.Dense[0] {
+		t.Fatalf("expect the same byte array twice got %v", array.Dense)
+	}
+}
@@ -0,0 +1,56 @@
+package amf3
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+)
+
+// Errors that decoding and encoding fail with, for callers to tell the
+// kinds of failure apart with errors.Is. Errors carrying details wrap one
+// of them.
+var (
+	// ErrReferenceOutOfRange is wrapped by a *ReferenceError.
+	ErrReferenceOutOfRange = errors.New("reference out of range")
+	// ErrReferenceType is returned when a reference points at a value of
+	// another type than its marker asks for.
+	ErrReferenceType = errors.New("wrong ref type")
+	// ErrUnsupportedType is wrapped by an *UnsupportedTypeError.
+	ErrUnsupportedType = errors.New("unsupported type")
+	// ErrIntegerRange is returned for integers that do not fit in 29 bits.
+	ErrIntegerRange = errors.New("out of range")
+)
+
+// ReferenceError reports a reference to an entry past the end of one of
+// the reference tables.
+type ReferenceError struct {
+	Table string // "string", "object" or "trait"
+	Index int
+	Len   int // the entries in the table
+}
+
+func (e *ReferenceError) Error() string {
+	return e.Table + " reference " + strconv.Itoa(e.Index) + " out of range of " + strconv.Itoa(e.Len)
+}
+
+func (e *ReferenceError) Unwrap() error {
+	return ErrReferenceOutOfRange
+}
+
+// UnsupportedTypeError reports a Go type that has no encoding, or is used as
+// the key type of a map that has none.
+type UnsupportedTypeError struct {
+	Type   reflect.Type
+	MapKey bool // Type is the key type of a map
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	if e.MapKey {
+		return "unsupported map key type " + e.Type.String()
+	}
+	return "unsupported type " + e.Type.String()
+}
+
+func (e *UnsupportedTypeError) Unwrap() error {
+	return ErrUnsupportedType
+}
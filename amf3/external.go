@@ -0,0 +1,117 @@
+package amf3
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Externalizable is implemented by pointers to Go types standing for
+// ActionScript classes that implement IExternalizable. Objects of such a
+// class carry a body of the class's own making instead of members:
+// ReadExternal reads it, with dec.Decode for values and dec.Read for raw
+// bytes, and WriteExternal writes it back.
+type Externalizable interface {
+	ReadExternal(dec *Decoder) error
+	WriteExternal(enc *Encoder) error
+}
+
+var externalizableType = reflect.TypeOf((*Externalizable)(nil)).Elem()
+
+// RegisterExternalizable makes objects of the externalizable class
+// className decode into a new *T, t being T or *T, by calling its
+// ReadExternal method. Values of type *T encode as objects of the class.
+func RegisterExternalizable(className string, t reflect.Type) error {
+	return update(func(r *Registry) error {
+		return r.RegisterExternalizable(className, t)
+	})
+}
+
+// RegisterExternalizable is like the package-level RegisterExternalizable
+// for codecs bound to r.
+func (r *Registry) RegisterExternalizable(className string, t reflect.Type) error {
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || !reflect.PointerTo(t).Implements(externalizableType) {
+		return errors.New("class " + className + " must map to a type whose pointer implements Externalizable")
+	}
+	if old, ok := r.externalNames[t]; ok {
+		delete(r.externals, old)
+	}
+	r.externals[StringType(className)] = t
+	r.externalNames[t] = StringType(className)
+	return nil
+}
+
+// Read reads raw bytes of an externalizable body.
+func (dec *Decoder) Read(p []byte) (int, error) {
+	return dec.r.Read(p)
+}
+
+// Write writes raw bytes of an externalizable body.
+func (enc *Encoder) Write(p []byte) (int, error) {
+	return enc.bw.Write(p)
+}
+
+// readExternal reads the body of an object of the externalizable class of
+// trait, which must be registered or, with UnwrapFlexCollections, be one
+// of the Flex wrappers.
+func (dec *Decoder) readExternal(trait *Trait, refIndex int) (interface{}, error) {
+	if t := dec.registry().externals[trait.ClassName]; t != nil {
+		v := reflect.New(t).Interface().(Externalizable)
+		dec.refObjects[refIndex] = v
+		err := v.ReadExternal(dec)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	if dec.opts.UnwrapFlexCollections {
+		return dec.unwrapFlex(trait, refIndex)
+	}
+	return nil, errors.New("traits-ext not support: " + string(trait.ClassName))
+}
+
+// encodeExternal writes v as an object of its registered class.
+func (enc *Encoder) encodeExternal(v Externalizable) error {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	className, ok := enc.registry().externalNames[t]
+	if !ok {
+		className, ok = builtinExternals[t]
+	}
+	if !ok {
+		return errors.New("externalizable type " + t.String() + " has no registered class")
+	}
+	err := enc.bw.WriteByte(ObjectMarker)
+	if err != nil {
+		return err
+	}
+	ok, err = enc.writeObjectRef(v)
+	if ok || err != nil {
+		return err
+	}
+	enc.refObjects = append(enc.refObjects, v)
+	err = enc.writeTraits(enc.externalTrait(reflect.TypeOf(v), className))
+	if err != nil {
+		return err
+	}
+	return v.WriteExternal(enc)
+}
+
+// externalTrait returns the trait shared by the objects of the
+// externalizable type t.
+func (enc *Encoder) externalTrait(t reflect.Type, className StringType) *Trait {
+	key := structClass{typ: t, class: className}
+	trait := enc.structTraits[key]
+	if trait == nil {
+		trait = &Trait{ClassName: className, Externalizable: true}
+		if enc.structTraits == nil {
+			enc.structTraits = make(map[structClass]*Trait)
+		}
+		enc.structTraits[key] = trait
+	}
+	return trait
+}
@@ -0,0 +1,81 @@
+package amf3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+type externalPoint struct {
+	X, Y int32
+}
+
+func (p *externalPoint) ReadExternal(dec *Decoder) error {
+	return binary.Read(dec, binary.BigEndian, p)
+}
+
+func (p *externalPoint) WriteExternal(enc *Encoder) error {
+	return binary.Write(enc, binary.BigEndian, p)
+}
+
+type externalCollection struct {
+	Source interface{}
+}
+
+func (c *externalCollection) ReadExternal(dec *Decoder) error {
+	var err error
+	c.Source, err = dec.Decode()
+	return err
+}
+
+func (c *externalCollection) WriteExternal(enc *Encoder) error {
+	return enc.Encode(c.Source)
+}
+
+func TestExternalizable(t *testing.T) {
+	r := NewRegistry()
+	err := r.RegisterExternalizable("geom.Point", reflect.TypeOf(externalPoint{}))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	err = r.RegisterExternalizable(ArrayCollectionClass, reflect.TypeOf(&externalCollection{}))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if r.RegisterExternalizable("geom.Bad", reflect.TypeOf(0)) == nil {
+		t.Fatalf("expect error for a type that is not Externalizable")
+	}
+
+	p := &externalPoint{1, -1}
+	v := &externalCollection{Source: &ArrayType{Dense: []interface{}{p, p}}}
+	var buf bytes.Buffer
+	err = NewEncoderWithOptions(&buf, EncoderOptions{Registry: r}).Encode(v)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect := append([]byte{0x0a, 0x07, 0x43}, ArrayCollectionClass...)
+	expect = append(expect, 0x09, 0x05, 0x01, 0x0a, 0x07, 0x15)
+	expect = append(expect, "geom.Point"...)
+	expect = append(expect, 0, 0, 0, 1, 0xff, 0xff, 0xff, 0xff, 0x0a, 0x04)
+	if !bytes.Equal(buf.Bytes(), expect) {
+		t.Fatalf("expect %x got %x", expect, buf.Bytes())
+	}
+
+	got, err := NewDecoderWithOptions(bytes.NewReader(buf.Bytes()), DecoderOptions{Registry: r}).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	c, ok := got.(*externalCollection)
+	if !ok {
+		t.Fatalf("expect *externalCollection got %#v", got)
+	}
+	dense := c.Source.(*ArrayType).Dense
+	if *dense[0].(*externalPoint) != *p || dense[1] != dense[0] {
+		t.Fatalf("expect the same point twice got %v", dense)
+	}
+	_, err = NewDecoder(bytes.NewReader(buf.Bytes())).Decode()
+	if err == nil {
+		t.Fatalf("expect error for an unregistered externalizable class")
+	}
+}
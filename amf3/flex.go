@@ -0,0 +1,164 @@
+package amf3
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Flex classes that wrap another value in their externalized body.
+const (
+	ArrayCollectionClass    = "flex.messaging.io.ArrayCollection"
+	ObjectProxyClass        = "flex.messaging.io.ObjectProxy"
+	SerializationProxyClass = "flex.messaging.io.SerializationProxy"
+	ManagedObjectProxyClass = "flex.messaging.io.ManagedObjectProxy"
+)
+
+// unwrapFlex reads the body of a Flex wrapper and returns the value it
+// wraps, which also takes the place of the wrapper in the reference table.
+func (dec *Decoder) unwrapFlex(trait *Trait, refIndex int) (interface{}, error) {
+	var v interface{}
+	var err error
+	switch trait.ClassName {
+	case ArrayCollectionClass, ObjectProxyClass, SerializationProxyClass:
+		v, err = dec.decodeValue()
+	case ManagedObjectProxyClass:
+		v, err = dec.readManagedObjectProxy()
+	default:
+		return nil, errors.New("traits-ext not support: " + string(trait.ClassName))
+	}
+	if err != nil {
+		return nil, err
+	}
+	dec.refObjects[refIndex] = v
+	return v, nil
+}
+
+// readManagedObjectProxy reads an array of property names followed by the
+// value of each property, as written by LCDS for managed entities.
+func (dec *Decoder) readManagedObjectProxy() (*ObjectType, error) {
+	v, err := dec.decodeValue()
+	if err != nil {
+		return nil, err
+	}
+	names, ok := v.(*ArrayType)
+	if !ok {
+		return nil, errors.New("ManagedObjectProxy property names are not an array")
+	}
+	obj := &ObjectType{Trait: &Trait{IsDynamic: true}, Dynamic: make(map[StringType]interface{})}
+	for _, n := range names.Dense {
+		name, ok := n.(StringType)
+		if !ok {
+			return nil, errors.New("ManagedObjectProxy property name is not a string")
+		}
+		obj.Dynamic[name], err = dec.decodeValue()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+// ArrayCollection is a Flex ArrayCollection, decoded as the items of the
+// array it wraps. Register it with RegisterFlexCollections to decode
+// ArrayCollection objects into it; it encodes as one either way.
+type ArrayCollection []interface{}
+
+func (c *ArrayCollection) ReadExternal(dec *Decoder) error {
+	v, err := dec.decodeValue()
+	if err != nil {
+		return err
+	}
+	switch source := v.(type) {
+	case *ArrayType:
+		*c = source.Dense
+	case NullType:
+		*c = nil
+	default:
+		return errors.New("ArrayCollection source is not an array")
+	}
+	return nil
+}
+
+func (c *ArrayCollection) WriteExternal(enc *Encoder) error {
+	// the source is never wrapped itself
+	return enc.encodeArray(reflect.ValueOf([]interface{}(*c)), nil)
+}
+
+// ObjectProxy is a Flex ObjectProxy, decoded as the members of the object
+// it wraps. Register it with RegisterFlexCollections to decode ObjectProxy
+// objects into it; it encodes as one either way.
+type ObjectProxy map[StringType]interface{}
+
+func (p *ObjectProxy) ReadExternal(dec *Decoder) error {
+	v, err := dec.decodeValue()
+	if err != nil {
+		return err
+	}
+	obj, ok := v.(*ObjectType)
+	if !ok {
+		return errors.New("ObjectProxy does not wrap an object")
+	}
+	m := make(ObjectProxy, len(obj.Static)+len(obj.Dynamic))
+	if obj.Trait != nil {
+		for i, name := range obj.Trait.Attrs {
+			if i < len(obj.Static) {
+				m[name] = obj.Static[i]
+			}
+		}
+	}
+	for name, value := range obj.Dynamic {
+		m[name] = value
+	}
+	*p = m
+	return nil
+}
+
+func (p *ObjectProxy) WriteExternal(enc *Encoder) error {
+	return enc.encodeValue(&ObjectType{Trait: &Trait{IsDynamic: true}, Dynamic: *p})
+}
+
+var (
+	arrayCollectionType = reflect.TypeOf(ArrayCollection(nil))
+	objectProxyType     = reflect.TypeOf(ObjectProxy(nil))
+)
+
+// builtinExternals names the classes of the Flex types, which encode
+// without being registered.
+var builtinExternals = map[reflect.Type]StringType{
+	arrayCollectionType: ArrayCollectionClass,
+	objectProxyType:     ObjectProxyClass,
+}
+
+// RegisterFlexCollections makes ArrayCollection and ObjectProxy objects
+// decode into *ArrayCollection and *ObjectProxy.
+func RegisterFlexCollections() {
+	update(func(r *Registry) error {
+		r.RegisterFlexCollections()
+		return nil
+	})
+}
+
+// RegisterFlexCollections is like the package-level RegisterFlexCollections
+// for codecs bound to r.
+func (r *Registry) RegisterFlexCollections() {
+	r.RegisterExternalizable(ArrayCollectionClass, arrayCollectionType)
+	r.RegisterExternalizable(ObjectProxyClass, objectProxyType)
+}
+
+// writeCollectionHeader writes the start of an ArrayCollection standing for
+// ref, followed by its source array unless it is a reference. A nil ref, for
+// values such as Go arrays, is never written as a reference.
+func (enc *Encoder) writeCollectionHeader(ref interface{}) (written bool, err error) {
+	err = enc.bw.WriteByte(ObjectMarker)
+	if err != nil {
+		return false, err
+	}
+	if ref != nil {
+		ok, err := enc.writeObjectRef(ref)
+		if ok || err != nil {
+			return true, err
+		}
+	}
+	enc.refObjects = append(enc.refObjects, ref)
+	return false, enc.writeTraits(enc.externalTrait(reflect.PointerTo(arrayCollectionType), ArrayCollectionClass))
+}
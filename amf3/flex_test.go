@@ -0,0 +1,78 @@
+package amf3
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestFlexCollections(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterFlexCollections()
+	data := []byte{0x09, 0x05, 0x01, 0x0a, 0x07, 0x43}
+	data = append(data, ArrayCollectionClass...)
+	data = append(data, 0x09, 0x03, 0x01, 0x04, 0x01)
+	data = append(data, 0x0a, 0x07, 0x3b)
+	data = append(data, ObjectProxyClass...)
+	data = append(data, 0x0a, 0x0b, 0x01, 0x03, 'a', 0x04, 0x02, 0x01)
+
+	got, err := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{Registry: r}).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	array := got.(*ArrayType)
+	if c, ok := array.Dense[0].(*ArrayCollection); !ok || !reflect.DeepEqual(*c, ArrayCollection{IntegerType(1)}) {
+		t.Fatalf("expect collection [1] got %v", array.Dense[0])
+	}
+	if p, ok := array.Dense[1].(*ObjectProxy); !ok || (*p)["a"] != IntegerType(2) {
+		t.Fatalf("expect proxy a=2 got %v", array.Dense[1])
+	}
+
+	var buf bytes.Buffer
+	err = NewEncoder(&buf).Encode(&ArrayType{Dense: []interface{}{array.Dense[0], array.Dense[1]}})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("expect %x got %x", data, buf.Bytes())
+	}
+}
+
+func TestWrapFlexCollections(t *testing.T) {
+	items := []int{1}
+	var buf bytes.Buffer
+	enc := NewEncoderWithOptions(&buf, EncoderOptions{WrapFlexCollections: true})
+	err := enc.Encode(map[string]interface{}{"a": items, "b": items, "c": &ArrayType{Dense: []interface{}{}}})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect := []byte{0x0a, 0x0b, 0x01, 0x03, 'a', 0x0a, 0x07, 0x43}
+	expect = append(expect, ArrayCollectionClass...)
+	expect = append(expect, 0x09, 0x03, 0x01, 0x04, 0x01)
+	expect = append(expect, 0x03, 'b', 0x0a, 0x02)
+	expect = append(expect, 0x03, 'c', 0x0a, 0x05, 0x09, 0x01, 0x01, 0x01)
+	if !bytes.Equal(buf.Bytes(), expect) {
+		t.Fatalf("expect %x got %x", expect, buf.Bytes())
+	}
+
+	got, err := NewDecoderWithOptions(bytes.NewReader(buf.Bytes()), DecoderOptions{UnwrapFlexCollections: true}).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	obj := got.(*ObjectType)
+	if a, ok := obj.Dynamic["a"].(*ArrayType); !ok || obj.Dynamic["b"] != a || a.Dense[0] != IntegerType(1) {
+		t.Fatalf("expect a and b to share the array [1] got %v", obj.Dynamic)
+	}
+
+	buf.Reset()
+	err = NewEncoderWithOptions(&buf, EncoderOptions{WrapFlexCollections: true}).Encode([2]int{1, 2})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect = []byte{0x0a, 0x07, 0x43}
+	expect = append(expect, ArrayCollectionClass...)
+	expect = append(expect, 0x09, 0x05, 0x01, 0x04, 0x01, 0x04, 0x02)
+	if !bytes.Equal(buf.Bytes(), expect) {
+		t.Fatalf("expect %x got %x", expect, buf.Bytes())
+	}
+}
@@ -1,7 +1,6 @@
 package amf3
 
 import (
-	"errors"
 	"io"
 )
 
@@ -26,7 +25,7 @@ func encodeUInt29(n uint32) ([]byte, error) {
 		b[2] = byte(n>>8&0x7F | 0x80)
 		b[3] = byte(n)
 	} else {
-		return nil, errors.New("out of range")
+		return nil, ErrIntegerRange
 	}
 	return b, nil
 }
@@ -45,7 +44,6 @@ func EncodeInt29(w io.Writer, n int32) error {
 	if err != nil {
 		return err
 	}
-	un = un&0xFFFFFFF | (un & 0x80000000 >> 3)
 	return EncodeUInt29(w, un)
 }
 
@@ -54,7 +52,7 @@ func DecodeUInt29(r io.Reader) (uint32, error) {
 	i := 0
 	b := make([]byte, 1)
 	for {
-		_, err := r.Read(b)
+		_, err := io.ReadFull(r, b)
 		if err != nil {
 			return 0, err
 		}
@@ -93,7 +91,7 @@ func DecodeInt29(r io.Reader) (int32, error) {
 // signed int -> uint29
 func S2UInt29(i int32) (uint32, error) {
 	if i > 0xFFFFFFF || i < -0x10000000 {
-		return 0, errors.New("out of range")
+		return 0, ErrIntegerRange
 	}
 	ui := uint32(i)
 	ui = ui&0xFFFFFFF | (ui & 0x80000000 >> 3)
@@ -103,10 +101,10 @@ func S2UInt29(i int32) (uint32, error) {
 // uint29 -> signed int
 func U2SInt29(i uint32) (int32, error) {
 	if i > 0x1FFFFFFF {
-		return 0, errors.New("out of range")
+		return 0, ErrIntegerRange
 	}
 	if i&0x10000000 != 0 {
-		return int32(i | 0xFF000000), nil
+		return int32(i | 0xE0000000), nil
 	}
 	return int32(i), nil
 }
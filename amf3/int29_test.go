@@ -57,6 +57,8 @@ var testIntPair = []intPair{
 	{0x01111111, 0x01111111},
 	{0x0FFFFFFF, 0x0FFFFFFF},
 	{0x1FFFFFFF, -0x00000001},
+	{0x10000000, -0x10000000},
+	{0x1FFFFF00, -0x00000100},
 }
 
 func TestS2UInt29(t *testing.T) {
@@ -0,0 +1,108 @@
+package amf3
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// LengthError reports a count or length read from the input that cannot be
+// right, because it is over a configured limit or over what is left of the
+// input. It is returned before anything is allocated for it.
+type LengthError struct {
+	What   string // what is counted, e.g. "dense array count"
+	Length uint64
+	Limit  int64 // the configured limit or, with Remaining, the bytes left
+	// Remaining is set when Limit is the bytes left of the input rather
+	// than a configured limit.
+	Remaining bool
+}
+
+func (e *LengthError) Error() string {
+	if e.Remaining {
+		return e.What + " " + strconv.FormatUint(e.Length, 10) + " does not fit in the " + strconv.FormatInt(e.Limit, 10) + " bytes left"
+	}
+	return e.What + " " + strconv.FormatUint(e.Length, 10) + " over limit of " + strconv.FormatInt(e.Limit, 10)
+}
+
+// allocChunk is the most that is allocated up front for a length that
+// cannot be checked against the input left.
+const allocChunk = 64 << 10
+
+// remaining returns the number of bytes left in r, or -1 if unknown.
+func remaining(r io.Reader) int64 {
+	switch l := r.(type) {
+	case interface{ Remaining() int64 }:
+		return l.Remaining()
+	case interface{ Len() int }:
+		return int64(l.Len())
+	}
+	return -1
+}
+
+// checkLength fails with a *LengthError when n items of at least one byte
+// each cannot fit in what is left of r.
+func checkLength(r io.Reader, what string, n uint64) error {
+	if left := remaining(r); left >= 0 && n > uint64(left) {
+		return &LengthError{What: what, Length: n, Limit: left, Remaining: true}
+	}
+	return nil
+}
+
+// readLength reads the n bytes of a value whose length came from the input.
+func readLength(r io.Reader, what string, n uint64) ([]byte, error) {
+	err := checkLength(r, what, n)
+	if err != nil {
+		return nil, err
+	}
+	if n <= allocChunk || remaining(r) >= 0 {
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		return b, err
+	}
+	var buf bytes.Buffer
+	_, err = io.CopyN(&buf, r, int64(n))
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return buf.Bytes(), err
+}
+
+// checkCount fails with a *LengthError when the element count of an array,
+// vector or dictionary is over the MaxArrayCount option.
+func (dec *Decoder) checkCount(what string, n uint32) error {
+	if limit := dec.opts.MaxArrayCount; limit > 0 && n > limit {
+		return &LengthError{What: what, Length: uint64(n), Limit: int64(limit)}
+	}
+	return nil
+}
+
+// readStringBytes reads the n bytes of a string or XML document, failing
+// first when n is over the MaxStringLength option.
+func (dec *Decoder) readStringBytes(what string, n uint32) ([]byte, error) {
+	if limit := dec.opts.MaxStringLength; limit > 0 && n > limit {
+		return nil, &LengthError{What: what, Length: uint64(n), Limit: int64(limit)}
+	}
+	return readLength(dec.r, what, uint64(n))
+}
+
+// ErrMaxValueCount is returned when the decoder has decoded or skipped more
+// values than the MaxValueCount option allows.
+var ErrMaxValueCount = errors.New("maximum value count exceeded")
+
+// countValue counts one more value decoded or skipped, failing past the
+// MaxValueCount option.
+func (dec *Decoder) countValue() error {
+	if limit := dec.opts.MaxValueCount; limit > 0 && dec.valueCount >= limit {
+		return ErrMaxValueCount
+	}
+	dec.valueCount++
+	return nil
+}
+
+// ValueCount returns the number of values, nested ones included, that dec
+// has decoded or skipped.
+func (dec *Decoder) ValueCount() int {
+	return dec.valueCount
+}
@@ -0,0 +1,23 @@
+package amf3
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ErrUnknownMarker is wrapped by every *MarkerError, so that one errors.Is
+// test covers the unknown markers of both object encodings.
+var ErrUnknownMarker = errors.New("unknown marker")
+
+// MarkerError reports a value starting with a marker that no type has.
+type MarkerError struct {
+	Marker byte
+}
+
+func (e *MarkerError) Error() string {
+	return "unknown marker 0x" + strconv.FormatUint(uint64(e.Marker), 16)
+}
+
+func (e *MarkerError) Unwrap() error {
+	return ErrUnknownMarker
+}
@@ -0,0 +1,110 @@
+package amf3
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+)
+
+// DecodeError reports where decoding failed, as json.SyntaxError does.
+// Offset is the number of bytes of the input read before the failure,
+// Marker the marker of the innermost value being decoded, or -1 when the
+// failure came before any marker was read, and Path the way to that value
+// from the top, e.g. ".user.address" or "[2].name".
+type DecodeError struct {
+	Offset int64
+	Marker int
+	Path   string
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	s := "at offset " + strconv.FormatInt(e.Offset, 10)
+	if e.Path != "" {
+		s += " in " + e.Path
+	}
+	if e.Marker >= 0 {
+		s += " (marker 0x" + strconv.FormatUint(uint64(e.Marker), 16) + ")"
+	}
+	return s + ": " + e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// countingReader counts the bytes read from r, for the offsets of
+// DecodeErrors.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.(io.ByteReader).ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// Remaining passes on the bytes left in r, so that lengths can still be
+// checked against them.
+func (c *countingReader) Remaining() int64 {
+	return remaining(c.r)
+}
+
+// offset returns the bytes of the input consumed so far.
+func (dec *Decoder) offset() int64 {
+	if dec.counter == nil {
+		return 0
+	}
+	n := dec.counter.n
+	if br, ok := dec.r.(*bufio.Reader); ok {
+		n -= int64(br.Buffered())
+	}
+	return n
+}
+
+// valueError returns err, from decoding a value with marker, as a
+// *DecodeError.
+func (dec *Decoder) valueError(err error, marker byte) error {
+	de, ok := err.(*DecodeError)
+	if !ok {
+		return &DecodeError{Offset: dec.offset(), Marker: int(marker), Err: unexpectedEOF(err)}
+	}
+	if de.Marker < 0 {
+		de.Marker = int(marker)
+	}
+	return de
+}
+
+// atPath returns err, from decoding the value reached by elem, as a
+// *DecodeError with elem in front of its path.
+func (dec *Decoder) atPath(err error, elem string) error {
+	de, ok := err.(*DecodeError)
+	if !ok {
+		de = &DecodeError{Offset: dec.offset(), Marker: -1, Err: unexpectedEOF(err)}
+	}
+	de.Path = elem + de.Path
+	return de
+}
+
+func index(k int) string {
+	return "[" + strconv.Itoa(k) + "]"
+}
+
+// unexpectedEOF turns the end of the input in the middle of a value into an
+// error.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package amf3
+
+import (
+	"io"
+)
+
+// EncoderOptions configures an Encoder.
+type EncoderOptions struct {
+	// InlineTraits writes the traits of every object in full instead of
+	// referring to traits written before, for peers that mishandle trait
+	// references. Objects encoded from Go structs otherwise share one trait
+	// per type.
+	InlineTraits bool
+	// WrapFlexCollections writes arrays without associative members,
+	// including Go slices and arrays, as Flex ArrayCollections, which Flex clients
+	// expect for Java lists. Maps are not wrapped in ObjectProxy; encode
+	// an ObjectProxy for that.
+	WrapFlexCollections bool
+	// Registry, if set, provides the class aliases in place of the global
+	// registry.
+	Registry *Registry
+}
+
+// DecoderOptions configures a Decoder.
+type DecoderOptions struct {
+	// UnwrapFlexCollections decodes the externalizable Flex wrappers
+	// ArrayCollection, ObjectProxy, SerializationProxy and
+	// ManagedObjectProxy as the value they wrap. Other externalizable
+	// objects still fail to decode.
+	UnwrapFlexCollections bool
+	// Registry, if set, provides the externalizable classes in place of
+	// the global registry.
+	Registry *Registry
+	// Transcode, if set, converts the bytes of every string, property
+	// name and class name to UTF-8, for legacy clients that wrote them in
+	// another character set.
+	Transcode Transcoder
+	// MaxDepth limits how deep objects, arrays, vectors and dictionaries
+	// nest, failing with ErrMaxDepth past it; 0 means DefaultMaxDepth and
+	// a negative depth means no limit.
+	MaxDepth int
+	// MaxStringLength limits the size in bytes of strings, property names,
+	// class names and XML documents, failing with a *LengthError before
+	// anything is allocated for them; 0 means no limit.
+	MaxStringLength uint32
+	// MaxArrayCount limits the element count of dense arrays, vectors and
+	// dictionaries, failing with a *LengthError before anything is
+	// allocated for them; 0 means no limit.
+	MaxArrayCount uint32
+	// MaxValueCount limits the values, nested ones included, that the
+	// decoder decodes or skips over its life, failing with
+	// ErrMaxValueCount past it, so that input made of millions of tiny
+	// values cannot hold it for long; 0 means no limit.
+	MaxValueCount int
+}
+
+// Transcoder converts bytes written in a legacy character set such as
+// Shift-JIS or GBK to UTF-8, e.g. the Bytes method of the decoder of a
+// golang.org/x/text encoding. It may return bytes that are valid UTF-8
+// already unchanged, for archives mixing both.
+type Transcoder func(b []byte) ([]byte, error)
+
+func NewEncoderWithOptions(w io.Writer, opts EncoderOptions) *Encoder {
+	enc := NewEncoder(w)
+	enc.opts = opts
+	return enc
+}
+
+func NewDecoderWithOptions(r io.Reader, opts DecoderOptions) *Decoder {
+	dec := NewDecoder(r)
+	dec.opts = opts
+	return dec
+}
+
+// Options returns the options enc was created with.
+func (enc *Encoder) Options() EncoderOptions {
+	return enc.opts
+}
+
+// Options returns the options dec was created with.
+func (dec *Decoder) Options() DecoderOptions {
+	return dec.opts
+}
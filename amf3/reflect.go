@@ -0,0 +1,223 @@
+package amf3
+
+import (
+	"reflect"
+	"time"
+	"unsafe"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// anonymousTrait is the trait of the objects written for Go maps.
+var anonymousTrait = &Trait{IsDynamic: true}
+
+// refKey identifies a Go map, slice or struct pointer in the reference
+// table, so that a value shared by several fields, or a cycle, is written
+// once and referenced after.
+type refKey struct {
+	typ reflect.Type
+	ptr unsafe.Pointer
+	len int
+}
+
+// encodeReflect encodes Go values that are not AMF3 types: booleans,
+// numbers and strings become their AMF3 counterparts, time.Time becomes a
+// date, []byte a byte array, maps with string keys dynamic objects, structs
+// sealed objects and other slices and arrays dense arrays.
+func (enc *Encoder) encodeReflect(rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return enc.encodeValue(NullType{})
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return enc.encodeValue(NullType{})
+		}
+		elem := rv.Elem()
+		if rv.Kind() == reflect.Ptr && elem.Kind() == reflect.Struct && elem.Type() != timeType {
+			return enc.encodeStruct(elem, refKey{typ: rv.Type(), ptr: rv.UnsafePointer()}, enc.registry().aliases[elem.Type()])
+		}
+		return enc.encodeValue(elem.Interface())
+	case reflect.Bool:
+		if rv.Bool() {
+			return enc.encodeValue(TrueType{})
+		}
+		return enc.encodeValue(FalseType{})
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n := rv.Int(); n >= -0x10000000 && n <= 0x0FFFFFFF {
+			return enc.encodeValue(IntegerType(n))
+		}
+		return enc.encodeValue(DoubleType(rv.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n := rv.Uint(); n <= 0x0FFFFFFF {
+			return enc.encodeValue(IntegerType(n))
+		}
+		return enc.encodeValue(DoubleType(rv.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return enc.encodeValue(DoubleType(rv.Float()))
+	case reflect.String:
+		return enc.encodeValue(StringType(rv.String()))
+	case reflect.Slice:
+		if rv.IsNil() {
+			return enc.encodeValue(NullType{})
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return enc.encodeValue(ByteArrayType(rv.Bytes()))
+		}
+		return enc.encodeArray(rv, refKey{typ: rv.Type(), ptr: rv.UnsafePointer(), len: rv.Len()})
+	case reflect.Array:
+		if enc.opts.WrapFlexCollections {
+			_, err := enc.writeCollectionHeader(nil)
+			if err != nil {
+				return err
+			}
+		}
+		return enc.encodeArray(rv, nil)
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return &UnsupportedTypeError{Type: rv.Type().Key(), MapKey: true}
+		}
+		if rv.IsNil() {
+			return enc.encodeValue(NullType{})
+		}
+		err := enc.bw.WriteByte(ObjectMarker)
+		if err != nil {
+			return err
+		}
+		key := refKey{typ: rv.Type(), ptr: rv.UnsafePointer()}
+		ok, err := enc.writeObjectRef(key)
+		if ok || err != nil {
+			return err
+		}
+		enc.refObjects = append(enc.refObjects, key)
+		err = enc.writeTraits(anonymousTrait)
+		if err != nil {
+			return err
+		}
+		members := make(map[StringType]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			members[StringType(iter.Key().String())] = iter.Value().Interface()
+		}
+		return enc.writeMembers(members)
+	case reflect.Struct:
+		if rv.Type() == timeType {
+			return enc.encodeValue(NewDate(rv.Interface().(time.Time)))
+		}
+		return enc.encodeStruct(rv, nil, enc.registry().aliases[rv.Type()])
+	}
+	return &UnsupportedTypeError{Type: rv.Type()}
+}
+
+// encodeArray writes the slice or array rv as a dense array. A nil ref keeps
+// the array out of reach of later references.
+func (enc *Encoder) encodeArray(rv reflect.Value, ref interface{}) error {
+	if enc.opts.WrapFlexCollections && ref != nil {
+		written, err := enc.writeCollectionHeader(ref)
+		if written || err != nil {
+			return err
+		}
+		ref = nil
+	}
+	err := enc.bw.WriteByte(ArrayMarker)
+	if err != nil {
+		return err
+	}
+	if ref != nil {
+		ok, err := enc.writeObjectRef(ref)
+		if ok || err != nil {
+			return err
+		}
+	}
+	enc.refObjects = append(enc.refObjects, ref)
+	err = EncodeUInt29(enc.bw, uint32(rv.Len()<<1|0x01))
+	if err != nil {
+		return err
+	}
+	err = enc.writeString("")
+	if err != nil {
+		return err
+	}
+	for i := 0; i < rv.Len(); i++ {
+		err = enc.encodeValue(rv.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeStruct writes the struct rv as an object of class className, the
+// one registered for its type with RegisterAlias unless written as a
+// TypedValue. Its fields are sealed members, except omitempty ones, which
+// are written as dynamic members when they are not empty, so that every
+// value of the type and class shares the same traits.
+func (enc *Encoder) encodeStruct(rv reflect.Value, ref interface{}, className StringType) error {
+	err := enc.bw.WriteByte(ObjectMarker)
+	if err != nil {
+		return err
+	}
+	if ref != nil {
+		ok, err := enc.writeObjectRef(ref)
+		if ok || err != nil {
+			return err
+		}
+	}
+	enc.refObjects = append(enc.refObjects, ref)
+	fields := typeFields(rv.Type())
+	trait := enc.structTrait(rv.Type(), className, fields)
+	err = enc.writeTraits(trait)
+	if err != nil {
+		return err
+	}
+	dynamic := make(map[StringType]interface{})
+	for _, f := range fields {
+		fv, ok := fieldByIndex(rv, f.index)
+		if f.omitEmpty {
+			if ok && !fv.IsZero() {
+				dynamic[f.name] = fv.Interface()
+			}
+			continue
+		}
+		var v interface{}
+		if ok {
+			v = fv.Interface()
+		}
+		err = enc.encodeValue(v)
+		if err != nil {
+			return err
+		}
+	}
+	if trait.IsDynamic {
+		return enc.writeMembers(dynamic)
+	}
+	return nil
+}
+
+// structClass identifies the trait of a struct type written as a class.
+type structClass struct {
+	typ   reflect.Type
+	class StringType
+}
+
+// structTrait returns the trait of struct type t written as className, the
+// same one every time, so that the objects of a type after the first refer
+// to its trait.
+func (enc *Encoder) structTrait(t reflect.Type, className StringType, fields []field) *Trait {
+	key := structClass{typ: t, class: className}
+	if trait := enc.structTraits[key]; trait != nil {
+		return trait
+	}
+	trait := &Trait{ClassName: className}
+	for _, f := range fields {
+		if f.omitEmpty {
+			trait.IsDynamic = true
+		} else {
+			trait.Attrs = append(trait.Attrs, f.name)
+		}
+	}
+	if enc.structTraits == nil {
+		enc.structTraits = make(map[structClass]*Trait)
+	}
+	enc.structTraits[key] = trait
+	return trait
+}
@@ -0,0 +1,93 @@
+package amf3
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeStruct(t *testing.T) {
+	type node struct {
+		Name  string         `amf:"name"`
+		Count int            `amf:"count,omitempty"`
+		Next  *node          `amf:"next"`
+		Tags  map[string]int `amf:"tags"`
+		Kids  []string       `amf:"kids"`
+		skip  bool
+	}
+	a := &node{Name: "a", Tags: map[string]int{"x": -1}}
+	b := &node{Name: "b", Count: 2, Next: a, Kids: []string{"k"}}
+	a.Next = b
+	data := encode(t, a)
+	got, err := NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	obj := got.(*ObjectType)
+	if len(obj.Trait.Attrs) != 4 || !obj.Trait.IsDynamic || obj.Static[0] != StringType("a") {
+		t.Fatalf("expect sealed name, next, tags and kids got %+v", obj)
+	}
+	if _, ok := obj.Dynamic["count"]; ok {
+		t.Errorf("expect empty count omitted got %v", obj.Dynamic)
+	}
+	tags := obj.Static[2].(*ObjectType)
+	if tags.Dynamic["x"] != IntegerType(-1) {
+		t.Errorf("expect x=-1 got %+v", tags)
+	}
+	next := obj.Static[1].(*ObjectType)
+	if next.Dynamic["count"] != IntegerType(2) || next.Static[1] != obj {
+		t.Errorf("expect count=2 and a reference back got %+v", next)
+	}
+	if kids := next.Static[3].(*ArrayType); len(kids.Dense) != 1 || kids.Dense[0] != StringType("k") {
+		t.Errorf("expect kids [k] got %+v", kids)
+	}
+}
+
+func TestEncodeStructTraitReferences(t *testing.T) {
+	type point struct {
+		X int `amf:"x"`
+		Y int `amf:"y"`
+	}
+	points := []point{{1, 2}, {3, 4}, {5, 6}}
+	for _, inline := range []bool{false, true} {
+		var buf bytes.Buffer
+		err := NewEncoderWithOptions(&buf, EncoderOptions{InlineTraits: inline}).Encode(points)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		got, err := NewDecoder(&buf).Decode()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		dense := got.(*ArrayType).Dense
+		first, last := dense[0].(*ObjectType), dense[2].(*ObjectType)
+		if shared := first.Trait == last.Trait; shared == inline {
+			t.Errorf("inline=%v: expect shared traits %v got %v", inline, !inline, shared)
+		}
+		if last.Static[1] != IntegerType(6) {
+			t.Errorf("expect y=6 got %+v", last)
+		}
+	}
+}
+
+func TestRegistrySnapshot(t *testing.T) {
+	type account struct {
+		ID int `amf:"id"`
+	}
+	r := Snapshot()
+	err := r.RegisterAlias("tenant.Account", reflect.TypeOf(account{}))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var buf bytes.Buffer
+	err = NewEncoderWithOptions(&buf, EncoderOptions{Registry: r}).Encode(account{1})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("tenant.Account")) {
+		t.Fatalf("expect the alias of the bound registry got %x", buf.Bytes())
+	}
+	if bytes.Contains(encode(t, account{1}), []byte("tenant.Account")) {
+		t.Fatalf("expect the global registry to be unchanged")
+	}
+}
@@ -0,0 +1,9 @@
+package amf3
+
+// SeedReferences puts objs at the start of the object reference table, so
+// that object references 0 to len(objs)-1 resolve to them and the objects
+// decoded next count from len(objs), as amf0.Decoder.SeedReferences does
+// for AMF0.
+func (dec *Decoder) SeedReferences(objs []interface{}) {
+	dec.refObjects = append([]interface{}(nil), objs...)
+}
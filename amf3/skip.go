@@ -0,0 +1,203 @@
+package amf3
+
+import (
+	"io"
+)
+
+// skippedObject stands in the object reference table for values that were
+// skipped rather than decoded.
+type skippedObject struct {
+}
+
+// SkipValue consumes the next value without building it, for filtering
+// out values of no interest. Strings and traits are still entered in their
+// reference tables, as later values may refer to them, and skipped objects
+// take up their slots in the object table, though references to them fail
+// to decode. Externalizable objects are decoded, as only their class knows
+// where they end.
+func (dec *Decoder) SkipValue() error {
+	err := dec.skipValue()
+	if _, ok := err.(*DecodeError); !ok && err != nil && err != io.EOF {
+		err = &DecodeError{Offset: dec.offset(), Marker: -1, Err: err}
+	}
+	return err
+}
+
+func (dec *Decoder) skipValue() error {
+	u8 := make([]byte, 1)
+	_, err := io.ReadFull(dec.r, u8)
+	if err != nil {
+		return err
+	}
+	err = dec.countValue()
+	if err == nil {
+		err = dec.skipMarker(u8[0])
+	}
+	if err != nil {
+		return dec.valueError(err, u8[0])
+	}
+	return nil
+}
+
+// skipMarker skips the rest of a value whose marker has already been read.
+func (dec *Decoder) skipMarker(marker byte) error {
+	var err error
+	if isContainer(marker) {
+		err = dec.enter()
+		if err != nil {
+			return err
+		}
+		defer dec.leave()
+	}
+	switch marker {
+	case UndefinedMarker, NullMarker, FalseMarker, TrueMarker:
+		return nil
+	case IntegerMarker:
+		_, err = DecodeUInt29(dec.r)
+		return err
+	case DoubleMarker:
+		return dec.discard("double", 8)
+	case StringMarker:
+		_, err = dec.readString()
+		return err
+	case XmlDocMarker, XmlMarker, ByteArrayMarker:
+		return dec.skipObject(func(n uint32) error {
+			return dec.discard("length", uint64(n))
+		})
+	case DateMarker:
+		return dec.skipObject(func(uint32) error {
+			return dec.discard("date", 8)
+		})
+	case ArrayMarker:
+		return dec.skipObject(func(n uint32) error {
+			err := checkLength(dec.r, "dense array count", uint64(n))
+			if err != nil {
+				return err
+			}
+			err = dec.skipMembers()
+			if err != nil {
+				return err
+			}
+			return dec.skipValues(uint64(n))
+		})
+	case ObjectMarker:
+		ref, i, err := dec.readRefInt()
+		if err != nil {
+			return err
+		}
+		if ref {
+			_, err = dec.getRefObject(i)
+			return err
+		}
+		refIndex := len(dec.refObjects)
+		dec.refObjects = append(dec.refObjects, skippedObject{})
+		trait, err := dec.readTrait(i)
+		if err != nil {
+			return err
+		}
+		if trait.Externalizable {
+			_, err = dec.readExternal(trait, refIndex)
+			return err
+		}
+		err = dec.skipValues(uint64(len(trait.Attrs)))
+		if err != nil || !trait.IsDynamic {
+			return err
+		}
+		return dec.skipMembers()
+	case VectorIntMarker, VectorUintMarker, VectorDoubleMarker:
+		size := uint64(4)
+		if marker == VectorDoubleMarker {
+			size = 8
+		}
+		return dec.skipObject(func(n uint32) error {
+			return dec.discard("vector length", 1+uint64(n)*size)
+		})
+	case VectorObjectMarker:
+		return dec.skipObject(func(n uint32) error {
+			err := dec.discard("vector", 1)
+			if err != nil {
+				return err
+			}
+			err = checkLength(dec.r, "vector count", uint64(n))
+			if err != nil {
+				return err
+			}
+			_, err = dec.readString()
+			if err != nil {
+				return err
+			}
+			return dec.skipValues(uint64(n))
+		})
+	case DictionaryMarker:
+		return dec.skipObject(func(n uint32) error {
+			err := dec.discard("dictionary", 1)
+			if err != nil {
+				return err
+			}
+			err = checkLength(dec.r, "dictionary count", uint64(n)*2)
+			if err != nil {
+				return err
+			}
+			return dec.skipValues(uint64(n) * 2)
+		})
+	}
+	return &MarkerError{Marker: marker}
+}
+
+// skipObject reads the header of a value kept in the object reference
+// table. Unless it is a reference, it takes up a slot in the table and the
+// rest of the value is skipped by body, given the header after the
+// reference bit.
+func (dec *Decoder) skipObject(body func(i uint32) error) error {
+	ref, i, err := dec.readRefInt()
+	if err != nil {
+		return err
+	}
+	if ref {
+		_, err = dec.getRefObject(i)
+		return err
+	}
+	dec.refObjects = append(dec.refObjects, skippedObject{})
+	return body(i)
+}
+
+// skipValues skips n values.
+func (dec *Decoder) skipValues(n uint64) error {
+	for k := uint64(0); k < n; k++ {
+		err := dec.skipValue()
+		if err != nil {
+			return dec.atPath(err, index(int(k)))
+		}
+	}
+	return nil
+}
+
+// skipMembers skips named members up to the empty name ending them.
+func (dec *Decoder) skipMembers() error {
+	for {
+		name, err := dec.readString()
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			return nil
+		}
+		err = dec.skipValue()
+		if err != nil {
+			return dec.atPath(err, "."+string(name))
+		}
+	}
+}
+
+// discard consumes n bytes of the value named what.
+func (dec *Decoder) discard(what string, n uint64) error {
+	err := checkLength(dec.r, what, n)
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyN(io.Discard, dec.r, int64(n))
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
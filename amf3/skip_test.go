@@ -0,0 +1,79 @@
+package amf3
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type skipRecord struct {
+	Name  string                 `amf:"name"`
+	Tags  []string               `amf:"tags"`
+	When  time.Time              `amf:"when"`
+	Data  []byte                 `amf:"data"`
+	Score *VectorDoubleType      `amf:"score"`
+	Dict  *DictionaryType        `amf:"dict"`
+	Extra map[string]interface{} `amf:"extra"`
+}
+
+func TestSkipValue(t *testing.T) {
+	first := skipRecord{
+		Name:  "first",
+		Tags:  []string{"a", "shared"},
+		When:  time.UnixMilli(1000),
+		Data:  []byte{1, 2, 3},
+		Score: &VectorDoubleType{Items: []float64{1.5}},
+		Dict:  &DictionaryType{Entries: []DictionaryEntry{{Key: IntegerType(1), Value: StringType("one")}}},
+		Extra: map[string]interface{}{"n": 1},
+	}
+	// the second record refers to the strings and the trait of the first
+	second := skipRecord{Name: "shared", Tags: []string{"first"},
+		Score: &VectorDoubleType{}, Dict: &DictionaryType{}}
+
+	buf := new(bytes.Buffer)
+	enc := NewEncoder(buf)
+	for _, v := range []interface{}{first, second, ArrayCollection{1}} {
+		if err := enc.Encode(v); err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	data := buf.Bytes()
+
+	opts := DecoderOptions{UnwrapFlexCollections: true}
+	full := NewDecoderWithOptions(bytes.NewReader(data), opts)
+	var expect []interface{}
+	for i := 0; i < 3; i++ {
+		v, err := full.Decode()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		expect = append(expect, v)
+	}
+
+	r := bytes.NewReader(data)
+	dec := NewDecoderWithOptions(r, opts)
+	err := dec.SkipValue()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	got, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !reflect.DeepEqual(got, expect[1]) {
+		t.Errorf("expect %#v got %#v", expect[1], got)
+	}
+	err = dec.SkipValue()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if r.Len() != 0 {
+		t.Errorf("expect the input consumed, %d bytes left", r.Len())
+	}
+
+	err = NewDecoder(bytes.NewReader(data[:len(data)/2])).SkipValue()
+	if err == nil {
+		t.Errorf("expect error skipping a truncated value")
+	}
+}
@@ -12,7 +12,7 @@ type FalseType struct {
 type TrueType struct {
 }
 
-type IntegerType uint32
+type IntegerType int32
 type DoubleType float64
 type StringType string
 type NullStringType StringType
@@ -24,9 +24,10 @@ type ArrayType struct {
 }
 
 type Trait struct {
-	ClassName StringType
-	IsDynamic bool
-	Attrs     []StringType
+	ClassName      StringType
+	IsDynamic      bool
+	Externalizable bool // the object carries a custom body instead of members
+	Attrs          []StringType
 }
 
 type ObjectType struct {
@@ -37,3 +38,37 @@ type ObjectType struct {
 
 type XMLType string
 type ByteArrayType []byte
+
+// Vectors are typed arrays. A fixed vector cannot change length.
+type VectorIntType struct {
+	Fixed bool
+	Items []int32
+}
+
+type VectorUintType struct {
+	Fixed bool
+	Items []uint32
+}
+
+type VectorDoubleType struct {
+	Fixed bool
+	Items []float64
+}
+
+type VectorObjectType struct {
+	Fixed    bool
+	TypeName StringType // the class name of the items, or "*" for any
+	Items    []interface{}
+}
+
+// DictionaryType holds the entries of a Dictionary in order. Unlike object
+// members, keys may be any value, including objects.
+type DictionaryType struct {
+	WeakKeys bool
+	Entries  []DictionaryEntry
+}
+
+type DictionaryEntry struct {
+	Key   interface{}
+	Value interface{}
+}
@@ -0,0 +1,72 @@
+package amf3
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Valuer is implemented by values that stand for an AMF3 value without
+// being one, such as the values of the amf0 package, written after the
+// switch to AMF3 marker. Encoders and CanEncode use the value AMF3Value
+// returns in its place; a Valuer held by pointer is converted once per
+// encoder, so that references to it and cycles through it survive.
+type Valuer interface {
+	AMF3Value() (interface{}, error)
+}
+
+// TypedValue is a Go struct, or a map with string keys, written as an
+// object of class ClassName whatever alias its type has, as for the
+// TypedValue of the amf0 package.
+type TypedValue struct {
+	ClassName string
+	Value     interface{}
+}
+
+// encodeValuer encodes the AMF3 value that v stands for.
+func (enc *Encoder) encodeValuer(v Valuer) error {
+	if reflect.TypeOf(v).Kind() != reflect.Ptr {
+		conv, err := v.AMF3Value()
+		if err != nil {
+			return err
+		}
+		return enc.encodeValue(conv)
+	}
+	conv, ok := enc.converted[v]
+	if !ok {
+		var err error
+		conv, err = v.AMF3Value()
+		if err != nil {
+			return err
+		}
+		if enc.converted == nil {
+			enc.converted = make(map[interface{}]interface{})
+		}
+		enc.converted[v] = conv
+	}
+	return enc.encodeValue(conv)
+}
+
+func (enc *Encoder) encodeTyped(tv TypedValue) error {
+	rv := reflect.ValueOf(tv.Value)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().Kind() == reflect.Struct {
+		return enc.encodeStruct(rv.Elem(), refKey{typ: rv.Type(), ptr: rv.UnsafePointer()}, StringType(tv.ClassName))
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		return enc.encodeStruct(rv, nil, StringType(tv.ClassName))
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String && !rv.IsNil() {
+			members := make(map[StringType]interface{}, rv.Len())
+			iter := rv.MapRange()
+			for iter.Next() {
+				members[StringType(iter.Key().String())] = iter.Value().Interface()
+			}
+			return enc.encodeValue(&ObjectType{Trait: &Trait{ClassName: StringType(tv.ClassName), IsDynamic: true}, Dynamic: members})
+		}
+	}
+	return typedValueError(tv)
+}
+
+func typedValueError(tv TypedValue) error {
+	return errors.New("typed value of class " + tv.ClassName + " must be a struct or a map with string keys")
+}
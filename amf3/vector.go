@@ -0,0 +1,197 @@
+package amf3
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// readVector reads a vector after its marker.
+func (dec *Decoder) readVector(marker byte) (interface{}, error) {
+	ref, i, err := dec.readRefInt()
+	if err != nil {
+		return nil, err
+	}
+	if ref {
+		obj, err := dec.getRefObject(i)
+		if err != nil {
+			return nil, err
+		}
+		if !isVector(obj, marker) {
+			return nil, ErrReferenceType
+		}
+		return obj, nil
+	}
+	err = dec.checkCount("vector count", i)
+	if err != nil {
+		return nil, err
+	}
+	count := int(i)
+	fixed := make([]byte, 1)
+	_, err = io.ReadFull(dec.r, fixed)
+	if err != nil {
+		return nil, err
+	}
+	switch marker {
+	case VectorIntMarker, VectorUintMarker, VectorDoubleMarker:
+		size := uint64(4)
+		if marker == VectorDoubleMarker {
+			size = 8
+		}
+		data, err := readLength(dec.r, "vector length", uint64(count)*size)
+		if err != nil {
+			return nil, err
+		}
+		return dec.numericVector(marker, fixed[0] != 0, count, data), nil
+	}
+	err = checkLength(dec.r, "vector count", uint64(count))
+	if err != nil {
+		return nil, err
+	}
+	vector := &VectorObjectType{Fixed: fixed[0] != 0}
+	dec.refObjects = append(dec.refObjects, vector)
+	vector.TypeName, err = dec.readString()
+	if err != nil {
+		return nil, err
+	}
+	if count <= allocChunk || remaining(dec.r) >= 0 {
+		vector.Items = make([]interface{}, 0, count)
+	}
+	for k := 0; k < count; k++ {
+		value, err := dec.decodeValue()
+		if err != nil {
+			return nil, dec.atPath(err, index(k))
+		}
+		vector.Items = append(vector.Items, value)
+	}
+	return vector, nil
+}
+
+func (dec *Decoder) numericVector(marker byte, fixed bool, count int, data []byte) interface{} {
+	var v interface{}
+	switch marker {
+	case VectorIntMarker:
+		vector := &VectorIntType{Fixed: fixed, Items: make([]int32, count)}
+		for k := range vector.Items {
+			vector.Items[k] = int32(binary.BigEndian.Uint32(data[k*4:]))
+		}
+		v = vector
+	case VectorUintMarker:
+		vector := &VectorUintType{Fixed: fixed, Items: make([]uint32, count)}
+		for k := range vector.Items {
+			vector.Items[k] = binary.BigEndian.Uint32(data[k*4:])
+		}
+		v = vector
+	case VectorDoubleMarker:
+		vector := &VectorDoubleType{Fixed: fixed, Items: make([]float64, count)}
+		for k := range vector.Items {
+			vector.Items[k] = math.Float64frombits(binary.BigEndian.Uint64(data[k*8:]))
+		}
+		v = vector
+	}
+	dec.refObjects = append(dec.refObjects, v)
+	return v
+}
+
+func isVector(v interface{}, marker byte) bool {
+	switch v.(type) {
+	case *VectorIntType:
+		return marker == VectorIntMarker
+	case *VectorUintType:
+		return marker == VectorUintMarker
+	case *VectorDoubleType:
+		return marker == VectorDoubleMarker
+	case *VectorObjectType:
+		return marker == VectorObjectMarker
+	}
+	return false
+}
+
+// writeVectorHeader writes the marker, or a reference, and the length and
+// fixed flag of a vector. It reports whether a reference was written.
+func (enc *Encoder) writeVectorHeader(marker byte, v interface{}, count int, fixed bool) (bool, error) {
+	err := enc.bw.WriteByte(marker)
+	if err != nil {
+		return false, err
+	}
+	ok, err := enc.writeObjectRef(v)
+	if ok || err != nil {
+		return ok, err
+	}
+	enc.refObjects = append(enc.refObjects, v)
+	if count > 0x0FFFFFFF {
+		return false, errors.New("vector too long")
+	}
+	err = EncodeUInt29(enc.bw, uint32(count<<1|0x01))
+	if err != nil {
+		return false, err
+	}
+	flag := byte(0)
+	if fixed {
+		flag = 1
+	}
+	return false, enc.bw.WriteByte(flag)
+}
+
+func (enc *Encoder) encodeVector(v interface{}) error {
+	u64 := make([]byte, 8)
+	switch value := v.(type) {
+	case *VectorIntType:
+		ok, err := enc.writeVectorHeader(VectorIntMarker, value, len(value.Items), value.Fixed)
+		if ok || err != nil {
+			return err
+		}
+		for _, item := range value.Items {
+			binary.BigEndian.PutUint32(u64, uint32(item))
+			_, err = enc.bw.Write(u64[:4])
+			if err != nil {
+				return err
+			}
+		}
+	case *VectorUintType:
+		ok, err := enc.writeVectorHeader(VectorUintMarker, value, len(value.Items), value.Fixed)
+		if ok || err != nil {
+			return err
+		}
+		for _, item := range value.Items {
+			binary.BigEndian.PutUint32(u64, item)
+			_, err = enc.bw.Write(u64[:4])
+			if err != nil {
+				return err
+			}
+		}
+	case *VectorDoubleType:
+		ok, err := enc.writeVectorHeader(VectorDoubleMarker, value, len(value.Items), value.Fixed)
+		if ok || err != nil {
+			return err
+		}
+		for _, item := range value.Items {
+			binary.BigEndian.PutUint64(u64, math.Float64bits(item))
+			_, err = enc.bw.Write(u64)
+			if err != nil {
+				return err
+			}
+		}
+	case *VectorObjectType:
+		ok, err := enc.writeVectorHeader(VectorObjectMarker, value, len(value.Items), value.Fixed)
+		if ok || err != nil {
+			return err
+		}
+		typeName := value.TypeName
+		if typeName == "" {
+			typeName = "*"
+		}
+		err = enc.writeString(typeName)
+		if err != nil {
+			return err
+		}
+		for _, item := range value.Items {
+			err = enc.encodeValue(item)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package amf3
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestVectorRoundTrip(t *testing.T) {
+	ints := &VectorIntType{Fixed: true, Items: []int32{-1, 2}}
+	tests := []struct {
+		v      interface{}
+		expect []byte
+	}{
+		{ints, []byte{0x0d, 0x05, 0x01, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x02}},
+		{&VectorUintType{Items: []uint32{0xffffffff}}, []byte{0x0e, 0x03, 0x00, 0xff, 0xff, 0xff, 0xff}},
+		{&VectorDoubleType{Items: []float64{1.5}}, []byte{0x0f, 0x03, 0x00, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{&VectorObjectType{TypeName: "*", Items: []interface{}{StringType("a"), ints}},
+			[]byte{0x10, 0x05, 0x00, 0x03, '*', 0x06, 0x03, 'a',
+				0x0d, 0x05, 0x01, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x02}},
+		{&ArrayType{Associative: map[StringType]interface{}{}, Dense: []interface{}{ints, ints}},
+			[]byte{0x09, 0x05, 0x01, 0x0d, 0x05, 0x01, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x02, 0x0d, 0x02}},
+	}
+	for _, test := range tests {
+		got := encode(t, test.v)
+		if !bytes.Equal(got, test.expect) {
+			t.Errorf("expect %x got %x", test.expect, got)
+			continue
+		}
+		decoded, err := NewDecoder(bytes.NewReader(got)).Decode()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if !reflect.DeepEqual(decoded, test.v) {
+			t.Errorf("expect %+v got %+v", test.v, decoded)
+		}
+	}
+}
+
+func TestVectorReferenceType(t *testing.T) {
+	// a vector of uint referring back to a vector of int
+	data := []byte{0x09, 0x05, 0x01, 0x0d, 0x01, 0x00, 0x0e, 0x02}
+	_, err := NewDecoder(bytes.NewReader(data)).Decode()
+	if !errors.Is(err, ErrReferenceType) {
+		t.Fatalf("expect wrong ref type error got %v", err)
+	}
+}
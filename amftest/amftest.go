@@ -0,0 +1,112 @@
+// Package amftest provides readers and writers that misbehave on purpose,
+// for testing how code built on the amf packages copes with short reads,
+// slow or failing connections and writes that fail halfway.
+package amftest
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrFlaky is the error of a FlakyReader without an Err of its own.
+var ErrFlaky = errors.New("amftest: flaky read")
+
+// ErrWriterFull is the error of a FailingWriter without an Err of its own.
+var ErrWriterFull = errors.New("amftest: writer full")
+
+// ChunkedReader reads from R in chunks of the sizes in Sizes, one per read
+// and repeating the last once they run out, so that reads come back short
+// as they do from a network connection. Without Sizes every read returns a
+// single byte. Delay, if set, is waited before every read, for a peer
+// trickling its bytes.
+type ChunkedReader struct {
+	R     io.Reader
+	Sizes []int
+	Delay time.Duration
+
+	reads int
+}
+
+func (cr *ChunkedReader) Read(p []byte) (int, error) {
+	if cr.Delay > 0 {
+		time.Sleep(cr.Delay)
+	}
+	size := 1
+	if len(cr.Sizes) > 0 {
+		size = cr.Sizes[min(cr.reads, len(cr.Sizes)-1)]
+	}
+	cr.reads++
+	if size > 0 && len(p) > size {
+		p = p[:size]
+	}
+	return cr.R.Read(p)
+}
+
+// FlakyReader reads from R, failing every Every-th read with Err, or
+// ErrFlaky, without consuming anything, so that a caller retrying after a
+// transient error still sees all of the input. With Every 0 no read fails.
+type FlakyReader struct {
+	R     io.Reader
+	Every int
+	Err   error
+
+	reads int
+}
+
+func (fr *FlakyReader) Read(p []byte) (int, error) {
+	fr.reads++
+	if fr.Every > 0 && fr.reads%fr.Every == 0 {
+		if fr.Err != nil {
+			return 0, fr.Err
+		}
+		return 0, ErrFlaky
+	}
+	return fr.R.Read(p)
+}
+
+// FailingWriter accepts the first N bytes written to it, passing them on to
+// W unless it is nil, then fails with Err, or ErrWriterFull. The write
+// crossing the limit is short, as a write to a connection closed by the
+// peer can be.
+type FailingWriter struct {
+	W   io.Writer
+	N   int64
+	Err error
+
+	written int64
+}
+
+func (fw *FailingWriter) Write(p []byte) (int, error) {
+	err := fw.Err
+	if err == nil {
+		err = ErrWriterFull
+	}
+	left := fw.N - fw.written
+	if left <= 0 {
+		return 0, err
+	}
+	short := int64(len(p)) > left
+	if short {
+		p = p[:left]
+	}
+	n := len(p)
+	if fw.W != nil {
+		var werr error
+		n, werr = fw.W.Write(p)
+		if werr != nil {
+			fw.written += int64(n)
+			return n, werr
+		}
+	}
+	fw.written += int64(n)
+	if short {
+		return n, err
+	}
+	return n, nil
+}
+
+// Written returns the number of bytes fw accepted.
+func (fw *FailingWriter) Written() int64 {
+	return fw.written
+}
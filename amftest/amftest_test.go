@@ -0,0 +1,71 @@
+package amftest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestChunkedReader(t *testing.T) {
+	r := &ChunkedReader{R: bytes.NewReader([]byte("abcdefgh")), Sizes: []int{3, 2}}
+	var sizes []int
+	for {
+		p := make([]byte, 8)
+		n, err := r.Read(p)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		sizes = append(sizes, n)
+	}
+	if len(sizes) != 4 || sizes[0] != 3 || sizes[1] != 2 || sizes[2] != 2 || sizes[3] != 1 {
+		t.Errorf("expect reads of 3, 2, 2 and 1 bytes got %v", sizes)
+	}
+}
+
+func TestFlakyReader(t *testing.T) {
+	r := &FlakyReader{R: &ChunkedReader{R: bytes.NewReader([]byte("abcd"))}, Every: 2}
+	var got []byte
+	var fails int
+	for {
+		p := make([]byte, 4)
+		n, err := r.Read(p)
+		got = append(got, p[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, ErrFlaky) {
+			fails++
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	if string(got) != "abcd" || fails != 4 {
+		t.Errorf("expect abcd after 4 failures got %q after %d", got, fails)
+	}
+}
+
+func TestFailingWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &FailingWriter{W: &buf, N: 5}
+	n, err := w.Write([]byte("abc"))
+	if n != 3 || err != nil {
+		t.Fatalf("expect 3 bytes written got %d %v", n, err)
+	}
+	n, err = w.Write([]byte("defg"))
+	if n != 2 || !errors.Is(err, ErrWriterFull) {
+		t.Errorf("expect a short write of 2 bytes got %d %v", n, err)
+	}
+	n, err = w.Write([]byte("h"))
+	if n != 0 || !errors.Is(err, ErrWriterFull) {
+		t.Errorf("expect the write to fail got %d %v", n, err)
+	}
+	if buf.String() != "abcde" || w.Written() != 5 {
+		t.Errorf("expect abcde got %q", buf.String())
+	}
+}
@@ -0,0 +1,111 @@
+package amf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// DecodeBase64 decodes the single AMF0 or AMF3 value held, base64 encoded,
+// in s.
+func DecodeBase64(s string, version int) (interface{}, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSingle(data, version)
+}
+
+// EncodeBase64 encodes v in AMF0 or AMF3 and returns it base64 encoded.
+func EncodeBase64(v interface{}, version int) (string, error) {
+	data, err := encodeSingle(v, version)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// Base64Value is an AMF value carried in JSON as a base64 string. When
+// unmarshaling, the version is detected with Sniff, failing when neither
+// dialect makes sense of the data.
+type Base64Value struct {
+	Version int
+	Value   interface{}
+}
+
+func (b Base64Value) MarshalJSON() ([]byte, error) {
+	s, err := EncodeBase64(b.Value, b.Version)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
+}
+
+func (b *Base64Value) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	version, confidence := Sniff(raw)
+	if confidence == 0 {
+		return errors.New("base64 value holds neither AMF0 nor AMF3")
+	}
+	v, err := decodeSingle(raw, version)
+	if err != nil {
+		return err
+	}
+	b.Version, b.Value = version, v
+	return nil
+}
+
+// decodeSingle decodes data holding exactly one value.
+func decodeSingle(data []byte, version int) (interface{}, error) {
+	br := bytes.NewReader(data)
+	var v interface{}
+	var err error
+	switch version {
+	case AMF0:
+		v, err = amf0.NewDecoder(br).Decode()
+	case AMF3:
+		v, err = amf3.NewDecoder(br).Decode()
+	default:
+		return nil, errors.New("unknown object encoding")
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	if br.Len() > 0 {
+		return nil, errors.New("data holds more than one value")
+	}
+	return v, nil
+}
+
+func encodeSingle(v interface{}, version int) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch version {
+	case AMF0:
+		err = amf0.NewEncoder(&buf).Encode(v)
+	case AMF3:
+		err = amf3.NewEncoder(&buf).Encode(v)
+	default:
+		return nil, errors.New("unknown object encoding")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
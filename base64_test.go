@@ -0,0 +1,73 @@
+package amf
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+func TestBase64(t *testing.T) {
+	s, err := EncodeBase64(amf0.StringType("hi"), AMF0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "AgACaGk=" {
+		t.Errorf("expected AgACaGk=, got %s", s)
+	}
+	v, err := DecodeBase64(s, AMF0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != amf0.StringType("hi") {
+		t.Errorf("expected hi, got %v", v)
+	}
+	_, err = DecodeBase64("AgACaGkF", AMF0)
+	if err == nil {
+		t.Errorf("expected error for trailing value")
+	}
+}
+
+func TestBase64Value(t *testing.T) {
+	var event struct {
+		Name    string      `json:"name"`
+		Payload Base64Value `json:"payload"`
+	}
+	err := json.Unmarshal([]byte(`{"name": "login", "payload": "BgVoaQ=="}`), &event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Payload.Version != AMF3 || event.Payload.Value != amf3.StringType("hi") {
+		t.Errorf("expected AMF3 string hi, got %+v", event.Payload)
+	}
+	data, err := json.Marshal(event.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"BgVoaQ=="` {
+		t.Errorf("expected payload to encode back, got %s", data)
+	}
+	var b Base64Value
+	err = json.Unmarshal([]byte(`"/w=="`), &b)
+	if err == nil {
+		t.Errorf("expected error for a payload in neither dialect")
+	}
+}
+
+func TestBase64ValueDictionary(t *testing.T) {
+	dict := &amf3.DictionaryType{Entries: []amf3.DictionaryEntry{{Key: amf3.IntegerType(1), Value: amf3.StringType("a")}}}
+	data, err := json.Marshal(Base64Value{Version: AMF3, Value: dict})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b Base64Value
+	err = json.Unmarshal(data, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := b.Value.(*amf3.DictionaryType)
+	if b.Version != AMF3 || !ok || len(got.Entries) != 1 || got.Entries[0].Value != amf3.StringType("a") {
+		t.Errorf("expected the dictionary back, got %+v", b)
+	}
+}
@@ -0,0 +1,77 @@
+package amf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// DecodeError reports a payload that failed to decode.
+type DecodeError struct {
+	Name   string // file name or other label of the payload
+	Offset int64  // bytes of the payload consumed when decoding failed
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%s: offset %d: %s", e.Name, e.Offset, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeFiles decodes the packet stored in each of the named files. It
+// carries on past failures and returns them all joined with errors.Join, one
+// *DecodeError per file, so that a batch run reports every bad payload at
+// once. The packets of files that failed are nil.
+func DecodeFiles(names []string, opts DecoderOptions) ([]*Packet, error) {
+	packets := make([]*Packet, len(names))
+	var errs []error
+	for i, name := range names {
+		p, err := decodeFile(name, opts)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		packets[i] = p
+	}
+	return packets, errors.Join(errs...)
+}
+
+func decodeFile(name string, opts DecoderOptions) (*Packet, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, &DecodeError{Name: name, Err: err}
+	}
+	defer f.Close()
+	return decodeNamed(name, f, opts)
+}
+
+// decodeNamed decodes the packet in r, reporting failures as *DecodeError.
+func decodeNamed(name string, r io.Reader, opts DecoderOptions) (*Packet, error) {
+	cr := &countingReader{r: r}
+	br := bufio.NewReader(cr)
+	p, err := NewDecoderWithOptions(br, opts).Decode()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, &DecodeError{Name: name, Offset: cr.n - int64(br.Buffered()), Err: err}
+	}
+	return p, nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
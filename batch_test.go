@@ -0,0 +1,48 @@
+package amf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecodeFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"good.amf":      {0x00, 0x03, 0x00, 0x00, 0x00, 0x00},
+		"truncated.amf": {0x00, 0x03, 0x00},
+		"bad.amf":       {0x00, 0x03, 0x00, 0x01, 0x00, 0x01, 'h', 0x00, 0x00, 0x00, 0x00, 0x00, 0x0e},
+	}
+	var names []string
+	for _, name := range []string{"good.amf", "truncated.amf", "bad.amf", "missing.amf"} {
+		path := filepath.Join(dir, name)
+		if data, ok := files[name]; ok {
+			err := os.WriteFile(path, data, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		names = append(names, path)
+	}
+
+	packets, err := DecodeFiles(names, DecoderOptions{})
+	if packets[0] == nil || packets[1] != nil || packets[2] != nil || packets[3] != nil {
+		t.Errorf("expected only the first packet to decode, got %v", packets)
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("expected joined errors, got %v", err)
+	}
+	errs := joined.Unwrap()
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %v", errs)
+	}
+	var decodeErr *DecodeError
+	if !errors.As(errs[0], &decodeErr) || decodeErr.Name != names[1] || decodeErr.Offset != 3 {
+		t.Errorf("expected truncated.amf failing at offset 3, got %v", errs[0])
+	}
+	if !errors.As(errs[2], &decodeErr) || !errors.Is(decodeErr, os.ErrNotExist) {
+		t.Errorf("expected missing.amf not to exist, got %v", errs[2])
+	}
+}
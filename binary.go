@@ -0,0 +1,37 @@
+package amf
+
+import (
+	"bytes"
+)
+
+// EncodePacket returns the encoding of p.
+func EncodePacket(p *Packet) ([]byte, error) {
+	var buf bytes.Buffer
+	err := NewEncoder(&buf).Encode(p)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodePacket decodes the packet held in data. Bytes after the last
+// message are kept in Packet.Trailing.
+func DecodePacket(data []byte) (*Packet, error) {
+	return NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{CaptureTrailing: true}).Decode()
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (p *Packet) MarshalBinary() ([]byte, error) {
+	return EncodePacket(p)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing p with
+// the packet decoded from data.
+func (p *Packet) UnmarshalBinary(data []byte) error {
+	got, err := DecodePacket(data)
+	if err != nil {
+		return err
+	}
+	*p = *got
+	return nil
+}
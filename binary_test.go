@@ -0,0 +1,35 @@
+package amf
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/marcuswu/amf/amf0"
+)
+
+func TestPacketBinary(t *testing.T) {
+	p := NewPacket(0, 0)
+	p.AddMessage(NewMessage("echo", "/1", amf0.StringType("hi")))
+	data, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Packet
+	err = got.UnmarshalBinary(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Messages()) != 1 || got.Messages()[0].Data() != amf0.StringType("hi") {
+		t.Errorf("unexpected packet %+v", got)
+	}
+	again, err := EncodePacket(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, data) {
+		t.Errorf("expected %x, got %x", data, again)
+	}
+	if _, err := DecodePacket(data[:len(data)-1]); err == nil {
+		t.Errorf("expected error for truncated packet")
+	}
+}
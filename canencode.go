@@ -0,0 +1,22 @@
+package amf
+
+import (
+	"errors"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// CanEncode reports whether v can be encoded in the given object encoding,
+// AMF0 or AMF3, without producing any output. Every unsupported type or
+// field is reported with its path; see amf0.EncodeError and
+// amf3.EncodeError.
+func CanEncode(v interface{}, version int) error {
+	switch version {
+	case AMF0:
+		return amf0.CanEncode(v)
+	case AMF3:
+		return amf3.CanEncode(v)
+	}
+	return errors.New("unknown object encoding")
+}
@@ -0,0 +1,22 @@
+package amf
+
+import (
+	"testing"
+
+	"github.com/marcuswu/amf/amf3"
+)
+
+func TestCanEncode(t *testing.T) {
+	obj := &amf3.ObjectType{Dynamic: map[amf3.StringType]interface{}{"a": amf3.IntegerType(1)}}
+	if err := CanEncode(obj, AMF3); err != nil {
+		t.Errorf("expect no error got %v", err)
+	}
+	obj.Dynamic["b"] = make(chan int)
+	err := CanEncode(obj, AMF3)
+	if err == nil || err.Error() != ".b: unsupported type chan int" {
+		t.Errorf("expect error at .b got %v", err)
+	}
+	if err := CanEncode(obj, AMF0); err == nil {
+		t.Errorf("expect AMF0 to report the AMF3 object member")
+	}
+}
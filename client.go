@@ -0,0 +1,128 @@
+package amf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// RemotingClient calls the services of a Flash Remoting gateway.
+type RemotingClient struct {
+	URL string
+	// HTTPClient sends the requests; nil means http.DefaultClient.
+	HTTPClient *http.Client
+	// Headers are sent with every call, e.g. credentials.
+	Headers []*Header
+	// Version is the packet version of the requests, PacketVersion0 by
+	// default.
+	Version uint16
+
+	seq atomic.Uint64
+}
+
+func NewRemotingClient(url string) *RemotingClient {
+	return &RemotingClient{URL: url}
+}
+
+// Call calls service, a target URI such as "UserService.find", with args
+// and returns the decoded result. A status reply from the gateway is
+// returned as a *Fault.
+func (c *RemotingClient) Call(service string, args ...interface{}) (interface{}, error) {
+	return c.CallContext(context.Background(), service, args...)
+}
+
+// CallContext is Call with a context for the HTTP request.
+func (c *RemotingClient) CallContext(ctx context.Context, service string, args ...interface{}) (interface{}, error) {
+	responseURI := "/" + strconv.FormatUint(c.seq.Add(1), 10)
+	req := NewPacket(0, 0)
+	req.SetVersion(c.Version)
+	for _, h := range c.Headers {
+		req.AddHeader(h)
+	}
+	if args == nil {
+		args = []interface{}{}
+	}
+	req.AddMessage(NewMessage(service, responseURI, args))
+	data, err := EncodePacket(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", ContentTypeAMF)
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, httpResp.Body)
+		return nil, fmt.Errorf("gateway replied %s", httpResp.Status)
+	}
+	resp, err := NewDecoder(httpResp.Body).Decode()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range resp.Messages() {
+		switch m.TargetURI() {
+		case responseURI + "/onResult":
+			return m.Data(), nil
+		case responseURI + "/onStatus":
+			return nil, statusFault(m.Data())
+		}
+	}
+	return nil, errors.New("no reply for " + responseURI + " in response")
+}
+
+// statusFault reads the fault carried by an onStatus message body, which
+// gateways write with either code/description/details or
+// faultCode/faultString/faultDetail properties.
+func statusFault(v interface{}) *Fault {
+	f := &Fault{}
+	forEachChild(v, func(elem string, child interface{}) {
+		s, ok := stringValue(child)
+		if !ok {
+			return
+		}
+		switch strings.TrimPrefix(elem, ".") {
+		case "code", "faultCode":
+			f.Code = s
+		case "description", "faultString":
+			f.Description = s
+		case "details", "faultDetail":
+			f.Details = s
+		}
+	})
+	if f.Code == "" {
+		f.Code = "Server.Processing"
+	}
+	return f
+}
+
+// stringValue returns the text of the AMF0 and AMF3 string types.
+func stringValue(v interface{}) (string, bool) {
+	switch s := v.(type) {
+	case amf0.StringType:
+		return string(s), true
+	case amf0.LongStringType:
+		return string(s), true
+	case amf3.StringType:
+		return string(s), true
+	}
+	return "", false
+}
@@ -0,0 +1,36 @@
+package amf
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/marcuswu/amf/amf0"
+)
+
+func TestRemotingClient(t *testing.T) {
+	g := NewGatewayHandler()
+	g.Handle("Math.add", func(call *Call) (interface{}, error) {
+		a, b := call.Args[0].(amf0.NumberType), call.Args[1].(amf0.NumberType)
+		return a + b, nil
+	})
+	g.Handle("Math.fail", func(call *Call) (interface{}, error) {
+		return nil, &Fault{Code: "Client.Bad", Description: "bad input"}
+	})
+	srv := httptest.NewServer(g)
+	defer srv.Close()
+
+	c := NewRemotingClient(srv.URL)
+	got, err := c.Call("Math.add", 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != amf0.NumberType(5) {
+		t.Errorf("expected 5, got %v", got)
+	}
+	_, err = c.Call("Math.fail")
+	var f *Fault
+	if !errors.As(err, &f) || f.Code != "Client.Bad" || f.Description != "bad input" {
+		t.Errorf("expected Client.Bad fault, got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+// Command amf2go generates Go struct definitions from captured AMF payloads.
+//
+// Usage:
+//
+//	amf2go [-format auto|packet|amf0|amf3] [-pkg name] [-o file] payload...
+//
+// Every object found in the payloads becomes a struct with amf tags.
+// Typed objects get a class registration in an init function so that they
+// decode straight into the generated types. Every payload is read even when
+// some fail to decode, and all failures are reported together.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/marcuswu/amf"
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+func main() {
+	formatFlag := flag.String("format", "auto", "payload format: auto, packet, amf0 or amf3")
+	pkg := flag.String("pkg", "main", "package name of the generated file")
+	out := flag.String("o", "", "output file (default stdout)")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: amf2go [-format auto|packet|amf0|amf3] [-pkg name] [-o file] payload...")
+		os.Exit(2)
+	}
+
+	schema := amf.NewSchema()
+	var errs []error
+	for _, path := range flag.Args() {
+		err := observeFile(schema, path, *formatFlag)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+	if len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "amf2go: %d of %d payloads failed:\n%s\n", len(errs), flag.NArg(), errors.Join(errs...))
+		os.Exit(1)
+	}
+
+	src, err := generate(schema, *pkg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "amf2go: %s\n", err)
+		os.Exit(1)
+	}
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	err = os.WriteFile(*out, src, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "amf2go: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func observeFile(schema *amf.Schema, path, format string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if format == "auto" {
+		format = "packet"
+		_, err = amf.NewDecoder(bytes.NewReader(data)).Decode()
+		if err != nil {
+			format = "amf0"
+			if version, _ := amf.Sniff(data); version == amf.AMF3 {
+				format = "amf3"
+			}
+		}
+	}
+	switch format {
+	case "packet":
+		_, err = amf.NewDecoderWithOptions(bytes.NewReader(data), amf.DecoderOptions{Schema: schema}).Decode()
+		return err
+	case "amf0":
+		dec := amf0.NewBytesDecoder(data)
+		for {
+			v, err := dec.Decode()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			schema.Observe(name, v)
+		}
+	case "amf3":
+		dec := amf3.NewDecoder(bytes.NewReader(data))
+		for {
+			v, err := dec.Decode()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			schema.Observe(name, v)
+		}
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+func generate(schema *amf.Schema, pkg string) ([]byte, error) {
+	var body bytes.Buffer
+	err := schema.WriteGo(&body)
+	if err != nil {
+		return nil, err
+	}
+	var typed bool
+	for _, c := range schema.Classes() {
+		typed = typed || c.Typed
+	}
+	if typed {
+		body.WriteString("\n")
+		err = schema.WriteRegistrations(&body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by amf2go. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	var imports []string
+	if typed {
+		imports = append(imports, `"reflect"`)
+	}
+	if strings.Contains(body.String(), "time.Time") {
+		imports = append(imports, `"time"`)
+	}
+	if typed {
+		imports = append(imports, "", `"github.com/marcuswu/amf/amf0"`)
+	}
+	if len(imports) > 0 {
+		fmt.Fprintf(&src, "import (\n%s\n)\n\n", strings.Join(imports, "\n"))
+	}
+	src.Write(body.Bytes())
+	return format.Source(src.Bytes())
+}
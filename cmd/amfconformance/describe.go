@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/marcuswu/amf"
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// describer writes decoded values in the notation of the expected values of
+// vectors: scalars as kind(value), such as int(-1) or double(3.5),
+// strings quoted, and containers with their members in brackets. A
+// container met again is written as ref(n), n counting containers in the
+// order they were first met, so that vectors can check references.
+type describer struct {
+	b    strings.Builder
+	seen map[interface{}]int
+}
+
+func describe(v interface{}) string {
+	d := &describer{seen: make(map[interface{}]int)}
+	d.value(v)
+	return d.b.String()
+}
+
+func (d *describer) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&d.b, format, args...)
+}
+
+func number(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// text quotes s, writing a run of one repeated byte longer than 16 bytes as
+// "x"*n, as the bytes of vectors are written.
+func text(s string) string {
+	if len(s) > 16 && strings.Count(s, s[:1]) == len(s) {
+		return strconv.Quote(s[:1]) + "*" + strconv.Itoa(len(s))
+	}
+	return strconv.Quote(s)
+}
+
+func (d *describer) value(v interface{}) {
+	if v != nil && reflect.TypeOf(v).Kind() == reflect.Ptr {
+		if n, ok := d.seen[v]; ok {
+			d.printf("ref(%d)", n)
+			return
+		}
+		d.seen[v] = len(d.seen)
+	}
+	switch value := v.(type) {
+	case *amf.Packet:
+		d.printf("packet(v%d", value.Version())
+		for _, h := range value.Headers() {
+			d.printf(" header(%s %v ", text(h.Name()), h.MustUnderstand())
+			d.value(h.Data())
+			d.printf(")")
+		}
+		for _, m := range value.Messages() {
+			d.printf(" message(%s %s ", text(m.TargetURI()), text(m.ResponseURI()))
+			d.value(m.Data())
+			d.printf(")")
+		}
+		d.printf(")")
+	case amf0.NumberType:
+		d.printf("number(%s)", number(float64(value)))
+	case amf0.BooleanType:
+		d.printf("%v", bool(value))
+	case amf0.StringType:
+		d.printf("%s", text(string(value)))
+	case amf0.LongStringType:
+		d.printf("long(%s)", text(string(value)))
+	case amf0.XmlDocumentType:
+		d.printf("xmldoc(%s)", text(string(value)))
+	case amf0.NullType, amf3.NullType:
+		d.printf("null")
+	case amf0.UndefinedType, amf3.UndefinedType:
+		d.printf("undefined")
+	case amf0.UnsupportedType:
+		d.printf("unsupported")
+	case amf0.DateType:
+		d.printf("date(%s tz=%d)", number(value.Date), value.TimeZone)
+	case *amf0.ObjectType:
+		d.printf("object")
+		d.members0(amf0.EcmaArrayType(*value))
+	case *amf0.EcmaArrayType:
+		d.printf("ecma")
+		d.members0(*value)
+	case *amf0.StrictArrayType:
+		d.printf("strict")
+		d.items(*value)
+	case *amf0.TypedObjectType:
+		d.printf("typed(%s)", text(string(value.ClassName)))
+		d.members0(amf0.EcmaArrayType(value.Object))
+	case amf3.FalseType:
+		d.printf("false")
+	case amf3.TrueType:
+		d.printf("true")
+	case amf3.IntegerType:
+		d.printf("int(%d)", value)
+	case amf3.DoubleType:
+		d.printf("double(%s)", number(float64(value)))
+	case amf3.StringType:
+		d.printf("%s", text(string(value)))
+	case *amf3.XMLDocumentType:
+		d.printf("xmldoc(%s)", text(string(*value)))
+	case *amf3.XMLType:
+		d.printf("xml(%s)", text(string(*value)))
+	case *amf3.DateType:
+		d.printf("date(%s)", number(float64(*value)))
+	case *amf3.ByteArrayType:
+		d.printf("bytes(%x)", []byte(*value))
+	case *amf3.ArrayType:
+		d.printf("array")
+		d.b.WriteByte('[')
+		for i, item := range value.Dense {
+			if i > 0 {
+				d.b.WriteByte(' ')
+			}
+			d.value(item)
+		}
+		d.b.WriteByte(']')
+		d.members3(value.Associative)
+	case *amf3.ObjectType:
+		name := ""
+		if value.Trait != nil {
+			name = string(value.Trait.ClassName)
+		}
+		d.printf("object(%s)[", text(name))
+		for i, item := range value.Static {
+			if i > 0 {
+				d.b.WriteByte(' ')
+			}
+			d.printf("%s=", value.Trait.Attrs[i])
+			d.value(item)
+		}
+		d.b.WriteByte(']')
+		d.members3(value.Dynamic)
+	case *amf3.VectorIntType:
+		d.printf("ints%v", value.Items)
+	case *amf3.VectorUintType:
+		d.printf("uints%v", value.Items)
+	case *amf3.VectorDoubleType:
+		d.printf("doubles%v", value.Items)
+	case *amf3.VectorObjectType:
+		d.printf("objects(%s)", text(string(value.TypeName)))
+		d.items(value.Items)
+	case *amf3.DictionaryType:
+		d.printf("dictionary(weak=%v){", value.WeakKeys)
+		for i, entry := range value.Entries {
+			if i > 0 {
+				d.b.WriteByte(' ')
+			}
+			d.value(entry.Key)
+			d.b.WriteByte(':')
+			d.value(entry.Value)
+		}
+		d.b.WriteByte('}')
+	default:
+		d.printf("%T(%v)", v, v)
+	}
+}
+
+func (d *describer) items(items []interface{}) {
+	d.b.WriteByte('[')
+	for i, item := range items {
+		if i > 0 {
+			d.b.WriteByte(' ')
+		}
+		d.value(item)
+	}
+	d.b.WriteByte(']')
+}
+
+func (d *describer) members0(obj amf0.EcmaArrayType) {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, string(k))
+	}
+	sort.Strings(keys)
+	d.b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			d.b.WriteByte(' ')
+		}
+		d.printf("%s:", k)
+		d.value(obj[amf0.StringType(k)])
+	}
+	d.b.WriteByte('}')
+}
+
+func (d *describer) members3(obj map[amf3.StringType]interface{}) {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, string(k))
+	}
+	sort.Strings(keys)
+	d.b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			d.b.WriteByte(' ')
+		}
+		d.printf("%s:", k)
+		d.value(obj[amf3.StringType(k)])
+	}
+	d.b.WriteByte('}')
+}
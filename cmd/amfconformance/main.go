@@ -0,0 +1,235 @@
+// Command amfconformance checks this module against a set of test vectors
+// derived from the AMF0 and AMF3 specifications, covering every marker,
+// edge lengths and reference patterns, and prints a pass/fail matrix. A
+// roundtrip vector passes decoding when the bytes decode to its expected
+// value, and encoding when that value encodes back to the same bytes.
+//
+// Usage:
+//
+//	amfconformance [-run regexp] [-v]
+//
+// It exits with status 1 when any vector fails, so that pipelines vendoring
+// the module can check that it behaves as expected.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/marcuswu/amf"
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+//go:embed vectors.txt
+var vectorsText string
+
+type vector struct {
+	name   string
+	format string
+	expect string
+	data   []byte
+	value  string // the decoded value of roundtrip vectors, see describe
+}
+
+// result holds the outcome of the decode and encode checks, each "PASS",
+// "FAIL" or "-" when not applicable, and what went wrong.
+type result struct {
+	decode, encode string
+	detail         string
+}
+
+func main() {
+	run := flag.String("run", "", "only check vectors whose name matches this regexp")
+	verbose := flag.Bool("v", false, "print why vectors failed")
+	flag.Parse()
+	var filter *regexp.Regexp
+	if *run != "" {
+		var err error
+		filter, err = regexp.Compile(*run)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "amfconformance: %s\n", err)
+			os.Exit(2)
+		}
+	}
+	vectors, err := parseVectors(vectorsText)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "amfconformance: %s\n", err)
+		os.Exit(2)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "VECTOR\tFORMAT\tEXPECT\tDECODE\tENCODE")
+	failed, total := 0, 0
+	var details []string
+	for _, v := range vectors {
+		if filter != nil && !filter.MatchString(v.name) {
+			continue
+		}
+		total++
+		r := check(v)
+		if r.decode == "FAIL" || r.encode == "FAIL" {
+			failed++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", v.name, v.format, v.expect, r.decode, r.encode)
+		if r.detail != "" {
+			details = append(details, v.name+": "+r.detail)
+		}
+	}
+	tw.Flush()
+	if *verbose {
+		for _, d := range details {
+			fmt.Println(d)
+		}
+	}
+	fmt.Printf("%d of %d vectors passed\n", total-failed, total)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func parseVectors(text string) ([]vector, error) {
+	var vectors []vector
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for line := 1; sc.Scan(); line++ {
+		bytesText, value, hasValue := strings.Cut(sc.Text(), "=>")
+		fields := strings.Fields(bytesText)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("vectors line %d: expected name, format, expectation and bytes", line)
+		}
+		data, err := parseBytes(fields[3:])
+		if err != nil {
+			return nil, fmt.Errorf("vectors line %d: %w", line, err)
+		}
+		if hasValue != (fields[2] == "roundtrip") {
+			return nil, fmt.Errorf("vectors line %d: expected a decoded value for roundtrip vectors only", line)
+		}
+		vectors = append(vectors, vector{name: fields[0], format: fields[1], expect: fields[2], data: data, value: strings.TrimSpace(value)})
+	}
+	return vectors, sc.Err()
+}
+
+// parseBytes parses hex bytes, where xx*n repeats the byte xx n times.
+func parseBytes(tokens []string) ([]byte, error) {
+	var data []byte
+	for _, tok := range tokens {
+		count := 1
+		if i := strings.IndexByte(tok, '*'); i >= 0 {
+			n, err := strconv.Atoi(tok[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			tok, count = tok[:i], n
+		}
+		b, err := hex.DecodeString(tok)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, bytes.Repeat(b, count)...)
+	}
+	return data, nil
+}
+
+func check(v vector) (r result) {
+	r.decode, r.encode = "FAIL", "-"
+	defer func() {
+		if p := recover(); p != nil {
+			r.detail = fmt.Sprintf("panic: %v", p)
+		}
+	}()
+	value, err := decode(v.format, v.data)
+	switch v.expect {
+	case "error":
+		if err == nil {
+			r.detail = fmt.Sprintf("decoded %v, expected an error", value)
+			return r
+		}
+		r.decode = "PASS"
+		return r
+	case "roundtrip":
+		if err != nil {
+			r.detail = err.Error()
+			return r
+		}
+		if got := describe(value); got != v.value {
+			r.detail = "decoded " + got + ", expected " + v.value
+			return r
+		}
+		r.decode, r.encode = "PASS", "FAIL"
+		data, err := encode(v.format, value)
+		if err != nil {
+			r.detail = err.Error()
+			return r
+		}
+		if !bytes.Equal(data, v.data) {
+			r.detail = "encoded " + abbreviate(data)
+			return r
+		}
+		r.encode = "PASS"
+		return r
+	}
+	r.detail = "unknown expectation " + v.expect
+	return r
+}
+
+// decode decodes the single value or packet in data.
+func decode(format string, data []byte) (interface{}, error) {
+	br := bytes.NewReader(data)
+	var v interface{}
+	var err error
+	switch format {
+	case "amf0":
+		v, err = amf0.NewDecoder(br).Decode()
+	case "amf3":
+		v, err = amf3.NewDecoder(br).Decode()
+	case "packet":
+		return amf.DecodePacket(data)
+	default:
+		return nil, errors.New("unknown format " + format)
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	if br.Len() > 0 {
+		return nil, fmt.Errorf("%d bytes left after the value", br.Len())
+	}
+	return v, nil
+}
+
+func encode(format string, v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "amf0":
+		err = amf0.NewEncoder(&buf).Encode(v)
+	case "amf3":
+		err = amf3.NewEncoder(&buf).Encode(v)
+	case "packet":
+		return amf.EncodePacket(v.(*amf.Packet))
+	}
+	return buf.Bytes(), err
+}
+
+func abbreviate(data []byte) string {
+	if len(data) > 32 {
+		return hex.EncodeToString(data[:32]) + "..."
+	}
+	return hex.EncodeToString(data)
+}
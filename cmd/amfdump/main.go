@@ -0,0 +1,556 @@
+// Command amfdump prints the AMF payload read from standard input.
+//
+// Usage:
+//
+//	amfdump [-in raw|hex|base64] [-format auto|packet|amf0|amf3] [-out tree|json|hex-annotated] [-precision n]
+//
+// Payloads usually arrive pasted from logs rather than as files, so the
+// input may be hex or base64 text; whitespace and line breaks in it are
+// ignored. The output is the text tree of amf.Dump, JSON, or the bytes in
+// hex annotated with the packet fields and values they hold. With -format
+// auto the payload is read as a packet if it decodes as one, and as a
+// sequence of AMF0 or AMF3 values otherwise. Numbers in the tree and JSON
+// output are written in their shortest form, or with -precision digits
+// after the decimal point.
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/marcuswu/amf"
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+const usage = "usage: amfdump [-in raw|hex|base64] [-format auto|packet|amf0|amf3] [-out tree|json|hex-annotated] [-precision n]"
+
+func main() {
+	in := flag.String("in", "raw", "input encoding: raw, hex or base64")
+	formatFlag := flag.String("format", "auto", "payload format: auto, packet, amf0 or amf3")
+	out := flag.String("out", "tree", "output: tree, json or hex-annotated")
+	precision := flag.Int("precision", -1, "digits after the decimal point in numbers, or -1 for the shortest form")
+	flag.Parse()
+	if flag.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var ff amf.FloatFormat
+	if *precision >= 0 {
+		ff = amf.FloatFormat{Fixed: true, Precision: *precision}
+	}
+	r, err := inputReader(os.Stdin, *in)
+	if err == nil {
+		var data []byte
+		data, err = io.ReadAll(r)
+		if err == nil {
+			err = dump(os.Stdout, data, *formatFlag, *out, ff)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "amfdump: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// inputReader returns the payload bytes of r, decoded as they are read.
+func inputReader(r io.Reader, encoding string) (io.Reader, error) {
+	switch encoding {
+	case "raw":
+		return r, nil
+	case "hex":
+		return hex.NewDecoder(&spaceSkipper{r: r}), nil
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &spaceSkipper{r: r}), nil
+	}
+	return nil, fmt.Errorf("unknown input encoding %q", encoding)
+}
+
+// spaceSkipper drops the whitespace of text pasted from logs.
+type spaceSkipper struct {
+	r io.Reader
+}
+
+func (s *spaceSkipper) Read(p []byte) (int, error) {
+	for {
+		n, err := s.r.Read(p)
+		kept := 0
+		for _, b := range p[:n] {
+			if b < 0x80 && unicode.IsSpace(rune(b)) {
+				continue
+			}
+			p[kept] = b
+			kept++
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
+
+func dump(w io.Writer, data []byte, format, out string, ff amf.FloatFormat) error {
+	if format == "auto" {
+		format = "packet"
+		_, err := amf.DecodePacket(data)
+		if err != nil {
+			format = "amf0"
+			if version, _ := amf.Sniff(data); version == amf.AMF3 {
+				format = "amf3"
+			}
+		}
+	}
+	if out == "hex-annotated" {
+		a := &annotator{w: w, data: data}
+		switch format {
+		case "packet":
+			return a.packet()
+		case "amf0":
+			return a.values0(0)
+		case "amf3":
+			return a.values3(0)
+		}
+		return fmt.Errorf("unknown format %q", format)
+	}
+	if out != "tree" && out != "json" {
+		return fmt.Errorf("unknown output %q", out)
+	}
+	values, err := decode(data, format)
+	if err != nil {
+		return err
+	}
+	if out == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		for _, v := range values {
+			err = enc.Encode(jsonValue(v, make(map[interface{}]bool), ff))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, v := range values {
+		err = amf.DumpWithOptions(w, v, amf.DumpOptions{Float: ff})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decode returns the packet or values held in data.
+func decode(data []byte, format string) ([]interface{}, error) {
+	var values []interface{}
+	switch format {
+	case "packet":
+		p, err := amf.NewDecoderWithOptions(bytes.NewReader(data), amf.DecoderOptions{UnwrapFlexCollections: true}).Decode()
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{p}, nil
+	case "amf0":
+		dec := amf0.NewDecoderWithOptions(bytes.NewReader(data), amf0.DecoderOptions{
+			AMF3: amf3.DecoderOptions{UnwrapFlexCollections: true},
+		})
+		for {
+			v, err := dec.Decode()
+			if err == io.EOF {
+				return values, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+	case "amf3":
+		dec := amf3.NewDecoderWithOptions(bytes.NewReader(data), amf3.DecoderOptions{UnwrapFlexCollections: true})
+		for {
+			v, err := dec.Decode()
+			if err == io.EOF {
+				return values, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// jsonValue converts a decoded value or packet to the maps, slices and
+// scalars encoding/json writes. Class names are kept in a __class member;
+// a container holding itself is written as "(cycle)".
+func jsonValue(v interface{}, open map[interface{}]bool, ff amf.FloatFormat) interface{} {
+	switch value := v.(type) {
+	case *amf.Packet, *amf0.ObjectType, *amf0.EcmaArrayType, *amf0.StrictArrayType, *amf0.TypedObjectType,
+		*amf3.ArrayType, *amf3.ObjectType, *amf3.VectorObjectType, *amf3.DictionaryType, *amf3.ArrayCollection,
+		*amf3.ObjectProxy:
+		if open[v] {
+			return "(cycle)"
+		}
+		open[v] = true
+		defer delete(open, v)
+	case nil, amf0.NullType, amf0.UndefinedType, amf0.UnsupportedType, amf3.NullType, amf3.UndefinedType:
+		return nil
+	case amf0.NumberType:
+		return number(float64(value), ff)
+	case amf3.DoubleType:
+		return number(float64(value), ff)
+	case amf3.IntegerType:
+		return int32(value)
+	case amf0.BooleanType:
+		return bool(value)
+	case amf3.FalseType:
+		return false
+	case amf3.TrueType:
+		return true
+	case amf0.DateType:
+		return value.Time().Format("2006-01-02T15:04:05.999Z07:00")
+	case *amf3.DateType:
+		return value.Time().UTC().Format("2006-01-02T15:04:05.999Z07:00")
+	case *amf3.ByteArrayType:
+		return []byte(*value)
+	case *amf3.XMLDocumentType:
+		return string(*value)
+	case *amf3.XMLType:
+		return string(*value)
+	case *amf3.VectorIntType:
+		return value.Items
+	case *amf3.VectorUintType:
+		return value.Items
+	case *amf3.VectorDoubleType:
+		return value.Items
+	case amf0.StringType, amf0.LongStringType, amf0.XmlDocumentType, amf3.StringType, amf3.NullStringType:
+		return fmt.Sprintf("%s", value)
+	default:
+		return fmt.Sprint(v)
+	}
+
+	switch value := v.(type) {
+	case *amf.Packet:
+		headers, messages := []interface{}{}, []interface{}{}
+		for _, h := range value.Headers() {
+			headers = append(headers, map[string]interface{}{
+				"name": h.Name(), "mustUnderstand": h.MustUnderstand(), "data": jsonValue(h.Data(), open, ff),
+			})
+		}
+		for _, m := range value.Messages() {
+			messages = append(messages, map[string]interface{}{
+				"target": m.TargetURI(), "response": m.ResponseURI(), "data": jsonValue(m.Data(), open, ff),
+			})
+		}
+		return map[string]interface{}{"version": value.Version(), "headers": headers, "messages": messages}
+	case *amf0.ObjectType:
+		return jsonObject0(*value, "", open, ff)
+	case *amf0.EcmaArrayType:
+		return jsonObject0(*value, "", open, ff)
+	case *amf0.TypedObjectType:
+		return jsonObject0(value.Object, string(value.ClassName), open, ff)
+	case *amf0.StrictArrayType:
+		return jsonArray(*value, open, ff)
+	case *amf3.ArrayType:
+		if len(value.Associative) == 0 {
+			return jsonArray(value.Dense, open, ff)
+		}
+		obj := jsonObject3(value.Associative, "", open, ff)
+		for i, item := range value.Dense {
+			obj[fmt.Sprint(i)] = jsonValue(item, open, ff)
+		}
+		return obj
+	case *amf3.ObjectType:
+		var className string
+		members := make(map[amf3.StringType]interface{}, len(value.Static)+len(value.Dynamic))
+		if value.Trait != nil {
+			className = string(value.Trait.ClassName)
+			for i, name := range value.Trait.Attrs {
+				if i < len(value.Static) {
+					members[name] = value.Static[i]
+				}
+			}
+		}
+		for name, item := range value.Dynamic {
+			members[name] = item
+		}
+		return jsonObject3(members, className, open, ff)
+	case *amf3.VectorObjectType:
+		return jsonArray(value.Items, open, ff)
+	case *amf3.ArrayCollection:
+		return jsonArray(*value, open, ff)
+	case *amf3.ObjectProxy:
+		return jsonObject3(*value, "", open, ff)
+	case *amf3.DictionaryType:
+		entries := make([]interface{}, len(value.Entries))
+		for i, e := range value.Entries {
+			entries[i] = map[string]interface{}{"key": jsonValue(e.Key, open, ff), "value": jsonValue(e.Value, open, ff)}
+		}
+		return entries
+	}
+	return nil
+}
+
+// number writes f as ff formats it, keeping NaN and the infinities, which
+// JSON has no literal for, as text.
+func number(f float64, ff amf.FloatFormat) interface{} {
+	s := ff.Format(f)
+	if strings.ContainsAny(s, "NI") {
+		return s
+	}
+	return json.Number(s)
+}
+
+func jsonObject0(members map[amf0.StringType]interface{}, className string, open map[interface{}]bool, ff amf.FloatFormat) map[string]interface{} {
+	obj := make(map[string]interface{}, len(members)+1)
+	for name, item := range members {
+		obj[string(name)] = jsonValue(item, open, ff)
+	}
+	if className != "" {
+		obj["__class"] = className
+	}
+	return obj
+}
+
+func jsonObject3(members map[amf3.StringType]interface{}, className string, open map[interface{}]bool, ff amf.FloatFormat) map[string]interface{} {
+	obj := make(map[string]interface{}, len(members)+1)
+	for name, item := range members {
+		obj[string(name)] = jsonValue(item, open, ff)
+	}
+	if className != "" {
+		obj["__class"] = className
+	}
+	return obj
+}
+
+func jsonArray(items []interface{}, open map[interface{}]bool, ff amf.FloatFormat) []interface{} {
+	array := make([]interface{}, len(items))
+	for i, item := range items {
+		array[i] = jsonValue(item, open, ff)
+	}
+	return array
+}
+
+// annotator writes data in hex, 16 bytes to a line, each run of bytes
+// followed by what it holds.
+type annotator struct {
+	w    io.Writer
+	data []byte
+	off  int
+}
+
+// note writes the next n bytes described by text, indented by depth.
+func (a *annotator) note(n int, depth int, text string) error {
+	if n > len(a.data)-a.off {
+		return io.ErrUnexpectedEOF
+	}
+	b := a.data[a.off : a.off+n]
+	for first := true; first || len(b) > 0; first = false {
+		line := b
+		if len(line) > 16 {
+			line = line[:16]
+		}
+		hexText := strings.TrimSpace(fmt.Sprintf("% x", line))
+		if first {
+			fmt.Fprintf(a.w, "%06x  %-47s  %s%s\n", a.off, hexText, strings.Repeat("  ", depth), text)
+		} else {
+			fmt.Fprintf(a.w, "%06x  %s\n", a.off, hexText)
+		}
+		a.off += len(line)
+		b = b[len(line):]
+	}
+	return nil
+}
+
+func (a *annotator) u16() (int, error) {
+	if len(a.data)-a.off < 2 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return int(a.data[a.off])<<8 | int(a.data[a.off+1]), nil
+}
+
+// utf8 annotates a string preceded by its 16-bit length.
+func (a *annotator) utf8(depth int, what string) error {
+	n, err := a.u16()
+	if err != nil {
+		return err
+	}
+	if len(a.data)-a.off < 2+n {
+		return io.ErrUnexpectedEOF
+	}
+	return a.note(2+n, depth, fmt.Sprintf("%s %q", what, a.data[a.off+2:a.off+2+n]))
+}
+
+func (a *annotator) packet() error {
+	version, err := a.u16()
+	if err != nil {
+		return err
+	}
+	err = a.note(2, 0, fmt.Sprintf("version %d", version))
+	if err != nil {
+		return err
+	}
+	for _, what := range []string{"header", "message"} {
+		count, err := a.u16()
+		if err != nil {
+			return err
+		}
+		err = a.note(2, 0, fmt.Sprintf("%s count %d", what, count))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < count; i++ {
+			if what == "header" {
+				err = a.utf8(1, fmt.Sprintf("headers[%d] name", i))
+				if err == nil && a.off < len(a.data) {
+					err = a.note(1, 1, fmt.Sprintf("mustUnderstand %v", a.data[a.off] != 0))
+				}
+			} else {
+				err = a.utf8(1, fmt.Sprintf("messages[%d] target", i))
+				if err == nil {
+					err = a.utf8(1, "response")
+				}
+			}
+			if err == nil {
+				err = a.length()
+			}
+			if err == nil {
+				err = a.value0(2)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if a.off < len(a.data) {
+		return a.note(len(a.data)-a.off, 0, "trailing data")
+	}
+	return nil
+}
+
+// length annotates the 32-bit length before a header value or message body.
+func (a *annotator) length() error {
+	if len(a.data)-a.off < 4 {
+		return io.ErrUnexpectedEOF
+	}
+	n := uint32(a.data[a.off])<<24 | uint32(a.data[a.off+1])<<16 | uint32(a.data[a.off+2])<<8 | uint32(a.data[a.off+3])
+	if n == amf.UnknownLength {
+		return a.note(4, 1, "length unknown")
+	}
+	return a.note(4, 1, fmt.Sprintf("length %d", n))
+}
+
+// values0 annotates AMF0 values up to the end of the input.
+func (a *annotator) values0(depth int) error {
+	for a.off < len(a.data) {
+		err := a.value0(depth)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// value0 annotates one AMF0 value token by token. Values after a switch to
+// AMF3 are annotated whole.
+func (a *annotator) value0(depth int) error {
+	r := bytes.NewReader(a.data[a.off:])
+	dec := amf0.NewDecoderWithOptions(r, amf0.DecoderOptions{AMF3: amf3.DecoderOptions{UnwrapFlexCollections: true}})
+	open := 0
+	for {
+		t, err := dec.Token()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return fmt.Errorf("offset %#x: %w", a.off, err)
+		}
+		n := len(a.data) - a.off - r.Len()
+		switch t.(type) {
+		case amf0.ObjectStart, amf0.EcmaArrayStart, amf0.StrictArrayStart:
+			err = a.note(n, depth+open, tokenText(t))
+			open++
+		case amf0.End:
+			open--
+			if n > 0 {
+				err = a.note(n, depth+open, tokenText(t))
+			}
+		default:
+			err = a.note(n, depth+open, tokenText(t))
+		}
+		if err != nil {
+			return err
+		}
+		if _, name := t.(amf0.PropertyName); open == 0 && !name {
+			return nil
+		}
+	}
+}
+
+// values3 annotates AMF3 values up to the end of the input, one to a run.
+func (a *annotator) values3(depth int) error {
+	r := bytes.NewReader(a.data)
+	dec := amf3.NewDecoderWithOptions(r, amf3.DecoderOptions{UnwrapFlexCollections: true})
+	for a.off < len(a.data) {
+		v, err := dec.Decode()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return fmt.Errorf("offset %#x: %w", a.off, err)
+		}
+		err = a.note(len(a.data)-a.off-r.Len(), depth, valueText(v))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tokenText(t amf0.Token) string {
+	switch token := t.(type) {
+	case amf0.ObjectStart:
+		if token.ClassName != "" {
+			return fmt.Sprintf("typed object %q", token.ClassName)
+		}
+		return "object"
+	case amf0.EcmaArrayStart:
+		return fmt.Sprintf("ECMA array count %d", token.Count)
+	case amf0.StrictArrayStart:
+		return fmt.Sprintf("strict array count %d", token.Count)
+	case amf0.End:
+		return "end"
+	case amf0.PropertyName:
+		return fmt.Sprintf("name %q", string(token))
+	case amf0.Reference:
+		return fmt.Sprintf("reference %d", uint16(token))
+	}
+	return valueText(t)
+}
+
+// valueText describes a scalar or, by its type, a container.
+func valueText(v interface{}) string {
+	switch value := v.(type) {
+	case amf0.StringType, amf0.LongStringType, amf3.StringType:
+		return fmt.Sprintf("%T %q", v, value)
+	case nil:
+		return "nil"
+	}
+	var b bytes.Buffer
+	err := json.NewEncoder(&b).Encode(jsonValue(v, make(map[interface{}]bool), amf.FloatFormat{}))
+	if err != nil {
+		return fmt.Sprintf("%T", v)
+	}
+	text := strings.TrimSpace(b.String())
+	if len(text) > 60 {
+		text = text[:57] + "..."
+	}
+	return fmt.Sprintf("%T %s", v, text)
+}
@@ -0,0 +1,21 @@
+package amf
+
+// Object encoding versions, as given by the objectEncoding of an RTMP connect
+// command; see NegotiateEncoding.
+const (
+	AMF0 = 0
+	AMF3 = 3
+)
+
+// Packet envelope versions.
+const (
+	PacketVersion0 = 0 // Flash Player 8 and earlier
+	PacketVersion3 = 3 // Flash Player 9 and later, whose bodies may switch to AMF3
+)
+
+// ContentTypeAMF is the media type of AMF packets sent over HTTP.
+const ContentTypeAMF = "application/x-amf"
+
+// UnknownLength is the header or message length written by senders that do
+// not know the length of the value up front.
+const UnknownLength = 0xFFFFFFFF
@@ -0,0 +1,16 @@
+package amf
+
+import (
+	"time"
+
+	"github.com/marcuswu/amf/amf0"
+)
+
+// DateType is the AMF0 date. Struct fields of type time.Time decode from
+// the dates of either encoding and encode as dates.
+type DateType = amf0.DateType
+
+// NewDate returns the AMF0 date for t, in milliseconds since the Unix epoch.
+func NewDate(t time.Time) DateType {
+	return amf0.NewDate(t)
+}
@@ -6,20 +6,46 @@ package amf
 import (
 	"bufio"
 	"encoding/binary"
+	"errors"
 	"io"
+	"os"
+	"strconv"
+	"time"
 	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
 )
 
 type Decoder struct {
 	r       io.Reader
+	opts    DecoderOptions
+	values  *amf0.Decoder
+	body    *bodyReader
+	deadliner interface{ SetReadDeadline(time.Time) error }
+	src     io.Reader // the reader given to NewDecoder, for the size of the input
+	buf     *bufio.Reader
+	counter *countingReader // the bytes read from buf, for the offsets of errors
+	seeds   []interface{}
 }
 
+// Errors that packet decoding fails with, beside those of the value
+// decoders.
+var (
+	// ErrTrailingData is returned when bytes follow the last message and
+	// DecoderOptions.DisallowTrailingData is set.
+	ErrTrailingData = errors.New("trailing data after last message")
+	// ErrValueTooLong is returned when a header value or message body runs
+	// past its length and DecoderOptions.EnforceLengths is set.
+	ErrValueTooLong = errors.New("value longer than its length")
+)
+
 // should use io.LimitedReader
 func NewDecoder(r io.Reader) *Decoder {
-	if _, ok := r.(*bufio.Reader); ok {
-		return &Decoder{r: r}
+	buf, ok := r.(*bufio.Reader)
+	if !ok {
+		buf = bufio.NewReader(r)
 	}
-	return &Decoder{r: bufio.NewReader(r)}
+	counter := &countingReader{r: buf}
+	return &Decoder{r: counter, src: r, buf: buf, counter: counter}
 }
 
 func (dec *Decoder) Decode() (p *Packet, err error) {
@@ -34,76 +60,114 @@ func (dec *Decoder) Decode() (p *Packet, err error) {
 func (dec *Decoder) decodePacket() (p *Packet, err error) {
 	p = &Packet{}
 	u16 := make([]byte, 2)
+	dec.values = nil
+	start := dec.offset()
 
-	_, err = dec.r.Read(u16)
-	if err != nil {
+	_, err = io.ReadFull(dec.r, u16)
+	if err == io.EOF && dec.offset() == start {
 		return nil, err
 	}
+	if err != nil {
+		return nil, dec.packetError(err, "")
+	}
 	p.version = binary.BigEndian.Uint16(u16)
 
-	_, err = dec.r.Read(u16)
+	_, err = io.ReadFull(dec.r, u16)
 	if err != nil {
-		return nil, err
+		return nil, dec.packetError(err, "")
 	}
 	headerCount := binary.BigEndian.Uint16(u16)
+	// a header takes at least its name length, flag, value length and marker
+	err = dec.checkCount("header count", headerCount, dec.opts.MaxHeaderCount, 8)
+	if err != nil {
+		return nil, dec.packetError(err, "")
+	}
 
 	p.headers = make([]*Header, headerCount)
 	for i := 0; i < len(p.headers); i++ {
-		p.headers[i], err = dec.decodeHeader()
+		p.headers[i], err = dec.decodeHeader(i)
 		if err != nil {
-			return nil, err
+			return nil, dec.packetError(err, "headers["+strconv.Itoa(i)+"]")
+		}
+		if dec.opts.Schema != nil {
+			dec.opts.Schema.Observe(p.headers[i].name, p.headers[i].data)
 		}
 	}
 
-	_, err = dec.r.Read(u16)
+	_, err = io.ReadFull(dec.r, u16)
 	if err != nil {
-		return nil, err
+		return nil, dec.packetError(err, "")
 	}
 	messageCount := binary.BigEndian.Uint16(u16)
+	// a message takes at least its two URI lengths, value length and marker
+	err = dec.checkCount("message count", messageCount, dec.opts.MaxMessageCount, 9)
+	if err != nil {
+		return nil, dec.packetError(err, "")
+	}
 
 	p.messages = make([]*Message, messageCount)
 	for i := 0; i < len(p.messages); i++ {
-		p.messages[i], err = dec.decodeMessage()
+		p.messages[i], err = dec.decodeMessage(i)
 		if err != nil {
-			return nil, err
+			return nil, dec.packetError(err, "messages["+strconv.Itoa(i)+"]")
+		}
+		if dec.opts.Schema != nil {
+			dec.opts.Schema.Observe(p.messages[i].targetUri, p.messages[i].data)
+		}
+	}
+
+	if dec.opts.DisallowTrailingData {
+		n, err := dec.r.Read(make([]byte, 1))
+		if n > 0 {
+			return nil, dec.packetError(ErrTrailingData, "")
+		}
+		if err != nil && err != io.EOF {
+			return nil, dec.packetError(err, "")
+		}
+	} else if dec.opts.CaptureTrailing {
+		trailing, err := io.ReadAll(dec.r)
+		if err != nil {
+			return nil, dec.packetError(err, "")
+		}
+		if len(trailing) > 0 {
+			p.Trailing = trailing
 		}
 	}
 	
 	return
 }
 
-func (dec *Decoder) decodeHeader() (h *Header, err error) {
+func (dec *Decoder) decodeHeader(i int) (h *Header, err error) {
 	h = &Header{}
 	u8 := make([]byte, 1)
 	u16 := make([]byte, 2)
 	u32 := make([]byte, 4)
 
-	_, err = dec.r.Read(u16)
+	_, err = io.ReadFull(dec.r, u16)
 	if err != nil {
 		return nil, err
 	}
 	headerNameLen := binary.BigEndian.Uint16(u16)
 
 	headerNameBytes := make([]byte, headerNameLen)
-	_, err = dec.r.Read(headerNameBytes)
+	_, err = io.ReadFull(dec.r, headerNameBytes)
 	if err != nil {
 		return nil, err
 	}
 	h.name = string(headerNameBytes)
 
-	_, err = dec.r.Read(u8)
+	_, err = io.ReadFull(dec.r, u8)
 	if err != nil {
 		return nil, err
 	}
 	h.mustUnderstand = u8[0] != 0
 
-	_, err = dec.r.Read(u32)
+	_, err = io.ReadFull(dec.r, u32)
 	if err != nil {
 		return nil, err
 	}
 
-	var amf0Decoder *amf0.Decoder = amf0.NewDecoder(dec.r)
-	h.data, err = amf0Decoder.Decode()
+	h.data, err = dec.decodeBody(binary.BigEndian.Uint32(u32), "headers["+strconv.Itoa(i)+"]", h.name, false)
 	if err != nil {
 		return nil, err
 	}
@@ -111,47 +175,248 @@ func (dec *Decoder) decodeHeader() (h *Header, err error) {
 	return h, nil
 }
 
-func (dec *Decoder) decodeMessage() (m *Message, err error) {
+func (dec *Decoder) decodeMessage(i int) (m *Message, err error) {
 	m = &Message{}
 	u16 := make([]byte, 2)
 	u32 := make([]byte, 4)
 
-	_, err = dec.r.Read(u16)
+	_, err = io.ReadFull(dec.r, u16)
 	if err != nil {
 		return nil, err
 	}
 	targetUriLen := binary.BigEndian.Uint16(u16)
 
 	targetUriBytes := make([]byte, targetUriLen)
-	_, err = dec.r.Read(targetUriBytes)
+	_, err = io.ReadFull(dec.r, targetUriBytes)
 	if err != nil {
 		return nil, err
 	}
 	m.targetUri = string(targetUriBytes)
 
-	_, err = dec.r.Read(u16)
+	_, err = io.ReadFull(dec.r, u16)
 	if err != nil {
 		return nil, err
 	}
 	responseUriLen := binary.BigEndian.Uint16(u16)
 
 	responseUriBytes := make([]byte, responseUriLen)
-	_, err = dec.r.Read(responseUriBytes)
+	_, err = io.ReadFull(dec.r, responseUriBytes)
 	if err != nil {
 		return nil, err
 	}
 	m.responseUri = string(responseUriBytes)
 
-	_, err = dec.r.Read(u32)
+	_, err = io.ReadFull(dec.r, u32)
 	if err != nil {
 		return nil, err
 	}
 
-	var amf0Decoder *amf0.Decoder = amf0.NewDecoder(dec.r)
-	m.data, err = amf0Decoder.Decode()
+	m.data, err = dec.decodeBody(binary.BigEndian.Uint32(u32), "messages["+strconv.Itoa(i)+"]", m.targetUri, dec.opts.RawBodies)
 	if err != nil {
 		return nil, err
 	}
 
 	return m, nil
 }
+
+// decodeBody decodes a header value or message body of the given length,
+// found at path and called name for errors, as a RawMessage if raw is set.
+func (dec *Decoder) decodeBody(length uint32, path, name string, raw bool) (v interface{}, err error) {
+	values := dec.valueDecoder()
+	decode := values.Decode
+	if raw {
+		decode = func() (interface{}, error) { return values.DecodeRaw() }
+	}
+	// the offsets of value errors count from the first byte of the first value
+	base := dec.offset() - dec.body.read
+	defer func() {
+		if de, ok := err.(*amf0.DecodeError); ok {
+			de.Offset += base
+		}
+	}()
+	if dec.opts.ValueTimeout > 0 {
+		dec.body.deadline = time.Now().Add(dec.opts.ValueTimeout)
+		dec.body.timedOut = false
+		if dec.deadliner != nil {
+			// the deadline of the caller cannot be read back, so it is
+			// cleared rather than restored, as ValueTimeout documents
+			dec.deadliner.SetReadDeadline(dec.body.deadline)
+			defer dec.deadliner.SetReadDeadline(time.Time{})
+		}
+		defer func() { dec.body.deadline = time.Time{} }()
+	}
+	// a capture of an unknown value ends with the value only when bounded
+	bounded := dec.opts.EnforceLengths || dec.opts.CaptureUnknownMarkers
+	if !bounded || length == UnknownLength {
+		v, err = decode()
+		if err != nil && dec.body.timedOut {
+			return nil, dec.timeoutError(err, path, name)
+		}
+		return v, err
+	}
+	dec.body.n = int64(length)
+	defer func() { dec.body.n = -1 }()
+	v, err = decode()
+	if err != nil && dec.body.timedOut {
+		return nil, dec.timeoutError(err, path, name)
+	}
+	if err == io.EOF {
+		err = ErrValueTooLong
+	} else if de, ok := err.(*amf0.DecodeError); ok && dec.body.n == 0 && de.Err == io.ErrUnexpectedEOF {
+		de.Err = ErrValueTooLong
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.CopyN(io.Discard, dec.body, dec.body.n)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// timeoutError returns the *ValueTimeoutError for err, a failure to decode
+// the value at path in time. The error of a nested value stays an
+// *amf0.DecodeError, with the timeout as its cause, so that its offset and
+// path are kept.
+func (dec *Decoder) timeoutError(err error, path, name string) error {
+	timeout := &ValueTimeoutError{Path: path, Name: name, Limit: dec.opts.ValueTimeout}
+	if de, ok := err.(*amf0.DecodeError); ok {
+		timeout.Path += de.Path
+		de.Err = timeout
+		return de
+	}
+	return timeout
+}
+
+// checkCount fails with a *LengthError when the header or message count n
+// is over limit, or when n entries of at least size bytes each cannot fit in
+// what is left of the input.
+func (dec *Decoder) checkCount(what string, n uint16, limit int, size int64) error {
+	if limit > 0 && int(n) > limit {
+		return &LengthError{What: what, Length: uint64(n), Limit: int64(limit)}
+	}
+	if left := dec.remaining(); left >= 0 && int64(n)*size > left {
+		return &LengthError{What: what, Length: uint64(n), Limit: left, Remaining: true}
+	}
+	return nil
+}
+
+// remaining returns the bytes left in the input, or -1 if unknown.
+func (dec *Decoder) remaining() int64 {
+	left := int64(-1)
+	switch l := dec.src.(type) {
+	case interface{ Remaining() int64 }:
+		left = l.Remaining()
+	case interface{ Len() int }:
+		left = int64(l.Len())
+	}
+	if left >= 0 && dec.src != io.Reader(dec.buf) {
+		left += int64(dec.buf.Buffered())
+	}
+	return left
+}
+
+// offset returns the bytes of the input consumed so far.
+func (dec *Decoder) offset() int64 {
+	return dec.counter.n
+}
+
+// packetError returns err, from decoding the part of the packet at path, as
+// an *amf0.DecodeError with its offset in the input.
+func (dec *Decoder) packetError(err error, path string) error {
+	if de, ok := err.(*amf0.DecodeError); ok {
+		de.Path = path + de.Path
+		return de
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return &amf0.DecodeError{Offset: dec.offset(), Marker: -1, Path: path, Err: err}
+}
+
+// SeedReferences puts objs at the start of the reference table of every
+// header value and message body, as amf0.Decoder.SeedReferences does.
+func (dec *Decoder) SeedReferences(objs []interface{}) {
+	dec.seeds = append([]interface{}(nil), objs...)
+}
+
+// valueDecoder returns the decoder for the next header or message value,
+// with a fresh reference table unless references are shared across the
+// packet.
+func (dec *Decoder) valueDecoder() *amf0.Decoder {
+	if dec.values == nil {
+		dec.body = &bodyReader{r: dec.r, n: -1}
+		dec.values = amf0.NewDecoderWithOptions(dec.body, amf0.DecoderOptions{
+			Transcode:             dec.opts.Transcode,
+			MaxDepth:              dec.opts.MaxDepth,
+			MaxStringLength:       dec.opts.MaxStringLength,
+			MaxArrayCount:         dec.opts.MaxArrayCount,
+			MaxValueCount:         dec.opts.MaxValueCount,
+			CaptureUnknownMarkers: dec.opts.CaptureUnknownMarkers,
+			AMF3:                  amf3.DecoderOptions{UnwrapFlexCollections: dec.opts.UnwrapFlexCollections},
+		})
+		if dec.seeds != nil {
+			dec.values.SeedReferences(dec.seeds)
+		}
+	} else if !dec.opts.SharedReferences {
+		dec.values.ResetReferences()
+	}
+	return dec.values
+}
+
+// bodyReader reads the value of a header or message from r, stopping after
+// n bytes unless n is negative, and failing past deadline unless it is zero.
+// Being an io.ByteReader, it is read directly by the value decoders, so the
+// bounds hold for nested decoders too.
+type bodyReader struct {
+	r        io.Reader
+	n        int64
+	read     int64 // the bytes read through it, as value decoders count them
+	deadline time.Time
+	timedOut bool
+}
+
+func (br *bodyReader) Read(p []byte) (int, error) {
+	if br.n == 0 {
+		return 0, io.EOF
+	}
+	if !br.deadline.IsZero() && !time.Now().Before(br.deadline) {
+		br.timedOut = true
+		return 0, os.ErrDeadlineExceeded
+	}
+	if br.n > 0 && int64(len(p)) > br.n {
+		p = p[:br.n]
+	}
+	n, err := br.r.Read(p)
+	br.read += int64(n)
+	if br.n > 0 {
+		br.n -= int64(n)
+	}
+	if !br.deadline.IsZero() && errors.Is(err, os.ErrDeadlineExceeded) {
+		br.timedOut = true
+	}
+	return n, err
+}
+
+// Remaining returns the bytes left before the bound, or -1 when neither the
+// bound nor the size of the underlying reader is known.
+func (br *bodyReader) Remaining() int64 {
+	left := int64(-1)
+	switch l := br.r.(type) {
+	case interface{ Remaining() int64 }:
+		left = l.Remaining()
+	case interface{ Len() int }:
+		left = int64(l.Len())
+	}
+	if br.n >= 0 && (left < 0 || br.n < left) {
+		left = br.n
+	}
+	return left
+}
+
+func (br *bodyReader) ReadByte() (byte, error) {
+	b := make([]byte, 1)
+	_, err := io.ReadFull(br, b)
+	return b[0], err
+}
@@ -2,9 +2,15 @@ package amf
 
 import (
 	"bytes"
+	"errors"
+	"io"
+	"net"
 	"testing"
 	"reflect"
+	"time"
+	"github.com/marcuswu/amf/amf0"
 	"github.com/marcuswu/amf/amf3"
+	"github.com/marcuswu/amf/amftest"
 )
 
 func TestReadAMFPacket(t *testing.T) {
@@ -95,3 +101,371 @@ func TestReadAMFPacket(t *testing.T) {
 		}
 	}
 }
+
+func TestReadAMFPacketTrailing(t *testing.T) {
+	data := []byte{0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0xca, 0xfe}
+	decoder := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{CaptureTrailing: true})
+	got, err := decoder.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !bytes.Equal(got.Trailing, []byte{0xca, 0xfe}) {
+		t.Errorf("expected trailing bytes cafe, got %x", got.Trailing)
+	}
+
+	var buffer bytes.Buffer
+	err = NewEncoder(&buffer).Encode(got)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !bytes.Equal(buffer.Bytes(), data) {
+		t.Errorf("expected trailing bytes to be written back, got %x", buffer.Bytes())
+	}
+
+	decoder = NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{DisallowTrailingData: true})
+	_, err = decoder.Decode()
+	if err == nil {
+		t.Errorf("expected error for trailing data")
+	}
+}
+
+func TestReadAMFPacketSharedReferences(t *testing.T) {
+	data := []byte{
+		0x00, 0x00, 0x00, 0x01,
+		// header "h" with the object {a: true}
+		0x00, 0x01, 'h', 0x00, 0xff, 0xff, 0xff, 0xff,
+		0x03, 0x00, 0x01, 'a', 0x01, 0x01, 0x00, 0x00, 0x09,
+		0x00, 0x01,
+		// message "t" whose body refers to object 0
+		0x00, 0x01, 't', 0x00, 0x01, 'r', 0xff, 0xff, 0xff, 0xff,
+		0x07, 0x00, 0x00,
+	}
+	_, err := NewDecoder(bytes.NewReader(data)).Decode()
+	if err == nil {
+		t.Errorf("expected reference error across messages")
+	}
+
+	decoder := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{SharedReferences: true})
+	got, err := decoder.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got.messages[0].data != got.headers[0].data {
+		t.Errorf("expected message body to be the header object, got %v", got.messages[0].data)
+	}
+}
+
+func TestReadAMFPacketEnforceLengths(t *testing.T) {
+	packet := func(length byte, body ...byte) []byte {
+		data := []byte{0x00, 0x03, 0x00, 0x00, 0x00, 0x01,
+			0x00, 0x01, 't', 0x00, 0x01, 'r', 0x00, 0x00, 0x00, length}
+		return append(data, body...)
+	}
+
+	// a shorter value is followed by padding up to its length
+	data := packet(6, 0x02, 0x00, 0x01, 'x', 0x00, 0x00)
+	decoder := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{EnforceLengths: true, DisallowTrailingData: true})
+	got, err := decoder.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got.messages[0].data != amf0.StringType("x") {
+		t.Errorf("expected x, got %v", got.messages[0].data)
+	}
+
+	// the AMF3 string runs over the length of the message
+	data = packet(4, 0x11, 0x06, 0x07, 'a', 'b', 'c')
+	decoder = NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{EnforceLengths: true})
+	_, err = decoder.Decode()
+	if err == nil {
+		t.Errorf("expected error for nested AMF3 value over its length")
+	}
+	got, err = NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got.messages[0].data != amf3.StringType("abc") {
+		t.Errorf("expected abc without limits, got %v", got.messages[0].data)
+	}
+}
+
+func TestReadValue(t *testing.T) {
+	data := []byte{0x02, 0x00, 0x07, 'c', 'o', 'n', 'n', 'e', 'c', 't', 0x00, 0x3f, 0xf0, 0, 0, 0, 0, 0, 0}
+	v, err := DecodeValue(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if v != amf0.StringType("connect") {
+		t.Errorf("expected connect, got %v", v)
+	}
+
+	decoder := NewDecoder(bytes.NewReader(data))
+	v, err = decoder.ReadValue()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if v != amf0.StringType("connect") {
+		t.Errorf("expected connect, got %v", v)
+	}
+	v, err = decoder.ReadValue()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if v != amf0.NumberType(1) {
+		t.Errorf("expected 1, got %v", v)
+	}
+	_, err = decoder.ReadValue()
+	if err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func TestReadAMFPacketValueTimeout(t *testing.T) {
+	data := []byte{0x00, 0x03, 0x00, 0x01, 0x00, 0x01, 'h', 0x00, 0xff, 0xff, 0xff, 0xff,
+		0x02, 0x00, 0x20}
+	data = append(data, bytes.Repeat([]byte{'x'}, 0x20)...)
+	data = append(data, 0x00, 0x00)
+
+	decoder := NewDecoderWithOptions(&amftest.ChunkedReader{R: bytes.NewReader(data), Sizes: []int{15, 1}, Delay: time.Millisecond},
+		DecoderOptions{ValueTimeout: 10 * time.Millisecond})
+	_, err := decoder.Decode()
+	var timeoutErr *ValueTimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Path != "headers[0]" || timeoutErr.Name != "h" || !timeoutErr.Timeout() {
+		t.Errorf("expected value timeout in headers[0], got %v", err)
+	}
+
+	decoder = NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{ValueTimeout: time.Second})
+	_, err = decoder.Decode()
+	if err != nil {
+		t.Errorf("expected packet within the limit, got %v", err)
+	}
+
+	nested := []byte{0x00, 0x03, 0x00, 0x01, 0x00, 0x01, 'h', 0x00, 0xff, 0xff, 0xff, 0xff,
+		0x03, 0x00, 0x01, 'k', 0x02, 0x00, 0x20}
+	nested = append(nested, bytes.Repeat([]byte{'x'}, 0x20)...)
+	nested = append(nested, 0x00, 0x00, 0x09, 0x00, 0x00)
+	decoder = NewDecoderWithOptions(&amftest.ChunkedReader{R: bytes.NewReader(nested), Sizes: []int{19, 1}, Delay: time.Millisecond},
+		DecoderOptions{ValueTimeout: 10 * time.Millisecond})
+	_, err = decoder.Decode()
+	var de *amf0.DecodeError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Path != "headers[0].k" || !errors.As(err, &de) || de.Path != "headers[0].k" {
+		t.Errorf("expected value timeout in headers[0].k, got %v", err)
+	}
+
+	// a read blocked on a stalled connection is interrupted
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	go client.Write(data[:20])
+	decoder = NewDecoderWithOptions(server, DecoderOptions{ValueTimeout: 10 * time.Millisecond})
+	_, err = decoder.Decode()
+	if !errors.As(err, &timeoutErr) || timeoutErr.Path != "headers[0]" {
+		t.Errorf("expected value timeout on a stalled connection, got %v", err)
+	}
+}
+
+func TestReadAMFPacketCounts(t *testing.T) {
+	// a packet claiming 65535 headers in a few bytes
+	_, err := DecodePacket([]byte{0x00, 0x00, 0xff, 0xff, 0x00, 0x01, 'h', 0x00})
+	var le *LengthError
+	if !errors.As(err, &le) {
+		t.Errorf("expect length error for absurd header count got %v", err)
+	}
+	data := []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x02,
+		0x00, 0x01, 't', 0x00, 0x01, 'r', 0xff, 0xff, 0xff, 0xff, 0x05,
+		0x00, 0x01, 't', 0x00, 0x01, 'r', 0xff, 0xff, 0xff, 0xff, 0x05}
+	_, err = NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{MaxMessageCount: 1}).Decode()
+	if !errors.As(err, &le) || le.Limit != 1 {
+		t.Errorf("expect length error over the message limit got %v", err)
+	}
+	p, err := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{MaxMessageCount: 2}).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(p.Messages()) != 2 {
+		t.Errorf("expect 2 messages got %d", len(p.Messages()))
+	}
+	body := []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+		0x00, 0x01, 't', 0x00, 0x01, 'r', 0xff, 0xff, 0xff, 0xff,
+		0x0a, 0x00, 0x00, 0x00, 0x02, 0x05, 0x05}
+	_, err = NewDecoderWithOptions(bytes.NewReader(body), DecoderOptions{MaxArrayCount: 1}).Decode()
+	if !errors.As(err, &le) || le.Limit != 1 {
+		t.Errorf("expect length error over the array limit got %v", err)
+	}
+}
+
+func TestReadAMFPacketCaptureUnknownMarkers(t *testing.T) {
+	data := []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x02,
+		0x00, 0x01, 'a', 0x00, 0x01, 'r', 0x00, 0x00, 0x00, 0x02, 0x20, 'x',
+		0x00, 0x01, 'b', 0x00, 0x01, 'r', 0x00, 0x00, 0x00, 0x01, 0x05}
+	_, err := DecodePacket(data)
+	if !errors.Is(err, amf0.ErrUnknownMarker) {
+		t.Errorf("expect unknown marker error got %v", err)
+	}
+	p, err := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{EnforceLengths: true, CaptureUnknownMarkers: true}).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if u, ok := p.Messages()[0].Data().(amf0.UnknownValue); !ok || string(u.Data) != "x" {
+		t.Errorf("expect the unknown body captured got %#v", p.Messages()[0].Data())
+	}
+	if _, ok := p.Messages()[1].Data().(amf0.NullType); !ok {
+		t.Errorf("expect the second message decoded got %#v", p.Messages()[1].Data())
+	}
+}
+
+func TestReadAMFPacketCaptureUnknownMarkersBounded(t *testing.T) {
+	data := []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x03,
+		0x00, 0x01, 'a', 0x00, 0x01, 'r', 0x00, 0x00, 0x00, 0x02, 0x20, 'x',
+		0x00, 0x01, 'b', 0x00, 0x01, 'r', 0x00, 0x00, 0x00, 0x01, 0x05,
+		0x00, 0x01, 'c', 0x00, 0x01, 'r', 0x00, 0x00, 0x00, 0x04, 0x20, 'y', 'y', 'y'}
+	p, err := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{CaptureUnknownMarkers: true}).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(p.Messages()) != 3 {
+		t.Fatalf("expect 3 messages got %d", len(p.Messages()))
+	}
+	if u, ok := p.Messages()[0].Data().(amf0.UnknownValue); !ok || string(u.Data) != "x" {
+		t.Errorf("expect the unknown body captured got %#v", p.Messages()[0].Data())
+	}
+	if _, ok := p.Messages()[1].Data().(amf0.NullType); !ok {
+		t.Errorf("expect the second message decoded got %#v", p.Messages()[1].Data())
+	}
+	if u, ok := p.Messages()[2].Data().(amf0.UnknownValue); !ok || string(u.Data) != "yyy" {
+		t.Errorf("expect the third body captured got %#v", p.Messages()[2].Data())
+	}
+	_, err = NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{CaptureUnknownMarkers: true, MaxStringLength: 2}).Decode()
+	var le *amf0.LengthError
+	if !errors.As(err, &le) || le.Length != 3 {
+		t.Errorf("expect a LengthError for the third body got %v", err)
+	}
+}
+
+func TestReadAMFPacketErrorOffset(t *testing.T) {
+	data := []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+		0x00, 0x01, 'a', 0x00, 0x01, 'r', 0xff, 0xff, 0xff, 0xff,
+		0x03, 0x00, 0x01, 'k', 0x02, 0x00, 0x05, 'x'}
+	_, err := NewDecoder(struct{ io.Reader }{bytes.NewReader(data)}).Decode()
+	var de *amf0.DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("expect a DecodeError got %v", err)
+	}
+	if de.Offset != 24 || de.Marker != amf0.StringMarker || de.Path != "messages[0].k" {
+		t.Errorf("expect offset 24, string marker and path messages[0].k got %v", err)
+	}
+	_, err = NewDecoder(struct{ io.Reader }{bytes.NewReader(data[:8])}).Decode()
+	if !errors.As(err, &de) || de.Offset != 8 || de.Marker != -1 || de.Path != "messages[0]" || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expect unexpected EOF at offset 8 in messages[0] got %v", err)
+	}
+}
+
+func TestReadAMFPacketMaxValueCount(t *testing.T) {
+	data := []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x02,
+		0x00, 0x01, 'a', 0x00, 0x01, 'r', 0x00, 0x00, 0x00, 0x01, 0x05,
+		0x00, 0x01, 'b', 0x00, 0x01, 'r', 0x00, 0x00, 0x00, 0x01, 0x05}
+	_, err := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{MaxValueCount: 2}).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	// the count runs across messages
+	_, err = NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{MaxValueCount: 1}).Decode()
+	var de *amf0.DecodeError
+	if !errors.Is(err, amf0.ErrMaxValueCount) || !errors.As(err, &de) || de.Path != "messages[1]" {
+		t.Errorf("expect max value count error in messages[1] got %v", err)
+	}
+}
+
+func TestReadValueMaxValueCount(t *testing.T) {
+	data := bytes.Repeat([]byte{0x05}, 6)
+	data = append(data, 0x0a, 0x00, 0x00, 0x00, 0x05, 0x05, 0x05, 0x05, 0x05, 0x05)
+	dec := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{MaxValueCount: 5})
+	for i := 0; i < 5; i++ {
+		_, err := dec.ReadValue()
+		if err != nil {
+			t.Fatalf("value %d: %s", i, err)
+		}
+	}
+	err := dec.SkipValue()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	// the count runs within a value
+	_, err = dec.ReadValue()
+	if !errors.Is(err, amf0.ErrMaxValueCount) {
+		t.Errorf("expect max value count error got %v", err)
+	}
+}
+
+func TestReadAMFPacketSeedReferences(t *testing.T) {
+	data := []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x02,
+		0x00, 0x01, 'a', 0x00, 0x01, 'r', 0x00, 0x00, 0x00, 0x03, 0x07, 0x00, 0x00,
+		0x00, 0x01, 'b', 0x00, 0x01, 'r', 0x00, 0x00, 0x00, 0x03, 0x07, 0x00, 0x00}
+	_, err := DecodePacket(data)
+	if err == nil {
+		t.Fatalf("expect reference error without seeds")
+	}
+	server := &amf0.ObjectType{"name": amf0.StringType("fms")}
+	dec := NewDecoder(bytes.NewReader(data))
+	dec.SeedReferences([]interface{}{server})
+	p, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, m := range p.Messages() {
+		if m.Data() != server {
+			t.Errorf("expect the seeded object got %v", m.Data())
+		}
+	}
+}
+
+func TestReadAMFPacketChunkedReader(t *testing.T) {
+	p := NewPacket(0, 0)
+	p.AddHeader(NewHeader("credentials", true, &amf0.ObjectType{"userid": amf0.StringType("u"), "password": amf0.StringType("p")}))
+	p.AddMessage(NewMessage("Service.call", "/1", &amf0.StrictArrayType{amf0.NumberType(1), amf0.StringType("two")}))
+	p.AddMessage(NewMessage("Service.other", "/2", &amf3.ArrayType{Dense: []interface{}{amf3.IntegerType(3)}}))
+	data, err := EncodePacket(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect, err := DecodePacket(data)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	// reads come back short, as they do from a network connection
+	for _, sizes := range [][]int{nil, {5, 2}} {
+		got, err := NewDecoder(&amftest.ChunkedReader{R: bytes.NewReader(data), Sizes: sizes}).Decode()
+		if err != nil {
+			t.Fatalf("chunks of %v: %s", sizes, err)
+		}
+		if !reflect.DeepEqual(got, expect) {
+			t.Errorf("chunks of %v: expect %v got %v", sizes, expect, got)
+		}
+	}
+}
+
+func TestReadAMFPacketBackToBack(t *testing.T) {
+	var buffer bytes.Buffer
+	for _, target := range []string{"first", "second"} {
+		p := NewPacket(0, 0)
+		p.AddMessage(NewMessage(target, "/1", amf0.StringType(target)))
+		err := NewEncoder(&buffer).Encode(p)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	decoder := NewDecoder(&buffer)
+	for _, target := range []string{"first", "second"} {
+		p, err := decoder.Decode()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if p.Messages()[0].TargetURI() != target || len(p.Trailing) != 0 {
+			t.Errorf("expected packet %s without trailing bytes, got %s and %x", target, p.Messages()[0].TargetURI(), p.Trailing)
+		}
+	}
+	_, err := decoder.Decode()
+	if err != io.EOF {
+		t.Errorf("expected EOF after the last packet, got %v", err)
+	}
+}
@@ -0,0 +1,8 @@
+// Package amf reads and writes AMF packets, the envelopes of Flash Remoting,
+// and offers tools working on decoded values of either object encoding.
+//
+// The value codecs live in their own packages: amf0 for the original
+// encoding and amf3 for the one introduced with ActionScript 3. Code that
+// handles a single dialect can import just that package. The aliases in this
+// package keep the names of the single-package layout working.
+package amf
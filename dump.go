@@ -0,0 +1,113 @@
+package amf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// Dump writes v, a decoded value or *Packet, as an indented text tree, one
+// value per line. AMF3 objects start with a __traits line giving their
+// class name, sealed members and the dynamic and externalizable flags, as
+// trait mismatches are a common cause of AMF3 interop failures. A container
+// reached a second time is marked as a reference instead of being written
+// again.
+func Dump(w io.Writer, v interface{}) error {
+	return DumpWithOptions(w, v, DumpOptions{})
+}
+
+// DumpOptions configures DumpWithOptions.
+type DumpOptions struct {
+	// Float formats numbers.
+	Float FloatFormat
+}
+
+func DumpWithOptions(w io.Writer, v interface{}, opts DumpOptions) error {
+	d := &dumper{w: bufio.NewWriter(w), seen: make(map[interface{}]bool), opts: opts}
+	if p, ok := v.(*Packet); ok {
+		fmt.Fprintf(d.w, "packet v%d\n", p.version)
+		for i, h := range p.headers {
+			if h == nil {
+				continue
+			}
+			fmt.Fprintf(d.w, "  headers[%d] %q mustUnderstand=%v\n", i, h.name, h.mustUnderstand)
+			d.value(2, "data", h.data)
+		}
+		for i, m := range p.messages {
+			if m == nil {
+				continue
+			}
+			fmt.Fprintf(d.w, "  messages[%d] %q %q\n", i, m.targetUri, m.responseUri)
+			d.value(2, "data", m.data)
+		}
+	} else {
+		d.value(0, "", v)
+	}
+	return d.w.Flush()
+}
+
+type dumper struct {
+	w    *bufio.Writer
+	seen map[interface{}]bool
+	opts DumpOptions
+}
+
+func (d *dumper) value(depth int, elem string, v interface{}) {
+	prefix := strings.Repeat("  ", depth)
+	if elem != "" {
+		prefix += elem + " "
+	}
+	if !isContainer(v) {
+		fmt.Fprintf(d.w, "%s%s\n", prefix, scalarText(v, d.opts.Float))
+		return
+	}
+	label := fmt.Sprintf("%T", v)
+	if name, ok := className(v); ok {
+		label += " " + name
+	}
+	if id := identity(v); id != nil {
+		if d.seen[id] {
+			fmt.Fprintf(d.w, "%s%s (reference)\n", prefix, label)
+			return
+		}
+		d.seen[id] = true
+	}
+	fmt.Fprintf(d.w, "%s%s\n", prefix, label)
+	if obj, ok := v.(*amf3.ObjectType); ok {
+		fmt.Fprintf(d.w, "%s  __traits %s\n", strings.Repeat("  ", depth), traitText(obj.Trait))
+	}
+	forEachChild(v, func(elem string, child interface{}) {
+		d.value(depth+1, elem, child)
+	})
+}
+
+// traitText describes an AMF3 trait for dumps.
+func traitText(t *amf3.Trait) string {
+	if t == nil {
+		return "none"
+	}
+	attrs := make([]string, len(t.Attrs))
+	for i, a := range t.Attrs {
+		attrs[i] = string(a)
+	}
+	return fmt.Sprintf("class=%q sealed=[%s] dynamic=%v externalizable=%v",
+		t.ClassName, strings.Join(attrs, " "), t.IsDynamic, t.Externalizable)
+}
+
+func scalarText(v interface{}, f FloatFormat) string {
+	switch value := v.(type) {
+	case amf0.NumberType:
+		return fmt.Sprintf("%T %s", v, f.Format(float64(value)))
+	case amf3.DoubleType:
+		return fmt.Sprintf("%T %s", v, f.Format(float64(value)))
+	case amf0.StringType, amf0.LongStringType, amf3.StringType:
+		return fmt.Sprintf("%T %q", v, value)
+	case nil:
+		return "nil"
+	}
+	return fmt.Sprintf("%T %v", v, v)
+}
@@ -0,0 +1,66 @@
+package amf
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/marcuswu/amf/amf3"
+)
+
+func TestDump(t *testing.T) {
+	obj := &amf3.ObjectType{
+		Trait:   &amf3.Trait{ClassName: "com.example.User", IsDynamic: true, Attrs: []amf3.StringType{"name"}},
+		Static:  []interface{}{amf3.StringType("ann")},
+		Dynamic: map[amf3.StringType]interface{}{"age": amf3.DoubleType(31.5)},
+	}
+	array := &amf3.ArrayType{Dense: []interface{}{obj, obj}}
+	var buf bytes.Buffer
+	err := Dump(&buf, array)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `*amf3.ArrayType
+  [0] *amf3.ObjectType com.example.User
+    __traits class="com.example.User" sealed=[name] dynamic=true externalizable=false
+    .name amf3.StringType "ann"
+    .age amf3.DoubleType 31.5
+  [1] *amf3.ObjectType com.example.User (reference)
+`
+	if buf.String() != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, buf.String())
+	}
+}
+
+func TestDumpDictionary(t *testing.T) {
+	key := &amf3.ArrayType{Dense: []interface{}{amf3.IntegerType(1)}}
+	dict := &amf3.DictionaryType{Entries: []amf3.DictionaryEntry{
+		{Key: key, Value: amf3.StringType("one")},
+		{Key: amf3.IntegerType(2), Value: key},
+	}}
+	var buf bytes.Buffer
+	err := Dump(&buf, dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `*amf3.DictionaryType
+  [0].key *amf3.ArrayType
+    [0] amf3.IntegerType 1
+  [0].value amf3.StringType "one"
+  [1].key amf3.IntegerType 2
+  [1].value *amf3.ArrayType (reference)
+`
+	if buf.String() != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, buf.String())
+	}
+}
+
+func TestDumpFloatFormat(t *testing.T) {
+	var buf bytes.Buffer
+	err := DumpWithOptions(&buf, amf3.DoubleType(0.1), DumpOptions{Float: FloatFormat{Fixed: true, Precision: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "amf3.DoubleType 0.100\n" {
+		t.Errorf("expected fixed number, got %q", got)
+	}
+}
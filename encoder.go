@@ -7,12 +7,14 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"io"
-	"github.com/marcuswu/amf/amf3"
+	"github.com/marcuswu/amf/amf0"
 )
 
 type Encoder struct {
 	w       *bufio.Writer
+	seeds   []interface{}
 }
 
 // should use io.LimitedReader
@@ -26,28 +28,39 @@ func NewEncoder(w io.Writer) *Encoder {
 func (enc *Encoder) Encode(p *Packet) (err error) {
 	err = enc.encodePacket(p)
 	if err != nil {
-		return nil
+		return err
 	}
-	enc.w.Flush()
+	err = enc.w.Flush()
 
 	return
 }
 
+// Flush writes any buffered data to the underlying writer.
+func (enc *Encoder) Flush() error {
+	return enc.w.Flush()
+}
+
 func (enc *Encoder) encodePacket(p *Packet) (err error) {
 	u16 := make([]byte, 2)
 
 	binary.BigEndian.PutUint16(u16, p.version)
 	_, err = enc.w.Write(u16)
 	if err != nil {
-		return nil
+		return err
 	}
 
+	if len(p.headers) > 0xFFFF || len(p.messages) > 0xFFFF {
+		return errors.New("too many headers or messages")
+	}
 	binary.BigEndian.PutUint16(u16, uint16(len(p.headers)))
 	_, err = enc.w.Write(u16)
 	if err != nil {
 		return err
 	}
 	for i := range p.headers {
+		if p.headers[i] == nil {
+			return errors.New("packet header not set")
+		}
 		err = enc.encodeHeader(p.headers[i])
 		if err != nil {
 			return err
@@ -60,30 +73,43 @@ func (enc *Encoder) encodePacket(p *Packet) (err error) {
 		return err
 	}
 	for i := range p.messages {
+		if p.messages[i] == nil {
+			return errors.New("packet message not set")
+		}
 		err = enc.encodeMessage(p.messages[i])
 		if err != nil {
 			return err
 		}
 	}
 
+	if len(p.Trailing) > 0 {
+		_, err = enc.w.Write(p.Trailing)
+		if err != nil {
+			return err
+		}
+	}
+
 	return
 }
 
 func (enc *Encoder) encodeHeader(h *Header) (err error) {
-	h = &Header{}
 	u8 := make([]byte, 1)
 	u16 := make([]byte, 2)
 	u32 := make([]byte, 4)
 
+	if len(h.name) > 0xFFFF {
+		return errors.New("header name too long")
+	}
+
 	binary.BigEndian.PutUint16(u16, uint16(len(h.name)))
 	_, err = enc.w.Write(u16)
 	if err != nil {
-		return nil
+		return err
 	}
 
 	_, err = enc.w.Write([]byte(h.name))
 	if err != nil {
-		return nil
+		return err
 	}
 
 	if h.mustUnderstand {
@@ -93,27 +119,21 @@ func (enc *Encoder) encodeHeader(h *Header) (err error) {
 	}
 	_, err = enc.w.Write(u8)
 	if err != nil {
-		return nil
+		return err
 	}
 
-	var messageBuffer *bytes.Buffer = &bytes.Buffer{}
-	var amf3Encoder *amf3.Encoder = amf3.NewEncoder(messageBuffer)
-	amf3Encoder.Encode(h.data)
-	var messageLen uint32 = uint32(messageBuffer.Len()) + 1
-
-	binary.BigEndian.PutUint32(u32, messageLen)
-	_, err = enc.w.Write(u32)
+	body, err := enc.encodeBody(h.data)
 	if err != nil {
-		return nil
+		return err
 	}
 
-	//Switch to AMF3
-	_, err = enc.w.Write([]byte{ 0x11 })
+	binary.BigEndian.PutUint32(u32, uint32(len(body)))
+	_, err = enc.w.Write(u32)
 	if err != nil {
-		return nil
+		return err
 	}
 
-	_, err = enc.w.Write(messageBuffer.Bytes())
+	_, err = enc.w.Write(body)
 	if err != nil {
 		return err
 	}
@@ -125,6 +145,10 @@ func (enc *Encoder) encodeMessage(m *Message) (err error) {
 	u16 := make([]byte, 2)
 	u32 := make([]byte, 4)
 
+	if len(m.targetUri) > 0xFFFF || len(m.responseUri) > 0xFFFF {
+		return errors.New("message URI too long")
+	}
+
 	binary.BigEndian.PutUint16(u16, uint16(len(m.targetUri)))
 	_, err = enc.w.Write(u16)
 	if err != nil {
@@ -147,27 +171,65 @@ func (enc *Encoder) encodeMessage(m *Message) (err error) {
 		return err
 	}
 
-	var messageBuffer *bytes.Buffer = &bytes.Buffer{}
-	var amf3Encoder *amf3.Encoder = amf3.NewEncoder(messageBuffer)
-	amf3Encoder.Encode(m.data)
-//	messageLen := uint32(messageBuffer.Len())
-
-	binary.BigEndian.PutUint32(u32, 1)
-	_, err = enc.w.Write(u32)
+	body, err := enc.encodeBody(m.data)
 	if err != nil {
-		return nil
+		return err
 	}
 
-	//Switch to AMF3
-	_, err = enc.w.Write([]byte{ 0x11 })
+	binary.BigEndian.PutUint32(u32, uint32(len(body)))
+	_, err = enc.w.Write(u32)
 	if err != nil {
-		return nil
+		return err
 	}
 
-	_, err = enc.w.Write(messageBuffer.Bytes())
+	_, err = enc.w.Write(body)
 	if err != nil {
 		return err
 	}
 
 	return
 }
+
+// SeedReferences puts objs at the start of the reference table of every
+// header value and message body, as amf0.Encoder.SeedReferences does.
+func (enc *Encoder) SeedReferences(objs []interface{}) {
+	enc.seeds = append([]interface{}(nil), objs...)
+}
+
+// encodeBody encodes a header value or message body in AMF0, switching to
+// AMF3 for AMF3 values. Every body has its own reference table, starting
+// with the seeds.
+func (enc *Encoder) encodeBody(v interface{}) ([]byte, error) {
+	var body bytes.Buffer
+	e := amf0.NewEncoder(&body)
+	if enc.seeds != nil {
+		e.SeedReferences(enc.seeds)
+	}
+	err := e.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+// WriteTo writes the packet to w: its version, each header with its name,
+// must-understand flag and length, and each message with its target and
+// response URIs and length, followed by any trailing bytes. Lengths are
+// always those of the encoded values. It implements io.WriterTo.
+func (p *Packet) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	err := NewEncoder(cw).Encode(p)
+	return cw.n, err
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
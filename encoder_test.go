@@ -2,8 +2,11 @@ package amf
 
 import (
 	"bytes"
+	"errors"
 	"testing"
+	"github.com/marcuswu/amf/amf0"
 	"github.com/marcuswu/amf/amf3"
+	"github.com/marcuswu/amf/amftest"
 //	"os"
 )
 
@@ -41,7 +44,7 @@ func TestWriteAMFPacket(t *testing.T) {
 
 	var expect []byte = []byte{0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, 0x12, 0x4C, 0x6F, 0x67, 0x69, 0x6E, 0x2E,
 	0x70, 0x72, 0x6F, 0x63, 0x65, 0x73, 0x73, 0x4C, 0x6F, 0x67, 0x69, 0x6E, 0x00, 0x02, 0x2F, 0x30, 0x00, 0x00, 0x00,
-	0x01, 0x11, 0x09, 0x03, 0x01, 0x09, 0x0F, 0x01, 0x04, 0x36, 0x04, 0x01, 0x06, 0x33, 0x38, 0x31, 0x62, 0x32, 0x62,
+	0x4C, 0x11, 0x09, 0x03, 0x01, 0x09, 0x0F, 0x01, 0x04, 0x36, 0x04, 0x01, 0x06, 0x33, 0x38, 0x31, 0x62, 0x32, 0x62,
 	0x34, 0x64, 0x65, 0x62, 0x65, 0x37, 0x35, 0x61, 0x34, 0x64, 0x2D, 0x35, 0x36, 0x39, 0x33, 0x39, 0x36, 0x34, 0x34,
 	0x33, 0x06, 0x01, 0x06, 0x1F, 0x33, 0x35, 0x39, 0x31, 0x32, 0x35, 0x30, 0x35, 0x31, 0x35, 0x36, 0x31, 0x32, 0x37,
 	0x34, 0x06, 0x1F, 0x38, 0x31, 0x62, 0x32, 0x62, 0x34, 0x64, 0x65, 0x62, 0x65, 0x37, 0x35, 0x61, 0x34, 0x64, 0x06,
@@ -56,3 +59,128 @@ func TestWriteAMFPacket(t *testing.T) {
 		t.Error("failed -- packet failed to encode to the epected byte array")
 	}
 }
+
+func TestWriteAMFPacketRoundTrip(t *testing.T) {
+	data := []byte{0x00, 0x00, 0x00, 0x01,
+		0x00, 0x01, 'h', 0x01, 0x00, 0x00, 0x00, 0x0b,
+		0x03, 0x00, 0x01, 'a', 0x02, 0x00, 0x01, 'b', 0x00, 0x00, 0x09,
+		0x00, 0x01,
+		0x00, 0x01, 't', 0x00, 0x01, 'r', 0x00, 0x00, 0x00, 0x05,
+		0x0a, 0x00, 0x00, 0x00, 0x00}
+	got, err := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{EnforceLengths: true}).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var buffer bytes.Buffer
+	err = NewEncoder(&buffer).Encode(got)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !bytes.Equal(buffer.Bytes(), data) {
+		t.Errorf("expected %x, got %x", data, buffer.Bytes())
+	}
+}
+
+func TestPacketWriteTo(t *testing.T) {
+	p := NewPacket(0, 0)
+	p.SetVersion(PacketVersion3)
+	p.AddHeader(NewHeader("h", false, amf3.IntegerType(5)))
+	p.AddMessage(NewMessage("t", "/1", &amf3.ArrayType{Dense: []interface{}{amf3.StringType("x")}}))
+	var buffer bytes.Buffer
+	n, err := p.WriteTo(&buffer)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if n != int64(buffer.Len()) {
+		t.Errorf("expected %d bytes written, got %d", buffer.Len(), n)
+	}
+	// lengths must be exact for a length-checking decoder to accept it
+	got, err := NewDecoderWithOptions(&buffer, DecoderOptions{EnforceLengths: true, DisallowTrailingData: true}).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got.Messages()[0].Data().(*amf3.ArrayType).Dense[0] != amf3.StringType("x") {
+		t.Errorf("unexpected body %v", got.Messages()[0].Data())
+	}
+}
+
+func TestEncodeValue(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := EncodeValue(buf, "connect")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	err = EncodeValue(buf, 1)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect := []byte{0x02, 0x00, 0x07, 'c', 'o', 'n', 'n', 'e', 'c', 't', 0x00, 0x3f, 0xf0, 0, 0, 0, 0, 0, 0}
+	if !bytes.Equal(buf.Bytes(), expect) {
+		t.Errorf("expected %x, got %x", expect, buf.Bytes())
+	}
+	err = EncodeValue(buf, make(chan int))
+	if err == nil {
+		t.Errorf("expected error encoding a channel")
+	}
+}
+
+func TestWriteAMFPacketRawBodies(t *testing.T) {
+	data := []byte{0x00, 0x03, 0x00, 0x00,
+		0x00, 0x01,
+		0x00, 0x01, 't', 0x00, 0x01, 'r', 0xff, 0xff, 0xff, 0xff,
+		0x03, 0x00, 0x01, 'a', 0x11, 0x06, 0x03, 'b', 0x00, 0x00, 0x09}
+	got, err := NewDecoderWithOptions(bytes.NewReader(data), DecoderOptions{RawBodies: true}).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	raw, ok := got.messages[0].data.(RawMessage)
+	if !ok || !bytes.Equal(raw, data[16:]) {
+		t.Fatalf("expected raw body %x, got %#v", data[16:], got.messages[0].data)
+	}
+	var buffer bytes.Buffer
+	err = NewEncoder(&buffer).Encode(got)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect := append(append([]byte{}, data[:12]...), 0x00, 0x00, 0x00, byte(len(raw)))
+	expect = append(expect, raw...)
+	if !bytes.Equal(buffer.Bytes(), expect) {
+		t.Errorf("expected %x, got %x", expect, buffer.Bytes())
+	}
+}
+
+func TestWriteAMFPacketFailingWriter(t *testing.T) {
+	p := NewPacket(0, 0)
+	p.AddMessage(NewMessage("a", "/1", "b"))
+	var buffer bytes.Buffer
+	err := NewEncoder(&buffer).Encode(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for n := 0; n < buffer.Len(); n++ {
+		err = NewEncoder(&amftest.FailingWriter{N: int64(n)}).Encode(p)
+		if !errors.Is(err, amftest.ErrWriterFull) {
+			t.Errorf("expected the write error after %d bytes, got %v", n, err)
+		}
+	}
+}
+
+func TestWriteAMFPacketSeedReferences(t *testing.T) {
+	server := &amf0.ObjectType{"name": amf0.StringType("fms")}
+	p := NewPacket(0, 0)
+	p.AddMessage(NewMessage("a", "r", server))
+	p.AddMessage(NewMessage("b", "r", server))
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SeedReferences([]interface{}{server})
+	err := enc.Encode(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect := []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x02,
+		0x00, 0x01, 'a', 0x00, 0x01, 'r', 0x00, 0x00, 0x00, 0x03, 0x07, 0x00, 0x00,
+		0x00, 0x01, 'b', 0x00, 0x01, 'r', 0x00, 0x00, 0x00, 0x03, 0x07, 0x00, 0x00}
+	if !bytes.Equal(buf.Bytes(), expect) {
+		t.Fatalf("expect %x got %x", expect, buf.Bytes())
+	}
+}
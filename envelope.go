@@ -0,0 +1,68 @@
+package amf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// An envelope guards an encoded payload at rest against truncation and
+// corruption. It is laid out as a big-endian uint32 payload length, the
+// payload, and the big-endian IEEE CRC-32 of the payload.
+
+// SealEnvelope wraps payload in an envelope.
+func SealEnvelope(payload []byte) []byte {
+	out := make([]byte, 4, len(payload)+8)
+	binary.BigEndian.PutUint32(out, uint32(len(payload)))
+	out = append(out, payload...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(payload))
+}
+
+// OpenEnvelope validates the envelope in data and returns its payload.
+// Trailing bytes after the envelope are an error.
+func OpenEnvelope(data []byte) ([]byte, error) {
+	if len(data) < 8 {
+		return nil, errors.New("envelope truncated")
+	}
+	n := binary.BigEndian.Uint32(data)
+	if uint64(len(data)) != uint64(n)+8 {
+		if uint64(len(data)) < uint64(n)+8 {
+			return nil, errors.New("envelope truncated")
+		}
+		return nil, errors.New("data after envelope")
+	}
+	return checkEnvelope(data[4:4+n], data[4+n:])
+}
+
+// ReadEnvelope reads one envelope from r and returns its payload. It returns
+// io.EOF when r ends before the envelope starts.
+func ReadEnvelope(r io.Reader) ([]byte, error) {
+	prefix := make([]byte, 4)
+	_, err := io.ReadFull(r, prefix)
+	if err != nil {
+		return nil, err
+	}
+	// grow the buffer as data arrives so that a corrupt length cannot
+	// allocate more than the stream holds
+	size := int64(binary.BigEndian.Uint32(prefix)) + 4
+	var buf bytes.Buffer
+	_, err = io.CopyN(&buf, r, size)
+	if err == io.EOF {
+		return nil, errors.New("envelope truncated")
+	}
+	if err != nil {
+		return nil, err
+	}
+	data := buf.Bytes()
+	n := len(data) - 4
+	return checkEnvelope(data[:n], data[n:])
+}
+
+func checkEnvelope(payload, sum []byte) ([]byte, error) {
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(sum) {
+		return nil, errors.New("envelope checksum mismatch")
+	}
+	return payload, nil
+}
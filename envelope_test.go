@@ -0,0 +1,52 @@
+package amf
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+	payload := []byte{0x02, 0x00, 0x01, 'a'}
+	sealed := SealEnvelope(payload)
+	got, err := OpenEnvelope(sealed)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("expect %v got %v", payload, got)
+	}
+
+	_, err = OpenEnvelope(sealed[:len(sealed)-1])
+	if err == nil {
+		t.Errorf("expect error for truncated envelope")
+	}
+	corrupt := append([]byte(nil), sealed...)
+	corrupt[5] ^= 0xff
+	_, err = OpenEnvelope(corrupt)
+	if err == nil {
+		t.Errorf("expect error for corrupt payload")
+	}
+}
+
+func TestReadEnvelope(t *testing.T) {
+	stream := append(SealEnvelope([]byte{0x05}), SealEnvelope([]byte{0x01, 0x01})...)
+	r := bytes.NewReader(stream)
+	for _, expect := range [][]byte{{0x05}, {0x01, 0x01}} {
+		got, err := ReadEnvelope(r)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if !bytes.Equal(got, expect) {
+			t.Errorf("expect %v got %v", expect, got)
+		}
+	}
+	_, err := ReadEnvelope(r)
+	if err != io.EOF {
+		t.Errorf("expect EOF got %v", err)
+	}
+	_, err = ReadEnvelope(bytes.NewReader(stream[:6]))
+	if err == nil || err == io.EOF {
+		t.Errorf("expect truncation error got %v", err)
+	}
+}
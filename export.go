@@ -0,0 +1,149 @@
+package amf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// exportMagic starts every export, followed by the format version.
+var exportMagic = []byte{'A', 'M', 'F', 'X', 1}
+
+// ExportHeader describes the payloads of an export, so that they can be
+// interpreted without the code that produced them.
+type ExportHeader struct {
+	Dialect string        `json:"dialect"` // "amf0" or "amf3"
+	Classes []ExportClass `json:"classes"`
+}
+
+// ExportClass describes one kind of object found in the payloads.
+type ExportClass struct {
+	Name   string   `json:"name"`
+	Typed  bool     `json:"typed"` // whether Name is an ActionScript class name
+	Fields []string `json:"fields"`
+}
+
+// Export writes values to w in the given object encoding, AMF0 or AMF3,
+// preceded by a header listing the classes and fields they hold. The header
+// and every payload are sealed in an envelope (see SealEnvelope).
+func Export(w io.Writer, version int, values []interface{}) error {
+	header := ExportHeader{Dialect: dialectName(version)}
+	if header.Dialect == "" {
+		return errors.New("unknown object encoding")
+	}
+	// the header describes the values as encoded, so Go structs and maps
+	// are observed as the objects a reader decodes
+	payloads := make([][]byte, len(values))
+	schema := NewSchema()
+	for i, v := range values {
+		data, err := encodeSingle(v, version)
+		if err != nil {
+			return err
+		}
+		decoded, err := decodeTree(data, version)
+		if err != nil {
+			return err
+		}
+		schema.Observe("Value", decoded)
+		payloads[i] = data
+	}
+	for _, c := range schema.Classes() {
+		fields := make([]string, 0, len(c.Fields))
+		for name := range c.Fields {
+			fields = append(fields, name)
+		}
+		sort.Strings(fields)
+		header.Classes = append(header.Classes, ExportClass{Name: c.Name, Typed: c.Typed, Fields: fields})
+	}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.Write(exportMagic)
+	buf.Write(SealEnvelope(data))
+	for _, data := range payloads {
+		buf.Write(SealEnvelope(data))
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// decodeTree decodes the single value in data as AMF types, without the
+// class mappings and aliases that turn objects into Go values.
+func decodeTree(data []byte, version int) (interface{}, error) {
+	br := bytes.NewReader(data)
+	var v interface{}
+	var err error
+	amf3Opts := amf3.DecoderOptions{Registry: amf3.NewRegistry()}
+	if version == AMF0 {
+		v, err = amf0.NewDecoderWithOptions(br, amf0.DecoderOptions{Registry: amf0.NewRegistry(), AMF3: amf3Opts}).Decode()
+	} else {
+		v, err = amf3.NewDecoderWithOptions(br, amf3Opts).Decode()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ExportReader reads the payloads written by Export.
+type ExportReader struct {
+	Header  ExportHeader
+	r       io.Reader
+	version int
+}
+
+// NewExportReader reads the header of the export in r.
+func NewExportReader(r io.Reader) (*ExportReader, error) {
+	magic := make([]byte, len(exportMagic))
+	_, err := io.ReadFull(r, magic)
+	if err != nil || !bytes.Equal(magic, exportMagic) {
+		return nil, errors.New("not an AMF export")
+	}
+	data, err := ReadEnvelope(r)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	er := &ExportReader{r: r}
+	err = json.Unmarshal(data, &er.Header)
+	if err != nil {
+		return nil, err
+	}
+	switch er.Header.Dialect {
+	case "amf0":
+		er.version = AMF0
+	case "amf3":
+		er.version = AMF3
+	default:
+		return nil, errors.New("unknown export dialect " + er.Header.Dialect)
+	}
+	return er, nil
+}
+
+// Next decodes the next payload. It returns io.EOF after the last one.
+func (er *ExportReader) Next() (interface{}, error) {
+	data, err := ReadEnvelope(er.r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSingle(data, er.version)
+}
+
+func dialectName(version int) string {
+	switch version {
+	case AMF0:
+		return "amf0"
+	case AMF3:
+		return "amf3"
+	}
+	return ""
+}
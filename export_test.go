@@ -0,0 +1,70 @@
+package amf
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/marcuswu/amf/amf0"
+)
+
+func TestExport(t *testing.T) {
+	values := []interface{}{
+		&amf0.TypedObjectType{ClassName: "com.example.Archived", Object: map[amf0.StringType]interface{}{"name": amf0.StringType("ann")}},
+		amf0.NumberType(7),
+	}
+	var buf bytes.Buffer
+	err := Export(&buf, AMF0, values)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	er, err := NewExportReader(&buf)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect := ExportHeader{Dialect: "amf0", Classes: []ExportClass{{Name: "com.example.Archived", Typed: true, Fields: []string{"name"}}}}
+	if !reflect.DeepEqual(er.Header, expect) {
+		t.Errorf("expect %+v got %+v", expect, er.Header)
+	}
+	for _, v := range values {
+		got, err := er.Next()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if !reflect.DeepEqual(got, v) {
+			t.Errorf("expect %v got %v", v, got)
+		}
+	}
+	if _, err := er.Next(); err != io.EOF {
+		t.Errorf("expect EOF got %v", err)
+	}
+
+	if _, err := NewExportReader(bytes.NewReader([]byte("AMF0"))); err == nil {
+		t.Errorf("expect error for missing magic")
+	}
+}
+
+func TestExportGoValues(t *testing.T) {
+	type user struct {
+		Name string `amf:"name"`
+	}
+	amf0.RegisterClass(amf0.ClassMapping{ClassName: "com.example.ExportUser", Type: reflect.TypeOf(user{})})
+	values := []interface{}{&user{Name: "ann"}, map[string]interface{}{"id": 1}}
+	var buf bytes.Buffer
+	err := Export(&buf, AMF0, values)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	er, err := NewExportReader(&buf)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expect := ExportHeader{Dialect: "amf0", Classes: []ExportClass{
+		{Name: "Value", Fields: []string{"id"}},
+		{Name: "com.example.ExportUser", Typed: true, Fields: []string{"name"}},
+	}}
+	if !reflect.DeepEqual(er.Header, expect) {
+		t.Errorf("expect %+v got %+v", expect, er.Header)
+	}
+}
@@ -0,0 +1,31 @@
+package amf
+
+import (
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// Names that say which dialect a type or constructor belongs to, for code
+// that uses the packet and value codecs side by side.
+type (
+	AMF0Packet  = Packet
+	AMF0Header  = Header
+	AMF0Message = Message
+	AMF0Encoder = Encoder
+	AMF0Decoder = Decoder
+
+	AMF0ValueEncoder = amf0.Encoder
+	AMF0ValueDecoder = amf0.Decoder
+	AMF3Encoder      = amf3.Encoder
+	AMF3Decoder      = amf3.Decoder
+)
+
+var (
+	NewAMF0Encoder = NewEncoder
+	NewAMF0Decoder = NewDecoder
+
+	NewAMF0ValueEncoder = amf0.NewEncoder
+	NewAMF0ValueDecoder = amf0.NewDecoder
+	NewAMF3Encoder      = amf3.NewEncoder
+	NewAMF3Decoder      = amf3.NewDecoder
+)
@@ -0,0 +1,119 @@
+package flex
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/marcuswu/amf"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// Bridge returns the service for the target URI "null", to which Flex
+// clients send their messages, serving them with the services of rt, as
+// registered for AMF0 clients:
+//
+//	g.Handle("null", flex.Bridge(g.Router()))
+//
+// A RemotingMessage calling operation op of destination d is served by the
+// service for the target URI "d.op", with the arguments held in its body,
+// and answered with an AcknowledgeMessage holding the result, or an
+// ErrorMessage. Pings are acknowledged with a new client ID, logouts and
+// disconnects with nothing; other commands fail.
+func Bridge(rt *amf.Router) amf.ServiceFunc {
+	return func(call *amf.Call) (interface{}, error) {
+		if len(call.Args) != 1 {
+			return nil, errors.New("expected one Flex message, got " + strconv.Itoa(len(call.Args)) + " values")
+		}
+		m, err := FromValue(call.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		switch m := m.(type) {
+		case *RemotingMessage:
+			return serveRemoting(rt, call, m)
+		case *CommandMessage:
+			switch m.Operation {
+			case ClientPingOperation:
+				ack := acknowledge(m.Abstract(), nil)
+				if ack.ClientID == "" {
+					ack.ClientID = newID()
+				}
+				ack.Headers = map[string]interface{}{FlexClientIDHeader: ack.ClientID}
+				return ack, nil
+			case LogoutOperation, DisconnectOperation:
+				return acknowledge(m.Abstract(), nil), nil
+			}
+			return nil, fault(m.Abstract(), &amf.Fault{
+				Code:        "Server.Processing",
+				Description: "unsupported command operation " + strconv.Itoa(m.Operation),
+			})
+		}
+		return nil, errors.New("unsupported Flex message")
+	}
+}
+
+func serveRemoting(rt *amf.Router, call *amf.Call, m *RemotingMessage) (interface{}, error) {
+	target := m.Destination + "." + m.Operation
+	fn := rt.Lookup(target)
+	if fn == nil {
+		return nil, fault(m.Abstract(), &amf.Fault{Code: "Server.ResourceNotFound", Description: "no service for " + target})
+	}
+	result, err := fn(&amf.Call{Request: call.Request, Headers: call.Headers, Target: target, Args: bodyArgs(m.Body)})
+	if err != nil {
+		return nil, fault(m.Abstract(), err)
+	}
+	// the acknowledgement is encoded in AMF3, which the gateway does not
+	// check for
+	if err := amf3.CanEncode(result); err != nil {
+		return nil, fault(m.Abstract(), err)
+	}
+	return acknowledge(m.Abstract(), result), nil
+}
+
+// bodyArgs returns the arguments held in the body of a RemotingMessage.
+func bodyArgs(body interface{}) []interface{} {
+	switch value := body.(type) {
+	case *amf3.ArrayType:
+		return value.Dense
+	case *amf3.ArrayCollection:
+		return []interface{}(*value)
+	case []interface{}:
+		return value
+	case nil, amf3.NullType, amf3.UndefinedType:
+		return nil
+	}
+	return []interface{}{body}
+}
+
+// acknowledge returns the reply to m holding body.
+func acknowledge(m *AbstractMessage, body interface{}) *AcknowledgeMessage {
+	ack := &AcknowledgeMessage{}
+	ack.Body = body
+	ack.ClientID = m.ClientID
+	ack.Destination = m.Destination
+	ack.MessageID = newID()
+	ack.Timestamp = time.Now().UnixMilli()
+	ack.CorrelationID = m.MessageID
+	return ack
+}
+
+// fault returns the ErrorMessage replying to m with err, an *amf.Fault
+// keeping its code.
+func fault(m *AbstractMessage, err error) *ErrorMessage {
+	var f *amf.Fault
+	if !errors.As(err, &f) {
+		f = &amf.Fault{Code: "Server.Processing", Description: err.Error()}
+	}
+	reply := &ErrorMessage{AcknowledgeMessage: *acknowledge(m, nil)}
+	reply.FaultCode = f.Code
+	reply.FaultString = f.Description
+	reply.FaultDetail = f.Details
+	return reply
+}
+
+// StatusBody makes an ErrorMessage the body of the onStatus message
+// reporting it.
+func (m *ErrorMessage) StatusBody() interface{} {
+	return m
+}
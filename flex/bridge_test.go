@@ -0,0 +1,77 @@
+package flex
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/marcuswu/amf"
+	"github.com/marcuswu/amf/amf3"
+)
+
+func TestBridge(t *testing.T) {
+	g := amf.NewGatewayHandler()
+	g.Handle("userService.find", func(call *amf.Call) (interface{}, error) {
+		return call.Args[0], nil
+	})
+	g.Handle("userService.fail", func(call *amf.Call) (interface{}, error) {
+		return nil, &amf.Fault{Code: "User.NotFound", Description: "no such user"}
+	})
+	g.Handle("null", Bridge(g.Router()))
+
+	req := amf.NewPacket(0, 0)
+	req.SetVersion(amf.PacketVersion3)
+	ping := NewPingCommand()
+	call := NewRemotingMessage("userService", "find", "ann")
+	failing := NewRemotingMessage("userService", "fail")
+	missing := NewRemotingMessage("userService", "drop")
+	for i, m := range []Message{ping, call, failing, missing} {
+		req.AddMessage(NewRequest("/"+string(rune('1'+i)), m))
+	}
+	data, err := amf.EncodePacket(req)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/gateway", bytes.NewReader(data))
+	r.Header.Set("Content-Type", amf.ContentTypeAMF)
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+	resp, err := amf.DecodePacket(w.Body.Bytes())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	msgs := resp.Messages()
+	if len(msgs) != 4 {
+		t.Fatalf("expect 4 replies got %v", msgs)
+	}
+
+	reply, err := FromValue(msgs[0].Data())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	ack, ok := reply.(*AcknowledgeMessage)
+	if msgs[0].TargetURI() != "/1/onResult" || !ok || ack.CorrelationID != ping.MessageID || len(ack.ClientID) != 36 {
+		t.Errorf("expect the ping acknowledged with a client ID got %s %+v", msgs[0].TargetURI(), reply)
+	}
+
+	reply, err = FromValue(msgs[1].Data())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	ack, ok = reply.(*AcknowledgeMessage)
+	if msgs[1].TargetURI() != "/2/onResult" || !ok || ack.CorrelationID != call.MessageID || ack.Body != amf3.StringType("ann") {
+		t.Errorf("expect the result acknowledged got %s %+v", msgs[1].TargetURI(), reply)
+	}
+
+	for i, code := range map[int]string{2: "User.NotFound", 3: "Server.ResourceNotFound"} {
+		reply, err = FromValue(msgs[i].Data())
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		fault, ok := reply.(*ErrorMessage)
+		if msgs[i].TargetURI() != "/"+string(rune('1'+i))+"/onStatus" || !ok || fault.FaultCode != code {
+			t.Errorf("expect an ErrorMessage with code %s got %s %+v", code, msgs[i].TargetURI(), reply)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Package flex provides the Flex messaging envelopes that BlazeDS and LCDS
+// endpoints exchange over AMF: remoting calls, commands such as ping and
+// login, acknowledgements and errors.
+//
+// Importing the package registers the message classes, so that the message
+// types encode as AMF3 typed objects, switching to AMF3 inside AMF0 packets.
+// Decoded messages are ObjectTypes, or Ext types for the small forms;
+// FromValue turns either into messages. Bridge serves Flex clients with the
+// services of an amf gateway.
+package flex
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/marcuswu/amf"
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// Classes of the Flex messages.
+const (
+	RemotingMessageClass    = "flex.messaging.messages.RemotingMessage"
+	AcknowledgeMessageClass = "flex.messaging.messages.AcknowledgeMessage"
+	CommandMessageClass     = "flex.messaging.messages.CommandMessage"
+	ErrorMessageClass       = "flex.messaging.messages.ErrorMessage"
+)
+
+// Operations of a CommandMessage.
+const (
+	SubscribeOperation      = 0
+	UnsubscribeOperation    = 1
+	PollOperation           = 2
+	ClientSyncOperation     = 4
+	ClientPingOperation     = 5
+	ClusterRequestOperation = 7
+	LoginOperation          = 8
+	LogoutOperation         = 9
+	DisconnectOperation     = 12
+)
+
+// Message headers.
+const (
+	// EndpointHeader names the endpoint a message is sent to.
+	EndpointHeader = "DSEndpoint"
+	// FlexClientIDHeader carries the client ID handed out by the server,
+	// "nil" until then.
+	FlexClientIDHeader = "DSId"
+	// MessagingVersionHeader carries the version of the messaging protocol
+	// spoken by the client.
+	MessagingVersionHeader = "DSMessagingVersion"
+)
+
+// Message is implemented by the pointers to the message types.
+type Message interface {
+	Abstract() *AbstractMessage
+}
+
+// AbstractMessage holds the members every message has.
+type AbstractMessage struct {
+	Body        interface{}            `amf:"body"`
+	ClientID    string                 `amf:"clientId"`
+	Destination string                 `amf:"destination"`
+	Headers     map[string]interface{} `amf:"headers"`
+	MessageID   string                 `amf:"messageId"`
+	Timestamp   int64                  `amf:"timestamp"`  // milliseconds since the epoch
+	TimeToLive  int64                  `amf:"timeToLive"` // in milliseconds
+}
+
+func (m *AbstractMessage) Abstract() *AbstractMessage {
+	return m
+}
+
+// AsyncMessage is a message that may be the reply to another.
+type AsyncMessage struct {
+	AbstractMessage
+	CorrelationID string `amf:"correlationId"`
+}
+
+// RemotingMessage calls the operation of a remote object destination, with
+// the arguments in Body.
+type RemotingMessage struct {
+	AbstractMessage
+	Operation string `amf:"operation"`
+	Source    string `amf:"source"`
+}
+
+// CommandMessage asks the messaging system itself for an operation, such as
+// ClientPingOperation or LoginOperation.
+type CommandMessage struct {
+	AsyncMessage
+	Operation int `amf:"operation"`
+}
+
+// AcknowledgeMessage replies to a message that succeeded, with the result in
+// Body.
+type AcknowledgeMessage struct {
+	AsyncMessage
+}
+
+// ErrorMessage replies to a message that failed.
+type ErrorMessage struct {
+	AcknowledgeMessage
+	FaultCode    string      `amf:"faultCode"`
+	FaultString  string      `amf:"faultString"`
+	FaultDetail  string      `amf:"faultDetail"`
+	RootCause    interface{} `amf:"rootCause"`
+	ExtendedData interface{} `amf:"extendedData"`
+}
+
+func (m *ErrorMessage) Error() string {
+	return m.FaultCode + ": " + m.FaultString
+}
+
+var classes = map[string]reflect.Type{
+	RemotingMessageClass:    reflect.TypeOf(RemotingMessage{}),
+	AcknowledgeMessageClass: reflect.TypeOf(AcknowledgeMessage{}),
+	CommandMessageClass:     reflect.TypeOf(CommandMessage{}),
+	ErrorMessageClass:       reflect.TypeOf(ErrorMessage{}),
+}
+
+func init() {
+	for className, t := range classes {
+		amf3.RegisterAlias(className, t)
+		amf0.RegisterEncoder(reflect.PointerTo(t), encodeAMF3)
+	}
+}
+
+func encodeAMF3(enc *amf0.Encoder, v interface{}) error {
+	return enc.EncodeAMF3(v)
+}
+
+// FromValue returns the message a decoded value stands for: an AMF3 object
+// of one of the message classes, a small message, or an AMF0 array holding
+// one, as in the body of a request.
+func FromValue(v interface{}) (Message, error) {
+	if array, ok := v.(*amf0.StrictArrayType); ok && len(*array) == 1 {
+		v = (*array)[0]
+	}
+	if m, ok := smallMessage(v); ok {
+		return m, nil
+	}
+	obj, ok := v.(*amf3.ObjectType)
+	if !ok || obj.Trait == nil {
+		return nil, errors.New("value is not a Flex message")
+	}
+	t, ok := classes[string(obj.Trait.ClassName)]
+	if !ok {
+		return nil, errors.New("unknown message class " + string(obj.Trait.ClassName))
+	}
+	m := reflect.New(t).Interface().(Message)
+	err := amf0.Assign(m, obj)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// NewRemotingMessage returns a call of operation on destination with args.
+func NewRemotingMessage(destination, operation string, args ...interface{}) *RemotingMessage {
+	if args == nil {
+		args = []interface{}{}
+	}
+	m := &RemotingMessage{Operation: operation}
+	m.init(destination, args)
+	return m
+}
+
+// NewPingCommand returns the ping a client sends before its first call, to
+// be handed its client ID.
+func NewPingCommand() *CommandMessage {
+	m := &CommandMessage{Operation: ClientPingOperation}
+	m.init("", map[string]interface{}{})
+	m.Headers[MessagingVersionHeader] = 1
+	return m
+}
+
+// NewLoginCommand returns a login with the given credentials.
+func NewLoginCommand(username, password string) *CommandMessage {
+	m := &CommandMessage{Operation: LoginOperation}
+	m.init("", base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+	return m
+}
+
+func (m *AbstractMessage) init(destination string, body interface{}) {
+	m.Body = body
+	m.Destination = destination
+	m.Headers = map[string]interface{}{FlexClientIDHeader: "nil"}
+	m.MessageID = newID()
+	m.Timestamp = time.Now().UnixMilli()
+}
+
+// newID returns a random UUID in the upper case form Flex uses.
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b[:])
+}
+
+// NewRequest returns the packet message that sends m to a BlazeDS or LCDS
+// endpoint, which take the target URI "null" and the message in an array.
+// Replies come back to responseURI+"/onResult" or responseURI+"/onStatus".
+func NewRequest(responseURI string, m Message) *amf.Message {
+	return amf.NewMessage("null", responseURI, []interface{}{m})
+}
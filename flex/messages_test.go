@@ -0,0 +1,120 @@
+package flex
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/marcuswu/amf"
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+func TestRemotingRequest(t *testing.T) {
+	p := amf.NewPacket(0, 0)
+	p.SetVersion(amf.PacketVersion3)
+	p.AddMessage(NewRequest("/1", NewRemotingMessage("userService", "find", 7)))
+	data, err := amf.EncodePacket(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !bytes.Contains(data, []byte{0x0a, 0x00, 0x00, 0x00, 0x01, 0x11, 0x0a}) {
+		t.Fatalf("expect an array holding one AMF3 object in %x", data)
+	}
+
+	p, err = amf.DecodePacket(data)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	m, err := FromValue(p.Messages()[0].Data())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	call, ok := m.(*RemotingMessage)
+	if !ok || call.Destination != "userService" || call.Operation != "find" || len(call.MessageID) != 36 {
+		t.Fatalf("unexpected message %+v", m)
+	}
+	if call.Headers[FlexClientIDHeader] != amf3.StringType("nil") {
+		t.Fatalf("expect DSId nil got %v", call.Headers)
+	}
+	if args, ok := call.Body.(*amf3.ArrayType); !ok || len(args.Dense) != 1 || args.Dense[0] != amf3.IntegerType(7) {
+		t.Fatalf("expect arguments [7] got %v", call.Body)
+	}
+}
+
+func TestErrorReply(t *testing.T) {
+	reply := &ErrorMessage{FaultCode: "Server.Processing", FaultString: "no such user"}
+	reply.CorrelationID = "A"
+	var buf bytes.Buffer
+	err := amf0.NewEncoder(&buf).Encode(reply)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	v, err := amf0.NewDecoder(&buf).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	m, err := FromValue(v)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	e, ok := m.(*ErrorMessage)
+	if !ok || e.CorrelationID != "A" || e.Error() != "Server.Processing: no such user" {
+		t.Fatalf("unexpected message %+v", m)
+	}
+	if _, err := FromValue(amf3.StringType("a")); err == nil {
+		t.Fatalf("expect error for a value that is not a message")
+	}
+}
+
+func TestCommands(t *testing.T) {
+	ping := NewPingCommand()
+	if ping.Operation != ClientPingOperation || ping.Headers[MessagingVersionHeader] != 1 {
+		t.Fatalf("unexpected ping %+v", ping)
+	}
+	login := NewLoginCommand("user", "secret")
+	if login.Operation != LoginOperation || login.Body != "dXNlcjpzZWNyZXQ=" {
+		t.Fatalf("unexpected login %+v", login)
+	}
+}
+
+func TestSmallMessages(t *testing.T) {
+	data := []byte{0x0a, 0x07, 0x07, 'D', 'S', 'K'}
+	data = append(data, 0x91, 0x01, 0x04, 0x05, 0x06, 0x03, 'M', 0x0c, 0x21)
+	data = append(data, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0)
+	data = append(data, 0x01, 0x06, 0x03, 'C')
+	// a member unknown to us
+	data = append(data, 0x01, 0x01)
+	v, err := amf3.NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	m, err := FromValue(v)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	ack, ok := m.(*AcknowledgeMessage)
+	if !ok || ack.Body != amf3.IntegerType(5) || ack.MessageID != "M" || ack.CorrelationID != "C" ||
+		ack.ClientID != "12345678-9ABC-DEF0-1234-56789ABCDEF0" {
+		t.Fatalf("unexpected message %+v", m)
+	}
+
+	cmd := &CommandMessageExt{}
+	cmd.Operation = ClientPingOperation
+	cmd.Destination = "d"
+	var buf bytes.Buffer
+	err = amf3.NewEncoder(&buf).Encode(cmd)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	v, err = amf3.NewDecoder(&buf).Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	m, err = FromValue(v)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got, ok := m.(*CommandMessage); !ok || got.Operation != ClientPingOperation || got.Destination != "d" {
+		t.Fatalf("unexpected message %+v", m)
+	}
+}
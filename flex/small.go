@@ -0,0 +1,206 @@
+package flex
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// Classes of the small forms of messages, which BlazeDS and LCDS send to
+// save space: externalizable objects whose members are announced by flags.
+const (
+	AsyncMessageExtClass       = "DSA"
+	AcknowledgeMessageExtClass = "DSK"
+	CommandMessageExtClass     = "DSC"
+)
+
+// AsyncMessageExt is the small form of an AsyncMessage.
+type AsyncMessageExt struct {
+	AsyncMessage
+}
+
+// AcknowledgeMessageExt is the small form of an AcknowledgeMessage.
+type AcknowledgeMessageExt struct {
+	AcknowledgeMessage
+}
+
+// CommandMessageExt is the small form of a CommandMessage.
+type CommandMessageExt struct {
+	CommandMessage
+}
+
+func init() {
+	amf3.RegisterExternalizable(AsyncMessageExtClass, reflect.TypeOf(AsyncMessageExt{}))
+	amf3.RegisterExternalizable(AcknowledgeMessageExtClass, reflect.TypeOf(AcknowledgeMessageExt{}))
+	amf3.RegisterExternalizable(CommandMessageExtClass, reflect.TypeOf(CommandMessageExt{}))
+}
+
+func (m *AsyncMessageExt) ReadExternal(dec *amf3.Decoder) error {
+	return m.AsyncMessage.readExternal(dec)
+}
+
+func (m *AsyncMessageExt) WriteExternal(enc *amf3.Encoder) error {
+	return m.AsyncMessage.writeExternal(enc)
+}
+
+func (m *AcknowledgeMessageExt) ReadExternal(dec *amf3.Decoder) error {
+	err := m.AsyncMessage.readExternal(dec)
+	if err != nil {
+		return err
+	}
+	// no members of its own yet
+	return readMembers(dec, nil)
+}
+
+func (m *AcknowledgeMessageExt) WriteExternal(enc *amf3.Encoder) error {
+	err := m.AsyncMessage.writeExternal(enc)
+	if err != nil {
+		return err
+	}
+	return writeMembers(enc, nil)
+}
+
+func (m *CommandMessageExt) ReadExternal(dec *amf3.Decoder) error {
+	err := m.AsyncMessage.readExternal(dec)
+	if err != nil {
+		return err
+	}
+	return readMembers(dec, [][]interface{}{{&m.Operation}})
+}
+
+func (m *CommandMessageExt) WriteExternal(enc *amf3.Encoder) error {
+	err := m.AsyncMessage.writeExternal(enc)
+	if err != nil {
+		return err
+	}
+	return writeMembers(enc, []interface{}{m.Operation})
+}
+
+// smallMessage returns the message a small message stands for.
+func smallMessage(v interface{}) (Message, bool) {
+	switch m := v.(type) {
+	case *AsyncMessageExt:
+		return &m.AsyncMessage, true
+	case *AcknowledgeMessageExt:
+		return &m.AcknowledgeMessage, true
+	case *CommandMessageExt:
+		return &m.CommandMessage, true
+	}
+	return nil, false
+}
+
+// uuidBytes stands for an ID sent as the 16 bytes of a UUID; readMembers
+// stores it in the string it points to.
+type uuidBytes struct {
+	id *string
+}
+
+func (m *AsyncMessage) readExternal(dec *amf3.Decoder) error {
+	err := m.AbstractMessage.readExternal(dec)
+	if err != nil {
+		return err
+	}
+	return readMembers(dec, [][]interface{}{{&m.CorrelationID, uuidBytes{&m.CorrelationID}}})
+}
+
+func (m *AsyncMessage) writeExternal(enc *amf3.Encoder) error {
+	err := m.AbstractMessage.writeExternal(enc)
+	if err != nil {
+		return err
+	}
+	return writeMembers(enc, []interface{}{m.CorrelationID})
+}
+
+func (m *AbstractMessage) readExternal(dec *amf3.Decoder) error {
+	return readMembers(dec, [][]interface{}{
+		{&m.Body, &m.ClientID, &m.Destination, &m.Headers, &m.MessageID, &m.Timestamp, &m.TimeToLive},
+		{uuidBytes{&m.ClientID}, uuidBytes{&m.MessageID}},
+	})
+}
+
+func (m *AbstractMessage) writeExternal(enc *amf3.Encoder) error {
+	return writeMembers(enc, []interface{}{
+		m.Body, m.ClientID, m.Destination, m.Headers, m.MessageID, m.Timestamp, m.TimeToLive,
+	})
+}
+
+// readMembers reads the flag bytes of one class of a small message, then
+// the members they announce into the pointers of dst, dst[i] standing for
+// the bits of flag byte i. Members unknown to dst are read and dropped, so
+// that messages from newer servers still decode.
+func readMembers(dec *amf3.Decoder, dst [][]interface{}) error {
+	var flags []byte
+	for {
+		var b [1]byte
+		_, err := io.ReadFull(dec, b[:])
+		if err != nil {
+			return err
+		}
+		flags = append(flags, b[0])
+		if b[0]&0x80 == 0 {
+			break
+		}
+	}
+	for i, f := range flags {
+		var known []interface{}
+		if i < len(dst) {
+			known = dst[i]
+		}
+		// as in BlazeDS, an unknown member in the bit before the
+		// continuation bit is not read
+		for bit := 0; bit < 7; bit++ {
+			if f&(1<<bit) == 0 || bit >= len(known) && bit >= 6 {
+				continue
+			}
+			v, err := dec.Decode()
+			if err != nil {
+				return err
+			}
+			if bit >= len(known) {
+				continue
+			}
+			if id, ok := known[bit].(uuidBytes); ok {
+				if b, ok := v.(*amf3.ByteArrayType); ok && len(*b) == 16 {
+					*id.id = formatUUID((*b)[:])
+				}
+				continue
+			}
+			err = amf0.Assign(known[bit], v)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeMembers writes the flag byte announcing the values of vs that are
+// not zero, followed by those values.
+func writeMembers(enc *amf3.Encoder, vs []interface{}) error {
+	var flags byte
+	for i, v := range vs {
+		if v != nil && !reflect.ValueOf(v).IsZero() {
+			flags |= 1 << i
+		}
+	}
+	_, err := enc.Write([]byte{flags})
+	if err != nil {
+		return err
+	}
+	for i, v := range vs {
+		if flags&(1<<i) != 0 {
+			err = enc.Encode(v)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func formatUUID(b []byte) string {
+	return fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,41 @@
+package amf
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// FloatFormat controls how numbers are written as text. The zero value
+// writes the shortest representation that reads back as the same float64,
+// the way ActionScript prints numbers, as in 0.1, 1e+21 and 1e-7, so that
+// text taken from a payload and encoded again gives the same bytes.
+type FloatFormat struct {
+	// Fixed writes Precision digits after the decimal point instead.
+	Fixed     bool
+	Precision int
+}
+
+// Format returns the text form of f.
+func (ff FloatFormat) Format(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	if ff.Fixed {
+		return strconv.FormatFloat(f, 'f', ff.Precision, 64)
+	}
+	if abs := math.Abs(f); abs == 0 || (abs >= 1e-6 && abs < 1e21) {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	// ActionScript writes exponents without a leading zero: 1e-7, not 1e-07
+	if i := strings.LastIndexAny(s, "+-"); i > 0 && s[i+1] == '0' {
+		s = s[:i+1] + s[i+2:]
+	}
+	return s
+}
@@ -0,0 +1,31 @@
+package amf
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFloatFormat(t *testing.T) {
+	tests := []struct {
+		ff     FloatFormat
+		f      float64
+		expect string
+	}{
+		{FloatFormat{}, 3, "3"},
+		{FloatFormat{}, 0.1, "0.1"},
+		{FloatFormat{}, 1e21, "1e+21"},
+		{FloatFormat{}, 123456789012, "123456789012"},
+		{FloatFormat{}, 1e-7, "1e-7"},
+		{FloatFormat{}, -2.5e-10, "-2.5e-10"},
+		{FloatFormat{}, -1e-7, "-1e-7"},
+		{FloatFormat{}, math.Inf(-1), "-Infinity"},
+		{FloatFormat{Fixed: true, Precision: 2}, 1.005, "1.00"},
+		{FloatFormat{Fixed: true, Precision: 2}, 3, "3.00"},
+	}
+	for _, test := range tests {
+		got := test.ff.Format(test.f)
+		if got != test.expect {
+			t.Errorf("expected %s for %v, got %s", test.expect, test.f, got)
+		}
+	}
+}
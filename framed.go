@@ -0,0 +1,80 @@
+package amf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Framing describes a big-endian length prefix written before each value,
+// outside the AMF envelope, as some gateways do.
+type Framing struct {
+	Size      int  // size of the prefix in bytes, 2 or 4
+	Inclusive bool // whether the length counts the prefix itself
+	// MaxFrameSize limits the length of a frame, failing with a
+	// *LengthError before reading it; 0 means no limit.
+	MaxFrameSize int64
+}
+
+// FramedDecoder decodes a stream of length-prefixed AMF0 or AMF3 values.
+// Each frame must hold exactly one value.
+type FramedDecoder struct {
+	r       io.Reader
+	version int
+	framing Framing
+}
+
+// NewFramedDecoder returns a decoder for the values of the given version,
+// AMF0 or AMF3, framed as described by framing.
+func NewFramedDecoder(r io.Reader, version int, framing Framing) *FramedDecoder {
+	return &FramedDecoder{r: r, version: version, framing: framing}
+}
+
+// Decode decodes the value of the next frame. It returns io.EOF when the
+// stream ends between frames.
+func (fd *FramedDecoder) Decode() (interface{}, error) {
+	frame, err := fd.readFrame()
+	if err != nil {
+		return nil, err
+	}
+	return decodeSingle(frame, fd.version)
+}
+
+func (fd *FramedDecoder) readFrame() ([]byte, error) {
+	size := fd.framing.Size
+	if size != 2 && size != 4 {
+		return nil, errors.New("frame length prefix must be 2 or 4 bytes")
+	}
+	prefix := make([]byte, size)
+	_, err := io.ReadFull(fd.r, prefix)
+	if err != nil {
+		return nil, err
+	}
+	var n uint32
+	if size == 2 {
+		n = uint32(binary.BigEndian.Uint16(prefix))
+	} else {
+		n = binary.BigEndian.Uint32(prefix)
+	}
+	if fd.framing.Inclusive {
+		if n < uint32(size) {
+			return nil, errors.New("frame length shorter than its prefix")
+		}
+		n -= uint32(size)
+	}
+	if limit := fd.framing.MaxFrameSize; limit > 0 && int64(n) > limit {
+		return nil, &LengthError{What: "frame length", Length: uint64(n), Limit: limit}
+	}
+	// grow the buffer as data arrives so that a corrupt length cannot
+	// allocate more than the stream holds
+	var buf bytes.Buffer
+	_, err = io.CopyN(&buf, fd.r, int64(n))
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,61 @@
+package amf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+func TestFramedDecoder(t *testing.T) {
+	data := []byte{0x00, 0x04, 0x02, 0x00, 0x01, 'a', 0x00, 0x02, 0x01, 0x01}
+	dec := NewFramedDecoder(bytes.NewReader(data), AMF0, Framing{Size: 2})
+	for _, expect := range []interface{}{amf0.StringType("a"), amf0.BooleanType(true)} {
+		got, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if got != expect {
+			t.Errorf("expected %v, got %v", expect, got)
+		}
+	}
+	_, err := dec.Decode()
+	if err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+
+	data = []byte{0x00, 0x00, 0x00, 0x06, 0x04, 0x05}
+	dec = NewFramedDecoder(bytes.NewReader(data), AMF3, Framing{Size: 4, Inclusive: true})
+	got, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got != amf3.IntegerType(5) {
+		t.Errorf("expected 5, got %v", got)
+	}
+
+	data = []byte{0x00, 0x02, 0x05, 0x05}
+	_, err = NewFramedDecoder(bytes.NewReader(data), AMF0, Framing{Size: 2}).Decode()
+	if err == nil {
+		t.Errorf("expected error for two values in a frame")
+	}
+	data = []byte{0x00, 0x04, 0x05}
+	_, err = NewFramedDecoder(bytes.NewReader(data), AMF0, Framing{Size: 2}).Decode()
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("expected unexpected EOF for a short frame, got %v", err)
+	}
+
+	data = []byte{0xff, 0xff, 0xff, 0xff, 0x05}
+	_, err = NewFramedDecoder(bytes.NewReader(data), AMF0, Framing{Size: 4, MaxFrameSize: 1 << 20}).Decode()
+	var le *LengthError
+	if !errors.As(err, &le) || le.Length != 0xffffffff {
+		t.Errorf("expected a LengthError for an oversized frame, got %v", err)
+	}
+	_, err = NewFramedDecoder(bytes.NewReader(data), AMF0, Framing{Size: 4}).Decode()
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("expected unexpected EOF for a corrupt length, got %v", err)
+	}
+}
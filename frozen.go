@@ -0,0 +1,350 @@
+package amf
+
+import (
+	"errors"
+	"maps"
+	"reflect"
+	"sort"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// ErrFrozen is returned when changing a frozen value.
+var ErrFrozen = errors.New("value is frozen")
+
+// Frozen is a read-only view of a decoded container, made by Freeze. Its
+// accessors return the contained values frozen in turn, so no part of the
+// tree can be changed through it, and it is safe for concurrent use.
+// Encoders of packages amf0 and amf3, and so packet encoders, write the
+// value it views; Copy returns a tree that may be changed.
+type Frozen struct {
+	v interface{}
+}
+
+func init() {
+	amf0.RegisterEncoder(reflect.TypeOf(&Frozen{}), func(enc *amf0.Encoder, v interface{}) error {
+		return enc.Encode(v.(*Frozen).v)
+	})
+}
+
+// AMF3Value returns the value f views, for the encoders of package amf3.
+func (f *Frozen) AMF3Value() (interface{}, error) {
+	return f.v, nil
+}
+
+// Freeze returns a read-only view of v, a value decoded by the amf0 or amf3
+// packages, for sharing a tree between goroutines, as with cached
+// templates. Containers and byte arrays become *Frozen, dates and XML held
+// by pointer become the values they point to, and other values are
+// returned as they are. The view does not copy v: v must not be changed
+// once frozen.
+func Freeze(v interface{}) interface{} {
+	switch value := v.(type) {
+	case amf0.ObjectType:
+		return &Frozen{&value}
+	case amf0.EcmaArrayType:
+		return &Frozen{&value}
+	case amf0.StrictArrayType:
+		return &Frozen{&value}
+	case amf3.ByteArrayType:
+		return &Frozen{&value}
+	case amf3.VectorIntType:
+		return &Frozen{&value}
+	case amf3.VectorUintType:
+		return &Frozen{&value}
+	case amf3.VectorDoubleType:
+		return &Frozen{&value}
+	case amf3.VectorObjectType:
+		return &Frozen{&value}
+	case amf3.DictionaryType:
+		return &Frozen{&value}
+	case *amf0.ObjectType, *amf0.EcmaArrayType, *amf0.StrictArrayType, *amf0.TypedObjectType,
+		*amf3.ArrayType, *amf3.ObjectType, *amf3.ByteArrayType, *amf3.VectorIntType,
+		*amf3.VectorUintType, *amf3.VectorDoubleType, *amf3.VectorObjectType,
+		*amf3.DictionaryType, *amf3.ArrayCollection, *amf3.ObjectProxy:
+		return &Frozen{v}
+	case *amf3.DateType:
+		return *value
+	case *amf3.XMLType:
+		return *value
+	case *amf3.XMLDocumentType:
+		return *value
+	}
+	return v
+}
+
+// Kind returns the kind of the frozen value.
+func (f *Frozen) Kind() Kind {
+	switch f.v.(type) {
+	case *amf3.ByteArrayType:
+		return ByteArray
+	case *amf3.ArrayCollection:
+		return Array
+	case *amf3.ObjectProxy:
+		return Object
+	}
+	return KindOf(f.v)
+}
+
+// ClassName returns the class name of a typed object, or "".
+func (f *Frozen) ClassName() string {
+	name, _ := className(f.v)
+	return name
+}
+
+// Len returns the number of properties and items of the value, the number
+// of entries of a dictionary or the length of a byte array.
+func (f *Frozen) Len() int {
+	switch value := f.v.(type) {
+	case *amf0.ObjectType:
+		return len(*value)
+	case *amf0.EcmaArrayType:
+		return len(*value)
+	case *amf0.StrictArrayType:
+		return len(*value)
+	case *amf0.TypedObjectType:
+		return len(value.Object)
+	case *amf3.ArrayType:
+		return len(value.Associative) + len(value.Dense)
+	case *amf3.ObjectType:
+		return len(value.Static) + len(value.Dynamic)
+	case *amf3.ByteArrayType:
+		return len(*value)
+	case *amf3.VectorIntType:
+		return len(value.Items)
+	case *amf3.VectorUintType:
+		return len(value.Items)
+	case *amf3.VectorDoubleType:
+		return len(value.Items)
+	case *amf3.VectorObjectType:
+		return len(value.Items)
+	case *amf3.DictionaryType:
+		return len(value.Entries)
+	case *amf3.ArrayCollection:
+		return len(*value)
+	case *amf3.ObjectProxy:
+		return len(*value)
+	}
+	return 0
+}
+
+// Keys returns the property names of the value, the sealed members of an
+// AMF3 object first and the others by name.
+func (f *Frozen) Keys() []string {
+	var keys []string
+	forEachChild(f.v, func(elem string, child interface{}) {
+		if elem[0] == '.' {
+			keys = append(keys, elem[1:])
+		}
+	})
+	if p, ok := f.v.(*amf3.ObjectProxy); ok {
+		for name := range *p {
+			keys = append(keys, string(name))
+		}
+		sort.Strings(keys)
+	}
+	return keys
+}
+
+// Get returns the frozen property name of an object or ECMA array.
+func (f *Frozen) Get(name string) (interface{}, bool) {
+	var v interface{}
+	var ok bool
+	switch value := f.v.(type) {
+	case *amf0.ObjectType:
+		v, ok = (*value)[amf0.StringType(name)]
+	case *amf0.EcmaArrayType:
+		v, ok = (*value)[amf0.StringType(name)]
+	case *amf0.TypedObjectType:
+		v, ok = value.Object[amf0.StringType(name)]
+	case *amf3.ArrayType:
+		v, ok = value.Associative[amf3.StringType(name)]
+	case *amf3.ObjectType:
+		if value.Trait != nil {
+			for i, attr := range value.Trait.Attrs {
+				if string(attr) == name && i < len(value.Static) {
+					return Freeze(value.Static[i]), true
+				}
+			}
+		}
+		v, ok = value.Dynamic[amf3.StringType(name)]
+	case *amf3.ObjectProxy:
+		v, ok = (*value)[amf3.StringType(name)]
+	}
+	if !ok {
+		return nil, false
+	}
+	return Freeze(v), true
+}
+
+// Index returns the frozen item i of an array or vector, the value of entry
+// i of a dictionary or byte i of a byte array.
+func (f *Frozen) Index(i int) (interface{}, bool) {
+	if i < 0 || i >= f.Len() {
+		return nil, false
+	}
+	switch value := f.v.(type) {
+	case *amf0.StrictArrayType:
+		return Freeze((*value)[i]), true
+	case *amf3.ArrayType:
+		if i < len(value.Dense) {
+			return Freeze(value.Dense[i]), true
+		}
+	case *amf3.ByteArrayType:
+		return (*value)[i], true
+	case *amf3.VectorIntType:
+		return value.Items[i], true
+	case *amf3.VectorUintType:
+		return value.Items[i], true
+	case *amf3.VectorDoubleType:
+		return value.Items[i], true
+	case *amf3.VectorObjectType:
+		return Freeze(value.Items[i]), true
+	case *amf3.DictionaryType:
+		return Freeze(value.Entries[i].Value), true
+	case *amf3.ArrayCollection:
+		return Freeze((*value)[i]), true
+	}
+	return nil, false
+}
+
+// Set fails with ErrFrozen.
+func (f *Frozen) Set(name string, v interface{}) error {
+	return ErrFrozen
+}
+
+// SetIndex fails with ErrFrozen.
+func (f *Frozen) SetIndex(i int, v interface{}) error {
+	return ErrFrozen
+}
+
+// Copy returns a deep copy of the frozen value, which may be changed.
+// Values shared within the tree are shared within the copy.
+func (f *Frozen) Copy() interface{} {
+	return thaw(f.v, make(map[interface{}]interface{}))
+}
+
+// thaw copies the containers of v, copying each shared one once.
+func thaw(v interface{}, copies map[interface{}]interface{}) interface{} {
+	if c, ok := copies[identity(v)]; ok {
+		return c
+	}
+	switch value := v.(type) {
+	case *Frozen:
+		return thaw(value.v, copies)
+	case *amf0.ObjectType:
+		c := make(amf0.ObjectType, len(*value))
+		copies[v] = &c
+		for k, child := range *value {
+			c[k] = thaw(child, copies)
+		}
+		return &c
+	case *amf0.EcmaArrayType:
+		c := make(amf0.EcmaArrayType, len(*value))
+		copies[v] = &c
+		for k, child := range *value {
+			c[k] = thaw(child, copies)
+		}
+		return &c
+	case *amf0.TypedObjectType:
+		c := &amf0.TypedObjectType{ClassName: value.ClassName, Object: maps.Clone(value.Object)}
+		copies[v] = c
+		for k, child := range c.Object {
+			c.Object[k] = thaw(child, copies)
+		}
+		return c
+	case *amf0.StrictArrayType:
+		c := make(amf0.StrictArrayType, len(*value))
+		copies[v] = &c
+		for i, child := range *value {
+			c[i] = thaw(child, copies)
+		}
+		return &c
+	case *amf3.ArrayType:
+		c := &amf3.ArrayType{Associative: make(map[amf3.StringType]interface{}, len(value.Associative))}
+		copies[v] = c
+		for k, child := range value.Associative {
+			c.Associative[k] = thaw(child, copies)
+		}
+		c.Dense = thawSlice(value.Dense, copies)
+		return c
+	case *amf3.ObjectType:
+		c := &amf3.ObjectType{Trait: value.Trait}
+		copies[v] = c
+		if value.Trait != nil {
+			trait := *value.Trait
+			trait.Attrs = append([]amf3.StringType(nil), trait.Attrs...)
+			c.Trait = &trait
+		}
+		c.Static = thawSlice(value.Static, copies)
+		if value.Dynamic != nil {
+			c.Dynamic = make(map[amf3.StringType]interface{}, len(value.Dynamic))
+			for k, child := range value.Dynamic {
+				c.Dynamic[k] = thaw(child, copies)
+			}
+		}
+		return c
+	case *amf3.ByteArrayType:
+		c := append(amf3.ByteArrayType(nil), *value...)
+		copies[v] = &c
+		return &c
+	case *amf3.VectorIntType:
+		c := &amf3.VectorIntType{Fixed: value.Fixed, Items: append([]int32(nil), value.Items...)}
+		copies[v] = c
+		return c
+	case *amf3.VectorUintType:
+		c := &amf3.VectorUintType{Fixed: value.Fixed, Items: append([]uint32(nil), value.Items...)}
+		copies[v] = c
+		return c
+	case *amf3.VectorDoubleType:
+		c := &amf3.VectorDoubleType{Fixed: value.Fixed, Items: append([]float64(nil), value.Items...)}
+		copies[v] = c
+		return c
+	case *amf3.VectorObjectType:
+		c := &amf3.VectorObjectType{Fixed: value.Fixed, TypeName: value.TypeName}
+		copies[v] = c
+		c.Items = thawSlice(value.Items, copies)
+		return c
+	case *amf3.DictionaryType:
+		c := &amf3.DictionaryType{WeakKeys: value.WeakKeys, Entries: make([]amf3.DictionaryEntry, len(value.Entries))}
+		copies[v] = c
+		for i, entry := range value.Entries {
+			c.Entries[i] = amf3.DictionaryEntry{Key: thaw(entry.Key, copies), Value: thaw(entry.Value, copies)}
+		}
+		return c
+	case *amf3.ArrayCollection:
+		c := new(amf3.ArrayCollection)
+		copies[v] = c
+		*c = thawSlice(*value, copies)
+		return c
+	case *amf3.ObjectProxy:
+		c := make(amf3.ObjectProxy, len(*value))
+		copies[v] = &c
+		for k, child := range *value {
+			c[k] = thaw(child, copies)
+		}
+		return &c
+	case *amf3.DateType:
+		c := *value
+		return &c
+	case *amf3.XMLType:
+		c := *value
+		return &c
+	case *amf3.XMLDocumentType:
+		c := *value
+		return &c
+	}
+	return v
+}
+
+func thawSlice(s []interface{}, copies map[interface{}]interface{}) []interface{} {
+	if s == nil {
+		return nil
+	}
+	c := make([]interface{}, len(s))
+	for i, child := range s {
+		c[i] = thaw(child, copies)
+	}
+	return c
+}
@@ -0,0 +1,96 @@
+package amf
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+func TestFreeze(t *testing.T) {
+	inner := &amf3.ObjectType{
+		Trait:   &amf3.Trait{ClassName: "Item", Attrs: []amf3.StringType{"id"}},
+		Static:  []interface{}{amf3.IntegerType(1)},
+		Dynamic: map[amf3.StringType]interface{}{},
+	}
+	date := amf3.DateType(5)
+	tree := &amf0.ObjectType{
+		"items": &amf3.ArrayType{Dense: []interface{}{inner, inner}},
+		"when":  &date,
+	}
+	f, ok := Freeze(tree).(*Frozen)
+	if !ok || f.Kind() != Object || f.Len() != 2 {
+		t.Fatalf("unexpected frozen value %v", Freeze(tree))
+	}
+	if keys := f.Keys(); len(keys) != 2 || keys[0] != "items" || keys[1] != "when" {
+		t.Fatalf("expect keys [items when] got %v", keys)
+	}
+	if when, _ := f.Get("when"); when != date {
+		t.Fatalf("expect the date by value got %v", when)
+	}
+	items, _ := f.Get("items")
+	item, _ := items.(*Frozen).Index(0)
+	if item.(*Frozen).ClassName() != "Item" {
+		t.Fatalf("expect an Item got %v", item)
+	}
+	if id, _ := item.(*Frozen).Get("id"); id != amf3.IntegerType(1) {
+		t.Fatalf("expect id 1 got %v", id)
+	}
+	if f.Set("a", 1) != ErrFrozen || items.(*Frozen).SetIndex(0, 1) != ErrFrozen {
+		t.Fatalf("expect ErrFrozen")
+	}
+
+	c := f.Copy().(*amf0.ObjectType)
+	copied := (*c)["items"].(*amf3.ArrayType)
+	if copied.Dense[0] == inner || copied.Dense[0] != copied.Dense[1] {
+		t.Fatalf("expect one copy of the shared item")
+	}
+	copied.Dense[0].(*amf3.ObjectType).Static[0] = amf3.IntegerType(2)
+	if inner.Static[0] != amf3.IntegerType(1) {
+		t.Fatalf("copy changed the frozen tree")
+	}
+
+	var frozen, plain bytes.Buffer
+	err := amf0.NewEncoder(&frozen).Encode(items)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	amf0.NewEncoder(&plain).Encode((*tree)["items"])
+	if !bytes.Equal(frozen.Bytes(), plain.Bytes()) {
+		t.Fatalf("expect %x got %x", plain.Bytes(), frozen.Bytes())
+	}
+}
+
+func TestFreezeEncodeAMF3(t *testing.T) {
+	inner := &amf3.ObjectType{
+		Trait:   &amf3.Trait{ClassName: "Item", Attrs: []amf3.StringType{"id"}},
+		Static:  []interface{}{amf3.IntegerType(1)},
+		Dynamic: map[amf3.StringType]interface{}{},
+	}
+	tree := &amf3.ArrayType{Dense: []interface{}{inner, inner}}
+	var frozen, plain bytes.Buffer
+	err := amf3.NewEncoder(&frozen).Encode(Freeze(tree))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	amf3.NewEncoder(&plain).Encode(tree)
+	if !bytes.Equal(frozen.Bytes(), plain.Bytes()) {
+		t.Fatalf("expect %x got %x", plain.Bytes(), frozen.Bytes())
+	}
+	if err = amf3.CanEncode(Freeze(tree)); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	frozen.Reset()
+	plain.Reset()
+	opts := amf0.EncoderOptions{AVMPlus: true}
+	err = amf0.NewEncoderWithOptions(&frozen, opts).Encode(Freeze(tree))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	amf0.NewEncoderWithOptions(&plain, opts).Encode(tree)
+	if !bytes.Equal(frozen.Bytes(), plain.Bytes()) {
+		t.Fatalf("expect %x got %x", plain.Bytes(), frozen.Bytes())
+	}
+}
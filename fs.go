@@ -0,0 +1,41 @@
+package amf
+
+import (
+	"io/fs"
+	"iter"
+)
+
+// DecodedFile is a packet decoded from a file by DecodeFS.
+type DecodedFile struct {
+	Path   string
+	Packet *Packet
+}
+
+// DecodeFS decodes every file of fsys matching glob, in lexical order, as
+// with fs.Glob. A file that fails to decode yields a *DecodeError and the
+// walk carries on with the next file; a malformed glob yields a single
+// error.
+func DecodeFS(fsys fs.FS, glob string) iter.Seq2[DecodedFile, error] {
+	return func(yield func(DecodedFile, error) bool) {
+		names, err := fs.Glob(fsys, glob)
+		if err != nil {
+			yield(DecodedFile{}, err)
+			return
+		}
+		for _, name := range names {
+			p, err := decodeFSFile(fsys, name)
+			if !yield(DecodedFile{Path: name, Packet: p}, err) {
+				return
+			}
+		}
+	}
+}
+
+func decodeFSFile(fsys fs.FS, name string) (*Packet, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, &DecodeError{Name: name, Err: err}
+	}
+	defer f.Close()
+	return decodeNamed(name, f, DecoderOptions{})
+}
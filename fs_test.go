@@ -0,0 +1,43 @@
+package amf
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDecodeFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"captures/a.amf": {Data: []byte{0x00, 0x03, 0x00, 0x00, 0x00, 0x00}},
+		"captures/b.amf": {Data: []byte{0x00, 0x03}},
+		"captures/c.txt": {Data: []byte("notes")},
+	}
+	var paths []string
+	var failed []string
+	for file, err := range DecodeFS(fsys, "captures/*.amf") {
+		var decodeErr *DecodeError
+		if errors.As(err, &decodeErr) {
+			failed = append(failed, decodeErr.Name)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if file.Packet.version != 3 {
+			t.Errorf("expected version 3, got %d", file.Packet.version)
+		}
+		paths = append(paths, file.Path)
+	}
+	if len(paths) != 1 || paths[0] != "captures/a.amf" {
+		t.Errorf("expected captures/a.amf to decode, got %v", paths)
+	}
+	if len(failed) != 1 || failed[0] != "captures/b.amf" {
+		t.Errorf("expected captures/b.amf to fail, got %v", failed)
+	}
+
+	for _, err := range DecodeFS(fsys, "[") {
+		if err == nil {
+			t.Errorf("expected error for malformed glob")
+		}
+	}
+}
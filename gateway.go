@@ -0,0 +1,184 @@
+package amf
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// Call is one message of a remoting request, as handed to a ServiceFunc.
+type Call struct {
+	Request *http.Request
+	Headers []*Header // the headers of the packet carrying the call
+	Target  string    // the target URI, such as "UserService.find"
+	Args    []interface{}
+}
+
+// ServiceFunc serves the calls to one target URI. Its result is sent back
+// in an onResult message, encoded as by amf0.Encoder; an error is sent in
+// an onStatus message, see Fault.
+type ServiceFunc func(call *Call) (interface{}, error)
+
+// Fault is an error reported to the caller in an onStatus message. Errors
+// that are not a *Fault are reported with the code "Server.Processing".
+type Fault struct {
+	Code        string
+	Description string
+	Details     string
+}
+
+func (f *Fault) Error() string {
+	return f.Code + ": " + f.Description
+}
+
+// StatusError is an error that makes up the body of its onStatus message
+// itself, such as the ErrorMessage a Flex client expects.
+type StatusError interface {
+	error
+	StatusBody() interface{}
+}
+
+// GatewayHandler is a Flash Remoting gateway. It serves application/x-amf
+// POST requests by calling the service registered for the target URI of
+// every message, and answers with a packet holding a message for each, sent
+// to the call's response URI followed by /onResult or /onStatus.
+type GatewayHandler struct {
+	router *Router
+	// DecoderOptions configures the decoding of requests, as for
+	// NewDecoderWithOptions.
+	DecoderOptions DecoderOptions
+	// MaxBodySize limits the size in bytes of request bodies, answering
+	// larger ones with 413 Request Entity Too Large; 0 means
+	// DefaultMaxBodySize and a negative size means no limit.
+	MaxBodySize int64
+}
+
+// DefaultMaxBodySize is the request body limit of a GatewayHandler whose
+// MaxBodySize is 0.
+const DefaultMaxBodySize = 8 << 20
+
+func NewGatewayHandler() *GatewayHandler {
+	return NewGatewayHandlerWithRouter(NewRouter())
+}
+
+// NewGatewayHandlerWithRouter returns a gateway serving the calls routed by
+// rt.
+func NewGatewayHandlerWithRouter(rt *Router) *GatewayHandler {
+	return &GatewayHandler{router: rt}
+}
+
+// Handle registers fn for the target URI target, replacing any service
+// registered before. Patterns are routed as by Router.Handle.
+func (g *GatewayHandler) Handle(target string, fn ServiceFunc) {
+	g.router.Handle(target, fn)
+}
+
+// Router returns the router of g, for adding patterns, regular expressions,
+// middleware and a fallback.
+func (g *GatewayHandler) Router() *Router {
+	return g.router
+}
+
+func (g *GatewayHandler) service(target string) ServiceFunc {
+	return g.router.Lookup(target)
+}
+
+func (g *GatewayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "AMF gateways only accept POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != ContentTypeAMF {
+		http.Error(w, "expected "+ContentTypeAMF, http.StatusUnsupportedMediaType)
+		return
+	}
+	body := r.Body
+	if limit := g.maxBodySize(); limit > 0 {
+		body = http.MaxBytesReader(w, r.Body, limit)
+	}
+	req, err := NewDecoderWithOptions(body, g.DecoderOptions).Decode()
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "AMF packet over "+strconv.FormatInt(tooLarge.Limit, 10)+" bytes", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, "malformed AMF packet: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := NewPacket(0, 0)
+	resp.SetVersion(req.Version())
+	for _, m := range req.Messages() {
+		resp.AddMessage(g.serve(r, req, m))
+	}
+	data, err := EncodePacket(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ContentTypeAMF)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+}
+
+func (g *GatewayHandler) maxBodySize() int64 {
+	if g.MaxBodySize == 0 {
+		return DefaultMaxBodySize
+	}
+	return g.MaxBodySize
+}
+
+// serve calls the service for m and returns the response message.
+func (g *GatewayHandler) serve(r *http.Request, req *Packet, m *Message) *Message {
+	fn := g.service(m.TargetURI())
+	if fn == nil {
+		return faultMessage(m, &Fault{Code: "Server.ResourceNotFound", Description: "no service for " + m.TargetURI()})
+	}
+	result, err := fn(&Call{Request: r, Headers: req.Headers(), Target: m.TargetURI(), Args: callArgs(m.Data())})
+	if err != nil {
+		return faultMessage(m, err)
+	}
+	// an unencodable result is reported to the caller rather than failing
+	// the whole response
+	if err := amf0.CanEncode(result); err != nil {
+		return faultMessage(m, err)
+	}
+	return NewMessage(m.ResponseURI()+"/onResult", "", result)
+}
+
+func faultMessage(m *Message, err error) *Message {
+	var se StatusError
+	if errors.As(err, &se) {
+		return NewMessage(m.ResponseURI()+"/onStatus", "", se.StatusBody())
+	}
+	var f *Fault
+	if !errors.As(err, &f) {
+		f = &Fault{Code: "Server.Processing", Description: err.Error()}
+	}
+	status := &amf0.ObjectType{
+		"level":       amf0.StringType("error"),
+		"code":        amf0.StringType(f.Code),
+		"description": amf0.StringType(f.Description),
+		"details":     amf0.StringType(f.Details),
+	}
+	return NewMessage(m.ResponseURI()+"/onStatus", "", status)
+}
+
+// callArgs returns the arguments held in a message body, which is normally
+// an array of them.
+func callArgs(body interface{}) []interface{} {
+	switch value := body.(type) {
+	case *amf0.StrictArrayType:
+		return []interface{}(*value)
+	case *amf3.ArrayType:
+		return value.Dense
+	case nil, amf0.NullType, amf0.UndefinedType:
+		return nil
+	}
+	return []interface{}{body}
+}
@@ -0,0 +1,155 @@
+package amf
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/marcuswu/amf/amf0"
+)
+
+func TestGatewayHandler(t *testing.T) {
+	g := NewGatewayHandler()
+	g.Handle("Echo.echo", func(call *Call) (interface{}, error) {
+		return call.Args[0], nil
+	})
+	g.Handle("Echo.fail", func(call *Call) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+
+	req := NewPacket(0, 0)
+	req.AddMessage(NewMessage("Echo.echo", "/1", []interface{}{"hi"}))
+	req.AddMessage(NewMessage("Echo.fail", "/2", []interface{}{}))
+	req.AddMessage(NewMessage("Missing.call", "/3", nil))
+	data, err := EncodePacket(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/gateway", bytes.NewReader(data))
+	r.Header.Set("Content-Type", ContentTypeAMF)
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != ContentTypeAMF {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	resp, err := DecodePacket(w.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := resp.Messages()
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 messages, got %v", msgs)
+	}
+	if msgs[0].TargetURI() != "/1/onResult" || msgs[0].Data() != amf0.StringType("hi") {
+		t.Errorf("unexpected result %v %v", msgs[0].TargetURI(), msgs[0].Data())
+	}
+	for i, code := range map[int]string{1: "Server.Processing", 2: "Server.ResourceNotFound"} {
+		status, ok := msgs[i].Data().(*amf0.ObjectType)
+		if msgs[i].TargetURI() != req.Messages()[i].ResponseURI()+"/onStatus" || !ok || (*status)["code"] != amf0.StringType(code) {
+			t.Errorf("expected %s status, got %v %v", code, msgs[i].TargetURI(), msgs[i].Data())
+		}
+	}
+
+	w = httptest.NewRecorder()
+	g.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/gateway", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for GET, got %d", w.Code)
+	}
+}
+
+type serviceUser struct {
+	ID   int    `amf:"id"`
+	Name string `amf:"name"`
+}
+
+type userService struct{}
+
+func (userService) Find(id int) (*serviceUser, error) {
+	if id != 7 {
+		return nil, &Fault{Code: "User.NotFound", Description: "no user " + strconv.Itoa(id)}
+	}
+	return &serviceUser{ID: id, Name: "ann"}, nil
+}
+
+func (userService) Rename(call *Call, u serviceUser, name string) serviceUser {
+	u.Name = name + " via " + call.Target
+	return u
+}
+
+func TestGatewayLimits(t *testing.T) {
+	g := NewGatewayHandler()
+	g.Handle("Echo.echo", func(call *Call) (interface{}, error) {
+		return call.Args[0], nil
+	})
+	req := NewPacket(0, 0)
+	req.AddMessage(NewMessage("Echo.echo", "/1", []interface{}{"hi", "there"}))
+	data, err := EncodePacket(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := func() int {
+		r := httptest.NewRequest(http.MethodPost, "/gateway", bytes.NewReader(data))
+		r.Header.Set("Content-Type", ContentTypeAMF)
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, r)
+		return w.Code
+	}
+	if code := post(); code != http.StatusOK {
+		t.Fatalf("expect 200 got %d", code)
+	}
+	g.MaxBodySize = int64(len(data) - 1)
+	if code := post(); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expect 413 got %d", code)
+	}
+	g.MaxBodySize = -1
+	g.DecoderOptions = DecoderOptions{MaxValueCount: 2}
+	if code := post(); code != http.StatusBadRequest {
+		t.Errorf("expect 400 got %d", code)
+	}
+}
+
+func TestGatewayRegisterService(t *testing.T) {
+	g := NewGatewayHandler()
+	if err := g.RegisterService("UserService", userService{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.RegisterService("Empty", struct{}{}); err == nil {
+		t.Errorf("expected error for a service without methods")
+	}
+
+	req := NewPacket(0, 0)
+	req.AddMessage(NewMessage("UserService.find", "/1", []interface{}{7}))
+	req.AddMessage(NewMessage("UserService.Find", "/2", []interface{}{8}))
+	req.AddMessage(NewMessage("UserService.rename", "/3", []interface{}{map[string]interface{}{"id": 1, "name": "bob"}, "rob"}))
+	req.AddMessage(NewMessage("UserService.find", "/4", []interface{}{}))
+	data, err := EncodePacket(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/gateway", bytes.NewReader(data))
+	r.Header.Set("Content-Type", ContentTypeAMF)
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+	resp, err := DecodePacket(w.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := resp.Messages()
+	var u serviceUser
+	if err := amf0.Assign(&u, msgs[0].Data()); err != nil || u != (serviceUser{7, "ann"}) {
+		t.Errorf("unexpected find result %v %v", msgs[0].Data(), err)
+	}
+	if status, ok := msgs[1].Data().(*amf0.ObjectType); !ok || (*status)["code"] != amf0.StringType("User.NotFound") {
+		t.Errorf("expected User.NotFound status, got %v %v", msgs[1].TargetURI(), msgs[1].Data())
+	}
+	u = serviceUser{}
+	if err := amf0.Assign(&u, msgs[2].Data()); err != nil || u != (serviceUser{1, "rob via UserService.rename"}) {
+		t.Errorf("unexpected rename result %v %v", msgs[2].Data(), err)
+	}
+	if msgs[3].TargetURI() != "/4/onStatus" {
+		t.Errorf("expected status for a wrong argument count, got %v", msgs[3].TargetURI())
+	}
+}
@@ -0,0 +1,128 @@
+package amf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// DumpGraph writes the object graph of v, a decoded value or *Packet, in
+// Graphviz DOT format. Every container is a node; a container reached a
+// second time, through an AMF reference or a shared Go pointer, is joined by
+// a dashed edge instead of being drawn again, so sharing is easy to spot.
+func DumpGraph(w io.Writer, v interface{}) error {
+	return DumpGraphWithOptions(w, v, GraphOptions{})
+}
+
+// GraphOptions configures DumpGraphWithOptions.
+type GraphOptions struct {
+	// Float formats numbers in node labels.
+	Float FloatFormat
+}
+
+func DumpGraphWithOptions(w io.Writer, v interface{}, opts GraphOptions) error {
+	g := &graph{w: bufio.NewWriter(w), ids: make(map[interface{}]string), opts: opts}
+	fmt.Fprintln(g.w, "digraph amf {")
+	fmt.Fprintln(g.w, "\tnode [shape=box];")
+	if p, ok := v.(*Packet); ok {
+		root := g.newNode(fmt.Sprintf("packet v%d", p.version))
+		for i, h := range p.headers {
+			if h == nil {
+				continue
+			}
+			id := g.newNode(fmt.Sprintf("header %s", h.name))
+			g.edge(root, id, fmt.Sprintf("headers[%d]", i), false)
+			g.edge(id, g.value(h.data), "data", g.shared(h.data))
+		}
+		for i, m := range p.messages {
+			if m == nil {
+				continue
+			}
+			id := g.newNode(fmt.Sprintf("message %s %s", m.targetUri, m.responseUri))
+			g.edge(root, id, fmt.Sprintf("messages[%d]", i), false)
+			g.edge(id, g.value(m.data), "data", g.shared(m.data))
+		}
+	} else {
+		g.value(v)
+	}
+	fmt.Fprintln(g.w, "}")
+	return g.w.Flush()
+}
+
+type graph struct {
+	w    *bufio.Writer
+	n    int
+	ids  map[interface{}]string
+	opts GraphOptions
+}
+
+func (g *graph) newNode(label string) string {
+	id := fmt.Sprintf("n%d", g.n)
+	g.n++
+	fmt.Fprintf(g.w, "\t%s [label=%s];\n", id, dotQuote(label))
+	return id
+}
+
+func (g *graph) edge(from, to, label string, ref bool) {
+	style := ""
+	if ref {
+		style = ", style=dashed"
+	}
+	fmt.Fprintf(g.w, "\t%s -> %s [label=%s%s];\n", from, to, dotQuote(label), style)
+}
+
+// shared reports whether v is a container that already has a node.
+func (g *graph) shared(v interface{}) bool {
+	id := identity(v)
+	if id == nil {
+		return false
+	}
+	_, ok := g.ids[id]
+	return ok
+}
+
+// value returns the node for v, writing it and its children if needed.
+func (g *graph) value(v interface{}) string {
+	key := identity(v)
+	if key != nil {
+		if id, ok := g.ids[key]; ok {
+			return id
+		}
+	}
+	if !isContainer(v) {
+		switch n := v.(type) {
+		case amf0.NumberType:
+			return g.newNode(fmt.Sprintf("%T %s", v, g.opts.Float.Format(float64(n))))
+		case amf3.DoubleType:
+			return g.newNode(fmt.Sprintf("%T %s", v, g.opts.Float.Format(float64(n))))
+		}
+		return g.newNode(fmt.Sprintf("%T %v", v, v))
+	}
+	label := fmt.Sprintf("%T", v)
+	if name, ok := className(v); ok {
+		label += " " + name
+	}
+	if obj, ok := v.(*amf3.ObjectType); ok {
+		label += "\n" + traitText(obj.Trait)
+	}
+	id := g.newNode(label)
+	if key != nil {
+		g.ids[key] = id
+	}
+	forEachChild(v, func(elem string, child interface{}) {
+		ref := g.shared(child)
+		g.edge(id, g.value(child), elem, ref)
+	})
+	return id
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,40 @@
+package amf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/marcuswu/amf/amf0"
+)
+
+func TestDumpGraph(t *testing.T) {
+	shared := amf0.ObjectType{"id": amf0.NumberType(1)}
+	root := amf0.StrictArrayType{&shared, &shared}
+	var buf bytes.Buffer
+	err := DumpGraph(&buf, &root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "digraph amf {\n") || !strings.HasSuffix(out, "}\n") {
+		t.Errorf("expected a digraph, got:\n%s", out)
+	}
+	if !strings.Contains(out, "n0 -> n1 [label=\"[0]\"];") {
+		t.Errorf("expected edge to the first element, got:\n%s", out)
+	}
+	if !strings.Contains(out, "n0 -> n1 [label=\"[1]\", style=dashed];") {
+		t.Errorf("expected dashed reference edge to the shared object, got:\n%s", out)
+	}
+}
+
+func TestDumpGraphFloatFormat(t *testing.T) {
+	var buf bytes.Buffer
+	err := DumpGraphWithOptions(&buf, amf0.NumberType(1e21), GraphOptions{Float: FloatFormat{Fixed: true, Precision: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"amf0.NumberType 1000000000000000000000.0"`) {
+		t.Errorf("expected fixed number label, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,259 @@
+package amf
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// Hash returns a SHA-256 digest of the structure of v, a value decoded by
+// the amf0 or amf3 packages, for telling whether two payloads carry the
+// same data. It does not depend on the order of properties, nor on how a
+// value happens to be represented: strings of either encoding and length,
+// AMF3 integers and numbers, byte arrays and dates by value or by pointer,
+// and values wrapped in a Variant or by Freeze hash the same. Values shared
+// by reference hash as their content, except for cycles.
+func Hash(v interface{}) [32]byte {
+	h := sha256.New()
+	(&hasher{h: h, seen: make(map[interface{}]int)}).value(v)
+	var sum [32]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// Tags written before each value.
+const (
+	hashNull byte = iota
+	hashUndefined
+	hashBoolean
+	hashNumber
+	hashString
+	hashDate
+	hashXML
+	hashByteArray
+	hashObject
+	hashArray
+	hashDictionary
+	hashCycle
+	hashOther
+)
+
+type hasher struct {
+	h    hash.Hash
+	seen map[interface{}]int // containers being hashed, by depth
+}
+
+func (hs *hasher) tag(t byte) {
+	hs.h.Write([]byte{t})
+}
+
+func (hs *hasher) number(f float64) {
+	if f == 0 {
+		f = 0 // and not -0
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], math.Float64bits(f))
+	hs.h.Write(b[:])
+}
+
+func (hs *hasher) string(s string) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(len(s)))
+	hs.h.Write(b[:])
+	hs.h.Write([]byte(s))
+}
+
+func (hs *hasher) value(v interface{}) {
+	switch value := v.(type) {
+	case *Frozen:
+		hs.value(value.v)
+		return
+	case amf0.Variant:
+		hs.value(value.Value)
+		return
+	case nil, amf0.NullType, amf3.NullType:
+		hs.tag(hashNull)
+		return
+	case amf0.UndefinedType, amf3.UndefinedType:
+		hs.tag(hashUndefined)
+		return
+	case amf0.BooleanType:
+		hs.tag(hashBoolean)
+		hs.h.Write([]byte{boolByte(bool(value))})
+		return
+	case amf3.TrueType:
+		hs.tag(hashBoolean)
+		hs.h.Write([]byte{1})
+		return
+	case amf3.FalseType:
+		hs.tag(hashBoolean)
+		hs.h.Write([]byte{0})
+		return
+	case amf0.NumberType:
+		hs.tag(hashNumber)
+		hs.number(float64(value))
+		return
+	case amf3.DoubleType:
+		hs.tag(hashNumber)
+		hs.number(float64(value))
+		return
+	case amf3.IntegerType:
+		hs.tag(hashNumber)
+		hs.number(float64(value))
+		return
+	case amf3.NullStringType:
+		hs.tag(hashString)
+		hs.string("")
+		return
+	case amf0.DateType:
+		hs.tag(hashDate)
+		hs.number(value.Date)
+		return
+	case amf3.DateType:
+		hs.tag(hashDate)
+		hs.number(float64(value))
+		return
+	case *amf3.DateType:
+		hs.value(*value)
+		return
+	case amf3.ByteArrayType:
+		hs.tag(hashByteArray)
+		hs.string(string(value))
+		return
+	case *amf3.ByteArrayType:
+		hs.value(*value)
+		return
+	case amf3.DictionaryType:
+		hs.value(&value)
+		return
+	}
+	if s, ok := stringValue(v); ok {
+		hs.tag(hashString)
+		hs.string(s)
+		return
+	}
+	if s, ok := xmlValue(v); ok {
+		hs.tag(hashXML)
+		hs.string(s)
+		return
+	}
+
+	if id := identity(v); id != nil {
+		if depth, ok := hs.seen[id]; ok {
+			hs.tag(hashCycle)
+			hs.number(float64(len(hs.seen) - depth))
+			return
+		}
+		hs.seen[id] = len(hs.seen)
+		defer delete(hs.seen, id)
+	}
+	switch value := v.(type) {
+	case *amf3.DictionaryType:
+		hs.dictionary(value)
+		return
+	case *amf3.ArrayCollection:
+		hs.tag(hashArray)
+		hs.members(nil, *value)
+		return
+	case *amf3.ObjectProxy:
+		props := make(map[string]interface{}, len(*value))
+		for name, child := range *value {
+			props[string(name)] = child
+		}
+		hs.tag(hashObject)
+		hs.string("")
+		hs.members(props, nil)
+		return
+	}
+	if !isContainer(v) {
+		hs.tag(hashOther)
+		hs.string(fmt.Sprintf("%T %v", v, v))
+		return
+	}
+	props := make(map[string]interface{})
+	var items []interface{}
+	forEachChild(v, func(elem string, child interface{}) {
+		if strings.HasPrefix(elem, ".") {
+			props[elem[1:]] = child
+		} else {
+			items = append(items, child)
+		}
+	})
+	switch KindOf(v) {
+	case Object, TypedObject:
+		name, _ := className(v)
+		hs.tag(hashObject)
+		hs.string(name)
+	default:
+		hs.tag(hashArray)
+	}
+	hs.members(props, items)
+}
+
+// members hashes properties by name, then items in order.
+func (hs *hasher) members(props map[string]interface{}, items []interface{}) {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hs.number(float64(len(names)))
+	for _, name := range names {
+		hs.string(name)
+		hs.value(props[name])
+	}
+	hs.number(float64(len(items)))
+	for _, item := range items {
+		hs.value(item)
+	}
+}
+
+// dictionary hashes the entries of d in an order of their own hashes, as
+// keys that are not strings cannot be sorted.
+func (hs *hasher) dictionary(d *amf3.DictionaryType) {
+	entries := make([][]byte, len(d.Entries))
+	for i, entry := range d.Entries {
+		sub := &hasher{h: sha256.New(), seen: hs.seen}
+		sub.value(entry.Key)
+		sub.value(entry.Value)
+		entries[i] = sub.h.Sum(nil)
+	}
+	sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i], entries[j]) < 0 })
+	hs.tag(hashDictionary)
+	hs.number(float64(len(entries)))
+	for _, entry := range entries {
+		hs.h.Write(entry)
+	}
+}
+
+// xmlValue returns the text of the AMF0 and AMF3 XML types.
+func xmlValue(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case amf0.XmlDocumentType:
+		return string(x), true
+	case amf3.XMLDocumentType:
+		return string(x), true
+	case *amf3.XMLDocumentType:
+		return string(*x), true
+	case amf3.XMLType:
+		return string(x), true
+	case *amf3.XMLType:
+		return string(*x), true
+	}
+	return "", false
+}
+
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,36 @@
+package amf
+
+import (
+	"testing"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+func TestHash(t *testing.T) {
+	a := &amf0.EcmaArrayType{"width": amf0.NumberType(640), "title": amf0.StringType("live")}
+	b := &amf3.ArrayType{Associative: map[amf3.StringType]interface{}{
+		"title": amf3.StringType("live"),
+		"width": amf3.IntegerType(640),
+	}}
+	if Hash(a) != Hash(b) {
+		t.Fatalf("expect equal hashes for the same metadata")
+	}
+	if Hash(a) != Hash(Freeze(a)) || Hash(amf0.LongStringType("x")) != Hash(amf0.StringType("x")) {
+		t.Fatalf("expect wrappers not to change the hash")
+	}
+	c := &amf0.EcmaArrayType{"width": amf0.NumberType(641), "title": amf0.StringType("live")}
+	if Hash(a) == Hash(c) {
+		t.Fatalf("expect different hashes for different metadata")
+	}
+	if Hash(&amf0.StrictArrayType{amf0.NumberType(1), amf0.NumberType(2)}) == Hash(&amf0.StrictArrayType{amf0.NumberType(2), amf0.NumberType(1)}) {
+		t.Fatalf("expect array order to change the hash")
+	}
+	if Hash(amf0.StringType("")) == Hash(amf0.NullType{}) {
+		t.Fatalf("expect the empty string to differ from null")
+	}
+
+	cycle := &amf0.ObjectType{}
+	(*cycle)["self"] = cycle
+	Hash(cycle)
+}
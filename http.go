@@ -0,0 +1,16 @@
+package amf
+
+import (
+	"mime"
+	"net/http"
+)
+
+// IsAMFRequest reports whether r is a POST carrying an AMF packet, going by
+// its Content-Type.
+func IsAMFRequest(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == ContentTypeAMF
+}
@@ -0,0 +1,31 @@
+package amf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsAMFRequest(t *testing.T) {
+	tests := []struct {
+		method      string
+		contentType string
+		expect      bool
+	}{
+		{http.MethodPost, "application/x-amf", true},
+		{http.MethodPost, "Application/X-AMF; charset=binary", true},
+		{http.MethodGet, "application/x-amf", false},
+		{http.MethodPost, "application/json", false},
+		{http.MethodPost, "", false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, "/gateway", nil)
+		if test.contentType != "" {
+			r.Header.Set("Content-Type", test.contentType)
+		}
+		got := IsAMFRequest(r)
+		if got != test.expect {
+			t.Errorf("expected %v for %s %q, got %v", test.expect, test.method, test.contentType, got)
+		}
+	}
+}
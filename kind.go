@@ -0,0 +1,110 @@
+package amf
+
+import (
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// Kind classifies decoded values of either object encoding.
+type Kind int
+
+const (
+	Unknown Kind = iota
+	Number
+	Integer
+	Boolean
+	String
+	Date
+	XML
+	ByteArray
+	Null
+	Undefined
+	Unsupported
+	Object
+	TypedObject
+	EcmaArray
+	Array
+	Dictionary
+)
+
+var kindNames = [...]string{
+	Unknown:     "unknown",
+	Number:      "number",
+	Integer:     "integer",
+	Boolean:     "boolean",
+	String:      "string",
+	Date:        "date",
+	XML:         "xml",
+	ByteArray:   "bytearray",
+	Null:        "null",
+	Undefined:   "undefined",
+	Unsupported: "unsupported",
+	Object:      "object",
+	TypedObject: "typedobject",
+	EcmaArray:   "ecma",
+	Array:       "array",
+	Dictionary:  "dictionary",
+}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return kindNames[Unknown]
+	}
+	return kindNames[k]
+}
+
+// KindOf returns the kind of a value decoded by the amf0 or amf3 packages.
+// An AMF3 array with associative entries is an EcmaArray, and an AMF3
+// object with a class name is a TypedObject.
+func KindOf(v interface{}) Kind {
+	switch value := v.(type) {
+	case amf0.NumberType, amf3.DoubleType:
+		return Number
+	case amf3.IntegerType:
+		return Integer
+	case amf0.BooleanType, amf3.TrueType, amf3.FalseType:
+		return Boolean
+	case amf0.StringType, amf0.LongStringType, amf3.StringType, amf3.NullStringType:
+		return String
+	case amf0.DateType, amf3.DateType, *amf3.DateType:
+		return Date
+	case amf0.XmlDocumentType, amf0.XmlDocumentSpan, amf3.XMLDocumentType, *amf3.XMLDocumentType, amf3.XMLType, *amf3.XMLType:
+		return XML
+	case amf3.ByteArrayType, *amf3.ByteArrayType:
+		return ByteArray
+	case amf0.NullType, amf3.NullType, nil:
+		return Null
+	case amf0.UndefinedType, amf3.UndefinedType:
+		return Undefined
+	case amf0.UnsupportedType:
+		return Unsupported
+	case *amf0.ObjectType:
+		return Object
+	case *amf0.TypedObjectType:
+		return TypedObject
+	case *amf3.ObjectType:
+		if _, typed := className(value); typed {
+			return TypedObject
+		}
+		return Object
+	case *amf0.EcmaArrayType:
+		return EcmaArray
+	case *amf0.StrictArrayType:
+		return Array
+	case *amf3.ArrayType:
+		if len(value.Associative) > 0 {
+			return EcmaArray
+		}
+		return Array
+	case *amf3.VectorIntType, *amf3.VectorUintType, *amf3.VectorDoubleType, *amf3.VectorObjectType,
+		*amf3.ArrayCollection:
+		return Array
+	case *amf3.ObjectProxy:
+		return Object
+	case amf3.DictionaryType, *amf3.DictionaryType:
+		return Dictionary
+	case amf0.Variant:
+		return KindOf(value.Value)
+	}
+	return Unknown
+}
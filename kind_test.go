@@ -0,0 +1,44 @@
+package amf
+
+import (
+	"testing"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		v      interface{}
+		expect Kind
+	}{
+		{amf0.NumberType(1), Number},
+		{amf3.IntegerType(1), Integer},
+		{amf3.TrueType{}, Boolean},
+		{amf0.LongStringType("a"), String},
+		{amf0.DateType{}, Date},
+		{&amf3.ByteArrayType{}, ByteArray},
+		{nil, Null},
+		{amf0.UndefinedType{}, Undefined},
+		{&amf0.ObjectType{}, Object},
+		{&amf0.TypedObjectType{ClassName: "a"}, TypedObject},
+		{&amf3.ObjectType{Trait: &amf3.Trait{ClassName: "a"}}, TypedObject},
+		{&amf3.ArrayType{Associative: map[amf3.StringType]interface{}{"a": amf3.NullType{}}}, EcmaArray},
+		{&amf0.StrictArrayType{}, Array},
+		{&amf3.VectorIntType{}, Array},
+		{&amf3.ArrayCollection{}, Array},
+		{&amf3.ObjectProxy{}, Object},
+		{&amf3.DictionaryType{}, Dictionary},
+		{amf0.Variant{Marker: amf0.StringMarker, Value: amf0.StringType("a")}, String},
+		{42, Unknown},
+	}
+	for _, test := range tests {
+		got := KindOf(test.v)
+		if got != test.expect {
+			t.Errorf("expected %v for %#v, got %v", test.expect, test.v, got)
+		}
+	}
+	if Array.String() != "array" || Kind(-1).String() != "unknown" {
+		t.Errorf("unexpected kind names %s %s", Array, Kind(-1))
+	}
+}
@@ -0,0 +1,123 @@
+package amf
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/marcuswu/amf/amf0"
+)
+
+// LengthError reports a count or length read from the input that is over a
+// configured limit or over what is left of the input.
+type LengthError = amf0.LengthError
+
+// SizeLimitError is returned by a reader from LimitReader when the stream
+// holds more than the bytes allowed.
+type SizeLimitError struct {
+	Limit int64
+}
+
+func (e *SizeLimitError) Error() string {
+	return "input larger than " + strconv.FormatInt(e.Limit, 10) + " bytes"
+}
+
+// TimeLimitError is returned by a reader from LimitReader when the stream
+// takes longer than allowed to read.
+type TimeLimitError struct {
+	Limit time.Duration
+}
+
+func (e *TimeLimitError) Error() string {
+	return "input took longer than " + e.Limit.String() + " to read"
+}
+
+// Timeout reports true, as net.Error does for timeouts.
+func (e *TimeLimitError) Timeout() bool {
+	return true
+}
+
+// ValueTimeoutError is returned by a packet Decoder when a header value or
+// message body takes longer than DecoderOptions.ValueTimeout to decode.
+type ValueTimeoutError struct {
+	Path  string // e.g. "headers[0]" or "messages[1].user.name"
+	Name  string // name of the header or target URI of the message
+	Limit time.Duration
+}
+
+func (e *ValueTimeoutError) Error() string {
+	msg := "value took longer than " + e.Limit.String() + " to decode"
+	if e.Path == "" {
+		return msg
+	}
+	if e.Name != "" {
+		return e.Path + " (" + e.Name + "): " + msg
+	}
+	return e.Path + ": " + msg
+}
+
+// Timeout reports true, as net.Error does for timeouts.
+func (e *ValueTimeoutError) Timeout() bool {
+	return true
+}
+
+// LimitReader returns a reader that fails with a *SizeLimitError once r
+// yields more than maxBytes bytes, and with a *TimeLimitError once maxDuration
+// has passed since the call. A zero limit is not enforced. Unlike
+// io.LimitReader, hitting the size limit is an error rather than an early
+// end of input, so a decoder cannot mistake a cut value for a complete one.
+//
+// If r has a SetReadDeadline method, as net.Conn does, a read blocked past
+// the time limit is interrupted; otherwise the limit is checked between
+// reads. The read deadline is set on r by the call and stays set once the
+// limited reader is done with, so reading r on afterwards needs a call to
+// SetReadDeadline(time.Time{}) first.
+func LimitReader(r io.Reader, maxBytes int64, maxDuration time.Duration) io.Reader {
+	lr := &limitReader{r: r, max: maxBytes, duration: maxDuration}
+	if maxDuration > 0 {
+		lr.deadline = time.Now().Add(maxDuration)
+		if d, ok := r.(interface{ SetReadDeadline(time.Time) error }); ok {
+			d.SetReadDeadline(lr.deadline)
+		}
+	}
+	return lr
+}
+
+type limitReader struct {
+	r        io.Reader
+	max      int64
+	n        int64
+	duration time.Duration
+	deadline time.Time
+}
+
+func (lr *limitReader) Read(p []byte) (int, error) {
+	if lr.duration > 0 && !time.Now().Before(lr.deadline) {
+		return 0, &TimeLimitError{Limit: lr.duration}
+	}
+	if lr.max > 0 {
+		if lr.n >= lr.max {
+			// find out whether the input ends at the limit
+			n, err := lr.r.Read(make([]byte, 1))
+			if n > 0 {
+				return 0, &SizeLimitError{Limit: lr.max}
+			}
+			return 0, lr.wrap(err)
+		}
+		if int64(len(p)) > lr.max-lr.n {
+			p = p[:lr.max-lr.n]
+		}
+	}
+	n, err := lr.r.Read(p)
+	lr.n += int64(n)
+	return n, lr.wrap(err)
+}
+
+func (lr *limitReader) wrap(err error) error {
+	if lr.duration > 0 && errors.Is(err, os.ErrDeadlineExceeded) {
+		return &TimeLimitError{Limit: lr.duration}
+	}
+	return err
+}
@@ -0,0 +1,33 @@
+package amf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestLimitReaderSize(t *testing.T) {
+	data := []byte{0x02, 0x00, 0x03, 'a', 'b', 'c'}
+	got, err := io.ReadAll(LimitReader(bytes.NewReader(data), int64(len(data)), 0))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("expect input at the limit read whole got %v %v", got, err)
+	}
+	_, err = io.ReadAll(LimitReader(bytes.NewReader(data), 4, 0))
+	var sizeErr *SizeLimitError
+	if !errors.As(err, &sizeErr) || sizeErr.Limit != 4 {
+		t.Errorf("expect size limit error got %v", err)
+	}
+}
+
+func TestLimitReaderTime(t *testing.T) {
+	r := LimitReader(iotest.OneByteReader(bytes.NewReader(make([]byte, 10))), 0, time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	_, err := io.ReadAll(r)
+	var timeErr *TimeLimitError
+	if !errors.As(err, &timeErr) || !timeErr.Timeout() {
+		t.Errorf("expect time limit error got %v", err)
+	}
+}
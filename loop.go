@@ -0,0 +1,162 @@
+package amf
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// ValueHandler handles one value decoded by DecodeLoop. An error ends the
+// loop.
+type ValueHandler func(ctx context.Context, v interface{}) error
+
+// LoopOptions configures DecodeLoopWithOptions.
+type LoopOptions struct {
+	// Version is the object encoding of the values, AMF0 or AMF3. AMF0
+	// values may switch to AMF3 as usual.
+	Version int
+	// Framing, if set, reads every value from a length-prefixed frame. Its
+	// MaxFrameSize defaults to MaxValueSize.
+	Framing *Framing
+	// MaxValueSize fails a value with a *SizeLimitError once it takes more
+	// than this many bytes; 0 means no limit.
+	MaxValueSize int64
+	// ReadTimeout bounds the time spent waiting for and reading each value
+	// on connections with a SetReadDeadline method; 0 means no limit.
+	ReadTimeout time.Duration
+	// Lenient carries on past framed values that fail to decode, handing
+	// the error to OnError. An unframed stream cannot be resynchronized
+	// after a bad value, so its errors always end the loop.
+	Lenient bool
+	OnError func(err error)
+}
+
+// DecodeLoop decodes AMF0 values from conn until it ends, calling handler
+// for each. See DecodeLoopWithOptions.
+func DecodeLoop(ctx context.Context, conn io.Reader, handler ValueHandler) error {
+	return DecodeLoopWithOptions(ctx, conn, handler, LoopOptions{})
+}
+
+// DecodeLoopWithOptions decodes values from conn, calling handler for each
+// in turn, until conn ends between values, handler fails or ctx is done.
+// It returns nil at the end of input, ctx.Err() once ctx is done, and
+// otherwise the first error, failures to decode being *DecodeError. Every
+// value starts with an empty reference table.
+//
+// Cancelling ctx interrupts a blocked read when conn has a SetReadDeadline
+// method, as net.Conn does.
+func DecodeLoopWithOptions(ctx context.Context, conn io.Reader, handler ValueHandler, opts LoopOptions) error {
+	if opts.Version != AMF0 && opts.Version != AMF3 {
+		return errors.New("unknown object encoding")
+	}
+	deadliner, _ := conn.(interface{ SetReadDeadline(time.Time) error })
+	if deadliner != nil {
+		stop := context.AfterFunc(ctx, func() {
+			deadliner.SetReadDeadline(time.Unix(1, 0))
+		})
+		defer stop()
+	}
+	name := "stream"
+	if c, ok := conn.(interface{ RemoteAddr() net.Addr }); ok {
+		name = c.RemoteAddr().String()
+	}
+	vr := &valueReader{r: bufio.NewReader(conn), max: opts.MaxValueSize}
+	values := amf0.NewDecoder(vr)
+	var frames *FramedDecoder
+	if opts.Framing != nil {
+		framing := *opts.Framing
+		if framing.MaxFrameSize == 0 {
+			framing.MaxFrameSize = opts.MaxValueSize
+		}
+		frames = NewFramedDecoder(vr, opts.Version, framing)
+	}
+	for {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+		if deadliner != nil && opts.ReadTimeout > 0 {
+			deadliner.SetReadDeadline(time.Now().Add(opts.ReadTimeout))
+		}
+		start := vr.total
+		vr.n = 0
+		var v interface{}
+		if frames != nil {
+			var frame []byte
+			frame, err = frames.readFrame()
+			if err == nil {
+				v, err = decodeSingle(frame, opts.Version)
+				if err != nil && opts.Lenient {
+					if opts.OnError != nil {
+						opts.OnError(&DecodeError{Name: name, Offset: start, Err: err})
+					}
+					continue
+				}
+			}
+		} else if opts.Version == AMF3 {
+			v, err = amf3.NewDecoder(vr).Decode()
+		} else {
+			values.ResetReferences()
+			v, err = values.Decode()
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err == io.EOF && vr.total == start {
+			return nil
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return &DecodeError{Name: name, Offset: start, Err: err}
+		}
+		err = handler(ctx, v)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// valueReader bounds the size of each value read through it and counts the
+// bytes of the whole stream. Being an io.ByteReader, it is read directly by
+// the value decoders, so they never read ahead of the current value.
+type valueReader struct {
+	r     *bufio.Reader
+	max   int64 // per value, or 0
+	n     int64 // bytes of the current value
+	total int64
+}
+
+func (vr *valueReader) Read(p []byte) (int, error) {
+	if vr.max > 0 {
+		if vr.n >= vr.max {
+			return 0, &SizeLimitError{Limit: vr.max}
+		}
+		if int64(len(p)) > vr.max-vr.n {
+			p = p[:vr.max-vr.n]
+		}
+	}
+	n, err := vr.r.Read(p)
+	vr.n += int64(n)
+	vr.total += int64(n)
+	return n, err
+}
+
+func (vr *valueReader) ReadByte() (byte, error) {
+	if vr.max > 0 && vr.n >= vr.max {
+		return 0, &SizeLimitError{Limit: vr.max}
+	}
+	b, err := vr.r.ReadByte()
+	if err == nil {
+		vr.n++
+		vr.total++
+	}
+	return b, err
+}
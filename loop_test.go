@@ -0,0 +1,79 @@
+package amf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/marcuswu/amf/amf0"
+)
+
+func TestDecodeLoop(t *testing.T) {
+	data := []byte{0x02, 0x00, 0x01, 'a', 0x00, 0x3f, 0xf0, 0, 0, 0, 0, 0, 0, 0x11, 0x04, 0x02}
+	var got []interface{}
+	err := DecodeLoop(context.Background(), bytes.NewReader(data), func(ctx context.Context, v interface{}) error {
+		got = append(got, v)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0] != amf0.StringType("a") || got[1] != amf0.NumberType(1) {
+		t.Fatalf("unexpected values %v", got)
+	}
+
+	err = DecodeLoop(context.Background(), bytes.NewReader(data[:6]), func(ctx context.Context, v interface{}) error { return nil })
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Offset != 4 {
+		t.Fatalf("expected a DecodeError at offset 4, got %v", err)
+	}
+
+	err = DecodeLoopWithOptions(context.Background(), bytes.NewReader(data), func(ctx context.Context, v interface{}) error { return nil },
+		LoopOptions{MaxValueSize: 5})
+	var sizeErr *SizeLimitError
+	if !errors.As(err, &sizeErr) {
+		t.Fatalf("expected a SizeLimitError, got %v", err)
+	}
+}
+
+func TestDecodeLoopLenient(t *testing.T) {
+	// the middle frame holds a truncated number
+	data := []byte{0x00, 0x01, 0x05, 0x00, 0x02, 0x00, 0x3f, 0x00, 0x01, 0x06}
+	var got []interface{}
+	var errs []error
+	err := DecodeLoopWithOptions(context.Background(), bytes.NewReader(data), func(ctx context.Context, v interface{}) error {
+		got = append(got, v)
+		return nil
+	}, LoopOptions{Framing: &Framing{Size: 2}, Lenient: true, OnError: func(err error) { errs = append(errs, err) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || len(errs) != 1 {
+		t.Fatalf("expected 2 values and 1 error, got %v and %v", got, errs)
+	}
+}
+
+func TestDecodeLoopCancel(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- DecodeLoop(ctx, server, func(ctx context.Context, v interface{}) error {
+			cancel()
+			return nil
+		})
+	}()
+	client.Write([]byte{0x05})
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("loop did not stop after cancel")
+	}
+}
@@ -0,0 +1,40 @@
+package amf
+
+import (
+	"io"
+
+	"github.com/marcuswu/amf/amf0"
+)
+
+// Marshal returns the AMF0 encoding of v, in the way encoding/json's Marshal
+// does for JSON. See amf0.Encoder for how Go values are mapped.
+func Marshal(v interface{}) ([]byte, error) {
+	return amf0.Marshal(v)
+}
+
+// Unmarshal decodes the single AMF0 value in data, such as an RTMP command
+// argument, into the value pointed to by v. Data left after the value is an
+// error.
+func Unmarshal(data []byte, v interface{}) error {
+	return amf0.Unmarshal(data, v)
+}
+
+// DecodeAs decodes the next AMF0 value of r into a T, e.g.
+//
+//	args, err := amf.DecodeAs[ConnectArgs](r)
+//
+// Readers that are not io.ByteReaders are buffered, so r may be read past
+// the value; wrap it in a bufio.Reader to decode several values in turn.
+func DecodeAs[T any](r io.Reader) (T, error) {
+	var v T
+	err := amf0.NewDecoder(r).DecodeValueInto(&v)
+	return v, err
+}
+
+// UnmarshalAs decodes the single AMF0 value in data into a T, as Unmarshal
+// does.
+func UnmarshalAs[T any](data []byte) (T, error) {
+	var v T
+	err := amf0.Unmarshal(data, &v)
+	return v, err
+}
@@ -0,0 +1,70 @@
+package amf
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestMarshalUnmarshal(t *testing.T) {
+	type connect struct {
+		App      string  `amf:"app"`
+		Encoding float64 `amf:"objectEncoding"`
+	}
+	data, err := Marshal(connect{App: "live", Encoding: 3})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var got connect
+	err = Unmarshal(data, &got)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got.App != "live" || got.Encoding != 3 {
+		t.Errorf("expect live/3 got %+v", got)
+	}
+
+	if err := Unmarshal(append(data, 0x05), &got); err == nil {
+		t.Errorf("expect error for trailing data")
+	}
+	if err := Unmarshal(data[:len(data)-1], &got); err == nil {
+		t.Errorf("expect error for truncated data")
+	}
+	if err := Unmarshal(data, got); err == nil {
+		t.Errorf("expect error for non-pointer")
+	}
+}
+
+func TestDecodeAs(t *testing.T) {
+	type connect struct {
+		App string `amf:"app"`
+	}
+	data, err := Marshal(connect{App: "live"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r := bytes.NewReader(append(data, data...))
+	for i := 0; i < 2; i++ {
+		got, err := DecodeAs[connect](r)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if got.App != "live" {
+			t.Errorf("expect live got %+v", got)
+		}
+	}
+	if _, err := DecodeAs[connect](r); err != io.EOF {
+		t.Errorf("expect EOF got %v", err)
+	}
+
+	got, err := UnmarshalAs[map[string]string](data)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got["app"] != "live" {
+		t.Errorf("expect live got %v", got)
+	}
+	if _, err := UnmarshalAs[float64](data); err == nil {
+		t.Errorf("expect error unmarshaling an object into a float64")
+	}
+}
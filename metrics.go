@@ -0,0 +1,76 @@
+package amf
+
+// Default bucket bounds used by NewClassifier.
+var (
+	DefaultSizeBounds  = []int{64, 256, 1024, 4096, 16384, 65536, 262144, 1048576}
+	DefaultDepthBounds = []int{1, 2, 4, 8, 16, 32}
+)
+
+// Classifier sorts payloads into size and depth buckets and reports every
+// classification to Observe, leaving the choice of metrics library to the
+// caller. A bucket index i means the value is at most the i-th bound; an
+// index equal to the number of bounds means it is above the last one.
+type Classifier struct {
+	SizeBounds  []int
+	DepthBounds []int
+	Observe     func(sizeBucket, depthBucket int)
+}
+
+func NewClassifier(observe func(sizeBucket, depthBucket int)) *Classifier {
+	return &Classifier{SizeBounds: DefaultSizeBounds, DepthBounds: DefaultDepthBounds, Observe: observe}
+}
+
+// Classify reports a payload of size encoded bytes that decodes to v.
+func (c *Classifier) Classify(size int, v interface{}) {
+	if c.Observe == nil {
+		return
+	}
+	c.Observe(bucket(c.SizeBounds, size), bucket(c.DepthBounds, Depth(v)))
+}
+
+func bucket(bounds []int, n int) int {
+	for i, bound := range bounds {
+		if n <= bound {
+			return i
+		}
+	}
+	return len(bounds)
+}
+
+// Depth returns the nesting depth of v: 0 for scalars, 1 for a container of
+// scalars and so on. For a packet it is the deepest header or message value.
+// Values reached again through references are not descended into twice.
+func Depth(v interface{}) int {
+	if p, ok := v.(*Packet); ok {
+		depth := 0
+		for _, h := range p.headers {
+			if h != nil {
+				depth = max(depth, Depth(h.data))
+			}
+		}
+		for _, m := range p.messages {
+			if m != nil {
+				depth = max(depth, Depth(m.data))
+			}
+		}
+		return depth
+	}
+	return depthOf(v, make(map[interface{}]bool))
+}
+
+func depthOf(v interface{}, seen map[interface{}]bool) int {
+	if !isContainer(v) {
+		return 0
+	}
+	if id := identity(v); id != nil {
+		if seen[id] {
+			return 0
+		}
+		seen[id] = true
+	}
+	depth := 0
+	forEachChild(v, func(elem string, child interface{}) {
+		depth = max(depth, depthOf(child, seen))
+	})
+	return depth + 1
+}
@@ -0,0 +1,41 @@
+package amf
+
+import (
+	"testing"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+func TestDepth(t *testing.T) {
+	inner := amf0.ObjectType{"foo": amf0.StringType("bar")}
+	outer := amf0.StrictArrayType{amf0.NumberType(1), &inner}
+	if d := Depth(&outer); d != 2 {
+		t.Errorf("expected depth 2, got %d", d)
+	}
+	if d := Depth(amf0.NumberType(1)); d != 0 {
+		t.Errorf("expected depth 0, got %d", d)
+	}
+
+	self := &amf3.ArrayType{}
+	self.Dense = []interface{}{self}
+	if d := Depth(self); d != 1 {
+		t.Errorf("expected depth 1 for self reference, got %d", d)
+	}
+}
+
+func TestClassifier(t *testing.T) {
+	var sizeBucket, depthBucket int
+	c := NewClassifier(func(s, d int) {
+		sizeBucket, depthBucket = s, d
+	})
+	obj := amf0.ObjectType{"foo": amf0.StringType("bar")}
+	c.Classify(100, &obj)
+	if sizeBucket != 1 || depthBucket != 0 {
+		t.Errorf("expected buckets 1 and 0, got %d and %d", sizeBucket, depthBucket)
+	}
+	c.Classify(1<<30, amf0.NullType{})
+	if sizeBucket != len(DefaultSizeBounds) {
+		t.Errorf("expected overflow size bucket, got %d", sizeBucket)
+	}
+}
@@ -0,0 +1,93 @@
+package amf
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// ClientCapabilities is what the command object of an RTMP connect tells
+// about the object encodings a client can use.
+type ClientCapabilities struct {
+	// ObjectEncoding is the objectEncoding property, and HasObjectEncoding
+	// whether the client sent one.
+	ObjectEncoding    float64
+	HasObjectEncoding bool
+	// FlashVer is the flashVer property, such as "WIN 11,2,202,235".
+	FlashVer string
+}
+
+// ConnectCapabilities reads the capabilities from cmd, the command object
+// of a connect command, decoded from AMF0 or AMF3.
+func ConnectCapabilities(cmd interface{}) ClientCapabilities {
+	var c ClientCapabilities
+	forEachChild(cmd, func(elem string, child interface{}) {
+		switch strings.TrimPrefix(elem, ".") {
+		case "objectEncoding":
+			switch n := child.(type) {
+			case amf0.NumberType:
+				c.ObjectEncoding, c.HasObjectEncoding = float64(n), true
+			case amf3.DoubleType:
+				c.ObjectEncoding, c.HasObjectEncoding = float64(n), true
+			case amf3.IntegerType:
+				c.ObjectEncoding, c.HasObjectEncoding = float64(n), true
+			}
+		case "flashVer":
+			c.FlashVer, _ = stringValue(child)
+		}
+	})
+	return c
+}
+
+// FlashPlayerVersion returns the major version of the Flash Player named
+// by FlashVer, or 0 when FlashVer names another client, such as
+// "FMLE/3.0 (compatible; FMSc/1.0)".
+func (c ClientCapabilities) FlashPlayerVersion() int {
+	platform, version, ok := strings.Cut(c.FlashVer, " ")
+	if !ok || platform == "" || strings.ContainsAny(platform, "/(") {
+		return 0
+	}
+	major, _, _ := strings.Cut(version, ",")
+	n, err := strconv.Atoi(major)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// EncodingPolicy is what a server allows when negotiating the object
+// encoding of a connection.
+type EncodingPolicy struct {
+	// DisableAMF3 answers every client with AMF0, for servers whose
+	// handlers only deal in AMF0 values.
+	DisableAMF3 bool
+	// FlashPlayerDefaults gives Flash Player 9 and later clients that
+	// leave objectEncoding out AMF3, the default of their NetConnection,
+	// instead of AMF0. Tools such as ffmpeg send the flashVer of Flash
+	// Player 9 without objectEncoding and expect AMF0, so it is off by
+	// default.
+	FlashPlayerDefaults bool
+}
+
+// NegotiateEncoding returns the object encoding, AMF0 or AMF3, to answer a
+// connect with and to use for the rest of the connection. A client asking
+// for AMF3 gets it unless the policy disables it; any other objectEncoding,
+// including ones no version of Flash defines, gets AMF0, as does a client
+// sending none unless policy.FlashPlayerDefaults applies.
+func NegotiateEncoding(client ClientCapabilities, policy EncodingPolicy) int {
+	if policy.DisableAMF3 {
+		return AMF0
+	}
+	if client.HasObjectEncoding {
+		if client.ObjectEncoding == AMF3 {
+			return AMF3
+		}
+		return AMF0
+	}
+	if policy.FlashPlayerDefaults && client.FlashPlayerVersion() >= 9 {
+		return AMF3
+	}
+	return AMF0
+}
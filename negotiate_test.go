@@ -0,0 +1,34 @@
+package amf
+
+import (
+	"testing"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	fp9 := "WIN 9,0,115,0"
+	tests := []struct {
+		cmd    interface{}
+		policy EncodingPolicy
+		expect int
+	}{
+		{&amf0.ObjectType{"objectEncoding": amf0.NumberType(3)}, EncodingPolicy{}, AMF3},
+		{&amf0.ObjectType{"objectEncoding": amf0.NumberType(0)}, EncodingPolicy{}, AMF0},
+		{&amf0.ObjectType{"objectEncoding": amf0.NumberType(3)}, EncodingPolicy{DisableAMF3: true}, AMF0},
+		{&amf0.ObjectType{"objectEncoding": amf0.NumberType(1)}, EncodingPolicy{}, AMF0},
+		{&amf0.ObjectType{"flashVer": amf0.StringType(fp9)}, EncodingPolicy{}, AMF0},
+		{&amf0.ObjectType{"flashVer": amf0.StringType(fp9)}, EncodingPolicy{FlashPlayerDefaults: true}, AMF3},
+		{&amf0.ObjectType{"flashVer": amf0.StringType("MAC 8,0,24,0")}, EncodingPolicy{FlashPlayerDefaults: true}, AMF0},
+		{&amf0.ObjectType{"flashVer": amf0.StringType("FMLE/3.0 (compatible; FMSc/1.0)")}, EncodingPolicy{FlashPlayerDefaults: true}, AMF0},
+		{&amf0.ObjectType{"flashVer": amf0.StringType(fp9), "objectEncoding": amf0.NumberType(0)}, EncodingPolicy{FlashPlayerDefaults: true}, AMF0},
+		{&amf3.ArrayType{Associative: map[amf3.StringType]interface{}{"objectEncoding": amf3.IntegerType(3)}}, EncodingPolicy{}, AMF3},
+	}
+	for i, test := range tests {
+		got := NegotiateEncoding(ConnectCapabilities(test.cmd), test.policy)
+		if got != test.expect {
+			t.Errorf("%d: expect AMF%d got AMF%d", i, test.expect, got)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package amf
+
+import (
+	"io"
+	"time"
+
+	"github.com/marcuswu/amf/amf3"
+)
+
+// DecoderOptions configures a packet Decoder.
+type DecoderOptions struct {
+	// DisallowTrailingData makes Decode fail when bytes follow the last
+	// message.
+	DisallowTrailingData bool
+	// CaptureTrailing keeps the bytes following the last message, up to the
+	// end of the input, in Packet.Trailing. Otherwise Decode stops after the
+	// last message, so that the next Decode reads the packet following it,
+	// as on a stream of packets.
+	CaptureTrailing bool
+	// Schema, if set, observes every header and message value decoded.
+	// Headers are named after the header, message bodies after their target
+	// URI.
+	Schema *Schema
+	// SharedReferences lets header values and message bodies refer to
+	// objects decoded earlier in the same packet, as some legacy servers
+	// expect. By default every header and message starts with an empty
+	// reference table, as the specification requires.
+	SharedReferences bool
+	// EnforceLengths reads every header value and message body within the
+	// length written before it, failing when the value runs over and
+	// skipping what is left when it ends early. Nested AMF3 values share the
+	// bound. UnknownLength leaves the value unbounded.
+	EnforceLengths bool
+	// UnwrapFlexCollections decodes AMF3 ArrayCollection, ObjectProxy,
+	// SerializationProxy and ManagedObjectProxy objects as the value they
+	// wrap.
+	UnwrapFlexCollections bool
+	// ValueTimeout bounds the time spent decoding each header value and
+	// message body, so that a client trickling bytes cannot hold a decoder
+	// on one value, failing with a *ValueTimeoutError; 0 means no limit.
+	// On readers with a SetReadDeadline method, as net.Conn has, a read
+	// blocked past the limit is interrupted, and the read deadline is
+	// cleared after every value, replacing any deadline the caller set;
+	// otherwise the limit is checked between reads.
+	ValueTimeout time.Duration
+	// Transcode, if set, converts the bytes of every string in header
+	// values and message bodies to UTF-8, for archives of legacy clients
+	// that wrote them in another character set.
+	Transcode amf3.Transcoder
+	// MaxDepth limits how deep objects and arrays of header values and
+	// message bodies nest, as amf0.DecoderOptions.MaxDepth does.
+	MaxDepth int
+	// MaxStringLength limits the size in bytes of strings and XML documents
+	// in header values and message bodies, as
+	// amf0.DecoderOptions.MaxStringLength does.
+	MaxStringLength uint32
+	// MaxHeaderCount and MaxMessageCount limit the headers and messages a
+	// packet may hold, failing with a *LengthError before anything is
+	// allocated for them; 0 means no limit. Either count is also checked
+	// against what is left of the input where its size is known.
+	MaxHeaderCount  int
+	MaxMessageCount int
+	// MaxArrayCount limits the element count of arrays in header values
+	// and message bodies, as amf0.DecoderOptions.MaxArrayCount does.
+	MaxArrayCount uint32
+	// MaxValueCount limits the values a packet may hold across all its
+	// headers and messages, nested ones included, failing with
+	// amf0.ErrMaxValueCount past it; 0 means no limit.
+	MaxValueCount int
+	// CaptureUnknownMarkers decodes values with a marker that no type has as
+	// amf0.UnknownValues, as amf0.DecoderOptions.CaptureUnknownMarkers does.
+	// Such a value holds the rest of its header value or message body, so
+	// values with a known length are read within it, as with
+	// EnforceLengths; with UnknownLength it holds the rest of the packet.
+	// Captures are capped by MaxStringLength.
+	CaptureUnknownMarkers bool
+	// RawBodies decodes message bodies as RawMessages, checked but not
+	// built, which the Encoder writes back verbatim.
+	RawBodies bool
+}
+
+func NewDecoderWithOptions(r io.Reader, opts DecoderOptions) *Decoder {
+	dec := NewDecoder(r)
+	dec.opts = opts
+	dec.deadliner, _ = r.(interface{ SetReadDeadline(time.Time) error })
+	return dec
+}
+
+// Options returns the options dec was created with, for logging which
+// settings produced a decoded packet.
+func (dec *Decoder) Options() DecoderOptions {
+	return dec.opts
+}
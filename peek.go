@@ -0,0 +1,64 @@
+package amf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/marcuswu/amf/amf0"
+)
+
+// PeekHeader returns the value of the first header called name in the
+// packet data, and whether there is one, without decoding the messages. The
+// values of other headers are skipped by their length, and only decoded
+// when written with UnknownLength, so routing on a header such as
+// DSEndpoint costs little more than reading the header names.
+func PeekHeader(data []byte, name string) (interface{}, bool, error) {
+	if len(data) < 4 {
+		return nil, false, io.ErrUnexpectedEOF
+	}
+	count := int(binary.BigEndian.Uint16(data[2:]))
+	off := 4
+	for i := 0; i < count; i++ {
+		if len(data)-off < 2 {
+			return nil, false, io.ErrUnexpectedEOF
+		}
+		n := int(binary.BigEndian.Uint16(data[off:]))
+		off += 2
+		// the name, the must understand flag and the length
+		if len(data)-off < n+5 {
+			return nil, false, io.ErrUnexpectedEOF
+		}
+		headerName := string(data[off : off+n])
+		off += n + 1
+		length := binary.BigEndian.Uint32(data[off:])
+		off += 4
+		if length == UnknownLength {
+			r := bytes.NewReader(data[off:])
+			v, err := amf0.NewDecoder(r).Decode()
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				return nil, false, err
+			}
+			if headerName == name {
+				return v, true, nil
+			}
+			off = len(data) - r.Len()
+			continue
+		}
+		if uint64(len(data)-off) < uint64(length) {
+			return nil, false, io.ErrUnexpectedEOF
+		}
+		if headerName == name {
+			v, err := decodeSingle(data[off:off+int(length)], AMF0)
+			if err != nil {
+				return nil, false, err
+			}
+			return v, true, nil
+		}
+		off += int(length)
+	}
+	return nil, false, nil
+}
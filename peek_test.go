@@ -0,0 +1,38 @@
+package amf
+
+import (
+	"testing"
+
+	"github.com/marcuswu/amf/amf0"
+)
+
+func TestPeekHeader(t *testing.T) {
+	p := NewPacket(0, 0)
+	p.AddHeader(NewHeader("a", false, "x"))
+	p.AddHeader(NewHeader("DSEndpoint", false, "my-amf"))
+	p.AddMessage(NewMessage("s", "/1", amf0.StringType("body")))
+	data, err := EncodePacket(p)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	v, ok, err := PeekHeader(data, "DSEndpoint")
+	if err != nil || !ok || v != amf0.StringType("my-amf") {
+		t.Fatalf("expect my-amf got %v, %v, %v", v, ok, err)
+	}
+	_, ok, err = PeekHeader(data, "missing")
+	if err != nil || ok {
+		t.Fatalf("expect no header got %v, %v", ok, err)
+	}
+
+	// a header of unknown length ahead of the one looked for
+	data = []byte{0x00, 0x00, 0x00, 0x02,
+		0x00, 0x01, 'a', 0x00, 0xff, 0xff, 0xff, 0xff, 0x02, 0x00, 0x01, 'x',
+		0x00, 0x01, 'b', 0x00, 0x00, 0x00, 0x00, 0x01, 0x05}
+	v, ok, err = PeekHeader(data, "b")
+	if err != nil || !ok || v != (amf0.NullType{}) {
+		t.Fatalf("expect null got %v, %v, %v", v, ok, err)
+	}
+	if _, _, err = PeekHeader(data[:14], "b"); err == nil {
+		t.Fatalf("expect error for truncated headers")
+	}
+}
@@ -0,0 +1,129 @@
+package amf
+
+import (
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Recording is one AMF request and the response to it, as captured by
+// Record.
+type Recording struct {
+	Time       time.Time
+	RemoteAddr string
+	URL        string
+	StatusCode int
+	Request    []byte
+	Response   []byte
+	// Truncated reports that a body was cut at RecordOptions.MaxBodySize.
+	Truncated bool
+}
+
+// RecordSink receives the recordings made by Record. Record calls it once
+// the response has been written; it may be called concurrently.
+type RecordSink interface {
+	Record(rec *Recording)
+}
+
+// RecordFunc adapts a function to a RecordSink.
+type RecordFunc func(rec *Recording)
+
+func (f RecordFunc) Record(rec *Recording) {
+	f(rec)
+}
+
+// RecordOptions configures Record.
+type RecordOptions struct {
+	// Every records one AMF request in Every. Zero or one records all.
+	Every int
+	// MaxBodySize caps the number of bytes kept of each body. Zero keeps
+	// whole bodies.
+	MaxBodySize int
+	// Redact, if set, rewrites every body before it reaches the sink, e.g.
+	// to blank credentials. It must not keep the slice it is given.
+	Redact func(body []byte) []byte
+}
+
+// Record returns a handler that serves requests with next and passes the raw
+// AMF request and response bodies of a sample of them to sink. Requests that
+// are not AMF requests, as told by IsAMFRequest, are not recorded. Bodies
+// are copied as next reads and writes them, so a request body next does not
+// read is not recorded.
+func Record(next http.Handler, sink RecordSink, opts RecordOptions) http.Handler {
+	var n atomic.Uint64
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAMFRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if opts.Every > 1 && (n.Add(1)-1)%uint64(opts.Every) != 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rec := &Recording{Time: time.Now(), RemoteAddr: r.RemoteAddr, URL: r.URL.String()}
+		req := &capture{max: opts.MaxBodySize}
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.TeeReader(r.Body, req), r.Body}
+		rw := &recordingWriter{ResponseWriter: w, body: capture{max: opts.MaxBodySize}}
+		next.ServeHTTP(rw, r)
+
+		rec.StatusCode = rw.status
+		if rec.StatusCode == 0 {
+			rec.StatusCode = http.StatusOK
+		}
+		rec.Request, rec.Response = req.buf, rw.body.buf
+		rec.Truncated = req.truncated || rw.body.truncated
+		if opts.Redact != nil {
+			rec.Request = opts.Redact(rec.Request)
+			rec.Response = opts.Redact(rec.Response)
+		}
+		sink.Record(rec)
+	})
+}
+
+// capture keeps the first max bytes written to it, or all of them when max
+// is zero.
+type capture struct {
+	buf       []byte
+	max       int
+	truncated bool
+}
+
+func (c *capture) Write(p []byte) (int, error) {
+	keep := p
+	if c.max > 0 && len(c.buf)+len(keep) > c.max {
+		keep = keep[:c.max-len(c.buf)]
+		c.truncated = true
+	}
+	c.buf = append(c.buf, keep...)
+	return len(p), nil
+}
+
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   capture
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.body.Write(p[:n])
+	return n, err
+}
+
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,46 @@
+package amf
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecord(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", ContentTypeAMF)
+		w.Write(body)
+	})
+	var recs []*Recording
+	sink := RecordFunc(func(rec *Recording) { recs = append(recs, rec) })
+	h := Record(echo, sink, RecordOptions{
+		Every:       2,
+		MaxBodySize: 4,
+		Redact:      func(b []byte) []byte { return bytes.ReplaceAll(b, []byte("pw"), []byte("**")) },
+	})
+
+	for i := 0; i < 3; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/gateway", bytes.NewReader([]byte("pw123")))
+		r.Header.Set("Content-Type", ContentTypeAMF)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Body.String() != "pw123" {
+			t.Fatalf("expect the body passed through got %q", w.Body.String())
+		}
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(recs) != 2 {
+		t.Fatalf("expect 2 recordings got %d", len(recs))
+	}
+	rec := recs[0]
+	if string(rec.Request) != "**12" || string(rec.Response) != "**12" || !rec.Truncated {
+		t.Errorf("expect redacted, truncated bodies got %+v", rec)
+	}
+	if rec.StatusCode != http.StatusOK || rec.URL != "/gateway" {
+		t.Errorf("expect 200 for /gateway got %+v", rec)
+	}
+}
@@ -0,0 +1,112 @@
+package amf
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Middleware wraps a ServiceFunc, e.g. to check credentials or log calls
+// before passing them on to next.
+type Middleware func(next ServiceFunc) ServiceFunc
+
+// Router maps the target URIs of calls to services by pattern, for service
+// surfaces too large to register one URI at a time. A pattern is an exact
+// URI such as "UserService.find", or a prefix followed by "*" such as
+// "UserService.*", matching every URI that starts with the prefix. Regular
+// expressions are added with HandleRegexp.
+//
+// An exact URI wins over prefixes, a longer prefix over a shorter one, and
+// prefixes over regular expressions, which are tried in the order they
+// were added. Calls matching no route go to the fallback, if any.
+type Router struct {
+	mu       sync.RWMutex
+	exact    map[string]ServiceFunc
+	prefixes []prefixRoute // longest first
+	regexps  []regexpRoute
+	fallback ServiceFunc
+}
+
+type prefixRoute struct {
+	prefix string
+	fn     ServiceFunc
+}
+
+type regexpRoute struct {
+	re *regexp.Regexp
+	fn ServiceFunc
+}
+
+func NewRouter() *Router {
+	return &Router{exact: make(map[string]ServiceFunc)}
+}
+
+// Handle routes the target URIs matching pattern to fn wrapped in mw, the
+// first middleware outermost. It replaces any route added before for the
+// same pattern.
+func (rt *Router) Handle(pattern string, fn ServiceFunc, mw ...Middleware) {
+	fn = chain(fn, mw)
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	prefix, ok := strings.CutSuffix(pattern, "*")
+	if !ok {
+		rt.exact[pattern] = fn
+		return
+	}
+	for i := range rt.prefixes {
+		if rt.prefixes[i].prefix == prefix {
+			rt.prefixes[i].fn = fn
+			return
+		}
+	}
+	rt.prefixes = append(rt.prefixes, prefixRoute{prefix: prefix, fn: fn})
+	sort.SliceStable(rt.prefixes, func(i, j int) bool {
+		return len(rt.prefixes[i].prefix) > len(rt.prefixes[j].prefix)
+	})
+}
+
+// HandleRegexp routes the target URIs matched by re to fn wrapped in mw.
+func (rt *Router) HandleRegexp(re *regexp.Regexp, fn ServiceFunc, mw ...Middleware) {
+	fn = chain(fn, mw)
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.regexps = append(rt.regexps, regexpRoute{re: re, fn: fn})
+}
+
+// Fallback serves the calls matching no route with fn wrapped in mw.
+func (rt *Router) Fallback(fn ServiceFunc, mw ...Middleware) {
+	fn = chain(fn, mw)
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.fallback = fn
+}
+
+// Lookup returns the service for target, or nil if no route matches and
+// there is no fallback.
+func (rt *Router) Lookup(target string) ServiceFunc {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	if fn := rt.exact[target]; fn != nil {
+		return fn
+	}
+	for _, route := range rt.prefixes {
+		if strings.HasPrefix(target, route.prefix) {
+			return route.fn
+		}
+	}
+	for _, route := range rt.regexps {
+		if route.re.MatchString(target) {
+			return route.fn
+		}
+	}
+	return rt.fallback
+}
+
+// chain wraps fn in mw, the first middleware outermost.
+func chain(fn ServiceFunc, mw []Middleware) ServiceFunc {
+	for i := len(mw) - 1; i >= 0; i-- {
+		fn = mw[i](fn)
+	}
+	return fn
+}
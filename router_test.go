@@ -0,0 +1,60 @@
+package amf
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	named := func(name string) ServiceFunc {
+		return func(call *Call) (interface{}, error) { return name, nil }
+	}
+	var trace []string
+	logged := func(tag string) Middleware {
+		return func(next ServiceFunc) ServiceFunc {
+			return func(call *Call) (interface{}, error) {
+				trace = append(trace, tag)
+				return next(call)
+			}
+		}
+	}
+	rt := NewRouter()
+	rt.Handle("UserService.find", named("exact"))
+	rt.Handle("UserService.*", named("users"), logged("outer"), logged("inner"))
+	rt.Handle("UserService.admin.*", named("admin"))
+	rt.Handle("*", named("catch-all"))
+	rt.HandleRegexp(regexp.MustCompile(`^Legacy\d+\.`), named("legacy"))
+	rt.Fallback(named("fallback"))
+
+	for target, expect := range map[string]string{
+		"UserService.find":       "exact",
+		"UserService.save":       "users",
+		"UserService.admin.drop": "admin",
+		"Legacy2.call":           "catch-all",
+	} {
+		fn := rt.Lookup(target)
+		if fn == nil {
+			t.Fatalf("expect a route for %s", target)
+		}
+		got, _ := fn(&Call{Target: target})
+		if got != expect {
+			t.Errorf("expect %s for %s got %v", expect, target, got)
+		}
+	}
+	if len(trace) != 2 || trace[0] != "outer" || trace[1] != "inner" {
+		t.Errorf("expect the middleware outer first got %v", trace)
+	}
+
+	rt = NewRouter()
+	rt.HandleRegexp(regexp.MustCompile(`^Legacy\d+\.`), named("legacy"))
+	if fn := rt.Lookup("Other.call"); fn != nil {
+		t.Errorf("expect no route without a fallback")
+	}
+	rt.Fallback(named("fallback"))
+	for target, expect := range map[string]string{"Legacy2.call": "legacy", "Other.call": "fallback"} {
+		got, _ := rt.Lookup(target)(&Call{Target: target})
+		if got != expect {
+			t.Errorf("expect %s for %s got %v", expect, target, got)
+		}
+	}
+}
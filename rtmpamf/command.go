@@ -0,0 +1,105 @@
+// Package rtmpamf reads and writes the AMF0 bodies of RTMP command
+// messages.
+package rtmpamf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// Command is the body of an RTMP command message: the command name, a
+// transaction ID and a command object, followed by the arguments.
+type Command struct {
+	Name          string
+	TransactionID float64
+	Object        interface{} // usually null
+	Args          []interface{}
+}
+
+// DecodeCommand decodes a command message body. Peers are loose about
+// everything after the name, so a missing transaction ID reads as 0 and a
+// missing command object as nil. A value after the name that is not a
+// number is taken as the command object, not as the transaction ID.
+func DecodeCommand(data []byte) (*Command, error) {
+	dec := amf0.NewDecoder(bytes.NewReader(data))
+	v, err := dec.Decode()
+	if err == io.EOF {
+		return nil, errors.New("command has no name")
+	}
+	if err != nil {
+		return nil, err
+	}
+	name, ok := stringValue(v)
+	if !ok {
+		return nil, errors.New("command name is not a string")
+	}
+	c := &Command{Name: name}
+	var values []interface{}
+	for {
+		v, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	if len(values) > 0 {
+		if id, ok := numberValue(values[0]); ok {
+			c.TransactionID = id
+			values = values[1:]
+		}
+	}
+	if len(values) > 0 {
+		c.Object = values[0]
+		values = values[1:]
+	}
+	c.Args = values
+	return c, nil
+}
+
+// MarshalBinary encodes c as a command message body.
+func (c *Command) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := amf0.NewEncoder(&buf)
+	for _, v := range append([]interface{}{amf0.StringType(c.Name), amf0.NumberType(c.TransactionID), c.Object}, c.Args...) {
+		err := enc.Encode(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func stringValue(v interface{}) (string, bool) {
+	switch value := v.(type) {
+	case amf0.StringType:
+		return string(value), true
+	case amf0.LongStringType:
+		return string(value), true
+	case amf3.StringType:
+		return string(value), true
+	}
+	return "", false
+}
+
+func numberValue(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case amf0.NumberType:
+		return float64(value), true
+	case amf3.DoubleType:
+		return float64(value), true
+	case amf3.IntegerType:
+		return float64(value), true
+	case amf0.StringType:
+		f, err := strconv.ParseFloat(string(value), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
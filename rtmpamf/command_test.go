@@ -0,0 +1,81 @@
+package rtmpamf
+
+import (
+	"testing"
+)
+
+func TestStreamCommandRoundTrip(t *testing.T) {
+	data, err := NewStreamCommand(ReleaseStream, 2, "live?key").MarshalBinary()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	c, err := DecodeCommand(data)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if c.Name != ReleaseStream || c.TransactionID != 2 {
+		t.Fatalf("expect releaseStream 2 got %v %v", c.Name, c.TransactionID)
+	}
+	if name, ok := c.StreamName(); !ok || name != "live?key" {
+		t.Fatalf("expect live?key got %v", name)
+	}
+}
+
+func TestLooseStreamCommand(t *testing.T) {
+	// FCPublish with the transaction ID as a string and no stream name
+	data := []byte{0x02, 0x00, 0x09, 'F', 'C', 'P', 'u', 'b', 'l', 'i', 's', 'h',
+		0x02, 0x00, 0x01, '7'}
+	c, err := DecodeCommand(data)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if c.TransactionID != 7 {
+		t.Fatalf("expect transaction ID from string got %v", c.TransactionID)
+	}
+	if _, ok := c.StreamName(); ok {
+		t.Fatalf("expect no stream name got %+v", c)
+	}
+
+	// FCUnpublish with the stream name in place of the command object
+	data = []byte{0x02, 0x00, 0x0b, 'F', 'C', 'U', 'n', 'p', 'u', 'b', 'l', 'i', 's', 'h',
+		0x00, 0, 0, 0, 0, 0, 0, 0, 0,
+		0x02, 0x00, 0x03, 'c', 'a', 'm'}
+	c, err = DecodeCommand(data)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if name, ok := c.StreamName(); !ok || name != "cam" {
+		t.Fatalf("expect cam from command object got %v", name)
+	}
+}
+
+func TestFCStatus(t *testing.T) {
+	data, err := NewFCStatus(OnFCPublish, "NetStream.Publish.Start", "cam").MarshalBinary()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	c, err := DecodeCommand(data)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	code, description, ok := c.Status()
+	if !ok || code != "NetStream.Publish.Start" || description != "cam" {
+		t.Fatalf("expect status got %v %v %v", code, description, ok)
+	}
+}
+
+func TestCommandWithoutTransactionID(t *testing.T) {
+	// releaseStream with the stream name right after the command name
+	data := []byte{0x02, 0x00, 0x0d, 'r', 'e', 'l', 'e', 'a', 's', 'e', 'S', 't', 'r', 'e', 'a', 'm',
+		0x02, 0x00, 0x04, 'n', 'a', 'm', 'e'}
+	c, err := DecodeCommand(data)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if c.TransactionID != 0 {
+		t.Fatalf("expect no transaction ID got %v", c.TransactionID)
+	}
+	if name, ok := c.StreamName(); !ok || name != "name" {
+		t.Fatalf("expect name got %v", name)
+	}
+}
@@ -0,0 +1,87 @@
+package rtmpamf
+
+import (
+	"strconv"
+
+	"github.com/marcuswu/amf/amf0"
+)
+
+// The vendor commands sent around publishing by Flash Media Server clients
+// and kept by OBS, FFmpeg and most CDNs.
+const (
+	ReleaseStream = "releaseStream"
+	FCPublish     = "FCPublish"
+	FCUnpublish   = "FCUnpublish"
+	OnFCPublish   = "onFCPublish"
+	OnFCUnpublish = "onFCUnpublish"
+)
+
+// NewStreamCommand returns a releaseStream, FCPublish or FCUnpublish
+// command for stream, in the form FFmpeg sends it: a null command object
+// followed by the stream name.
+func NewStreamCommand(name string, transactionID float64, stream string) *Command {
+	return &Command{Name: name, TransactionID: transactionID, Args: []interface{}{amf0.StringType(stream)}}
+}
+
+// NewFCStatus returns an onFCPublish or onFCUnpublish command carrying a
+// status object with code and description, sent with transaction ID 0.
+func NewFCStatus(name, code, description string) *Command {
+	info := &amf0.ObjectType{
+		"code":        amf0.StringType(code),
+		"description": amf0.StringType(description),
+	}
+	return &Command{Name: name, Args: []interface{}{info}}
+}
+
+// StreamName returns the stream name of a releaseStream, FCPublish or
+// FCUnpublish command. It is normally the first argument, but some clients
+// send it as the command object, as a number, or inside an object under
+// "streamName" or "name".
+func (c *Command) StreamName() (string, bool) {
+	candidates := append([]interface{}{}, c.Args...)
+	candidates = append(candidates, c.Object)
+	for _, v := range candidates {
+		if name, ok := streamName(v); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func streamName(v interface{}) (string, bool) {
+	if s, ok := stringValue(v); ok {
+		return s, s != ""
+	}
+	if f, ok := v.(amf0.NumberType); ok {
+		return strconv.FormatFloat(float64(f), 'f', -1, 64), true
+	}
+	if obj, ok := v.(*amf0.ObjectType); ok {
+		for _, key := range []amf0.StringType{"streamName", "name"} {
+			if s, ok := stringValue((*obj)[key]); ok && s != "" {
+				return s, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Status returns the code and description of an onFCPublish or
+// onFCUnpublish command, found in its first object argument or, from some
+// servers, in the command object.
+func (c *Command) Status() (code, description string, ok bool) {
+	candidates := append([]interface{}{}, c.Args...)
+	candidates = append(candidates, c.Object)
+	for _, v := range candidates {
+		obj, isObj := v.(*amf0.ObjectType)
+		if !isObj {
+			continue
+		}
+		code, ok = stringValue((*obj)["code"])
+		if !ok {
+			continue
+		}
+		description, _ = stringValue((*obj)["description"])
+		return code, description, true
+	}
+	return "", "", false
+}
@@ -0,0 +1,300 @@
+package amf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// Schema infers the structure of decoded values: the objects seen, which
+// properties they carry, the types found in each property and whether the
+// property was always present. It can write the result as Go struct
+// definitions. A Schema is safe for concurrent use.
+type Schema struct {
+	mu      sync.Mutex
+	classes map[string]*ClassSchema
+}
+
+// ClassSchema describes one kind of object: a typed object class, or an
+// anonymous object named after where it was found.
+type ClassSchema struct {
+	Name      string // class name, or a name derived from the location
+	Typed     bool   // whether Name is an ActionScript class name
+	Samples   int
+	Fields    map[string]*FieldSchema
+	fieldList []string
+}
+
+// FieldSchema describes one property of an object.
+type FieldSchema struct {
+	Name    string
+	Present int // number of samples carrying the property
+	Shape   *Shape
+}
+
+// Shape collects what was seen in one place of the value tree.
+type Shape struct {
+	Kinds map[string]int // value kind, e.g. "number" or "object", to count
+	Class *ClassSchema   // for objects
+	Elem  *Shape         // for arrays
+}
+
+func NewSchema() *Schema {
+	return &Schema{classes: make(map[string]*ClassSchema)}
+}
+
+// Observe records v, naming anonymous objects after name.
+func (s *Schema) Observe(name string, v interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.observe(newShape(), goName(name), v, make(map[interface{}]bool))
+}
+
+// Classes returns the object schemas seen so far, sorted by name.
+func (s *Schema) Classes() []*ClassSchema {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	classes := make([]*ClassSchema, 0, len(s.classes))
+	for _, c := range s.classes {
+		classes = append(classes, c)
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i].Name < classes[j].Name })
+	return classes
+}
+
+func newShape() *Shape {
+	return &Shape{Kinds: make(map[string]int)}
+}
+
+func (s *Schema) class(name string, typed bool) *ClassSchema {
+	c := s.classes[name]
+	if c == nil {
+		c = &ClassSchema{Name: name, Typed: typed, Fields: make(map[string]*FieldSchema)}
+		s.classes[name] = c
+	}
+	return c
+}
+
+func (s *Schema) observe(sh *Shape, hint string, v interface{}, seen map[interface{}]bool) {
+	kind := kindName(v)
+	sh.Kinds[kind]++
+	if id := identity(v); id != nil {
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+	}
+	switch kind {
+	case "object":
+		name, typed := className(v)
+		if name == "" {
+			name = hint
+		}
+		c := s.class(name, typed)
+		if sh.Class == nil {
+			sh.Class = c
+		}
+		c.Samples++
+		forEachChild(v, func(elem string, child interface{}) {
+			prop := elem[1:]
+			f := c.Fields[prop]
+			if f == nil {
+				f = &FieldSchema{Name: prop, Shape: newShape()}
+				c.Fields[prop] = f
+				c.fieldList = append(c.fieldList, prop)
+			}
+			f.Present++
+			s.observe(f.Shape, name+goName(prop), child, seen)
+		})
+	case "array":
+		if sh.Elem == nil {
+			sh.Elem = newShape()
+		}
+		forEachChild(v, func(elem string, child interface{}) {
+			if elem[0] == '[' {
+				s.observe(sh.Elem, hint+"Item", child, seen)
+			}
+		})
+	}
+}
+
+// kindName classifies a decoded value for schema purposes.
+func kindName(v interface{}) string {
+	switch k := KindOf(v); k {
+	case TypedObject:
+		return Object.String()
+	case Unsupported:
+		return Unknown.String()
+	default:
+		return k.String()
+	}
+}
+
+func className(v interface{}) (string, bool) {
+	switch value := v.(type) {
+	case *amf0.TypedObjectType:
+		return string(value.ClassName), value.ClassName != ""
+	case *amf3.ObjectType:
+		if value.Trait != nil && value.Trait.ClassName != "" {
+			return string(value.Trait.ClassName), true
+		}
+	}
+	return "", false
+}
+
+// WriteGo writes Go struct definitions for every object seen. Properties
+// missing from some samples are tagged omitempty; objects that were
+// sometimes null become pointers.
+func (s *Schema) WriteGo(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	classes := s.Classes()
+	names := goTypeNames(classes)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, c := range classes {
+		if i > 0 {
+			fmt.Fprintln(bw)
+		}
+		if c.Typed {
+			fmt.Fprintf(bw, "// %s is the ActionScript class %s.\n", names[c], c.Name)
+		}
+		fmt.Fprintf(bw, "type %s struct {\n", names[c])
+		props := append([]string(nil), c.fieldList...)
+		sort.Strings(props)
+		fieldNames := make(map[string]bool)
+		for _, prop := range props {
+			f := c.Fields[prop]
+			tag := prop
+			if f.Present < c.Samples {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(bw, "\t%s %s `amf:%q`\n", uniqueName(goName(prop), fieldNames), goType(f.Shape, names), tag)
+		}
+		fmt.Fprintln(bw, "}")
+	}
+	return bw.Flush()
+}
+
+// WriteRegistrations writes an init function registering the struct types
+// written by WriteGo for every typed object class seen.
+func (s *Schema) WriteRegistrations(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	classes := s.Classes()
+	names := goTypeNames(classes)
+	fmt.Fprintln(bw, "func init() {")
+	for _, c := range classes {
+		if !c.Typed {
+			continue
+		}
+		fmt.Fprintf(bw, "\tif err := amf0.RegisterClass(amf0.ClassMapping{ClassName: %q, Type: reflect.TypeOf(%s{})}); err != nil {\n", c.Name, names[c])
+		fmt.Fprintln(bw, "\t\tpanic(err)")
+		fmt.Fprintln(bw, "\t}")
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// goTypeNames picks a distinct Go type name for every class.
+func goTypeNames(classes []*ClassSchema) map[*ClassSchema]string {
+	names := make(map[*ClassSchema]string)
+	used := make(map[string]bool)
+	for _, c := range classes {
+		names[c] = uniqueName(goTypeName(c.Name), used)
+	}
+	return names
+}
+
+// goType picks a Go type able to hold everything seen in sh.
+func goType(sh *Shape, names map[*ClassSchema]string) string {
+	var kinds []string
+	nullable := false
+	for kind := range sh.Kinds {
+		if kind == "null" || kind == "undefined" {
+			nullable = true
+			continue
+		}
+		kinds = append(kinds, kind)
+	}
+	if len(kinds) == 2 && sh.Kinds["number"] > 0 && sh.Kinds["integer"] > 0 {
+		kinds = []string{"number"}
+	}
+	if len(kinds) != 1 {
+		return "interface{}"
+	}
+	switch kinds[0] {
+	case "number":
+		return "float64"
+	case "integer":
+		return "int32"
+	case "boolean":
+		return "bool"
+	case "string", "xml":
+		return "string"
+	case "date":
+		return "time.Time"
+	case "bytearray":
+		return "[]byte"
+	case "ecma":
+		return "map[string]interface{}"
+	case "array":
+		if sh.Elem == nil {
+			return "[]interface{}"
+		}
+		return "[]" + goType(sh.Elem, names)
+	case "object":
+		if sh.Class == nil {
+			return "interface{}"
+		}
+		if nullable {
+			return "*" + names[sh.Class]
+		}
+		return names[sh.Class]
+	}
+	return "interface{}"
+}
+
+// goTypeName turns a class name such as com.example.User into User.
+func goTypeName(name string) string {
+	if i := strings.LastIndexAny(name, ".:"); i >= 0 && i < len(name)-1 {
+		name = name[i+1:]
+	}
+	return goName(name)
+}
+
+// goName turns a property name into an exported Go identifier.
+func goName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	s := b.String()
+	if s == "" || !unicode.IsLetter([]rune(s)[0]) {
+		s = "X" + s
+	}
+	return s
+}
+
+func uniqueName(name string, used map[string]bool) string {
+	n := name
+	for i := 2; used[n]; i++ {
+		n = fmt.Sprintf("%s%d", name, i)
+	}
+	used[n] = true
+	return n
+}
@@ -0,0 +1,46 @@
+package amf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/marcuswu/amf/amf0"
+)
+
+func TestSchemaWriteGo(t *testing.T) {
+	s := NewSchema()
+	first := &amf0.TypedObjectType{ClassName: "com.example.User", Object: map[amf0.StringType]interface{}{
+		"name":  amf0.StringType("a"),
+		"age":   amf0.NumberType(3),
+		"tags":  &amf0.StrictArrayType{amf0.StringType("x")},
+		"owner": amf0.NullType{},
+	}}
+	group := amf0.ObjectType{"id": amf0.NumberType(1)}
+	second := &amf0.TypedObjectType{ClassName: "com.example.User", Object: map[amf0.StringType]interface{}{
+		"name":  amf0.StringType("b"),
+		"owner": &group,
+	}}
+	s.Observe("getUser", first)
+	s.Observe("getUser", second)
+
+	var buf bytes.Buffer
+	err := s.WriteGo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := buf.String()
+	for _, line := range []string{
+		"type User struct {",
+		"\tAge float64 `amf:\"age,omitempty\"`",
+		"\tName string `amf:\"name\"`",
+		"\tOwner *UserOwner `amf:\"owner\"`",
+		"\tTags []string `amf:\"tags,omitempty\"`",
+		"type UserOwner struct {",
+		"\tId float64 `amf:\"id\"`",
+	} {
+		if !strings.Contains(src, line+"\n") {
+			t.Errorf("expected generated source to contain %q, got:\n%s", line, src)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package amf
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/marcuswu/amf/amf0"
+)
+
+var (
+	typeOfError = reflect.TypeOf((*error)(nil)).Elem()
+	typeOfCall  = reflect.TypeOf((*Call)(nil))
+)
+
+// RegisterService serves the exported methods of rcvr, as net/rpc does. A
+// method M is served for the target URIs name.M and name.m, m being M with
+// its first letter in lower case as ActionScript callers spell it.
+//
+// The arguments of a call are converted to the parameter types of the
+// method as by amf0.Unmarshal. A method may take a *Call as its first
+// parameter, and may return a result, an error, or both, the error coming
+// last. Methods of any other form are ignored; RegisterService fails when
+// none are left.
+func (g *GatewayHandler) RegisterService(name string, rcvr interface{}) error {
+	if name == "" {
+		return errors.New("service name is empty")
+	}
+	v := reflect.ValueOf(rcvr)
+	if !v.IsValid() {
+		return errors.New("service " + name + " is nil")
+	}
+	registered := 0
+	for i := 0; i < v.NumMethod(); i++ {
+		m := v.Type().Method(i)
+		if !m.IsExported() {
+			continue
+		}
+		fn := serviceMethod(v.Method(i))
+		if fn == nil {
+			continue
+		}
+		g.Handle(name+"."+m.Name, fn)
+		r, size := utf8.DecodeRuneInString(m.Name)
+		if lower := string(unicode.ToLower(r)) + m.Name[size:]; lower != m.Name {
+			g.Handle(name+"."+lower, fn)
+		}
+		registered++
+	}
+	if registered == 0 {
+		return errors.New("service " + name + " has no suitable methods")
+	}
+	return nil
+}
+
+// serviceMethod returns a ServiceFunc calling method, or nil if method
+// cannot be served.
+func serviceMethod(method reflect.Value) ServiceFunc {
+	t := method.Type()
+	if t.IsVariadic() {
+		return nil
+	}
+	withCall := t.NumIn() > 0 && t.In(0) == typeOfCall
+	first := 0
+	if withCall {
+		first = 1
+	}
+	withResult, withError := false, false
+	switch t.NumOut() {
+	case 0:
+	case 1:
+		withError = t.Out(0) == typeOfError
+		withResult = !withError
+	case 2:
+		if t.Out(1) != typeOfError {
+			return nil
+		}
+		withResult, withError = true, true
+	default:
+		return nil
+	}
+	return func(call *Call) (interface{}, error) {
+		if len(call.Args) != t.NumIn()-first {
+			return nil, &Fault{
+				Code:        "Server.Processing",
+				Description: call.Target + " takes " + strconv.Itoa(t.NumIn()-first) + " arguments, got " + strconv.Itoa(len(call.Args)),
+			}
+		}
+		in := make([]reflect.Value, t.NumIn())
+		if withCall {
+			in[0] = reflect.ValueOf(call)
+		}
+		for i, arg := range call.Args {
+			p := reflect.New(t.In(first + i))
+			err := amf0.Assign(p.Interface(), arg)
+			if err != nil {
+				return nil, &Fault{
+					Code:        "Server.Processing",
+					Description: "argument " + strconv.Itoa(i) + " of " + call.Target + ": " + err.Error(),
+				}
+			}
+			in[first+i] = p.Elem()
+		}
+		out := method.Call(in)
+		var result interface{}
+		if withResult {
+			result = out[0].Interface()
+		}
+		if withError {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+}
@@ -0,0 +1,88 @@
+package amf
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+type sniffResult struct {
+	ok       bool // every byte was consumed by whole values
+	values   int  // number of complete values decoded
+	consumed int  // bytes consumed by complete values
+}
+
+// Sniff guesses whether data holds AMF0 or AMF3 encoded values. It returns
+// the most likely version (AMF0 or AMF3) and a confidence between 0 and 1.
+// A confidence of 0 means neither dialect could make sense of the data.
+func Sniff(data []byte) (version int, confidence float32) {
+	if len(data) == 0 {
+		return AMF0, 0
+	}
+
+	// Markers above the AMF0 avmplus marker, which is also the AMF3
+	// Dictionary marker, are not valid in either dialect.
+	if data[0] > amf0.SwitchToAmf3Marker {
+		return AMF0, 0
+	}
+
+	r0 := sniffValues(data, func(r io.Reader) func() (interface{}, error) {
+		return amf0.NewDecoder(r).Decode
+	})
+	r3 := sniffValues(data, func(r io.Reader) func() (interface{}, error) {
+		return amf3.NewDecoder(r).Decode
+	})
+
+	s0 := sniffScore(r0, len(data))
+	s3 := sniffScore(r3, len(data))
+	if s3 > s0 {
+		return AMF3, s3 - s0/2
+	}
+	return AMF0, s0 - s3/2
+}
+
+// sniffValues decodes values from data until it is exhausted or a value
+// fails to decode.
+func sniffValues(data []byte, newDecode func(io.Reader) func() (interface{}, error)) (res sniffResult) {
+	src := bytes.NewReader(data)
+	br := bufio.NewReader(src)
+	decode := newDecode(br)
+
+	// Malformed input can drive the decoders into states they do not guard
+	// against; for sniffing that simply means the dialect does not match.
+	defer func() {
+		if recover() != nil {
+			res.ok = false
+		}
+	}()
+
+	for {
+		v, err := decode()
+		if err != nil || v == nil {
+			return
+		}
+		res.values++
+		res.consumed = len(data) - src.Len() - br.Buffered()
+		if res.consumed == len(data) {
+			res.ok = true
+			return
+		}
+	}
+}
+
+func sniffScore(res sniffResult, total int) float32 {
+	if !res.ok {
+		// Partial matches are weak evidence, proportional to how far the
+		// decoder got before giving up.
+		return 0.4 * float32(res.consumed) / float32(total)
+	}
+	// Blobs almost always hold a single value; a dialect that needs many
+	// values to explain the same bytes is the less likely one.
+	if res.values == 1 {
+		return 0.95
+	}
+	return 0.5 + 0.4/float32(res.values)
+}
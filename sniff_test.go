@@ -0,0 +1,33 @@
+package amf
+
+import (
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		data    []byte
+		version int
+	}{
+		{[]byte{0x02, 0x00, 0x03, 'f', 'o', 'o'}, AMF0},
+		{[]byte{0x03, 0x00, 0x03, 'f', 'o', 'o', 0x02, 0x00, 0x03, 'b', 'a', 'r', 0x00, 0x00, 0x09}, AMF0},
+		{[]byte{0x11, 0x06, 0x07, 'f', 'o', 'o'}, AMF0},
+		{[]byte{0x06, 0x07, 'f', 'o', 'o'}, AMF3},
+		{[]byte{0x09, 0x05, 0x01, 0x04, 0x01, 0x04, 0x02}, AMF3},
+		{[]byte{0x11, 0x05, 0x00, 0x04, 0x01, 0x06, 0x03, 'a', 0x06, 0x00, 0x01}, AMF3},
+	}
+	for _, test := range tests {
+		version, confidence := Sniff(test.data)
+		if version != test.version {
+			t.Errorf("sniff %x: expect version %d got %d", test.data, test.version, version)
+		}
+		if confidence <= 0.5 {
+			t.Errorf("sniff %x: expect confidence above 0.5 got %v", test.data, confidence)
+		}
+	}
+
+	_, confidence := Sniff([]byte{0xff, 0x00})
+	if confidence != 0 {
+		t.Errorf("expect confidence 0 for garbage, got %v", confidence)
+	}
+}
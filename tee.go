@@ -0,0 +1,78 @@
+package amf
+
+import (
+	"io"
+)
+
+// SinkPolicy tells a TeeWriter what to do when writing to a sink fails.
+type SinkPolicy int
+
+const (
+	// FailWrite fails the write with the sink's error. The bytes have
+	// reached the primary writer by then.
+	FailWrite SinkPolicy = iota
+	// DropSink stops writing to the sink and carries on without it.
+	DropSink
+	// IgnoreErrors keeps writing to the sink after it fails.
+	IgnoreErrors
+)
+
+// Sink is a writer receiving a copy of the bytes of a TeeWriter.
+type Sink struct {
+	W      io.Writer
+	Policy SinkPolicy
+	// OnError, if set, is told of every error of the sink, whatever the
+	// policy.
+	OnError func(err error)
+
+	dropped bool
+}
+
+// TeeWriter writes to a primary writer, usually the connection, and copies
+// what the primary writer accepted to sinks, e.g. for audit capture. Give
+// it to NewEncoder or the value encoders so that the sinks see the exact
+// bytes sent. A TeeWriter is not safe for concurrent use.
+type TeeWriter struct {
+	w     io.Writer
+	sinks []*Sink
+}
+
+// NewTeeWriter returns a writer to w that copies to sinks.
+func NewTeeWriter(w io.Writer, sinks ...*Sink) *TeeWriter {
+	return &TeeWriter{w: w, sinks: sinks}
+}
+
+// Write writes p to the primary writer, then what it accepted to each sink
+// in turn. An error of the primary writer is returned after the sinks are
+// written; the first error of a sink with policy FailWrite is returned
+// unless the primary writer failed, and skips the remaining sinks.
+func (t *TeeWriter) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	for _, s := range t.sinks {
+		if s.dropped || n == 0 {
+			continue
+		}
+		_, sinkErr := s.W.Write(p[:n])
+		if sinkErr == nil {
+			continue
+		}
+		if s.OnError != nil {
+			s.OnError(sinkErr)
+		}
+		switch s.Policy {
+		case FailWrite:
+			if err == nil {
+				return n, sinkErr
+			}
+			return n, err
+		case DropSink:
+			s.dropped = true
+		}
+	}
+	return n, err
+}
+
+// Dropped reports whether s was dropped after failing.
+func (s *Sink) Dropped() bool {
+	return s.dropped
+}
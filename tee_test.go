@@ -0,0 +1,34 @@
+package amf
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/marcuswu/amf/amftest"
+)
+
+func TestTeeWriter(t *testing.T) {
+	var conn, audit bytes.Buffer
+	var errs []error
+	dropped := &Sink{W: &amftest.FailingWriter{}, Policy: DropSink, OnError: func(err error) { errs = append(errs, err) }}
+	w := NewTeeWriter(&conn, dropped, &Sink{W: &audit})
+	p := NewPacket(0, 0)
+	p.AddMessage(NewMessage("a", "/1", "b"))
+	for i := 0; i < 2; i++ {
+		err := NewEncoder(w).Encode(p)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	if !bytes.Equal(conn.Bytes(), audit.Bytes()) || conn.Len() == 0 {
+		t.Fatalf("expect the audit sink to see %x got %x", conn.Bytes(), audit.Bytes())
+	}
+	if !dropped.Dropped() || len(errs) != 1 {
+		t.Fatalf("expect the failing sink to be dropped after one error, got %v", errs)
+	}
+
+	w = NewTeeWriter(&conn, &Sink{W: &amftest.FailingWriter{}})
+	if _, err := w.Write([]byte{1}); err == nil {
+		t.Fatalf("expect the sink error with FailWrite")
+	}
+}
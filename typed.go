@@ -0,0 +1,14 @@
+package amf
+
+import (
+	"github.com/marcuswu/amf/amf0"
+)
+
+// Typed returns v, a struct, a pointer to one or a map with string keys,
+// wrapped so that it encodes as an AMF0 typed object of class className,
+// without registering a class for its type, e.g.
+//
+//	amf.Typed("com.example.Ping", struct{ TS int64 }{ts})
+func Typed(className string, v interface{}) amf0.TypedValue {
+	return amf0.TypedValue{ClassName: className, Value: v}
+}
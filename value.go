@@ -0,0 +1,45 @@
+package amf
+
+import (
+	"io"
+
+	"github.com/marcuswu/amf/amf0"
+)
+
+// RawMessage is the encoding of one AMF0 value, kept as read, which
+// encodes verbatim. Message bodies decode as RawMessages with the
+// DecoderOptions RawBodies, so that a gateway can route a packet on its
+// headers and target URIs and pass the bodies on undecoded.
+type RawMessage = amf0.RawMessage
+
+// DecodeValue decodes the next AMF0 value of r, one with no packet around
+// it such as an RTMP command argument. Readers that are not io.ByteReaders
+// are buffered, so r may be read past the value; use a Decoder's ReadValue
+// to read several values in turn.
+func DecodeValue(r io.Reader) (interface{}, error) {
+	return amf0.NewDecoder(r).Decode()
+}
+
+// EncodeValue writes the AMF0 encoding of v to w with no packet around it,
+// as RTMP commands and FLV script tags carry values.
+func EncodeValue(w io.Writer, v interface{}) error {
+	return amf0.NewEncoder(w).Encode(v)
+}
+
+// SkipValue consumes the next AMF0 value of the stream, one with no packet
+// around it, without building it.
+func (dec *Decoder) SkipValue() error {
+	values := dec.valueDecoder()
+	values.ResetValueCount()
+	return values.SkipValue()
+}
+
+// ReadValue decodes the next AMF0 value of the stream, one with no packet
+// around it, with the options of dec. Values and packets may be read in
+// any order; unless SharedReferences is set, every value starts with an
+// empty reference table. ValueTimeout bounds the time spent on each value,
+// and MaxValueCount the values nested in each.
+func (dec *Decoder) ReadValue() (interface{}, error) {
+	dec.valueDecoder().ResetValueCount()
+	return dec.decodeBody(UnknownLength, "", "", false)
+}
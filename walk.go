@@ -0,0 +1,114 @@
+package amf
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/marcuswu/amf/amf0"
+	"github.com/marcuswu/amf/amf3"
+)
+
+// forEachChild calls fn for every value directly contained in v, which may be
+// any of the AMF0 or AMF3 container types. elem describes how the child is
+// reached from v, e.g. ".name", "[2]" or, for the entries of a dictionary,
+// "[2].key" and "[2].value". Object properties are visited in name order so
+// that walks are deterministic.
+func forEachChild(v interface{}, fn func(elem string, child interface{})) {
+	switch value := v.(type) {
+	case *amf0.ObjectType:
+		forEachProperty0(map[amf0.StringType]interface{}(*value), fn)
+	case amf0.ObjectType:
+		forEachProperty0(map[amf0.StringType]interface{}(value), fn)
+	case *amf0.EcmaArrayType:
+		forEachProperty0(map[amf0.StringType]interface{}(*value), fn)
+	case amf0.EcmaArrayType:
+		forEachProperty0(map[amf0.StringType]interface{}(value), fn)
+	case *amf0.TypedObjectType:
+		forEachProperty0(map[amf0.StringType]interface{}(value.Object), fn)
+	case *amf0.StrictArrayType:
+		forEachIndex(*value, fn)
+	case amf0.StrictArrayType:
+		forEachIndex(value, fn)
+	case *amf3.ArrayType:
+		forEachProperty3(value.Associative, fn)
+		forEachIndex(value.Dense, fn)
+	case *amf3.ObjectType:
+		if value.Trait != nil {
+			for i, name := range value.Trait.Attrs {
+				if i < len(value.Static) {
+					fn("."+string(name), value.Static[i])
+				}
+			}
+		}
+		forEachProperty3(value.Dynamic, fn)
+	case *amf3.VectorObjectType:
+		forEachIndex(value.Items, fn)
+	case *amf3.VectorIntType:
+		for i, item := range value.Items {
+			fn("["+strconv.Itoa(i)+"]", amf3.DoubleType(item))
+		}
+	case *amf3.VectorUintType:
+		for i, item := range value.Items {
+			fn("["+strconv.Itoa(i)+"]", amf3.DoubleType(item))
+		}
+	case *amf3.VectorDoubleType:
+		for i, item := range value.Items {
+			fn("["+strconv.Itoa(i)+"]", amf3.DoubleType(item))
+		}
+	case *amf3.DictionaryType:
+		for i, entry := range value.Entries {
+			fn("["+strconv.Itoa(i)+"].key", entry.Key)
+			fn("["+strconv.Itoa(i)+"].value", entry.Value)
+		}
+	}
+}
+
+func forEachProperty0(obj map[amf0.StringType]interface{}, fn func(string, interface{})) {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, string(k))
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fn("."+k, obj[amf0.StringType(k)])
+	}
+}
+
+func forEachProperty3(obj map[amf3.StringType]interface{}, fn func(string, interface{})) {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, string(k))
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fn("."+k, obj[amf3.StringType(k)])
+	}
+}
+
+func forEachIndex(array []interface{}, fn func(string, interface{})) {
+	for i, child := range array {
+		fn("["+strconv.Itoa(i)+"]", child)
+	}
+}
+
+// isContainer reports whether v can hold other values.
+func isContainer(v interface{}) bool {
+	switch v.(type) {
+	case *amf0.ObjectType, amf0.ObjectType, *amf0.EcmaArrayType, amf0.EcmaArrayType,
+		*amf0.TypedObjectType, *amf0.StrictArrayType, amf0.StrictArrayType,
+		*amf3.ArrayType, *amf3.ObjectType, *amf3.VectorIntType, *amf3.VectorUintType,
+		*amf3.VectorDoubleType, *amf3.VectorObjectType, *amf3.DictionaryType:
+		return true
+	}
+	return false
+}
+
+// identity returns a key identifying the container v for cycle detection,
+// or nil when v is not shared by reference.
+func identity(v interface{}) interface{} {
+	if v != nil && reflect.TypeOf(v).Kind() == reflect.Ptr {
+		return v
+	}
+	return nil
+}